@@ -193,7 +193,7 @@ func (a *Aggregate) GetUploadMetadataField(field string) string {
 
 // Apply event to the bulk upload
 func (a *Aggregate) Apply(evt gosignal.Event) error {
-	return sourcing.SafeApply(evt, a, a.routeEvent)
+	return sourcing.SafeApply(evt, a, upcasters.Apply(a.routeEvent))
 }
 
 func (a *Aggregate) ExportState() ([]byte, error) {
@@ -328,6 +328,10 @@ func (a *Aggregate) Create(cmd *eda.BulkUpload_Create) (*gosignal.Event, error)
 		if err := a.validateHealthAssessment(cmd); err != nil {
 			return nil, err
 		}
+	case eda.BulkUpload_ATTENDANCE:
+		if err := a.validateAttendance(cmd); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, errors.New("agg:create - invalid target domain")
 	}
@@ -355,6 +359,17 @@ func (a *Aggregate) validateHealthAssessment(cmd *eda.BulkUpload_Create) error {
 	}
 }
 
+func (a *Aggregate) validateAttendance(cmd *eda.BulkUpload_Create) error {
+	switch {
+	case cmd.UploadMetadata == nil:
+		return errors.New("upload metadata is required")
+	case cmd.UploadMetadata["school_id"] == "":
+		return errors.New("school id is required")
+	default:
+		return nil
+	}
+}
+
 func (a *Aggregate) validateNewStudents(cmd *eda.BulkUpload_Create) error {
 	switch {
 	case cmd.UploadMetadata == nil:
@@ -22,10 +22,13 @@ var migrations embed.FS
 
 type Repository interface {
 	loadBulkUpload(ctx context.Context, id string) (*Aggregate, error)
+	getEventHistory(ctx context.Context, id string) ([]gosignal.Event, error)
 	upsertProjection(upload *Aggregate) error
 	saveEvents(ctx context.Context, evts []gosignal.Event) error
 	listBulkUploads(ctx context.Context, limit, page uint) ([]sqlc.BulkUploadProjection, error)
 	countBulkUploads(ctx context.Context) (uint, error)
+	Projections() []infrastructure.Projection
+	EventStream() infrastructure.EventStream
 }
 
 type sqlRepository struct {
@@ -78,10 +81,30 @@ func (r *sqlRepository) loadBulkUpload(ctx context.Context, id string) (*Aggrega
 	return agg, nil
 }
 
+// getEventHistory returns the events of a bulk upload in version order
+func (r *sqlRepository) getEventHistory(ctx context.Context, id string) ([]gosignal.Event, error) {
+	return r.eventSourcing.LoadEvents(ctx, id, nil)
+}
+
 func (r *sqlRepository) upsertProjection(agg *Aggregate) error {
+	params, err := projectionParams(agg)
+	if err != nil {
+		return err
+	}
+
+	err = r.queries.UpsertBulkUploadProjection(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("failed to upsert bulk upload: %w", err)
+	}
+
+	return nil
+}
+
+// projectionParams maps a bulk upload aggregate to its projection row
+func projectionParams(agg *Aggregate) (sqlc.UpsertBulkUploadProjectionParams, error) {
 	metadata, err := json.Marshal(agg.data.UploadMetadata)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+		return sqlc.UpsertBulkUploadProjectionParams{}, fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
 	// Extract timestamps from status timestamps
@@ -107,7 +130,7 @@ func (r *sqlRepository) upsertProjection(agg *Aggregate) error {
 		initiatedAt = time.Now()
 	}
 
-	params := sqlc.UpsertBulkUploadProjectionParams{
+	return sqlc.UpsertBulkUploadProjectionParams{
 		ID:                      agg.ID,
 		Status:                  agg.data.Status.String(),
 		TargetDomain:            agg.data.TargetDomain.String(),
@@ -120,11 +143,53 @@ func (r *sqlRepository) upsertProjection(agg *Aggregate) error {
 		ProcessedRecords:        int64(agg.data.ProcessedRecords),
 		UploadMetadata:          string(metadata),
 		Version:                 int64(agg.Version),
+	}, nil
+}
+
+// Projections returns the bulk upload read model for the projector. The sqlc upsert is bound to the live
+// table, so rebuilds write through an equivalent statement that takes the table name.
+func (r *sqlRepository) Projections() []infrastructure.Projection {
+	return []infrastructure.Projection{{
+		Table:      "bulk_upload_projections",
+		EventTable: "bulk_upload_events",
+		Project: func(ctx context.Context, aggregateID string) (infrastructure.ProjectionWriter, error) {
+			agg, err := r.loadBulkUpload(ctx, aggregateID)
+			if err != nil {
+				return nil, err
+			}
+
+			params, err := projectionParams(agg)
+			if err != nil {
+				return nil, err
+			}
+
+			return func(ctx context.Context, tx *sql.Tx, table string) error {
+				return writeProjection(ctx, tx, table, params)
+			}, nil
+		},
+	}}
+}
+
+// EventStream describes the bulk upload events for the integrity check, uploads aren't snapshotted
+func (r *sqlRepository) EventStream() infrastructure.EventStream {
+	return infrastructure.EventStream{
+		EventTable: "bulk_upload_events",
+		New:        func() sourcing.Aggregate { return &Aggregate{} },
 	}
+}
 
-	err = r.queries.UpsertBulkUploadProjection(context.Background(), params)
+func writeProjection(ctx context.Context, tx *sql.Tx, table string, p sqlc.UpsertBulkUploadProjectionParams) error {
+	query := fmt.Sprintf(`INSERT OR REPLACE INTO %s (
+			id, status, target_domain, file_id, initiated_at, completed_at, invalidation_started_at,
+			invalidation_completed_at, total_records, processed_records, failed_records, upload_metadata, version,
+			updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))`, table)
+
+	_, err := tx.ExecContext(ctx, query, p.ID, p.Status, p.TargetDomain, p.FileID, p.InitiatedAt, p.CompletedAt,
+		p.InvalidationStartedAt, p.InvalidationCompletedAt, p.TotalRecords, p.ProcessedRecords, p.FailedRecords,
+		p.UploadMetadata, p.Version)
 	if err != nil {
-		return fmt.Errorf("failed to upsert bulk upload: %w", err)
+		return fmt.Errorf("failed to write bulk upload projection: %w", err)
 	}
 
 	return nil
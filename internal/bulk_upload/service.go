@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"geevly/gen/go/eda"
 	"geevly/internal/bulk_upload/db/sqlc"
+	"geevly/internal/infrastructure"
 
 	"github.com/Howard3/gosignal"
 	"github.com/google/uuid"
@@ -54,6 +55,23 @@ func (s *Service) GetBulkUpload(ctx context.Context, id string) (*Aggregate, err
 	return agg, nil
 }
 
+// GetBulkUploadAsOf replays the bulk upload's events up to a point in its past
+func (s *Service) GetBulkUploadAsOf(ctx context.Context, id string, at infrastructure.AsOf) (*Aggregate, error) {
+	events, err := s.repo.getEventHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bulk upload events: %w", err)
+	}
+
+	agg := &Aggregate{}
+	agg.SetID(id)
+
+	if err := infrastructure.ReplayAsOf(agg, events, at); err != nil {
+		return nil, fmt.Errorf("failed to replay bulk upload %s: %w", id, err)
+	}
+
+	return agg, nil
+}
+
 func (s *Service) Create(ctx context.Context, cmd *eda.BulkUpload_Create) (*Aggregate, error) {
 	if err := s.acl.ValidateFileID(ctx, cmd.FileId); err != nil {
 		return nil, fmt.Errorf("invalid file ID: %w", err)
@@ -77,19 +95,49 @@ func (s *Service) Create(ctx context.Context, cmd *eda.BulkUpload_Create) (*Aggr
 	return agg, nil
 }
 
+// RegisterCommands registers the bulk upload commands on the bus, the later processing steps are run by the
+// upload itself rather than dispatched
+func (s *Service) RegisterCommands(bus *infrastructure.CommandBus) {
+	infrastructure.HandleCommand(bus, "bulk_upload", func(ctx context.Context, _ string, cmd *eda.BulkUpload_Create, _ []byte) (any, error) {
+		return s.Create(ctx, cmd)
+	}, infrastructure.AllowRoles(infrastructure.RoleAdmin))
+}
+
 func (s *Service) SaveValidationErrors(ctx context.Context, id string, res []*eda.BulkUpload_ValidationError) error {
-	agg, err := s.repo.loadBulkUpload(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to load bulk upload: %w", err)
-	}
+	return s.withBulkUpload(ctx, id, func(agg *Aggregate) (*gosignal.Event, error) {
+		event, err := agg.AddValidationErrors(res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add validation errors: %w", err)
+		}
 
-	event, err := agg.AddValidationErrors(res)
-	if err != nil {
-		return fmt.Errorf("failed to add validation errors: %w", err)
-	}
+		return event, nil
+	})
+}
+
+// withBulkUpload loads the bulk upload, runs fn on it and stores the event. Processing steps act on whatever
+// version is current, so when another step stores the next version first fn is simply run again on the
+// reloaded upload.
+func (s *Service) withBulkUpload(ctx context.Context, id string, fn func(*Aggregate) (*gosignal.Event, error)) error {
+	var event *gosignal.Event
+	err := infrastructure.RetryOnConflict(func() error {
+		agg, err := s.repo.loadBulkUpload(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to load bulk upload: %w", err)
+		}
+
+		event, err = fn(agg)
+		if err != nil {
+			return err
+		}
 
-	if err := s.repo.saveEvents(ctx, []gosignal.Event{*event}); err != nil {
-		return fmt.Errorf("failed to save bulk upload event: %w", err)
+		if err := s.repo.saveEvents(ctx, []gosignal.Event{*event}); err != nil {
+			return fmt.Errorf("failed to save bulk upload event: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	s.eventHandlers.HandleBulkUploadEvent(ctx, event)
@@ -118,23 +166,14 @@ func (s *Service) ListBulkUploads(ctx context.Context, limit, page uint) (*ListR
 }
 
 func (s *Service) SetStatus(ctx context.Context, id string, status eda.BulkUpload_Status) error {
-	agg, err := s.repo.loadBulkUpload(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to load bulk upload: %w", err)
-	}
-
-	event, err := agg.setStatus(status)
-	if err != nil {
-		return fmt.Errorf("failed to set status: %w", err)
-	}
-
-	if err := s.repo.saveEvents(ctx, []gosignal.Event{*event}); err != nil {
-		return fmt.Errorf("failed to save bulk upload event: %w", err)
-	}
-
-	s.eventHandlers.HandleBulkUploadEvent(ctx, event)
+	return s.withBulkUpload(ctx, id, func(agg *Aggregate) (*gosignal.Event, error) {
+		event, err := agg.setStatus(status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set status: %w", err)
+		}
 
-	return nil
+		return event, nil
+	})
 }
 
 type RecordActions struct {
@@ -144,61 +183,34 @@ type RecordActions struct {
 }
 
 func (s *Service) MarkRecordsAsUpdated(ctx context.Context, id string, actions RecordActions) error {
-	agg, err := s.repo.loadBulkUpload(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to load bulk upload: %w", err)
-	}
-
-	event, err := agg.markRecordsAsUpdated(actions)
-	if err != nil {
-		return fmt.Errorf("failed to mark records as processed: %w", err)
-	}
-
-	if err := s.repo.saveEvents(ctx, []gosignal.Event{*event}); err != nil {
-		return fmt.Errorf("failed to save bulk upload event: %w", err)
-	}
-
-	s.eventHandlers.HandleBulkUploadEvent(ctx, event)
+	return s.withBulkUpload(ctx, id, func(agg *Aggregate) (*gosignal.Event, error) {
+		event, err := agg.markRecordsAsUpdated(actions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark records as processed: %w", err)
+		}
 
-	return nil
+		return event, nil
+	})
 }
 
 func (s *Service) MarkRecordsAsUndone(ctx context.Context, id string, actions RecordActions) error {
-	agg, err := s.repo.loadBulkUpload(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to load bulk upload: %w", err)
-	}
-
-	event, err := agg.markRecordsAsUndone(actions)
-	if err != nil {
-		return fmt.Errorf("failed to mark records as undone: %w", err)
-	}
-
-	if err := s.repo.saveEvents(ctx, []gosignal.Event{*event}); err != nil {
-		return fmt.Errorf("failed to save bulk upload event: %w", err)
-	}
-
-	s.eventHandlers.HandleBulkUploadEvent(ctx, event)
+	return s.withBulkUpload(ctx, id, func(agg *Aggregate) (*gosignal.Event, error) {
+		event, err := agg.markRecordsAsUndone(actions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark records as undone: %w", err)
+		}
 
-	return nil
+		return event, nil
+	})
 }
 
 func (s *Service) AddRecordsToProcess(ctx context.Context, id string, actions RecordActions) error {
-	agg, err := s.repo.loadBulkUpload(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to load bulk upload: %w", err)
-	}
-
-	event, err := agg.addRecordsToProcess(actions)
-	if err != nil {
-		return fmt.Errorf("failed to add records to process: %w", err)
-	}
-
-	if err := s.repo.saveEvents(ctx, []gosignal.Event{*event}); err != nil {
-		return fmt.Errorf("failed to save bulk upload event: %w", err)
-	}
-
-	s.eventHandlers.HandleBulkUploadEvent(ctx, event)
+	return s.withBulkUpload(ctx, id, func(agg *Aggregate) (*gosignal.Event, error) {
+		event, err := agg.addRecordsToProcess(actions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add records to process: %w", err)
+		}
 
-	return nil
+		return event, nil
+	})
 }
@@ -0,0 +1,7 @@
+package bulk_upload
+
+import "geevly/internal/infrastructure"
+
+// upcasters bring events stored in an older shape up to the one the aggregate handles, register them
+// here when an event's payload changes
+var upcasters = infrastructure.NewUpcasters()
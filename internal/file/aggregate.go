@@ -34,7 +34,7 @@ type Aggregate struct {
 
 // Apply event to the file
 func (a *Aggregate) Apply(evt gosignal.Event) error {
-	return sourcing.SafeApply(evt, a, a.routeEvent)
+	return sourcing.SafeApply(evt, a, upcasters.Apply(a.routeEvent))
 }
 
 // Route event to the appropriate handler
@@ -56,6 +56,7 @@ func (a *Aggregate) onFileCreated(evt gosignal.Event) error {
 	}
 	a.data = &eda.File{
 		Name:                   eventData.Name,
+		DomainReferenceOld:     eventData.DomainReferenceOld,
 		DomainReference:        eventData.DomainReference,
 		MimeType:               eventData.MimeType,
 		Size:                   eventData.Size,
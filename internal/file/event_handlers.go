@@ -2,6 +2,7 @@ package file
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"github.com/Howard3/gosignal"
@@ -17,25 +18,30 @@ func NewEventHandlers(repo Repository) *eventHandlers {
 	}
 }
 
-func (eh *eventHandlers) updateFileProjection(ctx context.Context, aggregateID string) {
+func (eh *eventHandlers) updateFileProjection(ctx context.Context, aggregateID string) error {
 	file, err := eh.repo.loadFile(ctx, aggregateID)
 	if err != nil {
-		slog.Error("failed to load file", "error", err)
-		return
+		return fmt.Errorf("failed to load file: %w", err)
 	}
 
 	if err := eh.repo.upsertFileProjection(ctx, file); err != nil {
-		slog.Error("failed to upsert file", "error", err)
-		return
+		return fmt.Errorf("failed to upsert file: %w", err)
 	}
+
+	return nil
 }
 
 // routeEvent is a method that routes an event to the appropriate handler
-func (eh *eventHandlers) routeEvent(ctx context.Context, evt *gosignal.Event) {
+func (eh *eventHandlers) routeEvent(ctx context.Context, evt *gosignal.Event) error {
 	switch evt.Type {
 	case EventFileCreated, EventFileDeleted:
-		eh.updateFileProjection(ctx, evt.AggregateID)
+		if err := eh.updateFileProjection(ctx, evt.AggregateID); err != nil {
+			slog.Error("failed to update file projection", "file_id", evt.AggregateID, "error", err)
+			return err
+		}
 	default:
 		slog.Error("unknown event type", "event", evt.Type)
 	}
+
+	return nil
 }
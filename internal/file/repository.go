@@ -20,6 +20,8 @@ type Repository interface {
 	saveEvent(ctx context.Context, evt *gosignal.Event) error
 	upsertFileProjection(ctx context.Context, file *Aggregate) error
 	validateFileID(ctx context.Context, fileID string) error
+	Projections() []infrastructure.Projection
+	EventStream() infrastructure.EventStream
 }
 
 type sqlRepository struct {
@@ -30,16 +32,21 @@ type sqlRepository struct {
 
 // use the following as the basis for the upsert
 func (sr *sqlRepository) upsertFileProjection(ctx context.Context, file *Aggregate) error {
-	query := `INSERT INTO files (id, domain, name, deleted, version, updated_at)
+	return sr.writeFileProjection(ctx, sr.db, "files", file)
+}
+
+// writeFileProjection - upserts the file's row in table
+func (sr *sqlRepository) writeFileProjection(ctx context.Context, db infrastructure.Execer, table string, file *Aggregate) error {
+	query := fmt.Sprintf(`INSERT INTO %s (id, domain, name, deleted, version, updated_at)
 		VALUES (?,?,?,?,?, CURRENT_TIMESTAMP)
 		ON CONFLICT (id) DO UPDATE SET
 		domain = excluded.domain,
 		name = excluded.name,
 		deleted = excluded.deleted,
 		version = excluded.version,
-		updated_at = excluded.updated_at;`
+		updated_at = excluded.updated_at;`, table)
 
-	_, err := sr.db.ExecContext(
+	_, err := db.ExecContext(
 		ctx, query, file.ID, file.data.GetDomainReference(), file.data.GetName(),
 		file.data.GetDeleted(), file.Version,
 	)
@@ -51,6 +58,32 @@ func (sr *sqlRepository) upsertFileProjection(ctx context.Context, file *Aggrega
 	return nil
 }
 
+// Projections returns the file read model for the projector
+func (sr *sqlRepository) Projections() []infrastructure.Projection {
+	return []infrastructure.Projection{{
+		Table:      "files",
+		EventTable: "file_events",
+		Project: func(ctx context.Context, aggregateID string) (infrastructure.ProjectionWriter, error) {
+			file, err := sr.loadFile(ctx, aggregateID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load file %s: %w", aggregateID, err)
+			}
+
+			return func(ctx context.Context, tx *sql.Tx, table string) error {
+				return sr.writeFileProjection(ctx, tx, table, file)
+			}, nil
+		},
+	}}
+}
+
+// EventStream describes the file events for the integrity check, files aren't snapshotted
+func (sr *sqlRepository) EventStream() infrastructure.EventStream {
+	return infrastructure.EventStream{
+		EventTable: "file_events",
+		New:        func() sourcing.Aggregate { return &Aggregate{} },
+	}
+}
+
 func (sr *sqlRepository) loadFile(ctx context.Context, id string) (*Aggregate, error) {
 	agg := Aggregate{}
 	agg.SetID(id)
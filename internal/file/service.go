@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
 
 	"github.com/Howard3/gosignal"
 	"github.com/oklog/ulid/v2"
@@ -29,6 +30,8 @@ type Service struct {
 	repo          Repository
 	eventHandlers *eventHandlers
 	storage       Storage
+	// consuming is set once the file projection is updated from the queue
+	consuming bool
 }
 
 func NewService(repo Repository, storage Storage) *Service {
@@ -70,12 +73,24 @@ func (s *Service) CreateFile(ctx context.Context, fileData []byte, file *eda.Fil
 		return "", fmt.Errorf("failed to save event: %w", err)
 	}
 
-	// don't run in goroutine, it's likely needed immediately after via "ValidateFileID"
+	// don't run in goroutine, it's likely needed immediately after via "ValidateFileID". When consuming
+	// from the queue the delivery repeats the upsert, which is harmless.
 	s.eventHandlers.routeEvent(ctx, evt)
 
 	return id.String(), nil
 }
 
+// RegisterCommands registers the file commands on the bus. A new file's contents are the command's
+// attachment, and files can be created without signing in since feeding proofs are uploaded that way.
+func (s *Service) RegisterCommands(bus *infrastructure.CommandBus) {
+	infrastructure.HandleCommand(bus, "file", func(ctx context.Context, _ string, cmd *eda.File_Create, fileData []byte) (any, error) {
+		return s.CreateFile(ctx, fileData, cmd)
+	})
+	infrastructure.HandleCommand(bus, "file", func(ctx context.Context, _ string, cmd *eda.File_Delete, _ []byte) (any, error) {
+		return nil, s.DeleteFile(ctx, cmd)
+	}, infrastructure.AllowRoles(infrastructure.RoleAdmin))
+}
+
 // DeleteFile invokes the aggregate to delete the file and removes it from storage
 func (s *Service) DeleteFile(ctx context.Context, cmd *eda.File_Delete) error {
 	_, err := s.withFile(ctx, func(f *Aggregate) (*gosignal.Event, error) {
@@ -126,7 +141,30 @@ func (s *Service) withFile(ctx context.Context, commandFunc func(*Aggregate) (*g
 		}
 	}
 
-	go s.eventHandlers.routeEvent(ctx, evt)
+	if !s.consuming {
+		go s.eventHandlers.routeEvent(ctx, evt)
+	}
 
 	return f, nil
 }
+
+// ConsumeEvents updates the file projection from the events delivered by the queue, in place of handling
+// them in the background after each save. It must be called before the service handles commands.
+func (s *Service) ConsumeEvents(ctx context.Context, q gosignal.Queue) error {
+	handle := func(ctx context.Context, evt gosignal.Event) error {
+		// queued events are as stored, bring them up to the shape the projection reads
+		evt, err := upcasters.Upcast(evt)
+		if err != nil {
+			return err
+		}
+		return s.eventHandlers.routeEvent(ctx, &evt)
+	}
+
+	if err := infrastructure.ConsumeEvents(ctx, q, infrastructure.Subscriber{Name: "file", EventTable: "file_events"}, handle, EventFileCreated, EventFileDeleted); err != nil {
+		return fmt.Errorf("failed to consume file events: %w", err)
+	}
+
+	s.consuming = true
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package file
+
+import (
+	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// upcasters bring events stored in an older shape up to the one the aggregate handles, register them
+// here when an event's payload changes
+var upcasters = infrastructure.NewUpcasters().
+	Register(EventFileCreated, 2, upcastDomainReference)
+
+// Field numbers on File.Create.Event
+const (
+	createdDomainReferenceField    = 2
+	createdDomainReferenceOldField = 9
+)
+
+// upcastDomainReference converts the domain reference of files created before it was an enum. Those
+// events carry the reference as a string in the field the enum now uses, as the File state kept it in
+// what's now domain_reference_old, and proto decodes it as unknown. The string moves to
+// domain_reference_old on the event and, when it names a domain reference, sets the enum too, so a
+// reference without an enum value is kept rather than lost.
+func upcastDomainReference(data []byte) ([]byte, error) {
+	var out []byte
+	legacy := false
+
+	for b := data; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return nil, protowire.ParseError(m)
+		}
+
+		field := b[:n+m]
+		b = b[n+m:]
+
+		if num != createdDomainReferenceField || typ != protowire.BytesType {
+			out = append(out, field...)
+			continue
+		}
+
+		legacy = true
+		value, _ := protowire.ConsumeBytes(field[n:])
+		if ref := legacyDomainReference(string(value)); ref != eda.File_UNKNOWN {
+			out = protowire.AppendTag(out, createdDomainReferenceField, protowire.VarintType)
+			out = protowire.AppendVarint(out, uint64(ref))
+		}
+		out = protowire.AppendTag(out, createdDomainReferenceOldField, protowire.BytesType)
+		out = protowire.AppendBytes(out, value)
+	}
+
+	if !legacy {
+		return data, nil
+	}
+
+	return out, nil
+}
+
+// legacyDomainReference maps a string domain reference such as "student-profile-photo" to the enum
+func legacyDomainReference(value string) eda.File_DomainReference {
+	name := strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(strings.TrimSpace(value)))
+	return eda.File_DomainReference(eda.File_DomainReference_value[name])
+}
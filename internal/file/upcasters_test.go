@@ -0,0 +1,138 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+
+	"github.com/Howard3/gosignal"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// legacyFileCreated encodes a FileCreated payload as files created before the domain reference was an
+// enum stored it, with the reference as a string in field 2
+func legacyFileCreated(name, domainReference string) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, name)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, domainReference)
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendString(b, "image/png")
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, 2048)
+	b = protowire.AppendTag(b, 5, protowire.BytesType)
+	b = protowire.AppendString(b, "png")
+	return b
+}
+
+func TestLegacyFileCreatedLoads(t *testing.T) {
+	for _, tc := range []struct {
+		stored string
+		want   eda.File_DomainReference
+	}{
+		{"student-profile-photo", eda.File_STUDENT_PROFILE_PHOTO},
+		{" feeding history", eda.File_FEEDING_HISTORY},
+		{"BULK_UPLOAD", eda.File_BULK_UPLOAD},
+		{"avatar", eda.File_UNKNOWN},
+	} {
+		t.Run(tc.stored, func(t *testing.T) {
+			agg := &Aggregate{}
+			agg.SetID("1")
+
+			evt := gosignal.Event{Type: EventFileCreated, AggregateID: "1", Data: legacyFileCreated("photo.png", tc.stored)}
+			if err := agg.Apply(evt); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := agg.data.GetDomainReference(); got != tc.want {
+				t.Errorf("domain reference %v, want %v", got, tc.want)
+			}
+			if got := agg.data.GetDomainReferenceOld(); got != tc.stored {
+				t.Errorf("stored domain reference %q, want %q", got, tc.stored)
+			}
+			if agg.data.GetName() != "photo.png" || agg.data.GetMimeType() != "image/png" || agg.data.GetSize() != 2048 || agg.data.GetExtension() != "png" {
+				t.Errorf("other fields not carried over: %v", agg.data)
+			}
+		})
+	}
+}
+
+func TestUpcastDomainReferenceLeavesCurrentEvents(t *testing.T) {
+	current, err := proto.Marshal(&eda.File_Create_Event{
+		Name:            "photo.png",
+		DomainReference: eda.File_FEEDING_TEMPORARY,
+		MimeType:        "image/png",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := upcastDomainReference(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, current) {
+		t.Error("current payload was rewritten")
+	}
+
+	upcast, err := upcastDomainReference(legacyFileCreated("photo.png", "feeding-temporary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := upcastDomainReference(upcast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, upcast) {
+		t.Error("upcasting twice rewrote the payload again")
+	}
+}
+
+func TestUpcastDomainReferenceRejectsMalformedPayload(t *testing.T) {
+	truncated := legacyFileCreated("photo.png", "student-profile-photo")
+	if _, err := upcastDomainReference(truncated[:len(truncated)-2]); err == nil {
+		t.Error("truncated payload upcast without an error")
+	}
+}
+
+func TestProjectionRebuildUpcastsLegacyFileCreated(t *testing.T) {
+	conn := infrastructure.SQLConnection{Type: "sqlite3", URI: "file:" + filepath.Join(t.TempDir(), "file.db")}
+	repo := NewRepository(conn, nil).(*sqlRepository)
+	t.Cleanup(func() { repo.db.Close() })
+
+	query := `INSERT INTO file_events (type, data, version, timestamp, aggregate_id) VALUES (?, ?, 0, ?, 1)`
+	data := legacyFileCreated("photo.png", "student-profile-photo")
+	if _, err := repo.db.Exec(query, EventFileCreated, data, time.Now().Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	projector, err := infrastructure.NewProjector(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := projector.Register(ctx, repo.Projections()...); err != nil {
+		t.Fatal(err)
+	}
+	if err := projector.Rebuild(ctx, "files"); err != nil {
+		t.Fatal(err)
+	}
+
+	var domain, name string
+	if err := repo.db.QueryRow(`SELECT domain, name FROM files WHERE id = '1'`).Scan(&domain, &name); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprint(int32(eda.File_STUDENT_PROFILE_PHOTO)); domain != want || name != "photo.png" {
+		t.Errorf("projected domain %s and name %q, want %s and photo.png", domain, name, want)
+	}
+}
@@ -0,0 +1,64 @@
+package infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Howard3/gosignal"
+	"github.com/Howard3/gosignal/sourcing"
+)
+
+// ErrNotYetCreated is returned when replaying an aggregate to a point before its first event
+var ErrNotYetCreated = errors.New("aggregate didn't exist yet")
+
+// AsOf is a point in an aggregate's past: the events stored before Time or, when Time is zero, the first
+// Version events, which is the aggregate at that version
+type AsOf struct {
+	Time    time.Time
+	Version uint64
+}
+
+// AsOfTime is the aggregate as it was at t
+func AsOfTime(t time.Time) AsOf {
+	return AsOf{Time: t}
+}
+
+// AsOfVersion is the aggregate once it reached version
+func AsOfVersion(version uint64) AsOf {
+	return AsOf{Version: version}
+}
+
+// Includes reports whether the event had happened by the point
+func (a AsOf) Includes(evt gosignal.Event) bool {
+	if a.Time.IsZero() {
+		return evt.Version < a.Version
+	}
+	return evt.Timestamp.Before(a.Time)
+}
+
+func (a AsOf) String() string {
+	if a.Time.IsZero() {
+		return fmt.Sprintf("version %d", a.Version)
+	}
+	return a.Time.Format(time.RFC3339)
+}
+
+// ReplayAsOf applies the events, in version order, that had happened by the point to a new aggregate
+func ReplayAsOf(agg sourcing.Aggregate, events []gosignal.Event, at AsOf) error {
+	for _, evt := range events {
+		if !at.Includes(evt) {
+			break
+		}
+
+		if err := agg.Apply(evt); err != nil {
+			return fmt.Errorf("apply %s version %d: %w", evt.Type, evt.Version, err)
+		}
+	}
+
+	if agg.GetVersion() == 0 {
+		return fmt.Errorf("%w as of %s", ErrNotYetCreated, at)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrUnknownCommand is returned when no domain registered a handler for the command's message type
+var ErrUnknownCommand = errors.New("unknown command")
+
+// ErrUnexpectedResult is returned by DispatchAs when the handler's result isn't of the requested type
+var ErrUnexpectedResult = errors.New("unexpected command result")
+
+// Command is a proto command message addressed to an aggregate
+type Command struct {
+	// AggregateID is the aggregate the command runs on, empty for commands that carry their own ID or
+	// create the aggregate
+	AggregateID string
+	Message     proto.Message
+	// Attachment travels with the command without being part of the message, such as an uploaded file
+	Attachment []byte
+}
+
+// Name is the full proto name of the command's message, which is what handlers are registered under
+func (c Command) Name() protoreflect.FullName {
+	if c.Message == nil {
+		return ""
+	}
+	return c.Message.ProtoReflect().Descriptor().FullName()
+}
+
+// CommandHandler runs a command, returning whatever the domain reports back, usually the updated aggregate
+type CommandHandler func(ctx context.Context, cmd Command) (any, error)
+
+// CommandRoute is a registered handler and what the middleware needs to know about it
+type CommandRoute struct {
+	Name   protoreflect.FullName
+	Domain string
+	// Roles are the actor roles allowed to dispatch the command, any actor may when empty
+	Roles []string
+	// Validate checks the message before it's handled, nil when the handler validates it itself
+	Validate func(proto.Message) error
+	handler  CommandHandler
+}
+
+// CommandMiddleware wraps the handler of a route, it's applied on every dispatch so it can rely on the route
+type CommandMiddleware func(route CommandRoute, next CommandHandler) CommandHandler
+
+// RouteOption configures a command route when it's registered
+type RouteOption func(*CommandRoute)
+
+// AllowRoles restricts the command to actors with one of the roles
+func AllowRoles(roles ...string) RouteOption {
+	return func(r *CommandRoute) {
+		r.Roles = append(r.Roles, roles...)
+	}
+}
+
+// ValidateWith checks the command's message before it reaches the handler
+func ValidateWith[T proto.Message](validate func(T) error) RouteOption {
+	return func(r *CommandRoute) {
+		r.Validate = func(msg proto.Message) error {
+			cmd, ok := msg.(T)
+			if !ok {
+				return fmt.Errorf("expected %T, got %T", cmd, msg)
+			}
+			return validate(cmd)
+		}
+	}
+}
+
+// CommandBus dispatches commands to the handler the owning domain registered for the message type, through
+// the bus's middleware. Domains register their commands at start-up, callers only need the bus.
+type CommandBus struct {
+	mu         sync.RWMutex
+	routes     map[protoreflect.FullName]CommandRoute
+	middleware []CommandMiddleware
+}
+
+// NewCommandBus creates a bus that runs commands through the middleware, outermost first
+func NewCommandBus(middleware ...CommandMiddleware) *CommandBus {
+	return &CommandBus{
+		routes:     make(map[protoreflect.FullName]CommandRoute),
+		middleware: middleware,
+	}
+}
+
+// HandleCommand registers the domain's handler for commands of type T. Registering a type twice is a
+// programming error and panics, like registering a route twice on an http.ServeMux.
+func HandleCommand[T proto.Message](bus *CommandBus, domain string, handle func(ctx context.Context, aggregateID string, cmd T, attachment []byte) (any, error), opts ...RouteOption) {
+	var zero T
+	route := CommandRoute{
+		Name:   zero.ProtoReflect().Descriptor().FullName(),
+		Domain: domain,
+		handler: func(ctx context.Context, cmd Command) (any, error) {
+			msg, ok := cmd.Message.(T)
+			if !ok {
+				return nil, fmt.Errorf("%w: expected %T, got %T", ErrUnknownCommand, msg, cmd.Message)
+			}
+			return handle(ctx, cmd.AggregateID, msg, cmd.Attachment)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&route)
+	}
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	if existing, ok := bus.routes[route.Name]; ok {
+		panic(fmt.Sprintf("command %s is already handled by the %s domain", route.Name, existing.Domain))
+	}
+
+	bus.routes[route.Name] = route
+}
+
+// Dispatch runs the command through the middleware to its handler
+func (b *CommandBus) Dispatch(ctx context.Context, cmd Command) (any, error) {
+	if cmd.Message == nil {
+		return nil, fmt.Errorf("%w: no message", ErrUnknownCommand)
+	}
+
+	b.mu.RLock()
+	route, ok := b.routes[cmd.Name()]
+	b.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCommand, cmd.Name())
+	}
+
+	handler := route.handler
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		handler = b.middleware[i](route, handler)
+	}
+
+	return handler(ctx, cmd)
+}
+
+// DispatchAs dispatches the command and returns the handler's result as a T
+func DispatchAs[T any](ctx context.Context, bus *CommandBus, cmd Command) (T, error) {
+	var out T
+
+	res, err := bus.Dispatch(ctx, cmd)
+	if err != nil {
+		return out, err
+	}
+
+	out, ok := res.(T)
+	if !ok {
+		return out, fmt.Errorf("%w: %s returned %T, expected %T", ErrUnexpectedResult, cmd.Name(), res, out)
+	}
+
+	return out, nil
+}
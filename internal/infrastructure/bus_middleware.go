@@ -0,0 +1,237 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Actor roles checked by AuthorizationMiddleware
+const (
+	RoleAdmin  = "admin"
+	RoleFeeder = "feeder"
+	RoleAPI    = "api"
+	// RoleSystem is held by background processes such as bulk uploads and scheduled scans, it may run any
+	// command
+	RoleSystem = "system"
+)
+
+// ErrForbidden is returned when the actor doesn't hold any of the roles the command allows
+var ErrForbidden = errors.New("not allowed to run this command")
+
+// ErrInvalidCommand wraps the reason a command failed validation
+var ErrInvalidCommand = errors.New("invalid command")
+
+// Actor is who a command is run for
+type Actor struct {
+	// ID identifies the user, empty when they aren't signed in
+	ID    string
+	Roles []string
+	// Source is where the command came from, such as "web", "api" or "system"
+	Source string
+}
+
+// SystemActor is who background processes dispatch commands as, they attach it with WithActor
+var SystemActor = Actor{ID: "system", Roles: []string{RoleSystem}, Source: "system"}
+
+// HasRole reports whether the actor holds any of the roles
+func (a Actor) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(a.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Actor) String() string {
+	id := a.ID
+	if id == "" {
+		id = "anonymous"
+	}
+	return fmt.Sprintf("%s via %s", id, a.Source)
+}
+
+type actorKey struct{}
+
+// WithActor returns a context that dispatches commands as the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor commands in the context run as
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
+
+// ActorMiddleware rejects commands dispatched without an actor, so a caller that forgot to attach one
+// doesn't run as the system
+func ActorMiddleware() CommandMiddleware {
+	return func(route CommandRoute, next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			if _, ok := ActorFromContext(ctx); !ok {
+				return nil, fmt.Errorf("%w: %s dispatched without an actor", ErrForbidden, route.Name)
+			}
+			return next(ctx, cmd)
+		}
+	}
+}
+
+// AuthorizationMiddleware rejects commands from actors without one of the roles the route allows
+func AuthorizationMiddleware() CommandMiddleware {
+	return func(route CommandRoute, next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			if len(route.Roles) == 0 {
+				return next(ctx, cmd)
+			}
+
+			actor, _ := ActorFromContext(ctx)
+			if !actor.HasRole(RoleSystem) && !actor.HasRole(route.Roles...) {
+				return nil, fmt.Errorf("%w: %s needs one of %v, %s has %v", ErrForbidden, route.Name, route.Roles, actor, actor.Roles)
+			}
+
+			return next(ctx, cmd)
+		}
+	}
+}
+
+// ValidationMiddleware runs the route's validation, and the message's own Validate method when it has one,
+// before the command reaches its handler
+func ValidationMiddleware() CommandMiddleware {
+	return func(route CommandRoute, next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			if v, ok := cmd.Message.(interface{ Validate() error }); ok {
+				if err := v.Validate(); err != nil {
+					return nil, fmt.Errorf("%w %s: %w", ErrInvalidCommand, route.Name, err)
+				}
+			}
+
+			if route.Validate != nil {
+				if err := route.Validate(cmd.Message); err != nil {
+					return nil, fmt.Errorf("%w %s: %w", ErrInvalidCommand, route.Name, err)
+				}
+			}
+
+			return next(ctx, cmd)
+		}
+	}
+}
+
+// LoggingMiddleware logs every command with its actor, outcome and how long it took
+func LoggingMiddleware(logger *slog.Logger) CommandMiddleware {
+	return func(route CommandRoute, next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			start := time.Now()
+			res, err := next(ctx, cmd)
+
+			actor, _ := ActorFromContext(ctx)
+			attrs := []any{
+				"command", route.Name,
+				"domain", route.Domain,
+				"aggregate_id", cmd.AggregateID,
+				"actor", actor.String(),
+				"duration", time.Since(start),
+			}
+
+			if err != nil {
+				logger.WarnContext(ctx, "command failed", append(attrs, "error", err)...)
+			} else {
+				logger.InfoContext(ctx, "command handled", attrs...)
+			}
+
+			return res, err
+		}
+	}
+}
+
+// CommandStats are the counts kept by CommandMetrics for one command
+type CommandStats struct {
+	Handled   uint64        `json:"handled"`
+	Failed    uint64        `json:"failed"`
+	Conflicts uint64        `json:"conflicts"`
+	Forbidden uint64        `json:"forbidden"`
+	Invalid   uint64        `json:"invalid"`
+	Total     time.Duration `json:"total_ns"`
+	Slowest   time.Duration `json:"slowest_ns"`
+}
+
+// CommandMetrics counts dispatched commands by name. It's an expvar.Var, so it can be published alongside
+// the runtime's own variables.
+type CommandMetrics struct {
+	mu    sync.Mutex
+	stats map[protoreflect.FullName]*CommandStats
+}
+
+func NewCommandMetrics() *CommandMetrics {
+	return &CommandMetrics{stats: make(map[protoreflect.FullName]*CommandStats)}
+}
+
+// Middleware records every command dispatched through it
+func (m *CommandMetrics) Middleware() CommandMiddleware {
+	return func(route CommandRoute, next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			start := time.Now()
+			res, err := next(ctx, cmd)
+			m.record(route.Name, time.Since(start), err)
+
+			return res, err
+		}
+	}
+}
+
+func (m *CommandMetrics) record(name protoreflect.FullName, took time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.stats[name]
+	if !ok {
+		stats = &CommandStats{}
+		m.stats[name] = stats
+	}
+
+	stats.Handled++
+	stats.Total += took
+	stats.Slowest = max(stats.Slowest, took)
+
+	switch {
+	case err == nil:
+	case IsConflict(err):
+		stats.Conflicts++
+	case errors.Is(err, ErrForbidden):
+		stats.Forbidden++
+	case errors.Is(err, ErrInvalidCommand):
+		stats.Invalid++
+	default:
+		stats.Failed++
+	}
+}
+
+// Snapshot returns a copy of the counts, keyed by command name
+func (m *CommandMetrics) Snapshot() map[string]CommandStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string]CommandStats, len(m.stats))
+	for name, stats := range m.stats {
+		out[string(name)] = *stats
+	}
+
+	return out
+}
+
+// String renders the counts as JSON for expvar
+func (m *CommandMetrics) String() string {
+	b, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
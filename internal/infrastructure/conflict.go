@@ -0,0 +1,103 @@
+package infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Howard3/gosignal/sourcing"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrConflict is matched by every ConflictError
+var ErrConflict = errors.New("the record was changed by someone else")
+
+// ConflictRetries is how many times a command is run against a reloaded aggregate after another write got
+// in first, before giving up
+const ConflictRetries = 3
+
+// ConflictError is returned when a command was based on a version of an aggregate that has since moved on
+// and it isn't safe to run it against the current one
+type ConflictError struct {
+	AggregateID string
+	// Expected is the version the command was based on, Current the version it's at now
+	Expected uint64
+	Current  uint64
+	// Changes are the fields changed between the two versions, empty when they couldn't be worked out
+	Changes []FieldChange
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s changed from version %d to %d", ErrConflict, e.AggregateID, e.Expected, e.Current)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// IsConflict reports whether the error came from running a command against a stale aggregate: either the
+// command's version is behind the aggregate's, or another write stored the same version first
+func IsConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, ErrConflict) || errors.Is(err, sourcing.ErrEventVersionNE) {
+		return true
+	}
+
+	// the event tables are unique on (aggregate_id, version), so losing the race to store a version
+	// surfaces as a constraint failure from the driver
+	return errors.Is(err, sourcing.ErrStoringEvents) && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// RetryOnConflict runs fn until it succeeds, fails with something other than a conflict, or has been
+// retried ConflictRetries times. fn must reload the aggregate each time it's called.
+func RetryOnConflict(fn func() error) error {
+	err := fn()
+	for i := 0; i < ConflictRetries && IsConflict(err); i++ {
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			// already decided against running the command again
+			return err
+		}
+
+		err = fn()
+	}
+
+	return err
+}
+
+// CommandVersion returns the aggregate version a command was based on, false when it has no version field
+func CommandVersion(cmd proto.Message) (uint64, bool) {
+	fd := versionField(cmd)
+	if fd == nil {
+		return 0, false
+	}
+
+	return cmd.ProtoReflect().Get(fd).Uint(), true
+}
+
+// RebaseCommand returns a copy of the command based on version instead, for commands that are still
+// valid against the aggregate as it is now
+func RebaseCommand[T proto.Message](cmd T, version uint64) T {
+	fd := versionField(cmd)
+	if fd == nil {
+		return cmd
+	}
+
+	rebased := proto.Clone(cmd).(T)
+	rebased.ProtoReflect().Set(fd, protoreflect.ValueOfUint64(version))
+
+	return rebased
+}
+
+func versionField(cmd proto.Message) protoreflect.FieldDescriptor {
+	fd := cmd.ProtoReflect().Descriptor().Fields().ByName("version")
+	if fd == nil || fd.Kind() != protoreflect.Uint64Kind || fd.Cardinality() == protoreflect.Repeated {
+		return nil
+	}
+
+	return fd
+}
@@ -0,0 +1,155 @@
+package infrastructure
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FieldChange is a field that differs between two versions of a message. Before and After are the values
+// rendered for display, empty when the field isn't set on that side.
+type FieldChange struct {
+	Path   string
+	Before string
+	After  string
+}
+
+// DiffMessages returns the fields that differ between two messages of the same type, either of which may
+// be nil. Nested messages are compared field by field, lists by position and maps by key, so a change
+// deep in the message is reported with its full path, such as "guardians[1].phone".
+func DiffMessages(before, after proto.Message) []FieldChange {
+	if before == nil && after == nil {
+		return nil
+	}
+
+	if before == nil {
+		before = after.ProtoReflect().Type().Zero().Interface()
+	}
+	if after == nil {
+		after = before.ProtoReflect().Type().Zero().Interface()
+	}
+
+	var changes []FieldChange
+	diffMessage(&changes, "", before.ProtoReflect(), after.ProtoReflect())
+
+	return changes
+}
+
+func diffMessage(changes *[]FieldChange, path string, before, after protoreflect.Message) {
+	fields := before.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		hasBefore, hasAfter := before.Has(fd), after.Has(fd)
+		if !hasBefore && !hasAfter {
+			continue
+		}
+
+		fieldPath := string(fd.Name())
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		switch {
+		case fd.IsList():
+			diffList(changes, fieldPath, fd, before.Get(fd).List(), after.Get(fd).List())
+		case fd.IsMap():
+			diffMap(changes, fieldPath, fd, before.Get(fd).Map(), after.Get(fd).Map())
+		default:
+			diffValue(changes, fieldPath, fd, before.Get(fd), hasBefore, after.Get(fd), hasAfter)
+		}
+	}
+}
+
+func diffList(changes *[]FieldChange, path string, fd protoreflect.FieldDescriptor, before, after protoreflect.List) {
+	for i := 0; i < max(before.Len(), after.Len()); i++ {
+		var b, a protoreflect.Value
+		if i < before.Len() {
+			b = before.Get(i)
+		}
+		if i < after.Len() {
+			a = after.Get(i)
+		}
+
+		diffValue(changes, fmt.Sprintf("%s[%d]", path, i), fd, b, i < before.Len(), a, i < after.Len())
+	}
+}
+
+func diffMap(changes *[]FieldChange, path string, fd protoreflect.FieldDescriptor, before, after protoreflect.Map) {
+	keys := map[string]protoreflect.MapKey{}
+	collect := func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys[k.String()] = k
+		return true
+	}
+	before.Range(collect)
+	after.Range(collect)
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		k := keys[name]
+		diffValue(changes, fmt.Sprintf("%s[%s]", path, name), fd.MapValue(), before.Get(k), before.Has(k), after.Get(k), after.Has(k))
+	}
+}
+
+// diffValue compares a singular value, or one element of a list or map, of the field
+func diffValue(changes *[]FieldChange, path string, fd protoreflect.FieldDescriptor, before protoreflect.Value, hasBefore bool, after protoreflect.Value, hasAfter bool) {
+	if fd.Message() != nil && hasBefore && hasAfter && !isTimestamp(fd) {
+		diffMessage(changes, path, before.Message(), after.Message())
+		return
+	}
+
+	if hasBefore && hasAfter && before.Equal(after) {
+		return
+	}
+
+	change := FieldChange{Path: path}
+	if hasBefore {
+		change.Before = formatValue(fd, before)
+	}
+	if hasAfter {
+		change.After = formatValue(fd, after)
+	}
+
+	*changes = append(*changes, change)
+}
+
+func isTimestamp(fd protoreflect.FieldDescriptor) bool {
+	return fd.Message() != nil && fd.Message().FullName() == "google.protobuf.Timestamp"
+}
+
+// formatValue renders a value of the field on one line
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch {
+	case isTimestamp(fd):
+		ts, ok := v.Message().Interface().(*timestamppb.Timestamp)
+		if !ok {
+			return prototext.Format(v.Message().Interface())
+		}
+		return ts.AsTime().Format("2006-01-02 15:04:05")
+	case fd.Message() != nil:
+		return prototext.MarshalOptions{}.Format(v.Message().Interface())
+	}
+
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if value := fd.Enum().Values().ByNumber(v.Enum()); value != nil {
+			return string(value.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("(%d bytes)", len(v.Bytes()))
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return v.String()
+	}
+}
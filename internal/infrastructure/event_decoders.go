@@ -0,0 +1,126 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Howard3/gosignal"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrUnknownEventType is returned when decoding an event whose type wasn't registered
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// EventDecoders is a domain's registry of the proto message each of its event types carries. Aggregates
+// decode their own events, the registry is for reading payloads anywhere else, such as history pages and
+// exports.
+type EventDecoders struct {
+	upcasters *Upcasters
+	messages  map[string]protoreflect.MessageType
+}
+
+// NewEventDecoders creates an empty registry, events are run through the upcasters before they're decoded
+func NewEventDecoders(upcasters *Upcasters) *EventDecoders {
+	return &EventDecoders{upcasters: upcasters, messages: make(map[string]protoreflect.MessageType)}
+}
+
+// Register maps an event type to the message its payload is. It panics when the type is registered twice
+// since registries are filled at init.
+func (d *EventDecoders) Register(eventType string, msg proto.Message) *EventDecoders {
+	if _, ok := d.messages[eventType]; ok {
+		panic(fmt.Sprintf("event type %q already has a decoder", eventType))
+	}
+
+	d.messages[eventType] = msg.ProtoReflect().Type()
+
+	return d
+}
+
+// Decode upcasts the event and unmarshals its payload into the message registered for its type
+func (d *EventDecoders) Decode(evt gosignal.Event) (proto.Message, error) {
+	mt, ok := d.messages[evt.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEventType, evt.Type)
+	}
+
+	if d.upcasters != nil {
+		var err error
+		if evt, err = d.upcasters.Upcast(evt); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := mt.New().Interface()
+	if err := proto.Unmarshal(evt.Data, msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s version %d: %w", evt.Type, evt.Version, err)
+	}
+
+	return msg, nil
+}
+
+// DecodedEvent is a stored event with its payload decoded
+type DecodedEvent struct {
+	gosignal.Event
+	// Message is the decoded payload, nil when it couldn't be decoded
+	Message proto.Message
+	Err     error
+	// Changes are the fields that differ from the previous event of the same type, or every field set on
+	// the first one
+	Changes []FieldChange
+}
+
+// DecodeHistory decodes an aggregate's events in order. A payload that can't be decoded is recorded on its
+// event rather than failing the whole history.
+func (d *EventDecoders) DecodeHistory(events []gosignal.Event) []DecodedEvent {
+	decoded := make([]DecodedEvent, len(events))
+	previous := make(map[string]proto.Message)
+
+	for i, evt := range events {
+		msg, err := d.Decode(evt)
+		decoded[i] = DecodedEvent{Event: evt, Message: msg, Err: err}
+		if err != nil {
+			continue
+		}
+
+		decoded[i].Changes = DiffMessages(previous[evt.Type], msg)
+		previous[evt.Type] = msg
+	}
+
+	return decoded
+}
+
+// ExportedEvent is the JSON form of a decoded event
+type ExportedEvent struct {
+	Version   uint64          `json:"version"`
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Export renders the event for a JSON export, with the payload in protobuf's JSON mapping
+func (e DecodedEvent) Export() ExportedEvent {
+	out := ExportedEvent{
+		Version:   e.Version,
+		Type:      e.Type,
+		Timestamp: e.Timestamp,
+	}
+
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+		return out
+	}
+
+	data, err := protojson.Marshal(e.Message)
+	if err != nil {
+		out.Error = fmt.Sprintf("failed to render payload: %s", err)
+		return out
+	}
+	out.Data = data
+
+	return out
+}
@@ -0,0 +1,482 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Howard3/gosignal"
+	"github.com/Howard3/gosignal/drivers/snapshots"
+	"github.com/Howard3/gosignal/sourcing"
+)
+
+// kinds of integrity issue
+const (
+	IssueVersionGap       = "version_gap"
+	IssueDuplicateVersion = "duplicate_version"
+	IssueUnreplayable     = "unreplayable"
+	IssueSnapshotMismatch = "snapshot_mismatch"
+	IssueProjectionDrift  = "projection_drift"
+)
+
+// EventStream is what the integrity check needs to know about a domain's event table to replay it
+type EventStream struct {
+	EventTable string
+	// New returns an empty aggregate for events to be applied to
+	New func() sourcing.Aggregate
+	// SnapshotTable is the domain's snapshot table, empty when it doesn't snapshot its aggregates
+	SnapshotTable string
+	// SnapshotMatches reports whether a snapshot holds the state of the aggregate, which has been
+	// replayed up to the snapshot's version
+	SnapshotMatches func(agg sourcing.Aggregate, snapshot []byte) (bool, error)
+}
+
+// IntegrityIssue is one problem found by the integrity check. Projection drift is reported per
+// projection, the other kinds per aggregate.
+type IntegrityIssue struct {
+	Kind        string
+	EventTable  string
+	AggregateID string
+	Version     uint64
+	EventType   string
+	Projection  string
+	Detail      string
+	// Repaired is set when the check fixed the issue, gaps and events that don't replay never are since
+	// that would mean rewriting history
+	Repaired bool
+}
+
+// IntegrityReport is the outcome of an integrity check
+type IntegrityReport struct {
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	Aggregates  int
+	Events      int
+	Snapshots   int // snapshots compared with their events
+	Projections int // projections compared with a replay
+	Issues      []IntegrityIssue
+}
+
+// Unrepaired returns the issues still standing
+func (r IntegrityReport) Unrepaired() []IntegrityIssue {
+	var issues []IntegrityIssue
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// IntegrityChecker replays every aggregate of every event stream to find corrupted streams, events that
+// no longer apply, snapshots that disagree with their events and projections that disagree with a replay
+type IntegrityChecker struct {
+	db        *sql.DB
+	projector *Projector
+	streams   []EventStream
+
+	// Repair deletes the snapshots that don't match their events and rebuilds the projections that
+	// drifted
+	Repair bool
+}
+
+// NewIntegrityChecker opens the database, projections are only compared when a projector is given
+func NewIntegrityChecker(conn SQLConnection, projector *Projector, streams ...EventStream) (*IntegrityChecker, error) {
+	db, err := conn.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	return &IntegrityChecker{db: db, projector: projector, streams: streams}, nil
+}
+
+// Check runs the integrity check. Streams are checked before projections so that, when repairing, the
+// projections are compared against aggregates loaded without the snapshots found to be bad.
+func (c *IntegrityChecker) Check(ctx context.Context) (IntegrityReport, error) {
+	report := IntegrityReport{StartedAt: time.Now()}
+
+	for _, stream := range c.streams {
+		if err := c.checkStream(ctx, stream, &report); err != nil {
+			return report, fmt.Errorf("check %s: %w", stream.EventTable, err)
+		}
+	}
+
+	if c.projector != nil {
+		for _, name := range c.projector.Names() {
+			if err := c.checkProjection(ctx, name, &report); err != nil {
+				return report, fmt.Errorf("check projection %s: %w", name, err)
+			}
+		}
+	}
+
+	report.FinishedAt = time.Now()
+
+	return report, nil
+}
+
+// checkStream reads the event table in aggregate and version order and replays each aggregate as its
+// events go by
+func (c *IntegrityChecker) checkStream(ctx context.Context, stream EventStream, report *IntegrityReport) error {
+	query := fmt.Sprintf(`SELECT type, data, version, timestamp, aggregate_id FROM %s ORDER BY aggregate_id, version, rowid`, stream.EventTable)
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+	defer rows.Close()
+
+	var replay *streamReplay
+	for rows.Next() {
+		var evt gosignal.Event
+		var timestamp int64
+		if err := rows.Scan(&evt.Type, &evt.Data, &evt.Version, &timestamp, &evt.AggregateID); err != nil {
+			return fmt.Errorf("scan event: %w", err)
+		}
+		evt.Timestamp = time.Unix(timestamp, 0)
+
+		if replay == nil || replay.id != evt.AggregateID {
+			if replay != nil {
+				replay.finish()
+			}
+
+			replay, err = c.startReplay(ctx, stream, evt.AggregateID, report)
+			if err != nil {
+				return err
+			}
+		}
+
+		replay.apply(evt)
+		report.Events++
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read events: %w", err)
+	}
+
+	if replay != nil {
+		replay.finish()
+	}
+
+	// the snapshots are deleted once the events have been read
+	rows.Close()
+
+	return c.repairSnapshots(ctx, stream, report)
+}
+
+// streamReplay is the replay of one aggregate by checkStream
+type streamReplay struct {
+	stream   EventStream
+	id       string
+	agg      sourcing.Aggregate
+	snapshot *sourcing.Snapshot
+	report   *IntegrityReport
+	// broken is set once an event failed to apply, the ones after it are skipped
+	broken bool
+}
+
+func (c *IntegrityChecker) startReplay(ctx context.Context, stream EventStream, id string, report *IntegrityReport) (*streamReplay, error) {
+	agg := stream.New()
+	agg.SetID(id)
+
+	replay := &streamReplay{stream: stream, id: id, agg: agg, report: report}
+	report.Aggregates++
+
+	if stream.SnapshotTable != "" {
+		store := snapshots.SQLStore{DB: c.db, TableName: stream.SnapshotTable}
+		snapshot, err := store.Load(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("load snapshot of %s: %w", id, err)
+		}
+		replay.snapshot = snapshot
+	}
+
+	return replay, nil
+}
+
+func (r *streamReplay) issue(kind string, evt gosignal.Event, detail string) {
+	r.report.Issues = append(r.report.Issues, IntegrityIssue{
+		Kind:        kind,
+		EventTable:  r.stream.EventTable,
+		AggregateID: r.id,
+		Version:     evt.Version,
+		EventType:   evt.Type,
+		Detail:      detail,
+	})
+}
+
+func (r *streamReplay) apply(evt gosignal.Event) {
+	if r.broken {
+		return
+	}
+
+	expected := r.agg.GetVersion()
+	switch {
+	case evt.Version < expected:
+		r.issue(IssueDuplicateVersion, evt, fmt.Sprintf("version %d stored more than once", evt.Version))
+		r.broken = true
+		return
+	case evt.Version > expected:
+		missing := fmt.Sprintf("version %d is missing", expected)
+		if evt.Version-expected > 1 {
+			missing = fmt.Sprintf("versions %d to %d are missing", expected, evt.Version-1)
+		}
+		r.issue(IssueVersionGap, evt, missing)
+		r.broken = true
+		return
+	}
+
+	if err := r.agg.Apply(evt); err != nil {
+		// errors joined by the sourcing package span several lines
+		r.issue(IssueUnreplayable, evt, strings.ReplaceAll(err.Error(), "\n", ": "))
+		r.broken = true
+		return
+	}
+
+	if r.snapshot != nil && r.agg.GetVersion() == r.snapshot.Version {
+		r.compareSnapshot()
+	}
+}
+
+// finish reports a snapshot that the replay never reached, it's either ahead of the stream or taken from
+// events that no longer replay
+func (r *streamReplay) finish() {
+	if r.snapshot == nil {
+		return
+	}
+
+	evt := gosignal.Event{Version: r.snapshot.Version}
+	if r.broken {
+		r.issue(IssueSnapshotMismatch, evt, "snapshot is past an event that doesn't replay")
+	} else {
+		r.issue(IssueSnapshotMismatch, evt, fmt.Sprintf("snapshot is at version %d, the stream ends at %d", r.snapshot.Version, r.agg.GetVersion()))
+	}
+	r.snapshot = nil
+}
+
+func (r *streamReplay) compareSnapshot() {
+	defer func() { r.snapshot = nil }()
+
+	r.report.Snapshots++
+	evt := gosignal.Event{Version: r.snapshot.Version}
+
+	if r.stream.SnapshotMatches == nil {
+		return
+	}
+
+	ok, err := r.stream.SnapshotMatches(r.agg, r.snapshot.Data)
+	switch {
+	case err != nil:
+		r.issue(IssueSnapshotMismatch, evt, fmt.Sprintf("snapshot doesn't decode: %s", err))
+	case !ok:
+		r.issue(IssueSnapshotMismatch, evt, "snapshot differs from the replayed state")
+	}
+}
+
+// repairSnapshots deletes the snapshots of the stream found to be bad, the aggregates are replayed from
+// their events on the next load and snapshotted again
+func (c *IntegrityChecker) repairSnapshots(ctx context.Context, stream EventStream, report *IntegrityReport) error {
+	if !c.Repair || stream.SnapshotTable == "" {
+		return nil
+	}
+
+	store := snapshots.SQLStore{DB: c.db, TableName: stream.SnapshotTable}
+	for i, issue := range report.Issues {
+		if issue.Kind != IssueSnapshotMismatch || issue.EventTable != stream.EventTable {
+			continue
+		}
+
+		if err := store.Delete(ctx, issue.AggregateID); err != nil {
+			return fmt.Errorf("delete snapshot of %s: %w", issue.AggregateID, err)
+		}
+		report.Issues[i].Repaired = true
+	}
+
+	return nil
+}
+
+// checkProjection compares a projection with a replay and, when repairing, rebuilds it. A projection with
+// aggregates that fail to project isn't rebuilt since the rebuild would stop at the first of them.
+func (c *IntegrityChecker) checkProjection(ctx context.Context, name string, report *IntegrityReport) error {
+	drift, err := c.projector.Verify(ctx, name)
+	if err != nil {
+		return err
+	}
+	report.Projections++
+
+	if !drift.Drifted() {
+		return nil
+	}
+
+	issue := IntegrityIssue{
+		Kind:       IssueProjectionDrift,
+		EventTable: drift.EventTable,
+		Projection: name,
+		Detail:     fmt.Sprintf("%d rows missing, %d rows unexpected", drift.Missing, drift.Unexpected),
+	}
+	if len(drift.Skipped) > 0 {
+		issue.Detail += fmt.Sprintf(", %d aggregates failed to project", len(drift.Skipped))
+	}
+
+	if c.Repair && len(drift.Skipped) == 0 {
+		if err := c.projector.Rebuild(ctx, name); err != nil {
+			return err
+		}
+		issue.Repaired = true
+	}
+
+	report.Issues = append(report.Issues, issue)
+
+	return nil
+}
+
+// ProjectionDrift is how the live table of a projection differs from a replay of its event table
+type ProjectionDrift struct {
+	Projection string
+	EventTable string
+	// Missing is the number of rows the replay has that the live table doesn't
+	Missing int64
+	// Unexpected is the number of live rows the replay doesn't have, a changed row counts as both
+	Unexpected int64
+	// Skipped are the aggregates that failed to project, their rows are missing from the replay
+	Skipped []string
+}
+
+// Drifted reports whether the live table differs from the replay
+func (d ProjectionDrift) Drifted() bool {
+	return d.Missing > 0 || d.Unexpected > 0
+}
+
+// unverifiedColumns are stamped with the time a row is written and differ between any two writes of it
+var unverifiedColumns = map[string]bool{"created_at": true, "updated_at": true}
+
+// Verify replays every aggregate of a projection into a scratch table and compares it with the live table,
+// which is left as it is. Events the live table hasn't caught up with yet show as drift.
+func (p *Projector) Verify(ctx context.Context, name string) (ProjectionDrift, error) {
+	proj, err := p.lookup(name)
+	if err != nil {
+		return ProjectionDrift{}, err
+	}
+
+	if !p.acquire(name) {
+		return ProjectionDrift{}, ErrProjectionBusy
+	}
+	defer p.release(name)
+
+	drift := ProjectionDrift{Projection: name, EventTable: proj.EventTable}
+	scratch := proj.Table + "_verify"
+
+	if err := p.createScratch(ctx, proj.Table, scratch); err != nil {
+		return drift, err
+	}
+
+	defer func() {
+		if _, err := p.db.ExecContext(context.Background(), fmt.Sprintf(`DROP TABLE IF EXISTS %s`, scratch)); err != nil {
+			slog.Error("failed to drop verify table", "table", scratch, "error", err)
+		}
+	}()
+
+	var position int64
+	for {
+		ids, last, err := p.nextAggregates(ctx, proj.EventTable, position, 0)
+		if err != nil {
+			return drift, err
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+
+		writers := make([]ProjectionWriter, 0, len(ids))
+		for _, id := range ids {
+			writer, err := proj.Project(ctx, id)
+			if err != nil {
+				drift.Skipped = append(drift.Skipped, id)
+				continue
+			}
+			writers = append(writers, writer)
+		}
+
+		if err := p.writeBatch(ctx, proj, scratch, "", writers, 0); err != nil {
+			return drift, err
+		}
+
+		position = last
+	}
+
+	columns, err := p.verifiedColumns(ctx, proj.Table)
+	if err != nil {
+		return drift, err
+	}
+
+	if drift.Missing, err = p.countExcept(ctx, columns, scratch, proj.Table); err != nil {
+		return drift, err
+	}
+
+	if drift.Unexpected, err = p.countExcept(ctx, columns, proj.Table, scratch); err != nil {
+		return drift, err
+	}
+
+	return drift, nil
+}
+
+func (p *Projector) createScratch(ctx context.Context, table, scratch string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	if err := copyTableSchema(ctx, tx, table, scratch); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// verifiedColumns returns the quoted columns of table that a replay is expected to reproduce
+func (p *Projector) verifiedColumns(ctx context.Context, table string) (string, error) {
+	rows, err := p.db.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return "", fmt.Errorf("read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return "", fmt.Errorf("scan column: %w", err)
+		}
+
+		if !unverifiedColumns[name] {
+			columns = append(columns, `"`+name+`"`)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("read columns of %s: %w", table, err)
+	}
+
+	if len(columns) == 0 {
+		return "", errors.New("no columns to compare in " + table)
+	}
+
+	return strings.Join(columns, ", "), nil
+}
+
+// countExcept counts the rows of table a that aren't in table b
+func (p *Projector) countExcept(ctx context.Context, columns, a, b string) (int64, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM (SELECT %s FROM %s EXCEPT SELECT %s FROM %s)`, columns, a, columns, b)
+
+	var n int64
+	if err := p.db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+		return 0, fmt.Errorf("compare %s with %s: %w", a, b, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,252 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Howard3/gosignal"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	JetStreamStream        = "EVENTS"
+	JetStreamSubjectPrefix = "events."
+
+	defaultJetStreamAckWait    = time.Minute
+	defaultJetStreamMaxDeliver = 10
+	jetStreamMaxAge            = 7 * 24 * time.Hour
+	jetStreamDuplicates        = 10 * time.Minute
+	jetStreamRelaySubscriber   = "jetstream"
+)
+
+// headers carrying the parts of the event that aren't in the message body
+const (
+	headerEventType   = "Event-Type"
+	headerAggregateID = "Event-Aggregate-Id"
+	headerVersion     = "Event-Version"
+	headerTimestamp   = "Event-Timestamp"
+)
+
+// JetStreamQueue is a gosignal queue on a NATS JetStream stream, for running several instances of the
+// app and letting other services subscribe to the domain events on "events.<type>".
+//
+// Events reach the stream through the SQL outbox: the queue relays every event stored to the stream, with
+// its aggregate and version as headers, and acknowledges it in the outbox once the stream has it. The
+// event's type, aggregate and version make up its message ID so a relay repeated after a crash is
+// dropped by the stream's duplicate window.
+//
+// Each subscriber and message type is a durable consumer, instances subscribing under the same name share
+// it and every message is handled by one of them.
+type JetStreamQueue struct {
+	js     jetstream.JetStream
+	outbox *SQLQueue
+
+	mu   sync.Mutex
+	subs map[string]*jetStreamSubscription
+	next int
+
+	// AckWait is how long a delivered message waits for its acknowledgement before it's delivered again
+	AckWait time.Duration
+	// MaxDeliver is the number of deliveries after which the stream gives up on a message
+	MaxDeliver int
+}
+
+type jetStreamSubscription struct {
+	consume jetstream.ConsumeContext
+	cancel  context.CancelFunc
+}
+
+// NewJetStreamQueue creates or updates the event stream and starts relaying the outbox to it until the
+// context is done
+func NewJetStreamQueue(ctx context.Context, nc *nats.Conn, outbox *SQLQueue) (*JetStreamQueue, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("open jetstream: %w", err)
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       JetStreamStream,
+		Subjects:   []string{JetStreamSubjectPrefix + ">"},
+		MaxAge:     jetStreamMaxAge,
+		Duplicates: jetStreamDuplicates,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create stream %s: %w", JetStreamStream, err)
+	}
+
+	q := &JetStreamQueue{
+		js:         js,
+		outbox:     outbox,
+		subs:       map[string]*jetStreamSubscription{},
+		AckWait:    defaultJetStreamAckWait,
+		MaxDeliver: defaultJetStreamMaxDeliver,
+	}
+
+	if err := ConsumeEvents(ctx, outbox, Subscriber{Name: jetStreamRelaySubscriber}, q.publish, AllMessageTypes); err != nil {
+		return nil, fmt.Errorf("relay outbox: %w", err)
+	}
+
+	return q, nil
+}
+
+// publish relays an event from the outbox to the stream
+func (q *JetStreamQueue) publish(ctx context.Context, evt gosignal.Event) error {
+	msg := nats.NewMsg(JetStreamSubjectPrefix + evt.Type)
+	msg.Data = evt.Data
+	msg.Header.Set(headerEventType, evt.Type)
+	msg.Header.Set(headerAggregateID, evt.AggregateID)
+	msg.Header.Set(headerVersion, strconv.FormatUint(evt.Version, 10))
+	msg.Header.Set(headerTimestamp, strconv.FormatInt(evt.Timestamp.Unix(), 10))
+
+	// event types belong to one domain and versions are unique per aggregate
+	id := evt.Type + ":" + evt.AggregateID + ":" + strconv.FormatUint(evt.Version, 10)
+	if _, err := q.js.PublishMsg(ctx, msg, jetstream.WithMsgID(id)); err != nil {
+		return fmt.Errorf("publish %s: %w", evt.Type, err)
+	}
+
+	return nil
+}
+
+// Send is called once the event is stored in the outbox, it wakes the relay
+func (q *JetStreamQueue) Send(messageType string, message []byte) error {
+	return q.outbox.Send(messageType, message)
+}
+
+// Subscribe subscribes to a message type under the type's name, see SubscribeAs
+func (q *JetStreamQueue) Subscribe(messageType string) (string, chan gosignal.QueueMessage, error) {
+	return q.SubscribeAs(Subscriber{Name: messageType}, messageType)
+}
+
+// SubscribeAs delivers the messages of messageType to the returned channel until Unsubscribe, which
+// closes it. The durable consumer is created on first use and starts with the messages published after.
+// The stream is filtered by subject, so the subscriber's event table doesn't matter.
+func (q *JetStreamQueue) SubscribeAs(subscriber Subscriber, messageType string) (string, chan gosignal.QueueMessage, error) {
+	durable := consumerName(subscriber.Name + "_" + messageType)
+
+	consumer, err := q.js.CreateOrUpdateConsumer(context.Background(), JetStreamStream, jetstream.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: JetStreamSubjectPrefix + messageType,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       q.AckWait,
+		MaxDeliver:    q.MaxDeliver,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("create consumer %s: %w", durable, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan gosignal.QueueMessage)
+
+	consume, err := consumer.Consume(func(msg jetstream.Msg) {
+		select {
+		case ch <- &jetStreamMessage{msg: msg}:
+		case <-ctx.Done():
+			_ = msg.Nak()
+		}
+	}, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		slog.Error("jetstream consumer error", "consumer", durable, "error", err)
+	}))
+	if err != nil {
+		cancel()
+		return "", nil, fmt.Errorf("consume %s: %w", durable, err)
+	}
+
+	go func() {
+		<-consume.Closed()
+		close(ch)
+	}()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.next++
+	id := durable + "#" + strconv.Itoa(q.next)
+	q.subs[id] = &jetStreamSubscription{consume: consume, cancel: cancel}
+
+	return id, ch, nil
+}
+
+// Unsubscribe stops the subscription and closes its channel, the durable consumer stays on the stream
+func (q *JetStreamQueue) Unsubscribe(_, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	sub, ok := q.subs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSubscription, id)
+	}
+
+	sub.cancel()
+	sub.consume.Stop()
+	delete(q.subs, id)
+
+	return nil
+}
+
+// consumerName replaces the characters a durable consumer name can't contain
+func consumerName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', '/', '\\', ' ', '\t':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// jetStreamMessage is a stream message with the event rebuilt from its headers
+type jetStreamMessage struct {
+	msg jetstream.Msg
+}
+
+// Attempts is the number of times the message has been delivered, this delivery included
+func (m *jetStreamMessage) Attempts() int {
+	meta, err := m.msg.Metadata()
+	if err != nil {
+		return 1
+	}
+	return int(meta.NumDelivered)
+}
+
+func (m *jetStreamMessage) Message() []byte {
+	return m.msg.Data()
+}
+
+func (m *jetStreamMessage) Type() string {
+	return m.msg.Headers().Get(headerEventType)
+}
+
+func (m *jetStreamMessage) Event() gosignal.Event {
+	h := m.msg.Headers()
+	version, _ := strconv.ParseUint(h.Get(headerVersion), 10, 64)
+	timestamp, _ := strconv.ParseInt(h.Get(headerTimestamp), 10, 64)
+
+	return gosignal.Event{
+		Type:        h.Get(headerEventType),
+		Data:        m.msg.Data(),
+		Version:     version,
+		Timestamp:   time.Unix(timestamp, 0),
+		AggregateID: h.Get(headerAggregateID),
+	}
+}
+
+func (m *jetStreamMessage) Ack() error {
+	return m.msg.Ack()
+}
+
+// Nack has the message delivered again straight away
+func (m *jetStreamMessage) Nack() error {
+	return m.msg.Nak()
+}
+
+// Retry has the message delivered again once params.BackoffUntil has passed
+func (m *jetStreamMessage) Retry(params gosignal.RetryParams) error {
+	return m.msg.NakWithDelay(time.Until(params.BackoffUntil))
+}
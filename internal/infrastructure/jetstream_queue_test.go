@@ -0,0 +1,258 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Howard3/gosignal"
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+)
+
+// runJetStreamServer starts an embedded NATS server with JetStream, stopped when the test ends
+func runJetStreamServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+
+	srv := natstest.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+
+	return srv
+}
+
+// jetStreamTestQueue connects a queue to srv, relaying from outbox until the test ends
+func jetStreamTestQueue(t *testing.T, srv *server.Server, outbox *SQLQueue) *JetStreamQueue {
+	t.Helper()
+
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	q, err := NewJetStreamQueue(ctx, nc, outbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return q
+}
+
+func streamMessages(t *testing.T, q *JetStreamQueue) uint64 {
+	t.Helper()
+
+	stream, err := q.js.Stream(context.Background(), JetStreamStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := stream.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info.State.Msgs
+}
+
+// eventually fails the test unless cond holds within a few seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func relayPosition(t *testing.T, db *sql.DB) int64 {
+	t.Helper()
+
+	var position int64
+	query := `SELECT position FROM queue_subscriptions WHERE subscriber = ? AND event_table = 'a_events'`
+	if err := db.QueryRow(query, jetStreamRelaySubscriber+"/"+AllMessageTypes).Scan(&position); err != nil {
+		t.Fatal(err)
+	}
+
+	return position
+}
+
+func TestJetStreamQueueRelaysOutboxOnce(t *testing.T) {
+	outbox, db := sqlQueueTestDB(t)
+	q := jetStreamTestQueue(t, runJetStreamServer(t), outbox)
+
+	storeTestEvent(t, db, "a_events", "created", "a1")
+	outbox.Send("created", nil)
+
+	eventually(t, "the event to be relayed", func() bool { return streamMessages(t, q) == 1 })
+	eventually(t, "the relay to acknowledge the event", func() bool { return relayPosition(t, db) == 1 })
+
+	// as if the process stopped after publishing but before the outbox recorded it
+	if _, err := db.Exec(`DELETE FROM queue_deliveries`); err != nil {
+		t.Fatal(err)
+	}
+	rewound, err := db.Exec(`UPDATE queue_subscriptions SET position = 0 WHERE subscriber = ?`, jetStreamRelaySubscriber+"/"+AllMessageTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := rewound.RowsAffected(); n == 0 {
+		t.Fatal("relay subscription not found")
+	}
+	outbox.Send("created", nil)
+
+	eventually(t, "the event to be relayed again", func() bool { return relayPosition(t, db) == 1 })
+	if n := streamMessages(t, q); n != 1 {
+		t.Errorf("stream holds %d messages after the relay repeated, want 1", n)
+	}
+}
+
+func TestJetStreamQueueSharesDurableConsumer(t *testing.T) {
+	srv := runJetStreamServer(t)
+	outbox, _ := sqlQueueTestDB(t)
+	first := jetStreamTestQueue(t, srv, outbox)
+	second := jetStreamTestQueue(t, srv, outbox)
+
+	worker := Subscriber{Name: "worker"}
+	_, firstCh, err := first.SubscribeAs(worker, "created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, secondCh, err := second.SubscribeAs(worker, "created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const events = 20
+	for i := range events {
+		evt := gosignal.Event{Type: "created", AggregateID: fmt.Sprint(i), Timestamp: time.Now()}
+		if err := first.publish(context.Background(), evt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := map[string]int{}
+	for len(seen) < events {
+		var msg gosignal.QueueMessage
+		select {
+		case msg = <-firstCh:
+		case msg = <-secondCh:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d events", len(seen), events)
+		}
+
+		seen[msg.(EventMessage).Event().AggregateID]++
+		if err := msg.Ack(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// anything delivered twice would arrive by now
+	select {
+	case msg := <-firstCh:
+		t.Errorf("%s delivered twice", msg.(EventMessage).Event().AggregateID)
+	case msg := <-secondCh:
+		t.Errorf("%s delivered twice", msg.(EventMessage).Event().AggregateID)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("%s delivered %d times", id, n)
+		}
+	}
+}
+
+func TestJetStreamQueueRedelivery(t *testing.T) {
+	outbox, _ := sqlQueueTestDB(t)
+	q := jetStreamTestQueue(t, runJetStreamServer(t), outbox)
+
+	id, ch, err := q.SubscribeAs(Subscriber{Name: "worker"}, "created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Unsubscribe("created", id)
+
+	published := gosignal.Event{Type: "created", AggregateID: "a1", Version: 3, Data: []byte("payload"), Timestamp: time.Unix(1700000000, 0)}
+	if err := q.publish(context.Background(), published); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := receive(t, ch)
+	if evt := msg.Event(); evt.AggregateID != "a1" || evt.Version != 3 || string(evt.Data) != "payload" || !evt.Timestamp.Equal(published.Timestamp) {
+		t.Errorf("event rebuilt from the message is %+v", evt)
+	}
+	if msg.Attempts() != 1 {
+		t.Errorf("first delivery: %d attempts", msg.Attempts())
+	}
+
+	if err := msg.Nack(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = receive(t, ch)
+	if msg.Attempts() != 2 {
+		t.Errorf("after a nack: %d attempts, want 2", msg.Attempts())
+	}
+
+	const backoff = 300 * time.Millisecond
+	retried := time.Now()
+	if err := msg.Retry(gosignal.RetryParams{BackoffUntil: retried.Add(backoff)}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = receive(t, ch)
+	if msg.Attempts() != 3 {
+		t.Errorf("after a retry: %d attempts, want 3", msg.Attempts())
+	}
+	if waited := time.Since(retried); waited < backoff-50*time.Millisecond {
+		t.Errorf("retry delivered after %s, want at least %s", waited, backoff)
+	}
+
+	if err := msg.Ack(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-ch:
+		t.Errorf("acknowledged message delivered again, attempt %d", msg.Attempts())
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestJetStreamQueueUnsubscribeClosesChannel(t *testing.T) {
+	outbox, _ := sqlQueueTestDB(t)
+	q := jetStreamTestQueue(t, runJetStreamServer(t), outbox)
+
+	id, ch, err := q.SubscribeAs(Subscriber{Name: "worker"}, "created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Unsubscribe("created", id); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("received a message after unsubscribing")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed after unsubscribing")
+	}
+
+	if err := q.Unsubscribe("created", id); err == nil {
+		t.Error("unsubscribing twice succeeded")
+	}
+}
@@ -0,0 +1,19 @@
+-- +goose Up
+-- One row per read model. position is the last event (by rowid of the event table) applied to the live
+-- table; the rebuild_* columns track a rebuild into the shadow table so it can resume after a restart.
+-- Times are unix seconds, like the event tables.
+CREATE TABLE IF NOT EXISTS projection_checkpoints (
+    name TEXT PRIMARY KEY,
+    event_table TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'live',
+    rebuild_position INTEGER NOT NULL DEFAULT 0,
+    rebuild_target INTEGER NOT NULL DEFAULT 0,
+    rebuild_started_at INTEGER NOT NULL DEFAULT 0,
+    rebuilt_at INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_at INTEGER NOT NULL DEFAULT 0
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS projection_checkpoints;
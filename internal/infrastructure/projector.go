@@ -0,0 +1,827 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var projectorMigrations embed.FS
+
+// projection statuses, as stored on the checkpoint
+const (
+	ProjectionLive             = "live"
+	ProjectionRebuildRequested = "rebuild_requested"
+	ProjectionRebuilding       = "rebuilding"
+	ProjectionFailed           = "failed"
+)
+
+// checkpoint columns a replay can advance
+const (
+	livePosition    = "position"
+	rebuildPosition = "rebuild_position"
+)
+
+const (
+	defaultProjectorBatchSize = 100
+	maxRebuildJobs            = 20
+)
+
+var (
+	ErrUnknownProjection = errors.New("unknown projection")
+	ErrUnknownAggregate  = errors.New("aggregate has no events")
+	ErrProjectionBusy    = errors.New("projection is being rebuilt or caught up")
+	ErrRebuildInProgress = errors.New("projection rebuild in progress")
+)
+
+// Execer is satisfied by both *sql.DB and *sql.Tx, for writers shared by the live update path and the
+// projector
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// ProjectionWriter replaces the rows of one aggregate in table, which is either the live table of the
+// projection or the shadow table of a rebuild
+type ProjectionWriter func(ctx context.Context, tx *sql.Tx, table string) error
+
+// Projection is a read model held in one table and derived from one event table. The table name is also
+// the projection's name.
+type Projection struct {
+	Table      string
+	EventTable string
+	// Project loads an aggregate and returns the writer for its rows. It is called before the projector
+	// opens its transaction because loading an aggregate may store a snapshot.
+	Project func(ctx context.Context, aggregateID string) (ProjectionWriter, error)
+}
+
+// ProjectionStatus is the checkpoint of a projection together with the head of its event table
+type ProjectionStatus struct {
+	Name             string
+	EventTable       string
+	Status           string
+	Position         int64 // last event applied to the live table
+	Head             int64 // last event in the event table
+	RebuildPosition  int64
+	RebuildTarget    int64
+	RebuildStartedAt time.Time
+	RebuiltAt        time.Time
+	LastError        string
+	Rows             int64 // rows in the live table
+	Running          bool  // a rebuild or catch up is running now
+}
+
+// Lag is how many event positions the live table is behind its event table
+func (s ProjectionStatus) Lag() int64 {
+	if s.Head < s.Position {
+		return 0
+	}
+	return s.Head - s.Position
+}
+
+// RebuildPercent is how far a running rebuild is towards the position it started at, 0 to 100
+func (s ProjectionStatus) RebuildPercent() int {
+	if s.RebuildTarget == 0 || s.RebuildPosition >= s.RebuildTarget {
+		return 100
+	}
+	return int(s.RebuildPosition * 100 / s.RebuildTarget)
+}
+
+// Projector keeps projections up to date by replaying their event tables in order from a checkpoint
+// persisted per projection.
+//
+// A rebuild replays every aggregate into a shadow table and then copies it over the live table in one
+// transaction, so readers never see a half built read model. Progress is checkpointed after every batch
+// and a rebuild interrupted by a restart picks up where it stopped.
+type Projector struct {
+	db          *sql.DB
+	projections map[string]Projection
+	names       []string
+
+	mu      sync.Mutex
+	running map[string]bool
+	jobs    []*RebuildJob
+
+	// BatchSize is the number of aggregates projected per transaction
+	BatchSize int
+	// OnProgress, when set, is called after every batch of a rebuild
+	OnProgress func(ProjectionStatus)
+}
+
+// NewProjector opens the database and migrates the checkpoint table
+func NewProjector(conn SQLConnection) (*Projector, error) {
+	db, err := conn.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	if err := MigrateSQLDatabase("projector", string(conn.Type), db, projectorMigrations); err != nil {
+		return nil, fmt.Errorf("migrate projector: %w", err)
+	}
+
+	return &Projector{
+		db:          db,
+		projections: map[string]Projection{},
+		running:     map[string]bool{},
+		BatchSize:   defaultProjectorBatchSize,
+	}, nil
+}
+
+// Register adds projections to the projector, it must be called before Run. A projection seen for the
+// first time starts at the end of its event table since its table was kept current by the domain's event
+// handlers until now.
+func (p *Projector) Register(ctx context.Context, projections ...Projection) error {
+	for _, proj := range projections {
+		if _, ok := p.projections[proj.Table]; ok {
+			return fmt.Errorf("projection %s registered twice", proj.Table)
+		}
+
+		query := fmt.Sprintf(`INSERT INTO projection_checkpoints (name, event_table, position, updated_at)
+			SELECT ?, ?, COALESCE(MAX(rowid), 0), ? FROM %s WHERE true
+			ON CONFLICT (name) DO UPDATE SET event_table = excluded.event_table`, proj.EventTable)
+		if _, err := p.db.ExecContext(ctx, query, proj.Table, proj.EventTable, time.Now().Unix()); err != nil {
+			return fmt.Errorf("register projection %s: %w", proj.Table, err)
+		}
+
+		p.projections[proj.Table] = proj
+		p.names = append(p.names, proj.Table)
+	}
+
+	return nil
+}
+
+// Names returns the registered projections in the order they were registered
+func (p *Projector) Names() []string {
+	return append([]string(nil), p.names...)
+}
+
+func (p *Projector) lookup(name string) (Projection, error) {
+	proj, ok := p.projections[name]
+	if !ok {
+		return Projection{}, fmt.Errorf("%w: %s", ErrUnknownProjection, name)
+	}
+
+	return proj, nil
+}
+
+// acquire marks a projection as running, it reports false when something else already is
+func (p *Projector) acquire(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running[name] {
+		return false
+	}
+	p.running[name] = true
+	return true
+}
+
+func (p *Projector) release(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.running, name)
+}
+
+func (p *Projector) isRunning(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.running[name]
+}
+
+// RequestRebuild flags a projection to be rebuilt on the next pass of Run
+func (p *Projector) RequestRebuild(ctx context.Context, name string) error {
+	if _, err := p.lookup(name); err != nil {
+		return err
+	}
+
+	query := `UPDATE projection_checkpoints SET status = ?, updated_at = ? WHERE name = ? AND status != ?`
+	if _, err := p.db.ExecContext(ctx, query, ProjectionRebuildRequested, time.Now().Unix(), name, ProjectionRebuilding); err != nil {
+		return fmt.Errorf("request rebuild of %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Run resumes interrupted rebuilds, runs requested ones and catches every projection up with its event
+// table, then repeats every interval until the context is done
+func (p *Projector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.runPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Projector) runPending(ctx context.Context) {
+	for _, name := range p.names {
+		status, err := p.Status(ctx, name)
+		if err != nil {
+			slog.Error("failed to read projection checkpoint", "projection", name, "error", err)
+			continue
+		}
+
+		switch status.Status {
+		case ProjectionRebuilding, ProjectionRebuildRequested:
+			err = p.Rebuild(ctx, name)
+		default:
+			err = p.CatchUp(ctx, name)
+		}
+
+		if err != nil && !errors.Is(err, ErrProjectionBusy) {
+			slog.Error("failed to update projection", "projection", name, "error", err)
+		}
+	}
+}
+
+// CatchUp projects the aggregates with events after the live checkpoint into the live table
+func (p *Projector) CatchUp(ctx context.Context, name string) error {
+	proj, err := p.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if !p.acquire(name) {
+		return ErrProjectionBusy
+	}
+	defer p.release(name)
+
+	status, err := p.Status(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.replay(ctx, proj, proj.Table, livePosition, status.Position, 0); err != nil {
+		p.recordError(ctx, name, status.Status, err)
+		return err
+	}
+
+	return nil
+}
+
+// Rebuild replays the whole event table into a shadow table and swaps it in for the live table. A rebuild
+// that was interrupted resumes from its checkpoint.
+func (p *Projector) Rebuild(ctx context.Context, name string) error {
+	proj, err := p.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if !p.acquire(name) {
+		return ErrProjectionBusy
+	}
+	defer p.release(name)
+
+	job := p.startJob(name, "")
+	err = p.rebuild(ctx, proj)
+	p.finishJob(job, err)
+
+	if err != nil {
+		p.recordError(ctx, name, ProjectionFailed, err)
+		return fmt.Errorf("rebuild %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// StartRebuild flags a projection for a rebuild and runs it in the background. The request is persisted
+// first, so a rebuild cut short by a restart is resumed by Run.
+func (p *Projector) StartRebuild(name string) error {
+	if _, err := p.lookup(name); err != nil {
+		return err
+	}
+
+	if p.isRunning(name) {
+		return ErrProjectionBusy
+	}
+
+	ctx := context.Background()
+	if err := p.RequestRebuild(ctx, name); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := p.Rebuild(ctx, name); err != nil && !errors.Is(err, ErrProjectionBusy) {
+			slog.Error("background projection rebuild failed", "projection", name, "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// RebuildAggregate rewrites the live rows of a single aggregate, leaving the checkpoint where it is. It
+// refuses while a rebuild of the projection is running or pending, since the swap at the end of the
+// rebuild would overwrite the rows it writes, and while a catch up is running.
+func (p *Projector) RebuildAggregate(ctx context.Context, name, aggregateID string) (err error) {
+	proj, err := p.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if !p.acquire(name) {
+		if status, err := p.Status(ctx, name); err == nil && status.Status == ProjectionRebuilding {
+			return fmt.Errorf("%w: %s", ErrRebuildInProgress, name)
+		}
+		return fmt.Errorf("%w: %s", ErrProjectionBusy, name)
+	}
+	defer p.release(name)
+
+	status, err := p.Status(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if status.Status == ProjectionRebuilding || status.Status == ProjectionRebuildRequested {
+		return fmt.Errorf("%w: %s", ErrRebuildInProgress, name)
+	}
+
+	var events int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE aggregate_id = ?`, proj.EventTable)
+	if err := p.db.QueryRowContext(ctx, query, aggregateID).Scan(&events); err != nil {
+		return fmt.Errorf("count events of %s: %w", aggregateID, err)
+	}
+
+	if events == 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownAggregate, aggregateID)
+	}
+
+	job := p.startJob(name, aggregateID)
+	defer func() { p.finishJob(job, err) }()
+
+	write, err := proj.Project(ctx, aggregateID)
+	if err != nil {
+		return fmt.Errorf("project aggregate %s: %w", aggregateID, err)
+	}
+
+	return p.writeBatch(ctx, proj, proj.Table, "", []ProjectionWriter{write}, 0)
+}
+
+func (p *Projector) rebuild(ctx context.Context, proj Projection) error {
+	shadow := proj.Table + "_rebuild"
+
+	status, err := p.Status(ctx, proj.Table)
+	if err != nil {
+		return err
+	}
+
+	exists, err := p.tableExists(ctx, shadow)
+	if err != nil {
+		return err
+	}
+
+	if status.Status != ProjectionRebuilding || !exists {
+		if err := p.startRebuild(ctx, proj, shadow); err != nil {
+			return err
+		}
+
+		if status, err = p.Status(ctx, proj.Table); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("rebuilding projection", "projection", proj.Table, "position", status.RebuildPosition, "target", status.RebuildTarget)
+
+	// replay up to where the event table ended when the rebuild started, then whatever was stored since
+	position, err := p.replay(ctx, proj, shadow, rebuildPosition, status.RebuildPosition, status.RebuildTarget)
+	if err != nil {
+		return err
+	}
+
+	if position, err = p.replay(ctx, proj, shadow, rebuildPosition, position, 0); err != nil {
+		return err
+	}
+
+	if err := p.swap(ctx, proj, shadow, position); err != nil {
+		return err
+	}
+
+	slog.Info("rebuilt projection", "projection", proj.Table, "position", position)
+
+	return nil
+}
+
+// startRebuild recreates the shadow table from the live table's schema and resets the rebuild checkpoint
+func (p *Projector) startRebuild(ctx context.Context, proj Projection, shadow string) (err error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = copyTableSchema(ctx, tx, proj.Table, shadow); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE projection_checkpoints SET
+			status = ?,
+			rebuild_position = 0,
+			rebuild_target = (SELECT COALESCE(MAX(rowid), 0) FROM %s),
+			rebuild_started_at = ?,
+			last_error = '',
+			updated_at = ?
+		WHERE name = ?`, proj.EventTable)
+	now := time.Now().Unix()
+	if _, err = tx.ExecContext(ctx, query, ProjectionRebuilding, now, now, proj.Table); err != nil {
+		return fmt.Errorf("reset rebuild checkpoint: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// copyTableSchema (re)creates the table "to" with the columns and constraints of table
+func copyTableSchema(ctx context.Context, tx *sql.Tx, table, to string) error {
+	var create string
+	if err := tx.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&create); err != nil {
+		return fmt.Errorf("read schema of %s: %w", table, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, to)); err != nil {
+		return fmt.Errorf("drop shadow table: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, renameCreateTable(create, table, to)); err != nil {
+		return fmt.Errorf("create shadow table: %w", err)
+	}
+
+	return nil
+}
+
+var createTableName = regexp.MustCompile("(?is)^\\s*CREATE\\s+TABLE\\s+(IF\\s+NOT\\s+EXISTS\\s+)?")
+
+// renameCreateTable rewrites a CREATE TABLE statement to create the table under a different name
+func renameCreateTable(create, table, to string) string {
+	loc := createTableName.FindStringIndex(create)
+	if loc == nil {
+		return create
+	}
+
+	rest := create[loc[1]:]
+	name := regexp.MustCompile("^[\"`\\[]?" + regexp.QuoteMeta(table) + "[\"`\\]]?")
+	return "CREATE TABLE " + to + name.ReplaceAllLiteralString(rest, "")
+}
+
+// swap copies the shadow table over the live table, drops it and moves the live checkpoint to where the
+// rebuild ended, all in one transaction. Events stored between the last replay and the swap are picked up
+// by the next catch up.
+//
+// Rows are upserted on the primary key rather than the live table being dropped, and only rows the
+// rebuild didn't produce are deleted. Other read models reference some live tables with ON DELETE CASCADE,
+// which would otherwise empty them. Turning foreign keys off instead would need a connection scoped
+// pragma, and the remote libsql driver doesn't promise that a pragma sticks to the connection the swap
+// runs on. The live table keeps its indexes, so none have to be recreated.
+func (p *Projector) swap(ctx context.Context, proj Projection, shadow string, position int64) (err error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	columns, keys, err := tableColumns(ctx, tx, proj.Table)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range swapQueries(proj.Table, shadow, columns, keys) {
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("copy rebuilt rows into %s: %w", proj.Table, err)
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %s`, shadow)); err != nil {
+		return fmt.Errorf("drop shadow table: %w", err)
+	}
+
+	now := time.Now().Unix()
+	query := `UPDATE projection_checkpoints SET
+			status = ?,
+			position = ?,
+			rebuild_position = 0,
+			rebuild_target = 0,
+			rebuilt_at = ?,
+			last_error = '',
+			updated_at = ?
+		WHERE name = ?`
+	if _, err = tx.ExecContext(ctx, query, ProjectionLive, position, now, now, proj.Table); err != nil {
+		return fmt.Errorf("update checkpoint: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// swapQueries are the statements that make the live table hold the shadow table's rows. A table without a
+// primary key can't be referenced by another, so its rows are simply replaced.
+func swapQueries(table, shadow string, columns, keys []string) []string {
+	cols := strings.Join(columns, ", ")
+	if len(keys) == 0 {
+		return []string{
+			fmt.Sprintf(`DELETE FROM %s`, table),
+			fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s`, table, cols, cols, shadow),
+		}
+	}
+
+	match := make([]string, len(keys))
+	for i, key := range keys {
+		match[i] = fmt.Sprintf(`%s.%s = %s.%s`, shadow, key, table, key)
+	}
+
+	var set []string
+	for _, column := range columns {
+		if !slices.Contains(keys, column) {
+			set = append(set, fmt.Sprintf(`%s = excluded.%s`, column, column))
+		}
+	}
+
+	// the WHERE clause keeps SQLite from reading ON CONFLICT as part of a join
+	upsert := fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s WHERE true ON CONFLICT (%s) DO `,
+		table, cols, cols, shadow, strings.Join(keys, ", "))
+	if len(set) == 0 {
+		upsert += "NOTHING"
+	} else {
+		upsert += "UPDATE SET " + strings.Join(set, ", ")
+	}
+
+	return []string{
+		fmt.Sprintf(`DELETE FROM %s WHERE NOT EXISTS (SELECT 1 FROM %s WHERE %s)`, table, shadow, strings.Join(match, " AND ")),
+		upsert,
+	}
+}
+
+// tableColumns returns the columns of a table and, in key order, those making up its primary key
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (columns, keys []string, err error) {
+	rows, err := tx.QueryContext(ctx, `SELECT name, pk FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	keyPositions := map[int]string{}
+	for rows.Next() {
+		var name string
+		var pk int
+		if err := rows.Scan(&name, &pk); err != nil {
+			return nil, nil, fmt.Errorf("scan column: %w", err)
+		}
+
+		columns = append(columns, `"`+name+`"`)
+		if pk > 0 {
+			keyPositions[pk] = `"` + name + `"`
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("table %s has no columns", table)
+	}
+
+	for i := 1; i <= len(keyPositions); i++ {
+		keys = append(keys, keyPositions[i])
+	}
+
+	return columns, keys, nil
+}
+
+// replay projects, batch by batch, every aggregate with events after the given position into table and
+// advances the checkpoint column in the same transaction. Aggregates are visited in the order of their
+// latest event, so once a batch commits everything up to its last position has been projected. An upTo of
+// zero replays to the end of the event table. It returns the position reached.
+func (p *Projector) replay(ctx context.Context, proj Projection, table, column string, position, upTo int64) (int64, error) {
+	for {
+		ids, last, err := p.nextAggregates(ctx, proj.EventTable, position, upTo)
+		if err != nil {
+			return position, err
+		}
+
+		if len(ids) == 0 {
+			return position, nil
+		}
+
+		writers := make([]ProjectionWriter, 0, len(ids))
+		for _, id := range ids {
+			writer, err := proj.Project(ctx, id)
+			if err != nil {
+				return position, fmt.Errorf("project aggregate %s: %w", id, err)
+			}
+			writers = append(writers, writer)
+		}
+
+		if err := p.writeBatch(ctx, proj, table, column, writers, last); err != nil {
+			return position, err
+		}
+
+		position = last
+
+		if column == rebuildPosition {
+			status, err := p.Status(ctx, proj.Table)
+			if err == nil {
+				slog.Info("projection rebuild progress", "projection", proj.Table, "position", position, "target", status.RebuildTarget, "percent", status.RebuildPercent())
+				if p.OnProgress != nil {
+					p.OnProgress(status)
+				}
+			}
+		}
+	}
+}
+
+// nextAggregates returns the next batch of aggregates with events after position and the position of
+// the latest event among them
+func (p *Projector) nextAggregates(ctx context.Context, eventTable string, position, upTo int64) ([]string, int64, error) {
+	where := "rowid > ?"
+	args := []any{position}
+	if upTo > 0 {
+		where += " AND rowid <= ?"
+		args = append(args, upTo)
+	}
+	args = append(args, p.BatchSize)
+
+	query := fmt.Sprintf(`SELECT aggregate_id, MAX(rowid) AS position FROM %s
+		WHERE %s
+		GROUP BY aggregate_id
+		ORDER BY position
+		LIMIT ?`, eventTable, where)
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query events of %s: %w", eventTable, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	last := position
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id, &last); err != nil {
+			return nil, 0, fmt.Errorf("scan aggregate: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, last, rows.Err()
+}
+
+func (p *Projector) writeBatch(ctx context.Context, proj Projection, table, column string, writers []ProjectionWriter, position int64) (err error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, write := range writers {
+		if err = write(ctx, tx, table); err != nil {
+			return err
+		}
+	}
+
+	if column != "" {
+		query := fmt.Sprintf(`UPDATE projection_checkpoints SET %s = ?, updated_at = ? WHERE name = ?`, column)
+		if _, err = tx.ExecContext(ctx, query, position, time.Now().Unix(), proj.Table); err != nil {
+			return fmt.Errorf("update checkpoint: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (p *Projector) recordError(ctx context.Context, name, status string, cause error) {
+	query := `UPDATE projection_checkpoints SET status = ?, last_error = ?, updated_at = ? WHERE name = ?`
+	if _, err := p.db.ExecContext(ctx, query, status, cause.Error(), time.Now().Unix(), name); err != nil {
+		slog.Error("failed to record projection error", "projection", name, "error", err)
+	}
+}
+
+func (p *Projector) tableExists(ctx context.Context, table string) (bool, error) {
+	var n int
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if err := p.db.QueryRowContext(ctx, query, table).Scan(&n); err != nil {
+		return false, fmt.Errorf("check table %s: %w", table, err)
+	}
+
+	return n > 0, nil
+}
+
+// Status returns the checkpoint of a projection
+func (p *Projector) Status(ctx context.Context, name string) (ProjectionStatus, error) {
+	proj, err := p.lookup(name)
+	if err != nil {
+		return ProjectionStatus{}, err
+	}
+
+	query := fmt.Sprintf(`SELECT name, event_table, status, position, (SELECT COALESCE(MAX(rowid), 0) FROM %s),
+			rebuild_position, rebuild_target, rebuild_started_at, rebuilt_at, last_error, (SELECT COUNT(*) FROM %s)
+		FROM projection_checkpoints WHERE name = ?`, proj.EventTable, proj.Table)
+
+	var s ProjectionStatus
+	var startedAt, rebuiltAt int64
+	err = p.db.QueryRowContext(ctx, query, name).Scan(&s.Name, &s.EventTable, &s.Status, &s.Position, &s.Head,
+		&s.RebuildPosition, &s.RebuildTarget, &startedAt, &rebuiltAt, &s.LastError, &s.Rows)
+	if err != nil {
+		return ProjectionStatus{}, fmt.Errorf("read checkpoint of %s: %w", name, err)
+	}
+
+	if startedAt > 0 {
+		s.RebuildStartedAt = time.Unix(startedAt, 0)
+	}
+	if rebuiltAt > 0 {
+		s.RebuiltAt = time.Unix(rebuiltAt, 0)
+	}
+	s.Running = p.isRunning(name)
+
+	return s, nil
+}
+
+// Statuses returns the checkpoints of every registered projection
+func (p *Projector) Statuses(ctx context.Context) ([]ProjectionStatus, error) {
+	statuses := make([]ProjectionStatus, 0, len(p.names))
+	for _, name := range p.names {
+		s, err := p.Status(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses, nil
+}
+
+// RebuildJob is a rebuild of a whole projection, or of one aggregate when AggregateID is set, run since
+// the server started
+type RebuildJob struct {
+	Projection  string
+	AggregateID string
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	Err         string
+}
+
+// Done reports whether the job has finished
+func (j RebuildJob) Done() bool {
+	return !j.FinishedAt.IsZero()
+}
+
+func (p *Projector) startJob(name, aggregateID string) *RebuildJob {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job := &RebuildJob{Projection: name, AggregateID: aggregateID, StartedAt: time.Now()}
+	p.jobs = append(p.jobs, job)
+	if len(p.jobs) > maxRebuildJobs {
+		p.jobs = p.jobs[len(p.jobs)-maxRebuildJobs:]
+	}
+
+	return job
+}
+
+func (p *Projector) finishJob(job *RebuildJob, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job.FinishedAt = time.Now()
+	if err != nil {
+		job.Err = err.Error()
+	}
+}
+
+// Jobs returns the most recent rebuild jobs, newest first
+func (p *Projector) Jobs() []RebuildJob {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs := make([]RebuildJob, 0, len(p.jobs))
+	for i := len(p.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, *p.jobs[i])
+	}
+
+	return jobs
+}
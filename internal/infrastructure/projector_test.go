@@ -0,0 +1,166 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// projectorTestDB is a database with a "parents" read model built from parent_events, which a
+// "children" table references with ON DELETE CASCADE
+func projectorTestDB(t *testing.T) (*Projector, *sql.DB) {
+	t.Helper()
+
+	conn := SQLConnection{Type: "sqlite3", URI: "file:" + filepath.Join(t.TempDir(), "projector.db") + "?_foreign_keys=on"}
+	p, err := NewProjector(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, _ := conn.Open()
+	t.Cleanup(func() { db.Close() })
+
+	for _, query := range []string{
+		`CREATE TABLE parent_events (aggregate_id TEXT NOT NULL, name TEXT NOT NULL)`,
+		`CREATE TABLE parents (id TEXT PRIMARY KEY, name TEXT NOT NULL)`,
+		`CREATE INDEX parents_name ON parents (name)`,
+		`CREATE TABLE children (parent_id TEXT NOT NULL REFERENCES parents (id) ON DELETE CASCADE)`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return p, db
+}
+
+// parentsProjection projects the latest name stored for each parent
+func parentsProjection(db *sql.DB) Projection {
+	return Projection{
+		Table:      "parents",
+		EventTable: "parent_events",
+		Project: func(ctx context.Context, aggregateID string) (ProjectionWriter, error) {
+			var name string
+			query := `SELECT name FROM parent_events WHERE aggregate_id = ? ORDER BY rowid DESC LIMIT 1`
+			if err := db.QueryRowContext(ctx, query, aggregateID).Scan(&name); err != nil {
+				return nil, err
+			}
+
+			return func(ctx context.Context, tx *sql.Tx, table string) error {
+				_, err := tx.ExecContext(ctx, `INSERT INTO `+table+` (id, name) VALUES (?, ?)
+					ON CONFLICT (id) DO UPDATE SET name = excluded.name`, aggregateID, name)
+				return err
+			}, nil
+		},
+	}
+}
+
+func count(t *testing.T, db *sql.DB, query string, args ...any) int {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestProjectorRebuildKeepsReferencingRows(t *testing.T) {
+	ctx := context.Background()
+	p, db := projectorTestDB(t)
+
+	for _, query := range []string{
+		`INSERT INTO parent_events (aggregate_id, name) VALUES ('a', 'Ann'), ('b', 'Bob')`,
+		// the live table is stale: a is misspelt and c has no events
+		`INSERT INTO parents (id, name) VALUES ('a', 'An'), ('c', 'Cat')`,
+		`INSERT INTO children (parent_id) VALUES ('a'), ('a'), ('c')`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.Register(ctx, parentsProjection(db)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Rebuild(ctx, "parents"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := count(t, db, `SELECT COUNT(*) FROM parents WHERE (id = 'a' AND name = 'Ann') OR (id = 'b' AND name = 'Bob')`); n != 2 {
+		t.Errorf("rebuilt parents: %d of 2 rows match", n)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM parents WHERE id = 'c'`); n != 0 {
+		t.Error("parent without events survived the rebuild")
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM children WHERE parent_id = 'a'`); n != 2 {
+		t.Errorf("children of a rebuilt parent: got %d, want 2", n)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM children WHERE parent_id = 'c'`); n != 0 {
+		t.Error("children of a removed parent weren't cascaded")
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM sqlite_master WHERE name IN ('parents_name', 'parents_rebuild')`); n != 1 {
+		t.Error("want the live index kept and the shadow table dropped")
+	}
+
+	status, err := p.Status(ctx, "parents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != ProjectionLive || status.Position != 2 {
+		t.Errorf("checkpoint after rebuild: %s at %d, want live at 2", status.Status, status.Position)
+	}
+}
+
+func TestProjectorRebuildAggregateWaitsForRebuild(t *testing.T) {
+	ctx := context.Background()
+	p, db := projectorTestDB(t)
+
+	if _, err := db.Exec(`INSERT INTO parent_events (aggregate_id, name) VALUES ('a', 'Ann')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Register(ctx, parentsProjection(db)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a catch up is running
+	p.acquire("parents")
+	if err := p.RebuildAggregate(ctx, "parents", "a"); !errors.Is(err, ErrProjectionBusy) {
+		t.Errorf("during a catch up: got %v, want ErrProjectionBusy", err)
+	}
+	p.release("parents")
+
+	// a rebuild is requested, or was interrupted, and hasn't been picked up yet
+	if err := p.RequestRebuild(ctx, "parents"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.RebuildAggregate(ctx, "parents", "a"); !errors.Is(err, ErrRebuildInProgress) {
+		t.Errorf("with a rebuild pending: got %v, want ErrRebuildInProgress", err)
+	}
+
+	// a rebuild is running
+	if _, err := db.Exec(`UPDATE projection_checkpoints SET status = ? WHERE name = 'parents'`, ProjectionRebuilding); err != nil {
+		t.Fatal(err)
+	}
+	p.acquire("parents")
+	if err := p.RebuildAggregate(ctx, "parents", "a"); !errors.Is(err, ErrRebuildInProgress) {
+		t.Errorf("during a rebuild: got %v, want ErrRebuildInProgress", err)
+	}
+	p.release("parents")
+
+	if n := count(t, db, `SELECT COUNT(*) FROM parents`); n != 0 {
+		t.Errorf("refused repairs wrote %d rows", n)
+	}
+
+	if err := p.Rebuild(ctx, "parents"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.RebuildAggregate(ctx, "parents", "a"); err != nil {
+		t.Errorf("after the rebuild: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+-- +goose Up
+-- The event tables are the outbox: an event is queued by the same insert that stores it. A subscription
+-- keeps, per event table, the position up to which every event of its type has been delivered, and the
+-- deliveries past it are tracked one row per event until they are acknowledged. Times are unix seconds.
+CREATE TABLE IF NOT EXISTS queue_subscriptions (
+    subscriber TEXT NOT NULL,
+    event_table TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (subscriber, event_table)
+);
+
+CREATE TABLE IF NOT EXISTS queue_deliveries (
+    subscriber TEXT NOT NULL,
+    event_table TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL DEFAULT 0,
+    delivered_at INTEGER NOT NULL DEFAULT 0,
+    failed_at INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (subscriber, event_table, position)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS queue_deliveries;
+DROP TABLE IF EXISTS queue_subscriptions;
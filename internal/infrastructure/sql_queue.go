@@ -0,0 +1,543 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Howard3/gosignal"
+)
+
+//go:embed queue/migrations/*.sql
+var queueMigrations embed.FS
+
+const (
+	defaultQueuePollInterval = 5 * time.Second
+	defaultQueueLease        = time.Minute
+	defaultQueueBatchSize    = 100
+	defaultQueueMaxAttempts  = 10
+	maxQueueBackoff          = 10 * time.Minute
+)
+
+// AllMessageTypes subscribes to the events of every type
+const AllMessageTypes = ""
+
+var ErrUnknownSubscription = errors.New("unknown subscription")
+
+// EventMessage is a queue message that carries the stored event it was queued for. The gosignal queue
+// interface only sends an event's type and payload, handlers need its aggregate and version too.
+type EventMessage interface {
+	gosignal.QueueMessage
+	Event() gosignal.Event
+}
+
+// Subscriber is a named consumer of a domain's events
+type Subscriber struct {
+	Name string
+	// EventTable is the table the domain stores its events in, a queue that reads events from their tables
+	// only looks there. Empty reads every table, which is what a relay of all events needs.
+	EventTable string
+}
+
+// DurableQueue is a queue whose subscriptions outlive the process. Subscribers with the same name share
+// the deliveries of a message type, each name gets every message of the type once.
+type DurableQueue interface {
+	gosignal.Queue
+	SubscribeAs(subscriber Subscriber, messageType string) (id string, ch chan gosignal.QueueMessage, err error)
+}
+
+// SQLQueue is a durable gosignal queue backed by the database the events are stored in. It's a
+// transactional outbox where the event tables are the outbox: an event is queued by the insert that
+// stores it, so a crash between storing the event and handling it can't lose the message. Send only
+// wakes the subscribers, which read the events from their tables.
+//
+// Deliveries are tracked per subscriber and event. A message that isn't acknowledged before its lease
+// runs out, including one held by a process that stopped, is delivered again, so handlers must be
+// idempotent.
+type SQLQueue struct {
+	db     *sql.DB
+	tables []string
+
+	mu   sync.Mutex
+	subs map[string]*queueSubscription
+	next int
+
+	// PollInterval is how often subscriptions look for retries, expired leases and events stored by
+	// other instances. Every subscription reads its event table once per interval.
+	PollInterval time.Duration
+	// Lease is how long a delivered message waits for its acknowledgement before it's delivered again
+	Lease time.Duration
+	// BatchSize is the number of events read per event table and poll
+	BatchSize int
+	// MaxAttempts is the number of deliveries after which a message is set aside as failed, 0 retries
+	// forever
+	MaxAttempts int
+}
+
+type queueSubscription struct {
+	id          string
+	subscriber  string
+	messageType string
+	tables      []string
+	ch          chan gosignal.QueueMessage
+	wake        chan struct{}
+	cancel      context.CancelFunc
+
+	mu sync.Mutex
+	// settled holds the tables with deliveries acknowledged or failed since they were last compacted
+	settled map[string]bool
+}
+
+// markSettled records that a delivery from table was acknowledged or failed, so the next pass compacts it
+func (sub *queueSubscription) markSettled(table string) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	sub.settled[table] = true
+}
+
+// takeSettled reports whether table has deliveries to compact and clears the mark
+func (sub *queueSubscription) takeSettled(table string) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	settled := sub.settled[table]
+	delete(sub.settled, table)
+	return settled
+}
+
+// NewSQLQueue opens the database and migrates the delivery tables, events are read from eventTables
+func NewSQLQueue(conn SQLConnection, eventTables ...string) (*SQLQueue, error) {
+	db, err := conn.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	migrations, err := fs.Sub(queueMigrations, "queue")
+	if err != nil {
+		return nil, fmt.Errorf("queue migrations: %w", err)
+	}
+
+	if err := MigrateSQLDatabase("queue", string(conn.Type), db, migrations); err != nil {
+		return nil, fmt.Errorf("migrate queue: %w", err)
+	}
+
+	return &SQLQueue{
+		db:           db,
+		tables:       eventTables,
+		subs:         map[string]*queueSubscription{},
+		PollInterval: defaultQueuePollInterval,
+		Lease:        defaultQueueLease,
+		BatchSize:    defaultQueueBatchSize,
+		MaxAttempts:  defaultQueueMaxAttempts,
+	}, nil
+}
+
+// Send is called once the event is stored, which queued it already. It wakes the subscribers of the
+// message type so they don't wait for their next poll.
+func (q *SQLQueue) Send(messageType string, _ []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, sub := range q.subs {
+		if sub.messageType != messageType && sub.messageType != AllMessageTypes {
+			continue
+		}
+
+		select {
+		case sub.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Subscribe subscribes to a message type under the type's name, reading every event table, see SubscribeAs
+func (q *SQLQueue) Subscribe(messageType string) (string, chan gosignal.QueueMessage, error) {
+	return q.SubscribeAs(Subscriber{Name: messageType}, messageType)
+}
+
+// SubscribeAs delivers the events of messageType, or of every type for AllMessageTypes, to the returned
+// channel until Unsubscribe, which closes it. Only the subscriber's event table is read, or every table
+// when it doesn't name one. A subscriber seen for the first time starts with the events stored after it
+// subscribed, one seen before resumes with the events it hasn't acknowledged.
+func (q *SQLQueue) SubscribeAs(subscriber Subscriber, messageType string) (string, chan gosignal.QueueMessage, error) {
+	name := subscriber.Name + "/" + messageType
+	now := time.Now().Unix()
+
+	tables := q.tables
+	if subscriber.EventTable != "" {
+		if !slices.Contains(q.tables, subscriber.EventTable) {
+			return "", nil, fmt.Errorf("subscribe %s: the queue doesn't read %s", name, subscriber.EventTable)
+		}
+		tables = []string{subscriber.EventTable}
+	}
+
+	// deliveries acknowledged before a restart may not have been compacted yet
+	settled := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		settled[table] = true
+	}
+
+	for _, table := range tables {
+		query := fmt.Sprintf(`INSERT INTO queue_subscriptions (subscriber, event_table, position, created_at)
+			SELECT ?, ?, COALESCE(MAX(rowid), 0), ? FROM %s WHERE true
+			ON CONFLICT (subscriber, event_table) DO NOTHING`, table)
+		if _, err := q.db.Exec(query, name, table, now); err != nil {
+			return "", nil, fmt.Errorf("subscribe %s to %s: %w", name, table, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q.mu.Lock()
+	q.next++
+	sub := &queueSubscription{
+		id:          name + "#" + strconv.Itoa(q.next),
+		subscriber:  name,
+		messageType: messageType,
+		tables:      tables,
+		ch:          make(chan gosignal.QueueMessage),
+		wake:        make(chan struct{}, 1),
+		cancel:      cancel,
+		settled:     settled,
+	}
+	q.subs[sub.id] = sub
+	q.mu.Unlock()
+
+	go q.poll(ctx, sub)
+
+	return sub.id, sub.ch, nil
+}
+
+// Unsubscribe stops the deliveries of a subscription and closes its channel. Messages it had delivered
+// but not acknowledged are delivered again once their lease runs out.
+func (q *SQLQueue) Unsubscribe(_, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	sub, ok := q.subs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSubscription, id)
+	}
+
+	sub.cancel()
+	delete(q.subs, id)
+
+	return nil
+}
+
+func (q *SQLQueue) poll(ctx context.Context, sub *queueSubscription) {
+	defer close(sub.ch)
+
+	ticker := time.NewTicker(q.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, table := range sub.tables {
+			if err := q.deliver(ctx, sub, table); err != nil && ctx.Err() == nil {
+				slog.Error("failed to deliver queued events", "subscriber", sub.subscriber, "table", table, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+type queuedEvent struct {
+	position int64
+	attempts int
+	event    gosignal.Event
+}
+
+// deliver hands the due events of one table to the subscription, batch by batch until none are left. The
+// table is compacted first when deliveries were settled since the last pass, an idle subscription only
+// reads.
+func (q *SQLQueue) deliver(ctx context.Context, sub *queueSubscription, table string) error {
+	for {
+		if sub.takeSettled(table) {
+			if err := q.compact(ctx, sub.subscriber, table); err != nil {
+				sub.markSettled(table)
+				return err
+			}
+		}
+
+		due, err := q.due(ctx, sub, table)
+		if err != nil {
+			return err
+		}
+
+		if len(due) == 0 {
+			return nil
+		}
+
+		for _, evt := range due {
+			if q.MaxAttempts > 0 && evt.attempts >= q.MaxAttempts {
+				if err := q.fail(ctx, sub.subscriber, table, evt); err != nil {
+					return err
+				}
+				sub.markSettled(table)
+				continue
+			}
+
+			claimed, err := q.claim(ctx, sub.subscriber, table, evt.position)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+
+			msg := &sqlQueueMessage{
+				q:        q,
+				sub:      sub,
+				table:    table,
+				position: evt.position,
+				attempts: evt.attempts + 1,
+				event:    evt.event,
+			}
+
+			select {
+			case sub.ch <- msg:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// due reads the events of the subscription's type that were never delivered or are due again
+func (q *SQLQueue) due(ctx context.Context, sub *queueSubscription, table string) ([]queuedEvent, error) {
+	query := fmt.Sprintf(`SELECT e.rowid, e.type, e.data, e.version, e.timestamp, e.aggregate_id, COALESCE(d.attempts, 0)
+		FROM %s e
+		JOIN queue_subscriptions s ON s.subscriber = ? AND s.event_table = ?
+		LEFT JOIN queue_deliveries d ON d.subscriber = s.subscriber AND d.event_table = s.event_table AND d.position = e.rowid
+		WHERE e.rowid > s.position AND (? = '' OR e.type = ?)
+			AND (d.position IS NULL OR (d.delivered_at = 0 AND d.failed_at = 0 AND d.next_attempt_at <= ?))
+		ORDER BY e.rowid
+		LIMIT ?`, table)
+
+	rows, err := q.db.QueryContext(ctx, query, sub.subscriber, table, sub.messageType, sub.messageType, time.Now().Unix(),
+		q.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("read queued events: %w", err)
+	}
+	defer rows.Close()
+
+	var due []queuedEvent
+	for rows.Next() {
+		var evt queuedEvent
+		var timestamp int64
+		if err := rows.Scan(&evt.position, &evt.event.Type, &evt.event.Data, &evt.event.Version, &timestamp,
+			&evt.event.AggregateID, &evt.attempts); err != nil {
+			return nil, fmt.Errorf("scan queued event: %w", err)
+		}
+		evt.event.Timestamp = time.Unix(timestamp, 0)
+		due = append(due, evt)
+	}
+
+	return due, rows.Err()
+}
+
+// claim leases an event to the subscriber, it reports false when another instance holds the lease
+func (q *SQLQueue) claim(ctx context.Context, subscriber, table string, position int64) (bool, error) {
+	now := time.Now()
+	query := `INSERT INTO queue_deliveries (subscriber, event_table, position, attempts, next_attempt_at)
+		VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT (subscriber, event_table, position) DO UPDATE
+			SET attempts = queue_deliveries.attempts + 1, next_attempt_at = excluded.next_attempt_at
+			WHERE queue_deliveries.delivered_at = 0 AND queue_deliveries.failed_at = 0
+				AND queue_deliveries.next_attempt_at <= ?`
+
+	res, err := q.db.ExecContext(ctx, query, subscriber, table, position, now.Add(q.Lease).Unix(), now.Unix())
+	if err != nil {
+		return false, fmt.Errorf("claim queued event: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim queued event: %w", err)
+	}
+
+	return n == 1, nil
+}
+
+// fail sets aside an event that ran out of attempts, it stays in the delivery table for inspection
+func (q *SQLQueue) fail(ctx context.Context, subscriber, table string, evt queuedEvent) error {
+	slog.Error("queued event ran out of delivery attempts", "subscriber", subscriber, "table", table,
+		"position", evt.position, "type", evt.event.Type, "aggregate_id", evt.event.AggregateID)
+
+	query := `UPDATE queue_deliveries SET failed_at = ? WHERE subscriber = ? AND event_table = ? AND position = ?`
+	if _, err := q.db.ExecContext(ctx, query, time.Now().Unix(), subscriber, table, evt.position); err != nil {
+		return fmt.Errorf("fail queued event: %w", err)
+	}
+
+	return nil
+}
+
+// compact moves the subscription's position past the events delivered without a gap before them and
+// drops their delivery rows
+func (q *SQLQueue) compact(ctx context.Context, subscriber, table string) error {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin compaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	advance := `UPDATE queue_subscriptions SET position = MAX(position, COALESCE(
+			(SELECT MIN(position) - 1 FROM queue_deliveries
+				WHERE subscriber = ? AND event_table = ? AND delivered_at = 0 AND failed_at = 0),
+			(SELECT MAX(position) FROM queue_deliveries WHERE subscriber = ? AND event_table = ?),
+			position))
+		WHERE subscriber = ? AND event_table = ?`
+	if _, err := tx.ExecContext(ctx, advance, subscriber, table, subscriber, table, subscriber, table); err != nil {
+		return fmt.Errorf("advance subscription: %w", err)
+	}
+
+	prune := `DELETE FROM queue_deliveries
+		WHERE subscriber = ? AND event_table = ? AND delivered_at > 0
+			AND position <= (SELECT position FROM queue_subscriptions WHERE subscriber = ? AND event_table = ?)`
+	if _, err := tx.ExecContext(ctx, prune, subscriber, table, subscriber, table); err != nil {
+		return fmt.Errorf("prune deliveries: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (q *SQLQueue) settle(subscriber, table string, position int64, column string, at int64) error {
+	query := fmt.Sprintf(`UPDATE queue_deliveries SET %s = ?
+		WHERE subscriber = ? AND event_table = ? AND position = ? AND delivered_at = 0`, column)
+	if _, err := q.db.Exec(query, at, subscriber, table, position); err != nil {
+		return fmt.Errorf("settle queued event: %w", err)
+	}
+
+	return nil
+}
+
+// sqlQueueMessage is an event leased to a subscriber
+type sqlQueueMessage struct {
+	q        *SQLQueue
+	sub      *queueSubscription
+	table    string
+	position int64
+	attempts int
+	event    gosignal.Event
+}
+
+// Attempts is the number of times the message has been delivered, this delivery included
+func (m *sqlQueueMessage) Attempts() int {
+	return m.attempts
+}
+
+func (m *sqlQueueMessage) Message() []byte {
+	return m.event.Data
+}
+
+func (m *sqlQueueMessage) Type() string {
+	return m.event.Type
+}
+
+func (m *sqlQueueMessage) Event() gosignal.Event {
+	return m.event
+}
+
+// Ack marks the message delivered to its subscriber
+func (m *sqlQueueMessage) Ack() error {
+	if err := m.q.settle(m.sub.subscriber, m.table, m.position, "delivered_at", time.Now().Unix()); err != nil {
+		return err
+	}
+
+	m.sub.markSettled(m.table)
+	return nil
+}
+
+// Nack releases the lease so the message is delivered again on the next poll
+func (m *sqlQueueMessage) Nack() error {
+	return m.q.settle(m.sub.subscriber, m.table, m.position, "next_attempt_at", 0)
+}
+
+// Retry releases the lease and holds the message back until params.BackoffUntil
+func (m *sqlQueueMessage) Retry(params gosignal.RetryParams) error {
+	return m.q.settle(m.sub.subscriber, m.table, m.position, "next_attempt_at", params.BackoffUntil.Unix())
+}
+
+// EventHandler handles an event delivered by a queue
+type EventHandler func(ctx context.Context, evt gosignal.Event) error
+
+// ConsumeEvents subscribes handle to the event types until the context is done. An event is acknowledged
+// once handled and retried with a growing backoff when handle fails. The queue must deliver
+// EventMessages, when it's a DurableQueue the subscriptions are named after the subscriber.
+func ConsumeEvents(ctx context.Context, q gosignal.Queue, subscriber Subscriber, handle EventHandler, types ...string) error {
+	for _, typ := range types {
+		var id string
+		var ch chan gosignal.QueueMessage
+		var err error
+		if dq, ok := q.(DurableQueue); ok {
+			id, ch, err = dq.SubscribeAs(subscriber, typ)
+		} else {
+			id, ch, err = q.Subscribe(typ)
+		}
+		if err != nil {
+			return fmt.Errorf("subscribe to %s: %w", typ, err)
+		}
+
+		go func(typ, id string) {
+			<-ctx.Done()
+			if err := q.Unsubscribe(typ, id); err != nil {
+				slog.Error("failed to unsubscribe", "subscriber", subscriber.Name, "type", typ, "error", err)
+			}
+		}(typ, id)
+
+		go consume(ctx, subscriber.Name, ch, handle)
+	}
+
+	return nil
+}
+
+func consume(ctx context.Context, subscriber string, ch chan gosignal.QueueMessage, handle EventHandler) {
+	for msg := range ch {
+		em, ok := msg.(EventMessage)
+		if !ok {
+			slog.Error("queue message doesn't carry its event", "subscriber", subscriber, "type", msg.Type())
+			continue
+		}
+
+		evt := em.Event()
+		if err := handle(ctx, evt); err != nil {
+			slog.Error("failed to handle event", "subscriber", subscriber, "type", evt.Type,
+				"aggregate_id", evt.AggregateID, "attempts", msg.Attempts(), "error", err)
+
+			if err := msg.Retry(gosignal.RetryParams{BackoffUntil: time.Now().Add(queueBackoff(msg.Attempts()))}); err != nil {
+				slog.Error("failed to schedule event retry", "subscriber", subscriber, "error", err)
+			}
+			continue
+		}
+
+		if err := msg.Ack(); err != nil {
+			slog.Error("failed to acknowledge event", "subscriber", subscriber, "type", evt.Type, "error", err)
+		}
+	}
+}
+
+// queueBackoff doubles from a second with every attempt, up to maxQueueBackoff
+func queueBackoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return maxQueueBackoff
+	}
+
+	return min(time.Second<<attempts, maxQueueBackoff)
+}
@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Howard3/gosignal"
+)
+
+// sqlQueueTestDB opens a queue over the a_events and b_events tables on a single connection, so
+// total_changes() counts every write the queue makes
+func sqlQueueTestDB(t *testing.T) (*SQLQueue, *sql.DB) {
+	t.Helper()
+
+	conn := SQLConnection{Type: "sqlite3", URI: "file:" + filepath.Join(t.TempDir(), "queue.db")}
+	db, err := conn.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	for _, table := range []string{"a_events", "b_events"} {
+		if _, err := db.Exec(`CREATE TABLE ` + table + ` (type TEXT, data BLOB, version INTEGER, timestamp INTEGER, aggregate_id TEXT)`); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q, err := NewSQLQueue(conn, "a_events", "b_events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.PollInterval = 10 * time.Millisecond
+
+	return q, db
+}
+
+func storeTestEvent(t *testing.T, db *sql.DB, table, eventType, aggregateID string) {
+	t.Helper()
+
+	query := `INSERT INTO ` + table + ` (type, data, version, timestamp, aggregate_id) VALUES (?, '', 0, ?, ?)`
+	if _, err := db.Exec(query, eventType, time.Now().Unix(), aggregateID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(t *testing.T, ch chan gosignal.QueueMessage) EventMessage {
+	t.Helper()
+
+	select {
+	case msg := <-ch:
+		return msg.(EventMessage)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message delivered")
+		return nil
+	}
+}
+
+func TestSQLQueueReadsOnlyTheSubscribersTable(t *testing.T) {
+	q, db := sqlQueueTestDB(t)
+
+	id, ch, err := q.SubscribeAs(Subscriber{Name: "a", EventTable: "a_events"}, "created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Unsubscribe("created", id)
+
+	storeTestEvent(t, db, "b_events", "created", "b1")
+	storeTestEvent(t, db, "a_events", "created", "a1")
+	q.Send("created", nil)
+
+	if evt := receive(t, ch).Event(); evt.AggregateID != "a1" {
+		t.Errorf("delivered %s, want a1 from a_events", evt.AggregateID)
+	}
+
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM queue_subscriptions WHERE subscriber = 'a/created'`).Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 1 {
+		t.Errorf("subscription tracks %d tables, want 1", tables)
+	}
+
+	if _, _, err := q.SubscribeAs(Subscriber{Name: "c", EventTable: "c_events"}, "created"); err == nil {
+		t.Error("subscribing to a table the queue doesn't read succeeded")
+	}
+}
+
+func TestSQLQueueCompactsOnlyAfterAcks(t *testing.T) {
+	q, db := sqlQueueTestDB(t)
+
+	changes := func() int {
+		var n int
+		if err := db.QueryRow(`SELECT total_changes()`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	id, ch, err := q.SubscribeAs(Subscriber{Name: "a", EventTable: "a_events"}, "created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Unsubscribe("created", id)
+
+	// let the first pass compact what a previous run may have left
+	time.Sleep(50 * time.Millisecond)
+
+	idle := changes()
+	time.Sleep(100 * time.Millisecond)
+	if n := changes() - idle; n != 0 {
+		t.Errorf("idle polling wrote %d rows", n)
+	}
+
+	storeTestEvent(t, db, "a_events", "created", "a1")
+	q.Send("created", nil)
+
+	msg := receive(t, ch)
+	if err := msg.Ack(); err != nil {
+		t.Fatal(err)
+	}
+	q.Send("created", nil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var deliveries, position int
+		if err := db.QueryRow(`SELECT (SELECT COUNT(*) FROM queue_deliveries),
+				(SELECT position FROM queue_subscriptions WHERE subscriber = 'a/created')`).Scan(&deliveries, &position); err != nil {
+			t.Fatal(err)
+		}
+		if deliveries == 0 && position == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("acknowledged delivery wasn't compacted: %d deliveries, position %d", deliveries, position)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	idle = changes()
+	time.Sleep(100 * time.Millisecond)
+	if n := changes() - idle; n != 0 {
+		t.Errorf("idle polling after the compaction wrote %d rows", n)
+	}
+}
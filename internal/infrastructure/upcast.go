@@ -0,0 +1,83 @@
+package infrastructure
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Howard3/gosignal"
+)
+
+// Upcaster rewrites a stored event payload into the shape of a later schema version of its event type.
+// Stored events don't record the schema version they were written at, so an upcaster sees every payload
+// of its type and must return the ones already in its shape unchanged.
+type Upcaster func(data []byte) ([]byte, error)
+
+type versionedUpcaster struct {
+	version int
+	upcast  Upcaster
+}
+
+// Upcasters is a domain's registry of upcasters by event type and the schema version they produce. It's
+// applied to events before the aggregate sees them, so aggregates and projections only deal with the
+// current shape of each event.
+type Upcasters struct {
+	byType map[string][]versionedUpcaster
+}
+
+func NewUpcasters() *Upcasters {
+	return &Upcasters{byType: map[string][]versionedUpcaster{}}
+}
+
+// Register adds the upcaster producing the given schema version of an event type, version 1 being the
+// shape the type was introduced with. It panics when the version is registered twice since registries
+// are filled at init.
+func (u *Upcasters) Register(eventType string, version int, upcast Upcaster) *Upcasters {
+	if version < 2 {
+		panic(fmt.Errorf("upcaster for %s must produce version 2 or later, got %d", eventType, version))
+	}
+
+	for _, existing := range u.byType[eventType] {
+		if existing.version == version {
+			panic(fmt.Errorf("upcaster for %s version %d registered twice", eventType, version))
+		}
+	}
+
+	upcasters := append(u.byType[eventType], versionedUpcaster{version: version, upcast: upcast})
+	sort.Slice(upcasters, func(i, j int) bool { return upcasters[i].version < upcasters[j].version })
+	u.byType[eventType] = upcasters
+
+	return u
+}
+
+// Version returns the current schema version of an event type
+func (u *Upcasters) Version(eventType string) int {
+	upcasters := u.byType[eventType]
+	if len(upcasters) == 0 {
+		return 1
+	}
+	return upcasters[len(upcasters)-1].version
+}
+
+// Upcast runs the event's payload through the upcasters of its type in version order
+func (u *Upcasters) Upcast(evt gosignal.Event) (gosignal.Event, error) {
+	for _, up := range u.byType[evt.Type] {
+		data, err := up.upcast(evt.Data)
+		if err != nil {
+			return evt, fmt.Errorf("upcast %s to version %d: %w", evt.Type, up.version, err)
+		}
+		evt.Data = data
+	}
+
+	return evt, nil
+}
+
+// Apply wraps an aggregate's event router so it receives upcast events
+func (u *Upcasters) Apply(route func(gosignal.Event) error) func(gosignal.Event) error {
+	return func(evt gosignal.Event) error {
+		evt, err := u.Upcast(evt)
+		if err != nil {
+			return err
+		}
+		return route(evt)
+	}
+}
@@ -0,0 +1,96 @@
+package infrastructure
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Howard3/gosignal"
+)
+
+// appendUpcaster marks the payload with the version it produced
+func appendUpcaster(version string) Upcaster {
+	return func(data []byte) ([]byte, error) {
+		return append(data, ">"+version...), nil
+	}
+}
+
+func TestUpcastersRunInVersionOrder(t *testing.T) {
+	u := NewUpcasters().
+		Register("Created", 4, appendUpcaster("4")).
+		Register("Created", 2, appendUpcaster("2")).
+		Register("Created", 3, appendUpcaster("3")).
+		Register("Deleted", 2, appendUpcaster("deleted 2"))
+
+	if v := u.Version("Created"); v != 4 {
+		t.Errorf("Created is at version %d, want 4", v)
+	}
+	if v := u.Version("Updated"); v != 1 {
+		t.Errorf("type without upcasters is at version %d, want 1", v)
+	}
+
+	evt, err := u.Upcast(gosignal.Event{Type: "Created", AggregateID: "1", Version: 5, Data: []byte("v1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(evt.Data) != "v1>2>3>4" {
+		t.Errorf("upcast payload %q, want v1>2>3>4", evt.Data)
+	}
+	if evt.AggregateID != "1" || evt.Version != 5 {
+		t.Errorf("upcasting changed the event's identity: %+v", evt)
+	}
+
+	evt, err = u.Upcast(gosignal.Event{Type: "Updated", Data: []byte("v1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(evt.Data) != "v1" {
+		t.Errorf("type without upcasters was rewritten to %q", evt.Data)
+	}
+}
+
+func TestUpcastersRegisterPanics(t *testing.T) {
+	for name, register := range map[string]func(){
+		"version 1": func() { NewUpcasters().Register("Created", 1, appendUpcaster("1")) },
+		"registered twice": func() {
+			NewUpcasters().Register("Created", 2, appendUpcaster("2")).Register("Created", 2, appendUpcaster("2"))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("registered without panicking")
+				}
+			}()
+			register()
+		})
+	}
+}
+
+func TestUpcastersApply(t *testing.T) {
+	errBroken := errors.New("broken payload")
+	u := NewUpcasters().
+		Register("Created", 2, appendUpcaster("2")).
+		Register("Created", 3, func(data []byte) ([]byte, error) {
+			if string(data) == "bad>2" {
+				return nil, errBroken
+			}
+			return append(data, ">3"...), nil
+		})
+
+	var routed []string
+	route := u.Apply(func(evt gosignal.Event) error {
+		routed = append(routed, string(evt.Data))
+		return nil
+	})
+
+	if err := route(gosignal.Event{Type: "Created", Data: []byte("v1")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := route(gosignal.Event{Type: "Created", Data: []byte("bad")}); !errors.Is(err, errBroken) {
+		t.Errorf("failed upcast returned %v, want %v", err, errBroken)
+	}
+
+	if len(routed) != 1 || routed[0] != "v1>2>3" {
+		t.Errorf("routed payloads %q, want only v1>2>3", routed)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"geevly/gen/go/eda"
+	"sort"
 	"time"
 
 	"github.com/Howard3/gosignal"
@@ -13,10 +14,15 @@ import (
 
 var ErrSchoolDoesNotExist = fmt.Errorf("school does not exist")
 var ErrMustHaveName = fmt.Errorf("school must have a name")
+var ErrInvalidMealCost = fmt.Errorf("meal cost must not be negative")
+var ErrInvalidBudgetPeriod = fmt.Errorf("invalid budget period")
 
 const EventCreateSchool = "CreateSchool"
 const EventUpdateSchool = "UpdateSchool"
 const EventSetSchoolPeriod = "SetSchoolPeriod"
+const EventSetMealCost = "SetMealCost"
+const EventSetBudgetPeriod = "SetBudgetPeriod"
+const EventSetMissedMealThreshold = "SetMissedMealThreshold"
 
 var ErrEventNotFound = fmt.Errorf("event not found")
 
@@ -26,7 +32,7 @@ type Aggregate struct {
 }
 
 func (agg *Aggregate) Apply(evt gosignal.Event) error {
-	return sourcing.SafeApply(evt, agg, agg.routeEvent)
+	return sourcing.SafeApply(evt, agg, upcasters.Apply(agg.routeEvent))
 }
 
 type wrappedEvent struct {
@@ -58,6 +64,15 @@ func (agg *Aggregate) routeEvent(evt gosignal.Event) (err error) {
 	case EventSetSchoolPeriod:
 		eventData = &eda.School_SetSchoolPeriod_Event{}
 		handler = agg.handleSetSchoolPeriod
+	case EventSetMealCost:
+		eventData = &eda.School_SetMealCost_Event{}
+		handler = agg.handleSetMealCost
+	case EventSetBudgetPeriod:
+		eventData = &eda.School_SetBudgetPeriod_Event{}
+		handler = agg.handleSetBudgetPeriod
+	case EventSetMissedMealThreshold:
+		eventData = &eda.School_SetMissedMealThreshold_Event{}
+		handler = agg.handleSetMissedMealThreshold
 	default:
 		return ErrEventNotFound
 	}
@@ -124,6 +139,57 @@ func (agg *Aggregate) SetSchoolPeriod(cmd *eda.School_SetSchoolPeriod) (*gosigna
 	})
 }
 
+// SetMealCost sets the cost of a single meal at the school
+func (agg *Aggregate) SetMealCost(cmd *eda.School_SetMealCost) (*gosignal.Event, error) {
+	if cmd.MealCost < 0 {
+		return nil, ErrInvalidMealCost
+	}
+
+	return agg.ApplyEvent(SchoolEvent{
+		eventType: EventSetMealCost,
+		data: &eda.School_SetMealCost_Event{
+			MealCost: cmd.MealCost,
+		},
+		version: cmd.Version,
+	})
+}
+
+// SetMissedMealThreshold sets how many school days a student can go without a meal before they're
+// followed up, 0 falls back to the default
+func (agg *Aggregate) SetMissedMealThreshold(cmd *eda.School_SetMissedMealThreshold) (*gosignal.Event, error) {
+	return agg.ApplyEvent(SchoolEvent{
+		eventType: EventSetMissedMealThreshold,
+		data: &eda.School_SetMissedMealThreshold_Event{
+			MissedMealThreshold: cmd.MissedMealThreshold,
+		},
+		version: cmd.Version,
+	})
+}
+
+// SetBudgetPeriod records the funded budget for a period, a period with the same start date is replaced
+func (agg *Aggregate) SetBudgetPeriod(cmd *eda.School_SetBudgetPeriod) (*gosignal.Event, error) {
+	period := cmd.GetPeriod()
+	if period == nil || period.StartDate == nil || period.EndDate == nil {
+		return nil, fmt.Errorf("%w: start and end dates are required", ErrInvalidBudgetPeriod)
+	}
+
+	if DateToTime(period.EndDate).Before(DateToTime(period.StartDate)) {
+		return nil, fmt.Errorf("%w: end date is before start date", ErrInvalidBudgetPeriod)
+	}
+
+	if period.FundedAmount < 0 {
+		return nil, fmt.Errorf("%w: funded amount must not be negative", ErrInvalidBudgetPeriod)
+	}
+
+	return agg.ApplyEvent(SchoolEvent{
+		eventType: EventSetBudgetPeriod,
+		data: &eda.School_SetBudgetPeriod_Event{
+			Period: period,
+		},
+		version: cmd.Version,
+	})
+}
+
 func (agg *Aggregate) UpdateSchool(cmd *eda.School_Update) (*gosignal.Event, error) {
 	return agg.ApplyEvent(SchoolEvent{
 		eventType: EventUpdateSchool,
@@ -165,6 +231,44 @@ func (agg *Aggregate) handleSetSchoolPeriod(we wrappedEvent) error {
 	return nil
 }
 
+func (agg *Aggregate) handleSetMealCost(we wrappedEvent) error {
+	data := we.data.(*eda.School_SetMealCost_Event)
+
+	agg.data.MealCost = data.MealCost
+
+	return nil
+}
+
+func (agg *Aggregate) handleSetMissedMealThreshold(we wrappedEvent) error {
+	data := we.data.(*eda.School_SetMissedMealThreshold_Event)
+
+	agg.data.MissedMealThreshold = data.MissedMealThreshold
+
+	return nil
+}
+
+func (agg *Aggregate) handleSetBudgetPeriod(we wrappedEvent) error {
+	data := we.data.(*eda.School_SetBudgetPeriod_Event)
+	start := DateToTime(data.Period.StartDate)
+
+	periods := make([]*eda.School_BudgetPeriod, 0, len(agg.data.BudgetPeriods)+1)
+	for _, p := range agg.data.BudgetPeriods {
+		if DateToTime(p.StartDate).Equal(start) {
+			continue
+		}
+		periods = append(periods, p)
+	}
+	periods = append(periods, data.Period)
+
+	sort.Slice(periods, func(i, j int) bool {
+		return DateToTime(periods[i].StartDate).Before(DateToTime(periods[j].StartDate))
+	})
+
+	agg.data.BudgetPeriods = periods
+
+	return nil
+}
+
 func (agg *Aggregate) handleUpdateSchool(we wrappedEvent) error {
 	data := we.data.(*eda.School_Update_Event)
 
@@ -201,3 +305,20 @@ func (agg *Aggregate) ExportState() ([]byte, error) {
 func (agg *Aggregate) GetData() *eda.School {
 	return agg.data
 }
+
+// BudgetPeriodAt returns the budget period covering the given time, or nil if there isn't one
+func (agg *Aggregate) BudgetPeriodAt(at time.Time) *eda.School_BudgetPeriod {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	for _, p := range agg.data.GetBudgetPeriods() {
+		if !day.Before(DateToTime(p.StartDate)) && !day.After(DateToTime(p.EndDate)) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// DateToTime converts a proto date to a UTC time at midnight
+func DateToTime(d *eda.Date) time.Time {
+	return time.Date(int(d.GetYear()), time.Month(d.GetMonth()), int(d.GetDay()), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,202 @@
+package school
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// SponsorshipPayment is a sponsorship payment made for a student enrolled in a school
+type SponsorshipPayment struct {
+	StudentID string
+	StartDate time.Time
+	EndDate   time.Time
+	Amount    float64
+}
+
+// UnfundedStudent is an active student without a sponsorship covering the report date
+type UnfundedStudent struct {
+	StudentID       string
+	StudentSchoolID string
+	Name            string
+}
+
+// BudgetACL is the anti-corruption layer the budget service uses to read feeding and sponsorship
+// data that belongs to the student domain.
+type BudgetACL interface {
+	CountMealsServed(ctx context.Context, schoolID uint64, from, to time.Time) (uint64, error)
+	ListSponsorshipPayments(ctx context.Context, schoolID uint64) ([]SponsorshipPayment, error)
+	ListUnfundedStudents(ctx context.Context, schoolID uint64, at time.Time) ([]UnfundedStudent, error)
+}
+
+// BudgetReport compares the funding of a school against the meals it has served within a budget period
+type BudgetReport struct {
+	SchoolID            uint64
+	SchoolName          string
+	MealCost            float64
+	HasPeriod           bool
+	PeriodStart         time.Time
+	PeriodEnd           time.Time
+	FundedAmount        float64 // program budget for the period
+	SponsorshipPayments float64 // sponsorship payments attributed to the period
+	MealsServed         uint64
+	Spent               float64
+	DailyBurn           float64
+	ProjectedRunOut     *time.Time // nil when nothing has been spent yet
+	UnfundedStudents    []UnfundedStudent
+}
+
+// TotalFunding is the program budget plus sponsorship payments
+func (br *BudgetReport) TotalFunding() float64 {
+	return br.FundedAmount + br.SponsorshipPayments
+}
+
+// Remaining is the funding left after the meals served so far
+func (br *BudgetReport) Remaining() float64 {
+	return br.TotalFunding() - br.Spent
+}
+
+// BurnPercent is the share of total funding that has been spent
+func (br *BudgetReport) BurnPercent() float64 {
+	if br.TotalFunding() <= 0 {
+		return 0
+	}
+
+	return br.Spent / br.TotalFunding() * 100
+}
+
+// FundedMeals is the number of meals the total funding pays for at the current meal cost
+func (br *BudgetReport) FundedMeals() uint64 {
+	if br.MealCost <= 0 {
+		return 0
+	}
+
+	return uint64(math.Floor(br.TotalFunding() / br.MealCost))
+}
+
+// RunsOutEarly reports whether the projected run-out date falls before the end of the period
+func (br *BudgetReport) RunsOutEarly() bool {
+	return br.HasPeriod && br.ProjectedRunOut != nil && br.ProjectedRunOut.Before(br.PeriodEnd)
+}
+
+// BudgetService reports on school budgets
+type BudgetService struct {
+	repo Repository
+	acl  BudgetACL
+}
+
+// NewBudgetService creates a new budget service
+func NewBudgetService(repo Repository, acl BudgetACL) *BudgetService {
+	return &BudgetService{
+		repo: repo,
+		acl:  acl,
+	}
+}
+
+// ListReports returns a budget report for every school as of the given time
+func (bs *BudgetService) ListReports(ctx context.Context, at time.Time) ([]*BudgetReport, error) {
+	schools, err := bs.repo.mapSchoolsByID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*BudgetReport, 0, len(schools))
+	for id := range schools {
+		report, err := bs.GetReport(ctx, id, at)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].SchoolName < reports[j].SchoolName
+	})
+
+	return reports, nil
+}
+
+// GetReport builds the budget report for a school using the budget period covering the given time
+func (bs *BudgetService) GetReport(ctx context.Context, schoolID uint64, at time.Time) (*BudgetReport, error) {
+	agg, err := bs.repo.loadSchool(ctx, schoolID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BudgetReport{
+		SchoolID:   schoolID,
+		SchoolName: agg.data.GetName(),
+		MealCost:   agg.data.GetMealCost(),
+	}
+
+	report.UnfundedStudents, err = bs.acl.ListUnfundedStudents(ctx, schoolID, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unfunded students: %w", err)
+	}
+
+	period := agg.BudgetPeriodAt(at)
+	if period == nil {
+		return report, nil
+	}
+
+	report.HasPeriod = true
+	report.PeriodStart = DateToTime(period.StartDate)
+	report.PeriodEnd = DateToTime(period.EndDate)
+	report.FundedAmount = period.FundedAmount
+
+	payments, err := bs.acl.ListSponsorshipPayments(ctx, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sponsorship payments: %w", err)
+	}
+
+	for _, p := range payments {
+		report.SponsorshipPayments += proratePayment(p, report.PeriodStart, report.PeriodEnd)
+	}
+
+	// feedings are counted through the end of the report day
+	to := time.Date(at.Year(), at.Month(), at.Day(), 23, 59, 59, 0, at.Location())
+	report.MealsServed, err = bs.acl.CountMealsServed(ctx, schoolID, report.PeriodStart, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count meals served: %w", err)
+	}
+
+	report.Spent = float64(report.MealsServed) * report.MealCost
+
+	elapsedDays := math.Floor(to.Sub(report.PeriodStart).Hours()/24) + 1
+	if elapsedDays > 0 && report.Spent > 0 {
+		report.DailyBurn = report.Spent / elapsedDays
+		daysLeft := math.Max(report.Remaining()/report.DailyBurn, 0)
+		runOut := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(daysLeft))
+		report.ProjectedRunOut = &runOut
+	}
+
+	return report, nil
+}
+
+// proratePayment attributes the share of a sponsorship payment that overlaps the given period
+func proratePayment(p SponsorshipPayment, start, end time.Time) float64 {
+	if p.Amount <= 0 || p.EndDate.Before(start) || p.StartDate.After(end) {
+		return 0
+	}
+
+	total := p.EndDate.Sub(p.StartDate).Hours()/24 + 1
+	if total <= 0 {
+		return p.Amount
+	}
+
+	overlapStart := p.StartDate
+	if start.After(overlapStart) {
+		overlapStart = start
+	}
+
+	overlapEnd := p.EndDate
+	if end.Before(overlapEnd) {
+		overlapEnd = end
+	}
+
+	overlap := overlapEnd.Sub(overlapStart).Hours()/24 + 1
+
+	return p.Amount * overlap / total
+}
@@ -0,0 +1,52 @@
+package school
+
+import (
+	"context"
+	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+)
+
+// RegisterCommands registers the school commands on the bus. They carry the school's ID themselves, so
+// they're dispatched without an aggregate ID.
+func (s *Service) RegisterCommands(bus *infrastructure.CommandBus) {
+	admin := infrastructure.AllowRoles(infrastructure.RoleAdmin)
+
+	infrastructure.HandleCommand(bus, "school", func(ctx context.Context, _ string, cmd *eda.School_Create, _ []byte) (any, error) {
+		return s.Create(ctx, cmd)
+	}, admin)
+	infrastructure.HandleCommand(bus, "school", func(ctx context.Context, _ string, cmd *eda.School_Update, _ []byte) (any, error) {
+		return s.Update(ctx, cmd)
+	}, admin, infrastructure.ValidateWith(func(cmd *eda.School_Update) error {
+		if cmd.Name == "" {
+			return ErrMustHaveName
+		}
+		return requireSchoolID(cmd.Id)
+	}))
+	infrastructure.HandleCommand(bus, "school", func(ctx context.Context, _ string, cmd *eda.School_SetSchoolPeriod, _ []byte) (any, error) {
+		return s.SetSchoolPeriod(ctx, cmd)
+	}, admin, infrastructure.ValidateWith(func(cmd *eda.School_SetSchoolPeriod) error {
+		return requireSchoolID(cmd.Id)
+	}))
+	infrastructure.HandleCommand(bus, "school", func(ctx context.Context, _ string, cmd *eda.School_SetMealCost, _ []byte) (any, error) {
+		return s.SetMealCost(ctx, cmd)
+	}, admin, infrastructure.ValidateWith(func(cmd *eda.School_SetMealCost) error {
+		return requireSchoolID(cmd.Id)
+	}))
+	infrastructure.HandleCommand(bus, "school", func(ctx context.Context, _ string, cmd *eda.School_SetMissedMealThreshold, _ []byte) (any, error) {
+		return s.SetMissedMealThreshold(ctx, cmd)
+	}, admin, infrastructure.ValidateWith(func(cmd *eda.School_SetMissedMealThreshold) error {
+		return requireSchoolID(cmd.Id)
+	}))
+	infrastructure.HandleCommand(bus, "school", func(ctx context.Context, _ string, cmd *eda.School_SetBudgetPeriod, _ []byte) (any, error) {
+		return s.SetBudgetPeriod(ctx, cmd)
+	}, admin, infrastructure.ValidateWith(func(cmd *eda.School_SetBudgetPeriod) error {
+		return requireSchoolID(cmd.Id)
+	}))
+}
+
+func requireSchoolID(id uint64) error {
+	if id == 0 {
+		return ErrSchoolIDInvalid
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package school
+
+import (
+	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+)
+
+// decoders map each school event type to its payload for the history page, register new event types here
+// as well as in the aggregate's routeEvent
+var decoders = infrastructure.NewEventDecoders(upcasters).
+	Register(EventCreateSchool, &eda.School_Create_Event{}).
+	Register(EventUpdateSchool, &eda.School_Update_Event{}).
+	Register(EventSetSchoolPeriod, &eda.School_SetSchoolPeriod_Event{}).
+	Register(EventSetMealCost, &eda.School_SetMealCost_Event{}).
+	Register(EventSetBudgetPeriod, &eda.School_SetBudgetPeriod_Event{}).
+	Register(EventSetMissedMealThreshold, &eda.School_SetMissedMealThreshold_Event{})
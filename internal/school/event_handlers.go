@@ -56,3 +56,30 @@ func (eh *eventHandlers) HandleSetStatusEvent(ctx context.Context, evt *gosignal
 func (eh *eventHandlers) HandleSetSchoolPeriodEvent(ctx context.Context, evt *gosignal.Event) {
 	eh.HandleNewSchoolEvent(ctx, evt)
 }
+
+// HandleSetMealCostEvent is a method that handles the SetMealCostEvent
+// functionally the same as HandleNewSchoolEvent, thus it just aliases it
+func (eh *eventHandlers) HandleSetMealCostEvent(ctx context.Context, evt *gosignal.Event) {
+	eh.HandleNewSchoolEvent(ctx, evt)
+}
+
+// HandleSetBudgetPeriodEvent is a method that handles the SetBudgetPeriodEvent
+// it reprojects the school's budget periods
+func (eh *eventHandlers) HandleSetBudgetPeriodEvent(ctx context.Context, evt *gosignal.Event) {
+	aggID, err := strconv.ParseUint(evt.AggregateID, 10, 64)
+	if err != nil {
+		slog.Error("failed to parse aggregate id", "error", err)
+		return
+	}
+
+	school, err := eh.repo.loadSchool(ctx, aggID)
+	if err != nil {
+		slog.Error("failed to load school", "error", err)
+		return
+	}
+
+	if err := eh.repo.upsertBudgetPeriodProjections(ctx, school); err != nil {
+		slog.Error("failed to upsert school budget periods", "error", err)
+		return
+	}
+}
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE schools ADD COLUMN meal_cost REAL NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS school_budget_periods (
+    school_id TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    funded_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY(school_id, start_date)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS school_budget_periods;
+ALTER TABLE schools DROP COLUMN meal_cost;
@@ -6,6 +6,7 @@ import (
 	"embed"
 	"fmt"
 	"geevly/internal/infrastructure"
+	"strconv"
 	"time"
 
 	"github.com/Howard3/gosignal"
@@ -28,6 +29,7 @@ type Location struct {
 type Repository interface {
 	loadSchool(ctx context.Context, id uint64) (*Aggregate, error)
 	upsertProjection(school *Aggregate) error
+	upsertBudgetPeriodProjections(ctx context.Context, school *Aggregate) error
 	saveEvents(ctx context.Context, evts []gosignal.Event) error
 	listSchools(ctx context.Context, limit, page uint) ([]*ProjectedSchool, error)
 	countSchools(ctx context.Context) (uint, error)
@@ -37,6 +39,8 @@ type Repository interface {
 	mapSchoolsByID(ctx context.Context) (map[uint64]string, error)
 	listLocations(ctx context.Context) ([]Location, error)
 	getSchoolIDsByLocation(ctx context.Context, location Location) ([]uint64, error)
+	Projections() []infrastructure.Projection
+	EventStream() infrastructure.EventStream
 }
 
 // ProjectedSchool is a struct that represents a school projection
@@ -52,6 +56,7 @@ type ProjectedSchool struct {
 	SchoolStartDay   *uint32 // school start day
 	SchoolEndMonth   *uint32 // school end month
 	SchoolEndDay     *uint32 // school end day
+	MealCost         float64 // cost of a single meal
 }
 
 type sqlRepository struct {
@@ -73,13 +78,18 @@ func (r *sqlRepository) loadSchool(ctx context.Context, id uint64) (*Aggregate,
 
 // upsertProjection - updates or inserts a projection
 func (r *sqlRepository) upsertProjection(agg *Aggregate) error {
+	return r.writeSchoolProjection(context.Background(), r.db, "schools", agg)
+}
+
+// writeSchoolProjection - upserts the school's row in table
+func (r *sqlRepository) writeSchoolProjection(ctx context.Context, db infrastructure.Execer, table string, agg *Aggregate) error {
 	if agg == nil || agg.data == nil {
 		return fmt.Errorf("cannot upsert nil aggregate")
 	}
 
-	query := `INSERT INTO schools
-		(id, name, active, version, updated_at, country, city, school_start_month, school_start_day, school_end_month, school_end_day)
-		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?, ?, ?, ?, ?)
+	query := fmt.Sprintf(`INSERT INTO %s
+		(id, name, active, version, updated_at, country, city, school_start_month, school_start_day, school_end_month, school_end_day, meal_cost)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT (id) DO UPDATE SET
 			name = EXCLUDED.name,
 			active = EXCLUDED.active,
@@ -90,9 +100,10 @@ func (r *sqlRepository) upsertProjection(agg *Aggregate) error {
 			school_start_month = EXCLUDED.school_start_month,
 			school_start_day = EXCLUDED.school_start_day,
 			school_end_month = EXCLUDED.school_end_month,
-			school_end_day = EXCLUDED.school_end_day
+			school_end_day = EXCLUDED.school_end_day,
+			meal_cost = EXCLUDED.meal_cost
 		RETURNING id;
-	`
+	`, table)
 
 	active := !agg.data.Disabled
 
@@ -106,7 +117,8 @@ func (r *sqlRepository) upsertProjection(agg *Aggregate) error {
 		schoolEndDay = agg.data.SchoolEnd.Day
 	}
 
-	_, err := r.db.Exec(
+	_, err := db.ExecContext(
+		ctx,
 		query,
 		agg.ID,
 		agg.data.Name,
@@ -118,6 +130,7 @@ func (r *sqlRepository) upsertProjection(agg *Aggregate) error {
 		schoolStartDay,
 		schoolEndMonth,
 		schoolEndDay,
+		agg.data.MealCost,
 	)
 
 	if err != nil {
@@ -126,13 +139,98 @@ func (r *sqlRepository) upsertProjection(agg *Aggregate) error {
 
 	return nil
 }
+
+// upsertBudgetPeriodProjections - replaces the budget period projections for a school
+func (r *sqlRepository) upsertBudgetPeriodProjections(ctx context.Context, agg *Aggregate) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = r.writeBudgetPeriodProjections(ctx, tx, "school_budget_periods", agg); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// writeBudgetPeriodProjections - replaces the budget period rows of a school in table
+func (r *sqlRepository) writeBudgetPeriodProjections(ctx context.Context, tx *sql.Tx, table string, agg *Aggregate) error {
+	if agg == nil || agg.data == nil {
+		return fmt.Errorf("cannot project budget periods for nil aggregate")
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE school_id = ?`, table), agg.GetID()); err != nil {
+		return fmt.Errorf("failed to delete budget periods: %w", err)
+	}
+
+	for _, p := range agg.data.GetBudgetPeriods() {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (school_id, start_date, end_date, funded_amount)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (school_id, start_date) DO UPDATE SET
+				end_date = EXCLUDED.end_date,
+				funded_amount = EXCLUDED.funded_amount`, table),
+			agg.GetID(), DateToTime(p.StartDate), DateToTime(p.EndDate), p.FundedAmount)
+		if err != nil {
+			return fmt.Errorf("failed to insert budget period: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Projections returns the school read models for the projector
+func (r *sqlRepository) Projections() []infrastructure.Projection {
+	return []infrastructure.Projection{
+		r.projection("schools", func(ctx context.Context, tx *sql.Tx, table string, agg *Aggregate) error {
+			return r.writeSchoolProjection(ctx, tx, table, agg)
+		}),
+		r.projection("school_budget_periods", r.writeBudgetPeriodProjections),
+	}
+}
+
+// EventStream describes the school events for the integrity check, schools aren't snapshotted
+func (r *sqlRepository) EventStream() infrastructure.EventStream {
+	return infrastructure.EventStream{
+		EventTable: "school_events",
+		New:        func() sourcing.Aggregate { return &Aggregate{} },
+	}
+}
+
+func (r *sqlRepository) projection(table string, write func(ctx context.Context, tx *sql.Tx, table string, agg *Aggregate) error) infrastructure.Projection {
+	return infrastructure.Projection{
+		Table:      table,
+		EventTable: "school_events",
+		Project: func(ctx context.Context, aggregateID string) (infrastructure.ProjectionWriter, error) {
+			id, err := strconv.ParseUint(aggregateID, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid school ID %q: %w", aggregateID, err)
+			}
+
+			agg, err := r.loadSchool(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			return func(ctx context.Context, tx *sql.Tx, table string) error {
+				return write(ctx, tx, table, agg)
+			}, nil
+		},
+	}
+}
 func (r *sqlRepository) saveEvents(ctx context.Context, evts []gosignal.Event) (_ error) {
 	return r.eventSourcing.Store(ctx, evts)
 }
 func (r *sqlRepository) listSchools(ctx context.Context, limit uint, page uint) ([]*ProjectedSchool, error) {
 	query := `
 		SELECT id, name, active, version, updated_at, country, city,
-		       school_start_month, school_start_day, school_end_month, school_end_day
+		       school_start_month, school_start_day, school_end_month, school_end_day, meal_cost
 		FROM schools
 		LIMIT ? OFFSET ?;
 	`
@@ -158,6 +256,7 @@ func (r *sqlRepository) listSchools(ctx context.Context, limit uint, page uint)
 		school := &ProjectedSchool{}
 		var country, city sql.NullString
 		var schoolStartMonth, schoolStartDay, schoolEndMonth, schoolEndDay sql.NullInt32
+		var mealCost sql.NullFloat64
 		if err := rows.Scan(
 			&school.ID,
 			&school.Name,
@@ -170,12 +269,14 @@ func (r *sqlRepository) listSchools(ctx context.Context, limit uint, page uint)
 			&schoolStartDay,
 			&schoolEndMonth,
 			&schoolEndDay,
+			&mealCost,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan school: %w", err)
 		}
 
 		school.Country = country.String
 		school.City = city.String
+		school.MealCost = mealCost.Float64
 
 		if schoolStartMonth.Valid {
 			month := uint32(schoolStartMonth.Int32)
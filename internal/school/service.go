@@ -2,8 +2,11 @@ package school
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+	"log/slog"
 
 	"github.com/Howard3/gosignal"
 )
@@ -55,20 +58,13 @@ func (s *Service) Create(ctx context.Context, cmd *eda.School_Create) (*eda.Scho
 
 // Update updates a school on this Aggregate
 func (s *Service) Update(ctx context.Context, cmd *eda.School_Update) (*eda.School_Update_Response, error) {
-	agg, err := s.repo.loadSchool(ctx, cmd.Id)
+	agg, evt, err := s.withSchool(ctx, cmd.Id, cmd.Version, func(agg *Aggregate) (*gosignal.Event, error) {
+		return agg.UpdateSchool(cmd)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	evt, err := agg.UpdateSchool(cmd)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := s.repo.saveEvents(ctx, []gosignal.Event{*evt}); err != nil {
-		return nil, err
-	}
-
 	s.eventHandlers.HandleUpdateSchoolEvent(ctx, evt)
 
 	return &eda.School_Update_Response{
@@ -78,28 +74,122 @@ func (s *Service) Update(ctx context.Context, cmd *eda.School_Update) (*eda.Scho
 
 // SetSchoolPeriod sets the school period for a school
 func (s *Service) SetSchoolPeriod(ctx context.Context, cmd *eda.School_SetSchoolPeriod) (*eda.School_SetSchoolPeriod_Response, error) {
-	agg, err := s.repo.loadSchool(ctx, cmd.Id)
+	agg, evt, err := s.withSchool(ctx, cmd.Id, cmd.Version, func(agg *Aggregate) (*gosignal.Event, error) {
+		return agg.SetSchoolPeriod(cmd)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	evt, err := agg.SetSchoolPeriod(cmd)
+	s.eventHandlers.HandleSetSchoolPeriodEvent(ctx, evt)
+
+	return &eda.School_SetSchoolPeriod_Response{
+		Id:     agg.GetIDUint64(),
+		School: agg.data,
+	}, nil
+}
+
+// SetMealCost sets the per-meal cost for a school
+func (s *Service) SetMealCost(ctx context.Context, cmd *eda.School_SetMealCost) (*eda.School_SetMealCost_Response, error) {
+	agg, evt, err := s.withSchool(ctx, cmd.Id, cmd.Version, func(agg *Aggregate) (*gosignal.Event, error) {
+		return agg.SetMealCost(cmd)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.saveEvents(ctx, []gosignal.Event{*evt}); err != nil {
+	s.eventHandlers.HandleSetMealCostEvent(ctx, evt)
+
+	return &eda.School_SetMealCost_Response{
+		Id:     agg.GetIDUint64(),
+		School: agg.data,
+	}, nil
+}
+
+// SetMissedMealThreshold sets the school days without a meal before a student at the school is followed up
+func (s *Service) SetMissedMealThreshold(ctx context.Context, cmd *eda.School_SetMissedMealThreshold) (*eda.School_SetMissedMealThreshold_Response, error) {
+	agg, _, err := s.withSchool(ctx, cmd.Id, cmd.Version, func(agg *Aggregate) (*gosignal.Event, error) {
+		return agg.SetMissedMealThreshold(cmd)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	s.eventHandlers.HandleSetSchoolPeriodEvent(ctx, evt)
+	return &eda.School_SetMissedMealThreshold_Response{
+		Id:     agg.GetIDUint64(),
+		School: agg.data,
+	}, nil
+}
 
-	return &eda.School_SetSchoolPeriod_Response{
+// SetBudgetPeriod sets the funded budget for a period on a school
+func (s *Service) SetBudgetPeriod(ctx context.Context, cmd *eda.School_SetBudgetPeriod) (*eda.School_SetBudgetPeriod_Response, error) {
+	agg, evt, err := s.withSchool(ctx, cmd.Id, cmd.Version, func(agg *Aggregate) (*gosignal.Event, error) {
+		return agg.SetBudgetPeriod(cmd)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.eventHandlers.HandleSetBudgetPeriodEvent(ctx, evt)
+
+	return &eda.School_SetBudgetPeriod_Response{
 		Id:     agg.GetIDUint64(),
 		School: agg.data,
 	}, nil
 }
 
+// withSchool loads the school, runs fn on it and stores the event. Every school command sets values the admin
+// saw on the form, so one based on an older version fails with a ConflictError instead of overwriting what
+// changed since. Losing the race to store the next version ends the same way once the school is reloaded.
+func (s *Service) withSchool(ctx context.Context, id, expected uint64, fn func(*Aggregate) (*gosignal.Event, error)) (*Aggregate, *gosignal.Event, error) {
+	var agg *Aggregate
+	var evt *gosignal.Event
+	err := infrastructure.RetryOnConflict(func() error {
+		var err error
+		agg, err = s.repo.loadSchool(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if expected != agg.GetVersion() {
+			return s.conflict(ctx, agg, expected)
+		}
+
+		evt, err = fn(agg)
+		if err != nil {
+			return err
+		}
+
+		return s.repo.saveEvents(ctx, []gosignal.Event{*evt})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return agg, evt, nil
+}
+
+// conflict describes what changed on the school since the version a command was based on
+func (s *Service) conflict(ctx context.Context, agg *Aggregate, expected uint64) error {
+	conflict := &infrastructure.ConflictError{
+		AggregateID: agg.GetID(),
+		Expected:    expected,
+		Current:     agg.GetVersion(),
+	}
+
+	before, err := s.GetAsOf(ctx, agg.GetIDUint64(), infrastructure.AsOfVersion(expected))
+	switch {
+	case err == nil:
+		conflict.Changes = infrastructure.DiffMessages(before.data, agg.data)
+	case errors.Is(err, infrastructure.ErrNotYetCreated):
+		conflict.Changes = infrastructure.DiffMessages(nil, agg.data)
+	default:
+		slog.Warn("failed to work out what changed on the school", "school_id", agg.GetID(), "error", err)
+	}
+
+	return conflict
+}
+
 // List returns a list of schools from the projection
 func (s *Service) List(ctx context.Context, limit, page uint) (*ListResponse, error) {
 	schools, err := s.repo.listSchools(ctx, limit, page)
@@ -128,9 +218,31 @@ func (s *Service) Get(ctx context.Context, id uint64) (*Aggregate, error) {
 	return agg, nil
 }
 
-// GetHistory returns the event history for a school aggregate
-func (s *Service) GetHistory(ctx context.Context, id uint64) ([]gosignal.Event, error) {
-	return s.repo.getEventHistory(ctx, id)
+// GetHistory returns the event history for a school aggregate with each payload decoded
+func (s *Service) GetHistory(ctx context.Context, id uint64) ([]infrastructure.DecodedEvent, error) {
+	events, err := s.repo.getEventHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load school events: %w", err)
+	}
+
+	return decoders.DecodeHistory(events), nil
+}
+
+// GetAsOf replays the school's events up to a point in its past
+func (s *Service) GetAsOf(ctx context.Context, id uint64, at infrastructure.AsOf) (*Aggregate, error) {
+	events, err := s.repo.getEventHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load school events: %w", err)
+	}
+
+	agg := &Aggregate{}
+	agg.SetIDUint64(id)
+
+	if err := infrastructure.ReplayAsOf(agg, events, at); err != nil {
+		return nil, fmt.Errorf("failed to replay school %d: %w", id, err)
+	}
+
+	return agg, nil
 }
 
 // mapSchoolsByID - returns a map of school IDs to school names
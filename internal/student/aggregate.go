@@ -1,11 +1,13 @@
 package student
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"log/slog"
 	"math"
 	"slices"
+	"strings"
 	"time"
 
 	"geevly/gen/go/eda"
@@ -22,6 +24,22 @@ var ErrVersionMismatch = fmt.Errorf("version mismatch")
 var ErrStudentNotFound = fmt.Errorf("student not found")
 var ErrHealthAssessmentNotFound = fmt.Errorf("health assessment not found")
 var ErrGradeReportNotFound = fmt.Errorf("grade report not found")
+var ErrInvalidHealthAssessment = fmt.Errorf("invalid health assessment")
+var ErrInvalidGradeReport = fmt.Errorf("invalid grade report")
+var ErrInvalidAttendance = fmt.Errorf("invalid attendance")
+var ErrAttendanceNotFound = fmt.Errorf("attendance not found")
+var ErrFeedingNotFound = fmt.Errorf("feeding not found")
+var ErrInvalidFeedingReview = fmt.Errorf("invalid feeding review")
+var ErrNotEnrolled = fmt.Errorf("student is not enrolled in a school")
+var ErrAlreadyInSchool = fmt.Errorf("student is already enrolled in this school")
+var ErrInvalidTransferDate = fmt.Errorf("invalid transfer date")
+var ErrGuardianNotFound = fmt.Errorf("guardian not found")
+var ErrInvalidGuardian = fmt.Errorf("invalid guardian")
+var ErrStudentMerged = fmt.Errorf("student was merged into another student")
+var ErrInvalidMerge = fmt.Errorf("invalid merge")
+var ErrStudentExited = fmt.Errorf("student has left the program")
+var ErrInvalidExit = fmt.Errorf("invalid exit")
+var ErrAlreadyPromoted = fmt.Errorf("student was already promoted for this school year")
 
 const EVENT_ADD_STUDENT = "AddStudent"
 const EVENT_SET_STUDENT_STATUS = "SetStudentStatus"
@@ -37,7 +55,31 @@ const EVENT_ADD_GRADE_REPORT = "AddGradeReport"
 const EVENT_ADD_HEALTH_ASSESSMENT = "AddHealthAssessment"
 const EVENT_REMOVE_HEALTH_ASSESSMENT = "RemoveHealthAssessment"
 const EVENT_REMOVE_GRADE_REPORT = "RemoveGradeReport"
+const EVENT_UPDATE_HEALTH_ASSESSMENT = "UpdateHealthAssessment"
+const EVENT_UPDATE_GRADE_REPORT = "UpdateGradeReport"
 const EVENT_UNDO_CREATE_STUDENT = "UndoCreateStudent"
+const EVENT_TRANSFER_STUDENT = "TransferStudent"
+const EVENT_ADD_GUARDIAN = "AddGuardian"
+const EVENT_UPDATE_GUARDIAN = "UpdateGuardian"
+const EVENT_REMOVE_GUARDIAN = "RemoveGuardian"
+const EVENT_MERGE_FROM_STUDENT = "MergeFromStudent"
+const EVENT_MERGE_INTO_STUDENT = "MergeIntoStudent"
+const EVENT_GRADUATE_STUDENT = "GraduateStudent"
+const EVENT_DROP_OUT_STUDENT = "DropOutStudent"
+const EVENT_TRANSFER_OUT_STUDENT = "TransferOutStudent"
+const EVENT_STUDENT_DECEASED = "StudentDeceased"
+const EVENT_PROMOTE_STUDENT = "PromoteStudent"
+const EVENT_MARK_ATTENDANCE = "MarkAttendance"
+const EVENT_REMOVE_ATTENDANCE = "RemoveAttendance"
+const EVENT_REVIEW_FEEDING = "ReviewFeeding"
+
+// exitEventTypes maps the statuses a student can leave the program with to the event recording it
+var exitEventTypes = map[eda.Student_Status]string{
+	eda.Student_GRADUATED:       EVENT_GRADUATE_STUDENT,
+	eda.Student_DROPPED_OUT:     EVENT_DROP_OUT_STUDENT,
+	eda.Student_TRANSFERRED_OUT: EVENT_TRANSFER_OUT_STUDENT,
+	eda.Student_DECEASED:        EVENT_STUDENT_DECEASED,
+}
 
 type wrappedEvent struct {
 	event gosignal.Event
@@ -45,10 +87,13 @@ type wrappedEvent struct {
 }
 
 type HealthReport struct {
+	ID                     uint64
 	AssessmentDate         time.Time
 	AssociatedBulkUploadId string
 	HeightCm               float32
 	WeightKg               float32
+	MuacMm                 float32
+	Oedema                 bool
 	sex                    *eda.Student_Sex
 	dob                    *time.Time
 }
@@ -58,33 +103,142 @@ func (h *HealthReport) BMI() float32 {
 	return h.WeightKg / (heightMeters * heightMeters)
 }
 
+// AgeYears returns the student's age when the assessment was made
 func (h *HealthReport) AgeYears() float64 {
 	if h.dob == nil {
 		return 0
 	}
 
-	return time.Since(*h.dob).Hours() / 24 / 365
+	return h.AssessmentDate.Sub(*h.dob).Hours() / 24 / 365
+}
+
+// AgeMonths returns the student's age in completed months when the assessment was made
+func (h *HealthReport) AgeMonths() int {
+	if h.dob == nil {
+		return 0
+	}
+
+	return ageInMonths(*h.dob, h.AssessmentDate)
 }
 
-func (h *HealthReport) NutritionalStatus() NutritionalStatus {
-	var gender Gender
+func (h *HealthReport) gender() (Gender, bool) {
 	switch *h.sex {
 	case eda.Student_MALE:
-		gender = Male
+		return Male, true
 	case eda.Student_FEMALE:
-		gender = Female
+		return Female, true
 	default:
-		return NutritionalStatusGenderError
+		return "", false
+	}
+}
+
+// assess computes the z-score of a measurement for the student's age and sex and classifies it
+func (h *HealthReport) assess(indicator GrowthIndicator, value float64, classify func(float64) NutritionalStatus) (GrowthAssessment, error) {
+	gender, ok := h.gender()
+	if !ok {
+		return GrowthAssessment{Status: NutritionalStatusGenderError}, nil
+	}
+
+	z, err := GrowthZScore(indicator, gender, h.AgeMonths(), value)
+	if errors.Is(err, ErrNoReferenceData) {
+		return GrowthAssessment{Status: NutritionalStatusUnavailable}, err
+	} else if err != nil {
+		return GrowthAssessment{Status: NutritionalStatusError}, err
+	}
+
+	return GrowthAssessment{Status: classify(z), ZScore: z, HasZScore: true}, nil
+}
+
+// IsUnder5 reports whether the student is assessed against the WHO child growth standards
+func (h *HealthReport) IsUnder5() bool {
+	return h.dob != nil && h.AgeMonths() <= Under5MaxAgeMonths
+}
+
+// BMIForAge classifies the student's BMI-for-age z-score
+func (h *HealthReport) BMIForAge() GrowthAssessment {
+	classify := ClassifyBMIForAge
+	if h.IsUnder5() {
+		classify = ClassifyWeightForHeight
+	}
+
+	assessment, err := h.assess(BMIForAge, float64(h.BMI()), classify)
+	if err != nil && !errors.Is(err, ErrNoReferenceData) {
+		slog.Error("error calculating BMI-for-age", "error", err)
+	}
+	return assessment
+}
+
+// WeightForHeight classifies the weight-for-length/height z-score of a child under 5
+func (h *HealthReport) WeightForHeight() GrowthAssessment {
+	gender, ok := h.gender()
+	if !ok {
+		return GrowthAssessment{Status: NutritionalStatusGenderError}
 	}
 
-	age := int(math.Round(h.AgeYears()))
+	z, err := WeightForLengthZScore(gender, h.AgeMonths(), float64(h.HeightCm), float64(h.WeightKg))
+	if errors.Is(err, ErrNoReferenceData) {
+		return GrowthAssessment{Status: NutritionalStatusUnavailable}
+	} else if err != nil {
+		slog.Error("error calculating weight-for-height", "error", err)
+		return GrowthAssessment{Status: NutritionalStatusError}
+	}
+
+	return GrowthAssessment{Status: ClassifyWeightForHeight(z), ZScore: z, HasZScore: true}
+}
+
+// NutritionalStatus classifies the student's BMI-for-age z-score. Children under 5 are assessed for
+// wasting instead, from weight-for-height, MUAC and oedema. When the growth reference doesn't cover an
+// older student's age the yearly threshold table is used instead and no z-score is returned.
+func (h *HealthReport) NutritionalStatus() GrowthAssessment {
+	if h.IsUnder5() {
+		gender, _ := h.gender()
+		assessment, err := CalculateUnder5NutritionalStatus(gender, Under5Measurements{
+			AgeMonths: h.AgeMonths(),
+			HeightCm:  float64(h.HeightCm),
+			WeightKg:  float64(h.WeightKg),
+			MuacMm:    float64(h.MuacMm),
+			Oedema:    h.Oedema,
+		})
+		if err != nil {
+			slog.Error("error calculating under 5 nutritional status", "error", err)
+		}
+		return assessment
+	}
 
-	status, err := CalculateNutritionalStatus(gender, age, h.BMI())
+	assessment, err := h.assess(BMIForAge, float64(h.BMI()), ClassifyBMIForAge)
+	switch {
+	case err == nil:
+		return assessment
+	case !errors.Is(err, ErrNoReferenceData):
+		slog.Error("error calculating nutritional status", "error", err)
+		return assessment
+	}
+
+	gender, _ := h.gender()
+	status, err := CalculateNutritionalStatus(gender, int(math.Round(h.AgeYears())), h.BMI())
 	if err != nil {
 		slog.Error("error calculating nutritional status", "error", err)
-		return NutritionalStatusError
+		return GrowthAssessment{Status: NutritionalStatusError}
+	}
+	return GrowthAssessment{Status: status}
+}
+
+// HeightForAge classifies the student's height-for-age z-score for stunting
+func (h *HealthReport) HeightForAge() GrowthAssessment {
+	assessment, err := h.assess(HeightForAge, float64(h.HeightCm), ClassifyHeightForAge)
+	if err != nil && !errors.Is(err, ErrNoReferenceData) {
+		slog.Error("error calculating height-for-age", "error", err)
 	}
-	return status
+	return assessment
+}
+
+// WeightForAge classifies the student's weight-for-age z-score, only available up to 10 years of age
+func (h *HealthReport) WeightForAge() GrowthAssessment {
+	assessment, err := h.assess(WeightForAge, float64(h.WeightKg), ClassifyWeightForAge)
+	if err != nil && !errors.Is(err, ErrNoReferenceData) {
+		slog.Error("error calculating weight-for-age", "error", err)
+	}
+	return assessment
 }
 
 type Aggregate struct {
@@ -95,7 +249,7 @@ type Aggregate struct {
 // Apply is called when an event is applied to the aggregate, it should be called from the
 // repository when applying new events or from commands as they're issued
 func (sd *Aggregate) Apply(evt gosignal.Event) error {
-	return sourcing.SafeApply(evt, sd, sd.routeEvent)
+	return sourcing.SafeApply(evt, sd, upcasters.Apply(sd.routeEvent))
 }
 
 // Apply is called when an event is applied to the aggregate, it should be called from the
@@ -157,9 +311,48 @@ func (sd *Aggregate) routeEvent(evt gosignal.Event) (err error) {
 	case EVENT_REMOVE_GRADE_REPORT:
 		eventData = &eda.Student_GradeReport_UndoEvent{}
 		handler = sd.handleRemoveGradeReport
+	case EVENT_UPDATE_HEALTH_ASSESSMENT:
+		eventData = &eda.Student_HealthAssessment_Event{}
+		handler = sd.handleUpdateHealthAssessment
+	case EVENT_UPDATE_GRADE_REPORT:
+		eventData = &eda.Student_GradeReport_Event{}
+		handler = sd.handleUpdateGradeReport
 	case EVENT_UNDO_CREATE_STUDENT:
 		eventData = &eda.Student_Create_UndoEvent{}
 		handler = sd.handleUndoCreateStudent
+	case EVENT_TRANSFER_STUDENT:
+		eventData = &eda.Student_Transfer_Event{}
+		handler = sd.handleTransferStudent
+	case EVENT_ADD_GUARDIAN:
+		eventData = &eda.Student_Guardian_Event{}
+		handler = sd.handleAddGuardian
+	case EVENT_UPDATE_GUARDIAN:
+		eventData = &eda.Student_Guardian_Event{}
+		handler = sd.handleUpdateGuardian
+	case EVENT_REMOVE_GUARDIAN:
+		eventData = &eda.Student_RemoveGuardian_Event{}
+		handler = sd.handleRemoveGuardian
+	case EVENT_MERGE_FROM_STUDENT:
+		eventData = &eda.Student_MergeFrom_Event{}
+		handler = sd.handleMergeFrom
+	case EVENT_MERGE_INTO_STUDENT:
+		eventData = &eda.Student_MergeInto_Event{}
+		handler = sd.handleMergeInto
+	case EVENT_GRADUATE_STUDENT, EVENT_DROP_OUT_STUDENT, EVENT_TRANSFER_OUT_STUDENT, EVENT_STUDENT_DECEASED:
+		eventData = &eda.Student_Exit_Event{}
+		handler = sd.handleExit
+	case EVENT_PROMOTE_STUDENT:
+		eventData = &eda.Student_PromoteGrade_Event{}
+		handler = sd.handlePromoteGrade
+	case EVENT_MARK_ATTENDANCE:
+		eventData = &eda.Student_Attendance_Event{}
+		handler = sd.handleMarkAttendance
+	case EVENT_REMOVE_ATTENDANCE:
+		eventData = &eda.Student_Attendance_UndoEvent{}
+		handler = sd.handleRemoveAttendance
+	case EVENT_REVIEW_FEEDING:
+		eventData = &eda.Student_ReviewFeeding_Event{}
+		handler = sd.handleReviewFeeding
 	default:
 		return ErrEventNotFound
 	}
@@ -207,10 +400,13 @@ func (sd *Aggregate) GetHealthAssessments() []*HealthReport {
 
 	for i, h := range sd.data.HealthAssessments {
 		hr[i] = &HealthReport{
+			ID:                     h.Id,
 			AssessmentDate:         h.AssessmentDate.AsTime(),
 			AssociatedBulkUploadId: h.AssociatedBulkUploadId,
 			HeightCm:               h.HeightCm,
 			WeightKg:               h.WeightKg,
+			MuacMm:                 h.MuacMm,
+			Oedema:                 h.Oedema,
 			sex:                    &sd.data.Sex,
 			dob:                    &dob,
 		}
@@ -229,6 +425,7 @@ func (sd *Aggregate) GetHealthAssessments() []*HealthReport {
 	return hr
 }
 
+// AddHealthAssessment adds an assessment from a bulk upload, the assessment is assigned the next free ID
 func (sd *Aggregate) AddHealthAssessment(cmd *eda.Student_HealthAssessment) (*gosignal.Event, error) {
 	if sd.data == nil {
 		return nil, ErrStudentNotFound
@@ -238,16 +435,50 @@ func (sd *Aggregate) AddHealthAssessment(cmd *eda.Student_HealthAssessment) (*go
 
 	return sd.ApplyEvent(StudentEvent{
 		eventType: EVENT_ADD_HEALTH_ASSESSMENT,
-		data: &eda.Student_HealthAssessment_Event{
-			AssessmentDate:         cmd.GetAssessmentDate(),
-			AssociatedBulkUploadId: cmd.GetAssociatedBulkUploadId(),
-			HeightCm:               cmd.GetHeightCm(),
-			WeightKg:               cmd.GetWeightKg(),
-		},
-		version: sd.Version,
+		data:      healthAssessmentEvent(sd.data.HealthAssessmentNextId+1, cmd),
+		version:   sd.Version,
+	})
+}
+
+// RecordHealthAssessment adds an assessment entered individually, it isn't part of any bulk upload
+func (sd *Aggregate) RecordHealthAssessment(cmd *eda.Student_AddHealthAssessment) (*gosignal.Event, error) {
+	if err := validateHealthAssessment(cmd.GetAssessment()); err != nil {
+		return nil, err
+	}
+
+	evt := healthAssessmentEvent(sd.data.HealthAssessmentNextId+1, cmd.GetAssessment())
+	evt.AssociatedBulkUploadId = ""
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_ADD_HEALTH_ASSESSMENT,
+		data:      evt,
+		version:   cmd.GetVersion(),
+	})
+}
+
+// UpdateHealthAssessment replaces the measurements of an existing assessment, it stays associated with
+// the bulk upload it came from so undoing the upload still removes it
+func (sd *Aggregate) UpdateHealthAssessment(cmd *eda.Student_UpdateHealthAssessment) (*gosignal.Event, error) {
+	if err := validateHealthAssessment(cmd.GetAssessment()); err != nil {
+		return nil, err
+	}
+
+	i := sd.findHealthAssessment(cmd.GetAssessment().GetId())
+	if i == -1 {
+		return nil, ErrHealthAssessmentNotFound
+	}
+
+	evt := healthAssessmentEvent(cmd.GetAssessment().GetId(), cmd.GetAssessment())
+	evt.AssociatedBulkUploadId = sd.data.HealthAssessments[i].AssociatedBulkUploadId
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_UPDATE_HEALTH_ASSESSMENT,
+		data:      evt,
+		version:   cmd.GetVersion(),
 	})
 }
 
+// RemoveHealthAssessment removes the assessment of a bulk upload when the upload is undone
 func (sd *Aggregate) RemoveHealthAssessment(bulkUploadID string) (*gosignal.Event, error) {
 	if sd.data == nil {
 		return nil, ErrStudentNotFound
@@ -262,28 +493,114 @@ func (sd *Aggregate) RemoveHealthAssessment(bulkUploadID string) (*gosignal.Even
 	})
 }
 
-func (sd *Aggregate) handleAddHealthAssessment(evt wrappedEvent) error {
-	event := evt.data.(*eda.Student_HealthAssessment_Event)
-	sd.data.HealthAssessments = append(sd.data.HealthAssessments, &eda.Student_HealthAssessment{
+// DeleteHealthAssessment removes a single assessment by its ID
+func (sd *Aggregate) DeleteHealthAssessment(cmd *eda.Student_RemoveHealthAssessment) (*gosignal.Event, error) {
+	i := sd.findHealthAssessment(cmd.GetId())
+	if i == -1 {
+		return nil, ErrHealthAssessmentNotFound
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_REMOVE_HEALTH_ASSESSMENT,
+		data: &eda.Student_HealthAssessment_UndoEvent{
+			Id:                     cmd.GetId(),
+			AssociatedBulkUploadId: sd.data.HealthAssessments[i].AssociatedBulkUploadId,
+		},
+		version: cmd.GetVersion(),
+	})
+}
+
+func validateHealthAssessment(a *eda.Student_HealthAssessment) error {
+	switch {
+	case a == nil:
+		return fmt.Errorf("%w: assessment is required", ErrInvalidHealthAssessment)
+	case a.GetAssessmentDate() == nil:
+		return fmt.Errorf("%w: assessment date is required", ErrInvalidHealthAssessment)
+	case a.GetAssessmentDate().AsTime().After(time.Now()):
+		return fmt.Errorf("%w: assessment date is in the future", ErrInvalidHealthAssessment)
+	case a.GetHeightCm() <= 0 || a.GetWeightKg() <= 0:
+		return fmt.Errorf("%w: height and weight are required", ErrInvalidHealthAssessment)
+	case a.GetMuacMm() < 0:
+		return fmt.Errorf("%w: arm circumference can't be negative", ErrInvalidHealthAssessment)
+	}
+
+	return nil
+}
+
+func healthAssessmentEvent(id uint64, a *eda.Student_HealthAssessment) *eda.Student_HealthAssessment_Event {
+	return &eda.Student_HealthAssessment_Event{
+		Id:                     id,
+		AssessmentDate:         a.GetAssessmentDate(),
+		AssociatedBulkUploadId: a.GetAssociatedBulkUploadId(),
+		HeightCm:               a.GetHeightCm(),
+		WeightKg:               a.GetWeightKg(),
+		MuacMm:                 a.GetMuacMm(),
+		Oedema:                 a.GetOedema(),
+	}
+}
+
+func healthAssessmentFromEvent(event *eda.Student_HealthAssessment_Event) *eda.Student_HealthAssessment {
+	return &eda.Student_HealthAssessment{
+		Id:                     event.Id,
 		AssessmentDate:         event.AssessmentDate,
 		AssociatedBulkUploadId: event.AssociatedBulkUploadId,
 		HeightCm:               event.HeightCm,
 		WeightKg:               event.WeightKg,
+		MuacMm:                 event.MuacMm,
+		Oedema:                 event.Oedema,
+	}
+}
+
+func (sd *Aggregate) findHealthAssessment(id uint64) int {
+	return slices.IndexFunc(sd.data.HealthAssessments, func(a *eda.Student_HealthAssessment) bool {
+		return a.Id == id
 	})
+}
+
+func (sd *Aggregate) handleAddHealthAssessment(evt wrappedEvent) error {
+	event := evt.data.(*eda.Student_HealthAssessment_Event)
+
+	// events from before assessments had ids get the next one, replay always assigns the same
+	if event.Id == 0 {
+		event.Id = sd.data.HealthAssessmentNextId + 1
+	}
+
+	sd.data.HealthAssessments = append(sd.data.HealthAssessments, healthAssessmentFromEvent(event))
+	sd.data.HealthAssessmentNextId = max(sd.data.HealthAssessmentNextId, event.Id)
+
+	return nil
+}
+
+func (sd *Aggregate) handleUpdateHealthAssessment(evt wrappedEvent) error {
+	event := evt.data.(*eda.Student_HealthAssessment_Event)
+
+	i := sd.findHealthAssessment(event.Id)
+	if i == -1 {
+		return ErrHealthAssessmentNotFound
+	}
+
+	sd.data.HealthAssessments[i] = healthAssessmentFromEvent(event)
+
 	return nil
 }
 
 func (sd *Aggregate) handleRemoveHealthAssessment(evt wrappedEvent) error {
 	event := evt.data.(*eda.Student_HealthAssessment_UndoEvent)
-	for i, assessment := range sd.data.HealthAssessments {
-		if assessment.AssociatedBulkUploadId == event.AssociatedBulkUploadId {
-			sd.data.HealthAssessments = slices.Delete(sd.data.HealthAssessments, i, i+1)
-			return nil
+	i := slices.IndexFunc(sd.data.HealthAssessments, func(a *eda.Student_HealthAssessment) bool {
+		if event.Id != 0 {
+			return a.Id == event.Id
 		}
+		return a.AssociatedBulkUploadId == event.AssociatedBulkUploadId
+	})
+	if i == -1 {
+		return ErrHealthAssessmentNotFound
 	}
-	return ErrHealthAssessmentNotFound
+
+	sd.data.HealthAssessments = slices.Delete(sd.data.HealthAssessments, i, i+1)
+	return nil
 }
 
+// AddGradeReport adds a grade report from a bulk upload, the report is assigned the next free ID
 func (sd *Aggregate) AddGradeReport(cmd *eda.Student_GradeReport) (*gosignal.Event, error) {
 	if sd.data == nil {
 		return nil, ErrStudentNotFound
@@ -293,17 +610,56 @@ func (sd *Aggregate) AddGradeReport(cmd *eda.Student_GradeReport) (*gosignal.Eve
 
 	return sd.ApplyEvent(StudentEvent{
 		eventType: EVENT_ADD_GRADE_REPORT,
-		data: &eda.Student_GradeReport_Event{
-			Grade:                  cmd.GetGrade(),
-			TestDate:               cmd.GetTestDate(),
-			AssociatedBulkUploadId: cmd.GetAssociatedBulkUploadId(),
-			SchoolYear:             cmd.GetSchoolYear(),
-			GradingPeriod:          cmd.GetGradingPeriod(),
-		},
-		version: sd.Version,
+		data:      gradeReportEvent(sd.data.GradeReportNextId+1, cmd),
+		version:   sd.Version,
+	})
+}
+
+// RecordGradeReport adds a grade report entered individually, it isn't part of any bulk upload
+func (sd *Aggregate) RecordGradeReport(cmd *eda.Student_AddGradeReport) (*gosignal.Event, error) {
+	if err := validateGradeReport(cmd.GetReport()); err != nil {
+		return nil, err
+	}
+
+	evt := gradeReportEvent(sd.data.GradeReportNextId+1, cmd.GetReport())
+	evt.AssociatedBulkUploadId = ""
+	if evt.GradingScale == nil {
+		evt.GradingScale = DefaultGradingScale()
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_ADD_GRADE_REPORT,
+		data:      evt,
+		version:   cmd.GetVersion(),
 	})
 }
 
+// UpdateGradeReport replaces the details of an existing grade report, it stays associated with the bulk
+// upload it came from
+func (sd *Aggregate) UpdateGradeReport(cmd *eda.Student_UpdateGradeReport) (*gosignal.Event, error) {
+	if err := validateGradeReport(cmd.GetReport()); err != nil {
+		return nil, err
+	}
+
+	i := sd.findGradeReport(cmd.GetReport().GetId())
+	if i == -1 {
+		return nil, ErrGradeReportNotFound
+	}
+
+	evt := gradeReportEvent(cmd.GetReport().GetId(), cmd.GetReport())
+	evt.AssociatedBulkUploadId = sd.data.GradeHistory[i].AssociatedBulkUploadId
+	if evt.GradingScale == nil {
+		evt.GradingScale = sd.data.GradeHistory[i].GradingScale
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_UPDATE_GRADE_REPORT,
+		data:      evt,
+		version:   cmd.GetVersion(),
+	})
+}
+
+// RemoveGradeReport removes the grade report of a bulk upload when the upload is undone
 func (sd *Aggregate) RemoveGradeReport(bulkUploadID string) (*gosignal.Event, error) {
 	if sd.data == nil {
 		return nil, ErrStudentNotFound
@@ -318,31 +674,235 @@ func (sd *Aggregate) RemoveGradeReport(bulkUploadID string) (*gosignal.Event, er
 	})
 }
 
+// DeleteGradeReport removes a single grade report by its ID
+func (sd *Aggregate) DeleteGradeReport(cmd *eda.Student_RemoveGradeReport) (*gosignal.Event, error) {
+	i := sd.findGradeReport(cmd.GetId())
+	if i == -1 {
+		return nil, ErrGradeReportNotFound
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_REMOVE_GRADE_REPORT,
+		data: &eda.Student_GradeReport_UndoEvent{
+			Id:                     cmd.GetId(),
+			AssociatedBulkUploadId: sd.data.GradeHistory[i].AssociatedBulkUploadId,
+		},
+		version: cmd.GetVersion(),
+	})
+}
+
+func (sd *Aggregate) findGradeReport(id uint64) int {
+	return slices.IndexFunc(sd.data.GradeHistory, func(g *eda.Student_GradeReport) bool {
+		return g.Id == id
+	})
+}
+
+func (sd *Aggregate) handleAddGradeReport(evt wrappedEvent) error {
+	event := evt.data.(*eda.Student_GradeReport_Event)
+
+	// events from before grade reports had ids get the next one, replay always assigns the same
+	if event.Id == 0 {
+		event.Id = sd.data.GradeReportNextId + 1
+	}
+
+	sd.data.GradeHistory = append(sd.data.GradeHistory, gradeReportFromEvent(event))
+	sd.data.GradeReportNextId = max(sd.data.GradeReportNextId, event.Id)
+
+	return nil
+}
+
+func (sd *Aggregate) handleUpdateGradeReport(evt wrappedEvent) error {
+	event := evt.data.(*eda.Student_GradeReport_Event)
+
+	i := sd.findGradeReport(event.Id)
+	if i == -1 {
+		return ErrGradeReportNotFound
+	}
+
+	sd.data.GradeHistory[i] = gradeReportFromEvent(event)
+
+	return nil
+}
+
 func (sd *Aggregate) handleRemoveGradeReport(evt wrappedEvent) error {
 	event := evt.data.(*eda.Student_GradeReport_UndoEvent)
-	for i, gradeReport := range sd.data.GradeHistory {
-		if gradeReport.AssociatedBulkUploadId == event.AssociatedBulkUploadId {
-			sd.data.GradeHistory = slices.Delete(sd.data.GradeHistory, i, i+1)
-			return nil
+	i := slices.IndexFunc(sd.data.GradeHistory, func(g *eda.Student_GradeReport) bool {
+		if event.Id != 0 {
+			return g.Id == event.Id
 		}
+		return g.AssociatedBulkUploadId == event.AssociatedBulkUploadId
+	})
+	if i == -1 {
+		return ErrGradeReportNotFound
 	}
-	return ErrGradeReportNotFound
+
+	sd.data.GradeHistory = slices.Delete(sd.data.GradeHistory, i, i+1)
+	return nil
 }
 
-func (sd *Aggregate) handleAddGradeReport(evt wrappedEvent) error {
-	event := evt.data.(*eda.Student_GradeReport_Event)
-	sd.data.GradeHistory = append(sd.data.GradeHistory, &eda.Student_GradeReport{
-		Grade:                  event.Grade,
-		TestDate:               event.TestDate,
+// GetAttendance returns the student's attendance, oldest day first
+func (sd *Aggregate) GetAttendance() []*eda.Student_Attendance {
+	attendance := slices.Clone(sd.data.Attendance)
+	slices.SortFunc(attendance, func(a, b *eda.Student_Attendance) int {
+		return dateToTime(a.Date).Compare(dateToTime(b.Date))
+	})
+	return attendance
+}
+
+// AttendanceOn returns the attendance marked for a day, nil when the day wasn't marked
+func (sd *Aggregate) AttendanceOn(day time.Time) *eda.Student_Attendance {
+	i := sd.findAttendance(timeToDate(day))
+	if i == -1 {
+		return nil
+	}
+	return sd.data.Attendance[i]
+}
+
+func (sd *Aggregate) findAttendance(date *eda.Date) int {
+	return slices.IndexFunc(sd.data.Attendance, func(a *eda.Student_Attendance) bool {
+		return dateToTime(a.Date).Equal(dateToTime(date))
+	})
+}
+
+func validateAttendance(a *eda.Student_Attendance) error {
+	switch {
+	case a == nil:
+		return fmt.Errorf("%w: attendance is required", ErrInvalidAttendance)
+	case a.GetDate() == nil:
+		return fmt.Errorf("%w: date is required", ErrInvalidAttendance)
+	case dateToTime(a.GetDate()).After(time.Now()):
+		return fmt.Errorf("%w: date is in the future", ErrInvalidAttendance)
+	case a.GetStatus() == eda.Student_Attendance_UNKNOWN_ATTENDANCE:
+		return fmt.Errorf("%w: status is required", ErrInvalidAttendance)
+	case eda.Student_Attendance_Status_name[int32(a.GetStatus())] == "":
+		return fmt.Errorf("%w: unknown status %d", ErrInvalidAttendance, a.GetStatus())
+	}
+	return nil
+}
+
+func (sd *Aggregate) attendanceEvent(a *eda.Student_Attendance) *eda.Student_Attendance_Event {
+	schoolID := a.GetSchoolId()
+	if schoolID == "" {
+		schoolID = sd.data.SchoolId
+	}
+
+	reason := strings.TrimSpace(a.GetReason())
+	if a.GetStatus() == eda.Student_Attendance_PRESENT {
+		reason = ""
+	}
+
+	return &eda.Student_Attendance_Event{
+		Date:                   a.GetDate(),
+		Status:                 a.GetStatus(),
+		Reason:                 reason,
+		SchoolId:               schoolID,
+		RecordedBy:             a.GetRecordedBy(),
+		AssociatedBulkUploadId: a.GetAssociatedBulkUploadId(),
+	}
+}
+
+// MarkAttendance records the student's attendance for a day at the school they were enrolled in that day,
+// marking a day again replaces the earlier mark. Marking it the same way again records nothing, so
+// resubmitting a roster doesn't add an event for every student.
+func (sd *Aggregate) MarkAttendance(cmd *eda.Student_MarkAttendance) (*gosignal.Event, error) {
+	if sd.HasExited() {
+		return nil, ErrStudentExited
+	}
+	if err := validateAttendance(cmd.GetAttendance()); err != nil {
+		return nil, err
+	}
+
+	evt := sd.attendanceEvent(cmd.GetAttendance())
+	evt.AssociatedBulkUploadId = ""
+
+	if day := dateToTime(evt.Date); sd.SchoolAt(day) != evt.SchoolId {
+		return nil, fmt.Errorf("%w: student wasn't enrolled at school %s on %s", ErrInvalidAttendance, evt.SchoolId, day.Format("2006-01-02"))
+	}
+
+	if prev := sd.AttendanceOn(dateToTime(evt.Date)); prev != nil && prev.Status == evt.Status && prev.Reason == evt.Reason && prev.SchoolId == evt.SchoolId {
+		return nil, nil
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_MARK_ATTENDANCE,
+		data:      evt,
+		version:   cmd.GetVersion(),
+	})
+}
+
+// AddAttendance records attendance from a bulk upload
+func (sd *Aggregate) AddAttendance(a *eda.Student_Attendance) (*gosignal.Event, error) {
+	if sd.data == nil {
+		return nil, ErrStudentNotFound
+	}
+	if err := validateAttendance(a); err != nil {
+		return nil, err
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_MARK_ATTENDANCE,
+		data:      sd.attendanceEvent(a),
+		version:   sd.Version,
+	})
+}
+
+// RemoveAttendance removes the attendance marked by a bulk upload. Days the upload marked again are
+// removed too, the earlier mark isn't restored.
+func (sd *Aggregate) RemoveAttendance(bulkUploadID string) (*gosignal.Event, error) {
+	if sd.data == nil {
+		return nil, ErrStudentNotFound
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_REMOVE_ATTENDANCE,
+		data: &eda.Student_Attendance_UndoEvent{
+			AssociatedBulkUploadId: bulkUploadID,
+		},
+		version: sd.Version,
+	})
+}
+
+func (sd *Aggregate) handleMarkAttendance(evt wrappedEvent) error {
+	event := evt.data.(*eda.Student_Attendance_Event)
+
+	attendance := &eda.Student_Attendance{
+		Date:                   event.Date,
+		Status:                 event.Status,
+		Reason:                 event.Reason,
+		SchoolId:               event.SchoolId,
+		RecordedBy:             event.RecordedBy,
 		AssociatedBulkUploadId: event.AssociatedBulkUploadId,
-		SchoolYear:             event.SchoolYear,
-		GradingPeriod:          event.GradingPeriod,
+	}
+
+	if i := sd.findAttendance(event.Date); i != -1 {
+		sd.data.Attendance[i] = attendance
+		return nil
+	}
+
+	sd.data.Attendance = append(sd.data.Attendance, attendance)
+	return nil
+}
+
+func (sd *Aggregate) handleRemoveAttendance(evt wrappedEvent) error {
+	event := evt.data.(*eda.Student_Attendance_UndoEvent)
+
+	before := len(sd.data.Attendance)
+	sd.data.Attendance = slices.DeleteFunc(sd.data.Attendance, func(a *eda.Student_Attendance) bool {
+		return a.AssociatedBulkUploadId == event.AssociatedBulkUploadId
 	})
+	if len(sd.data.Attendance) == before {
+		return ErrAttendanceNotFound
+	}
+
 	return nil
 }
 
 // feed - handles the feeding of a student
 func (sd *Aggregate) Feed(cmd *eda.Student_Feeding) (*gosignal.Event, error) {
+	if sd.HasExited() {
+		return nil, ErrStudentExited
+	}
+
 	timestamp := cmd.GetUnixTimestamp()
 	if len(sd.data.FeedingReport) > 0 {
 		lastFeeding := sd.data.FeedingReport[len(sd.data.FeedingReport)-1]
@@ -373,6 +933,7 @@ func (sd *Aggregate) Feed(cmd *eda.Student_Feeding) (*gosignal.Event, error) {
 		data: &eda.Student_Feeding_Event{
 			UnixTimestamp: uint64(timestamp),
 			FileId:        cmd.GetFileId(),
+			FedBy:         cmd.GetFedBy(),
 		},
 		version: cmd.GetVersion(),
 	})
@@ -388,6 +949,75 @@ func (sd *Aggregate) handleFeedStudent(evt wrappedEvent) error {
 	return nil
 }
 
+// findFeeding returns the feeding with the given ID, feedings are identified by their unix timestamp
+func (sd *Aggregate) findFeeding(feedingID uint64) *eda.Student_Feeding_Event {
+	for _, f := range sd.data.FeedingReport {
+		if f.UnixTimestamp == feedingID {
+			return f
+		}
+	}
+	return nil
+}
+
+// ReviewFeeding records a reviewer's verdict on the proof photo of a feeding, a verdict other than
+// verified needs a reason
+func (sd *Aggregate) ReviewFeeding(cmd *eda.Student_ReviewFeeding) (*gosignal.Event, error) {
+	if sd.data == nil {
+		return nil, ErrStudentNotFound
+	}
+
+	review := cmd.GetReview()
+	switch {
+	case review == nil:
+		return nil, fmt.Errorf("%w: review is required", ErrInvalidFeedingReview)
+	case review.GetStatus() == eda.Student_Feeding_Review_UNREVIEWED:
+		return nil, fmt.Errorf("%w: status is required", ErrInvalidFeedingReview)
+	case eda.Student_Feeding_Review_Status_name[int32(review.GetStatus())] == "":
+		return nil, fmt.Errorf("%w: unknown status %d", ErrInvalidFeedingReview, review.GetStatus())
+	case review.GetStatus() != eda.Student_Feeding_Review_VERIFIED && review.GetReason() == "":
+		return nil, fmt.Errorf("%w: a reason is required when the photo isn't verified", ErrInvalidFeedingReview)
+	}
+
+	feeding := sd.findFeeding(cmd.GetFeedingId())
+	if feeding == nil {
+		return nil, ErrFeedingNotFound
+	}
+	if feeding.GetFileId() == "" {
+		return nil, fmt.Errorf("%w: the feeding has no proof photo", ErrInvalidFeedingReview)
+	}
+
+	reviewedAt := review.GetReviewedAt()
+	if reviewedAt == 0 {
+		reviewedAt = uint64(time.Now().Unix())
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_REVIEW_FEEDING,
+		data: &eda.Student_ReviewFeeding_Event{
+			FeedingId: cmd.GetFeedingId(),
+			Review: &eda.Student_Feeding_Review{
+				Status:     review.GetStatus(),
+				Reason:     review.GetReason(),
+				ReviewedBy: review.GetReviewedBy(),
+				ReviewedAt: reviewedAt,
+			},
+		},
+		version: cmd.GetVersion(),
+	})
+}
+
+func (sd *Aggregate) handleReviewFeeding(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_ReviewFeeding_Event)
+
+	feeding := sd.findFeeding(data.FeedingId)
+	if feeding == nil {
+		return ErrFeedingNotFound
+	}
+	feeding.Review = data.Review
+
+	return nil
+}
+
 func (sd *Aggregate) undoCreate(associatedBulkUploadId string) (*gosignal.Event, error) {
 	return sd.ApplyEvent(StudentEvent{
 		eventType: EVENT_UNDO_CREATE_STUDENT,
@@ -428,6 +1058,14 @@ func (sd *Aggregate) SetLookupCode(cmd *eda.Student_SetLookupCode) (*gosignal.Ev
 
 // SetStatus is a function that sets the status of a student, active or inactive
 func (sd *Aggregate) SetStatus(cmd *eda.Student_SetStatus) (*gosignal.Event, error) {
+	if sd.HasExited() {
+		return nil, ErrStudentExited
+	}
+
+	if _, isExit := exitEventTypes[cmd.GetStatus()]; isExit {
+		return nil, fmt.Errorf("%w: exit statuses are set by recording an exit", ErrInvalidExit)
+	}
+
 	return sd.ApplyEvent(StudentEvent{
 		eventType: EVENT_SET_STUDENT_STATUS,
 		data:      &eda.Student_SetStatus_Event{Status: cmd.GetStatus()},
@@ -469,6 +1107,44 @@ func (sd *Aggregate) UnenrollStudent(cmd *eda.Student_Unenroll) (*gosignal.Event
 	})
 }
 
+// TransferStudent moves an enrolled student to another school, closing the current enrollment
+// on the transfer date
+func (sd *Aggregate) TransferStudent(cmd *eda.Student_Transfer) (*gosignal.Event, error) {
+	if sd.HasExited() {
+		return nil, ErrStudentExited
+	}
+
+	if sd.data.SchoolId == "" {
+		return nil, ErrNotEnrolled
+	}
+
+	if cmd.GetToSchoolId() == sd.data.SchoolId {
+		return nil, ErrAlreadyInSchool
+	}
+
+	if cmd.GetTransferDate() == nil {
+		return nil, fmt.Errorf("%w: transfer date is required", ErrInvalidTransferDate)
+	}
+
+	transferDate := dateToTime(cmd.GetTransferDate())
+	if current := sd.currentEnrollment(); current != nil && current.StartDate != nil {
+		if transferDate.Before(dateToTime(current.StartDate)) {
+			return nil, fmt.Errorf("%w: transfer date is before the current enrollment started", ErrInvalidTransferDate)
+		}
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_TRANSFER_STUDENT,
+		data: &eda.Student_Transfer_Event{
+			FromSchoolId: sd.data.SchoolId,
+			ToSchoolId:   cmd.GetToSchoolId(),
+			TransferDate: cmd.GetTransferDate(),
+			Reason:       cmd.GetReason(),
+		},
+		version: cmd.GetVersion(),
+	})
+}
+
 // HandleSetStudentStatus handles the SetStudentStatus event
 func (sd *Aggregate) HandleSetStudentStatus(evt wrappedEvent) error {
 	data := evt.data.(*eda.Student_SetStatus_Event)
@@ -516,20 +1192,82 @@ func (sd *Aggregate) HandleUpdateStudent(evt wrappedEvent) error {
 func (sd *Aggregate) HandleEnrollStudent(evt wrappedEvent) error {
 	data := evt.data.(*eda.Student_Enroll_Event)
 
+	// re-enrolling in the same school only corrects the enrollment date
+	current := sd.currentEnrollment()
+	if current != nil && current.SchoolId == data.SchoolId {
+		current.StartDate = data.DateOfEnrollment
+	} else {
+		sd.closeEnrollment(data.DateOfEnrollment)
+		sd.openEnrollment(data.SchoolId, data.DateOfEnrollment)
+	}
+
 	sd.data.SchoolId = data.SchoolId
 	sd.data.DateOfEnrollment = data.DateOfEnrollment
 
+	// enrolling a student who left the program readmits them, they start out inactive like a new student
+	if sd.data.Exit != nil {
+		sd.data.Exit = nil
+		sd.data.Status = eda.Student_INACTIVE
+	}
+
 	return nil
 }
 
 // HandleUnenrollStudent handles the UnenrollStudent event
 func (sd *Aggregate) HandleUnenrollStudent(evt wrappedEvent) error {
+	// the unenroll event carries no date, so the enrollment closes on the day it was recorded
+	sd.closeEnrollment(timeToDate(evt.event.Timestamp))
+
 	sd.data.SchoolId = ""
 	sd.data.DateOfEnrollment = nil
 
 	return nil
 }
 
+func (sd *Aggregate) handleTransferStudent(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_Transfer_Event)
+
+	sd.closeEnrollment(data.TransferDate)
+	sd.openEnrollment(data.ToSchoolId, data.TransferDate)
+
+	sd.data.SchoolId = data.ToSchoolId
+	sd.data.DateOfEnrollment = data.TransferDate
+	sd.data.TransferHistory = append(sd.data.TransferHistory, data)
+
+	return nil
+}
+
+// currentEnrollment returns the open enrollment record, or nil when the student isn't enrolled
+func (sd *Aggregate) currentEnrollment() *eda.Student_EnrollmentRecord {
+	history := sd.data.EnrollmentHistory
+	if len(history) == 0 || history[len(history)-1].EndDate != nil {
+		return nil
+	}
+
+	return history[len(history)-1]
+}
+
+func (sd *Aggregate) openEnrollment(schoolID string, start *eda.Date) {
+	sd.data.EnrollmentHistory = append(sd.data.EnrollmentHistory, &eda.Student_EnrollmentRecord{
+		SchoolId:  schoolID,
+		StartDate: start,
+	})
+}
+
+func (sd *Aggregate) closeEnrollment(end *eda.Date) {
+	current := sd.currentEnrollment()
+	if current == nil {
+		return
+	}
+
+	// an undated close still has to end the interval so it stops being the current enrollment
+	if end == nil {
+		end = &eda.Date{}
+	}
+
+	current.EndDate = end
+}
+
 func (sd *Aggregate) handleSetLookupCode(evt wrappedEvent) error {
 	data := evt.data.(*eda.Student_SetLookupCode_Event)
 
@@ -560,9 +1298,11 @@ func (sd *Aggregate) handleUpdateSponsorship(evt wrappedEvent) error {
 
 	// Create new sponsorship record
 	newRecord := &eda.Student_SponsorshipRecord{
-		SponsorId: data.SponsorId,
-		StartDate: data.StartDate,
-		EndDate:   data.EndDate,
+		SponsorId:     data.SponsorId,
+		StartDate:     data.StartDate,
+		EndDate:       data.EndDate,
+		PaymentId:     data.PaymentId,
+		PaymentAmount: data.PaymentAmount,
 	}
 
 	// Add to sponsorship history
@@ -574,6 +1314,370 @@ func (sd *Aggregate) handleUpdateSponsorship(evt wrappedEvent) error {
 	return nil
 }
 
+// dateToTime converts a proto date to midnight UTC
+func dateToTime(d *eda.Date) time.Time {
+	return time.Date(int(d.GetYear()), time.Month(d.GetMonth()), int(d.GetDay()), 0, 0, 0, 0, time.UTC)
+}
+
+func timeToDate(t time.Time) *eda.Date {
+	return &eda.Date{Year: int32(t.Year()), Month: int32(t.Month()), Day: int32(t.Day())}
+}
+
+// AddGuardian adds a parent or guardian to the student, the guardian is assigned the next free ID
+func (sd *Aggregate) AddGuardian(cmd *eda.Student_AddGuardian) (*gosignal.Event, error) {
+	if err := validateGuardian(cmd.GetGuardian()); err != nil {
+		return nil, err
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_ADD_GUARDIAN,
+		data:      guardianEvent(sd.data.GuardianNextId+1, cmd.GetGuardian()),
+		version:   cmd.GetVersion(),
+	})
+}
+
+// UpdateGuardian replaces the details of an existing guardian
+func (sd *Aggregate) UpdateGuardian(cmd *eda.Student_UpdateGuardian) (*gosignal.Event, error) {
+	if err := validateGuardian(cmd.GetGuardian()); err != nil {
+		return nil, err
+	}
+
+	if sd.findGuardian(cmd.GetGuardian().GetId()) == -1 {
+		return nil, ErrGuardianNotFound
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_UPDATE_GUARDIAN,
+		data:      guardianEvent(cmd.GetGuardian().GetId(), cmd.GetGuardian()),
+		version:   cmd.GetVersion(),
+	})
+}
+
+func (sd *Aggregate) RemoveGuardian(cmd *eda.Student_RemoveGuardian) (*gosignal.Event, error) {
+	if sd.findGuardian(cmd.GetGuardianId()) == -1 {
+		return nil, ErrGuardianNotFound
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_REMOVE_GUARDIAN,
+		data:      &eda.Student_RemoveGuardian_Event{GuardianId: cmd.GetGuardianId()},
+		version:   cmd.GetVersion(),
+	})
+}
+
+func validateGuardian(g *eda.Student_Guardian) error {
+	switch {
+	case g == nil:
+		return fmt.Errorf("%w: guardian is required", ErrInvalidGuardian)
+	case strings.TrimSpace(g.GetName()) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidGuardian)
+	case g.GetConsentToContact() && strings.TrimSpace(g.GetPhone()) == "":
+		return fmt.Errorf("%w: a phone number is required when the guardian consents to contact", ErrInvalidGuardian)
+	}
+
+	return nil
+}
+
+func guardianEvent(id uint64, g *eda.Student_Guardian) *eda.Student_Guardian_Event {
+	return &eda.Student_Guardian_Event{
+		Id:               id,
+		Name:             strings.TrimSpace(g.GetName()),
+		Relationship:     strings.TrimSpace(g.GetRelationship()),
+		Phone:            strings.TrimSpace(g.GetPhone()),
+		ConsentToContact: g.GetConsentToContact(),
+		ConsentToShare:   g.GetConsentToShare(),
+	}
+}
+
+func (sd *Aggregate) findGuardian(id uint64) int {
+	return slices.IndexFunc(sd.data.Guardians, func(g *eda.Student_Guardian) bool {
+		return g.Id == id
+	})
+}
+
+func (sd *Aggregate) handleAddGuardian(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_Guardian_Event)
+
+	sd.data.Guardians = append(sd.data.Guardians, guardianFromEvent(data))
+	sd.data.GuardianNextId = max(sd.data.GuardianNextId, data.Id)
+
+	return nil
+}
+
+func (sd *Aggregate) handleUpdateGuardian(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_Guardian_Event)
+
+	i := sd.findGuardian(data.Id)
+	if i == -1 {
+		return ErrGuardianNotFound
+	}
+
+	sd.data.Guardians[i] = guardianFromEvent(data)
+
+	return nil
+}
+
+func (sd *Aggregate) handleRemoveGuardian(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_RemoveGuardian_Event)
+
+	i := sd.findGuardian(data.GuardianId)
+	if i == -1 {
+		return ErrGuardianNotFound
+	}
+
+	sd.data.Guardians = slices.Delete(sd.data.Guardians, i, i+1)
+
+	return nil
+}
+
+func guardianFromEvent(data *eda.Student_Guardian_Event) *eda.Student_Guardian {
+	return &eda.Student_Guardian{
+		Id:               data.Id,
+		Name:             data.Name,
+		Relationship:     data.Relationship,
+		Phone:            data.Phone,
+		ConsentToContact: data.ConsentToContact,
+		ConsentToShare:   data.ConsentToShare,
+	}
+}
+
+// GetGuardians returns the student's parents and guardians
+func (sd Aggregate) GetGuardians() []*eda.Student_Guardian {
+	return sd.data.Guardians
+}
+
+// IsMerged reports whether the student was merged into another student as a duplicate
+func (sd Aggregate) IsMerged() bool {
+	return sd.data.MergedIntoStudentId != ""
+}
+
+// MergeFrom folds the feedings, grades, health assessments and sponsorships of a duplicate student into
+// this one. Records this student already has are skipped, so a merge that is retried doesn't double up.
+func (sd *Aggregate) MergeFrom(source *Aggregate, version uint64) (*gosignal.Event, error) {
+	switch {
+	case source.GetID() == sd.GetID():
+		return nil, fmt.Errorf("%w: a student can't be merged into itself", ErrInvalidMerge)
+	case sd.data.IsDeleted || source.data.IsDeleted:
+		return nil, fmt.Errorf("%w: deleted students can't be merged", ErrInvalidMerge)
+	case sd.IsMerged():
+		return nil, ErrStudentMerged
+	case source.IsMerged() && source.data.MergedIntoStudentId != sd.GetID():
+		return nil, fmt.Errorf("%w: source was already merged into student %s", ErrInvalidMerge, source.data.MergedIntoStudentId)
+	}
+
+	evt := &eda.Student_MergeFrom_Event{SourceStudentId: source.GetID()}
+
+	fedOn := make(map[string]bool)
+	for _, f := range sd.data.FeedingReport {
+		fedOn[feedingDay(f)] = true
+	}
+	for _, f := range source.data.FeedingReport {
+		if !fedOn[feedingDay(f)] {
+			fedOn[feedingDay(f)] = true
+			evt.Feedings = append(evt.Feedings, f)
+		}
+	}
+
+	for _, g := range source.data.GradeHistory {
+		if !slices.ContainsFunc(sd.data.GradeHistory, func(existing *eda.Student_GradeReport) bool {
+			return sameBulkUpload(existing.AssociatedBulkUploadId, g.AssociatedBulkUploadId) ||
+				(proto.Equal(existing.TestDate, g.TestDate) && existing.SchoolYear == g.SchoolYear && existing.GradingPeriod == g.GradingPeriod)
+		}) {
+			evt.GradeReports = append(evt.GradeReports, g)
+		}
+	}
+
+	for _, h := range source.data.HealthAssessments {
+		if !slices.ContainsFunc(sd.data.HealthAssessments, func(existing *eda.Student_HealthAssessment) bool {
+			return sameBulkUpload(existing.AssociatedBulkUploadId, h.AssociatedBulkUploadId) ||
+				existing.AssessmentDate.AsTime().Equal(h.AssessmentDate.AsTime())
+		}) {
+			evt.HealthAssessments = append(evt.HealthAssessments, h)
+		}
+	}
+
+	for _, sp := range source.data.SponsorshipHistory {
+		if !slices.ContainsFunc(sd.data.SponsorshipHistory, func(existing *eda.Student_SponsorshipRecord) bool {
+			return proto.Equal(existing, sp)
+		}) {
+			evt.Sponsorships = append(evt.Sponsorships, sp)
+		}
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_MERGE_FROM_STUDENT,
+		data:      evt,
+		version:   version,
+	})
+}
+
+// MergeInto marks this student as a duplicate of the target, the records are expected to have been
+// folded into the target with MergeFrom first
+func (sd *Aggregate) MergeInto(cmd *eda.Student_MergeInto) (*gosignal.Event, error) {
+	switch {
+	case cmd.GetTargetStudentId() == "" || cmd.GetTargetStudentId() == sd.GetID():
+		return nil, fmt.Errorf("%w: invalid target student", ErrInvalidMerge)
+	case sd.IsMerged():
+		return nil, ErrStudentMerged
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_MERGE_INTO_STUDENT,
+		data:      &eda.Student_MergeInto_Event{TargetStudentId: cmd.GetTargetStudentId()},
+		version:   cmd.GetVersion(),
+	})
+}
+
+func (sd *Aggregate) handleMergeFrom(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_MergeFrom_Event)
+
+	sd.data.FeedingReport = append(sd.data.FeedingReport, data.Feedings...)
+	sd.data.FeedingNextId += uint64(len(data.Feedings))
+	// feeding validation compares against the last feeding, so the report has to stay in order
+	slices.SortStableFunc(sd.data.FeedingReport, func(a, b *eda.Student_Feeding_Event) int {
+		return cmp.Compare(a.UnixTimestamp, b.UnixTimestamp)
+	})
+
+	// merged records are renumbered after this student's own, their ids on the duplicate may clash. They're
+	// cloned first so renumbering leaves the event's payload, and whatever else holds those records, as it was
+	for _, g := range data.GradeReports {
+		g = proto.Clone(g).(*eda.Student_GradeReport)
+		sd.data.GradeReportNextId++
+		g.Id = sd.data.GradeReportNextId
+		sd.data.GradeHistory = append(sd.data.GradeHistory, g)
+	}
+	for _, h := range data.HealthAssessments {
+		h = proto.Clone(h).(*eda.Student_HealthAssessment)
+		sd.data.HealthAssessmentNextId++
+		h.Id = sd.data.HealthAssessmentNextId
+		sd.data.HealthAssessments = append(sd.data.HealthAssessments, h)
+	}
+	sd.data.SponsorshipHistory = append(sd.data.SponsorshipHistory, data.Sponsorships...)
+
+	return nil
+}
+
+func (sd *Aggregate) handleMergeInto(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_MergeInto_Event)
+
+	sd.data.MergedIntoStudentId = data.TargetStudentId
+	sd.data.Status = eda.Student_INACTIVE
+
+	return nil
+}
+
+// Exit records that the student left the program. The student stops being active and eligible for
+// sponsorship, and their enrollment closes on the exit date.
+func (sd *Aggregate) Exit(cmd *eda.Student_Exit) (*gosignal.Event, error) {
+	eventType, ok := exitEventTypes[cmd.GetStatus()]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: %s is not an exit status", ErrInvalidExit, cmd.GetStatus())
+	case sd.HasExited():
+		return nil, ErrStudentExited
+	case cmd.GetExitDate() == nil:
+		return nil, fmt.Errorf("%w: exit date is required", ErrInvalidExit)
+	case cmd.GetStatus() == eda.Student_DROPPED_OUT && strings.TrimSpace(cmd.GetReason()) == "":
+		return nil, fmt.Errorf("%w: a reason is required when a student drops out", ErrInvalidExit)
+	}
+
+	exitDate := dateToTime(cmd.GetExitDate())
+	if exitDate.After(time.Now()) {
+		return nil, fmt.Errorf("%w: exit date is in the future", ErrInvalidExit)
+	}
+	if current := sd.currentEnrollment(); current != nil && current.StartDate != nil {
+		if exitDate.Before(dateToTime(current.StartDate)) {
+			return nil, fmt.Errorf("%w: exit date is before the current enrollment started", ErrInvalidExit)
+		}
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: eventType,
+		data: &eda.Student_Exit_Event{
+			Status:   cmd.GetStatus(),
+			ExitDate: cmd.GetExitDate(),
+			Reason:   strings.TrimSpace(cmd.GetReason()),
+			SchoolId: sd.data.SchoolId,
+		},
+		version: cmd.GetVersion(),
+	})
+}
+
+func (sd *Aggregate) handleExit(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_Exit_Event)
+
+	// the school is kept so the student still shows up in that school's history
+	sd.closeEnrollment(data.ExitDate)
+	sd.data.Status = data.Status
+	sd.data.EligibleForSponsorship = false
+	sd.data.Exit = data
+
+	return nil
+}
+
+// HasExited reports whether the student left the program, e.g. graduated or dropped out
+func (sd Aggregate) HasExited() bool {
+	return sd.data.Exit != nil
+}
+
+// PromoteGrade moves an active student up one grade level for the school year ending on the
+// command's date, a student is promoted at most once per school year
+func (sd *Aggregate) PromoteGrade(cmd *eda.Student_PromoteGrade) (*gosignal.Event, error) {
+	if err := sd.CanPromote(cmd.GetSchoolYearEnd()); err != nil {
+		return nil, err
+	}
+
+	return sd.ApplyEvent(StudentEvent{
+		eventType: EVENT_PROMOTE_STUDENT,
+		data: &eda.Student_PromoteGrade_Event{
+			FromGrade:     sd.data.GradeLevel,
+			ToGrade:       sd.data.GradeLevel + 1,
+			SchoolYearEnd: cmd.GetSchoolYearEnd(),
+		},
+		version: cmd.GetVersion(),
+	})
+}
+
+// CanPromote returns why the student can't be promoted for the school year ending on the given date,
+// or nil when they can
+func (sd Aggregate) CanPromote(schoolYearEnd *eda.Date) error {
+	switch {
+	case schoolYearEnd == nil:
+		return fmt.Errorf("school year end is required")
+	case sd.HasExited():
+		return ErrStudentExited
+	case !sd.IsActive():
+		return fmt.Errorf("student is not active")
+	case sd.data.LastPromotionSchoolYearEnd != nil &&
+		!dateToTime(sd.data.LastPromotionSchoolYearEnd).Before(dateToTime(schoolYearEnd)):
+		return ErrAlreadyPromoted
+	}
+
+	// students enrolled after the school year ended are starting the next year in their current grade
+	if doe := sd.data.DateOfEnrollment; doe != nil && dateToTime(doe).After(dateToTime(schoolYearEnd)) {
+		return fmt.Errorf("student enrolled after the school year ended")
+	}
+
+	return nil
+}
+
+func (sd *Aggregate) handlePromoteGrade(evt wrappedEvent) error {
+	data := evt.data.(*eda.Student_PromoteGrade_Event)
+
+	sd.data.GradeLevel = data.ToGrade
+	sd.data.LastPromotionSchoolYearEnd = data.SchoolYearEnd
+
+	return nil
+}
+
+func feedingDay(f *eda.Student_Feeding_Event) string {
+	return time.Unix(int64(f.UnixTimestamp), 0).Format("2006-01-02")
+}
+
+func sameBulkUpload(a, b string) bool {
+	return a != "" && a == b
+}
+
 // StudentEvent is a struct that holds the event type and the data
 type StudentEvent struct {
 	eventType string
@@ -665,6 +1769,40 @@ func (sd Aggregate) MaxSponsorshipDate() *time.Time {
 	return max
 }
 
+// GetEnrollmentHistory returns the intervals during which the student was enrolled in a school, oldest first
+func (sd Aggregate) GetEnrollmentHistory() []*eda.Student_EnrollmentRecord {
+	return sd.data.EnrollmentHistory
+}
+
+// GetTransferHistory returns the transfers between schools, oldest first
+func (sd Aggregate) GetTransferHistory() []*eda.Student_Transfer_Event {
+	return sd.data.TransferHistory
+}
+
+// SchoolAt returns the ID of the school the student was enrolled in at the given time. When the
+// enrollment history doesn't cover the time, e.g. for records that pre-date enrollment, it falls back
+// to the current school.
+func (sd Aggregate) SchoolAt(t time.Time) string {
+	// enrollment intervals are whole days, compare on the calendar day of t in its own location
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	for i := len(sd.data.EnrollmentHistory) - 1; i >= 0; i-- {
+		record := sd.data.EnrollmentHistory[i]
+
+		if record.StartDate != nil && day.Before(dateToTime(record.StartDate)) {
+			continue
+		}
+
+		if record.EndDate != nil && !day.Before(dateToTime(record.EndDate)) {
+			continue
+		}
+
+		return record.SchoolId
+	}
+
+	return sd.data.SchoolId
+}
+
 // GetLastFeeding returns the last feeding event
 func (sd Aggregate) GetLastFeeding() *eda.Student_Feeding_Event {
 	if sd.data.FeedingReport == nil || len(sd.data.FeedingReport) == 0 {
@@ -686,6 +1824,10 @@ func (sd *Aggregate) SetProfilePhoto(cmd *eda.Student_SetProfilePhoto) (*gosigna
 }
 
 func (sd *Aggregate) SetEligibility(cmd *eda.Student_SetEligibility) (*gosignal.Event, error) {
+	if cmd.Eligible && sd.HasExited() {
+		return nil, ErrStudentExited
+	}
+
 	return sd.ApplyEvent(StudentEvent{
 		eventType: EVENT_SET_ELIGIBILITY,
 		data: &eda.Student_SetEligibility_Event{
@@ -699,9 +1841,11 @@ func (sd *Aggregate) UpdateSponsorship(cmd *eda.Student_UpdateSponsorship) (*gos
 	return sd.ApplyEvent(StudentEvent{
 		eventType: EVENT_UPDATE_SPONSORSHIP,
 		data: &eda.Student_UpdateSponsorship_Event{
-			SponsorId: cmd.SponsorId,
-			StartDate: cmd.StartDate,
-			EndDate:   cmd.EndDate,
+			SponsorId:     cmd.SponsorId,
+			StartDate:     cmd.StartDate,
+			EndDate:       cmd.EndDate,
+			PaymentId:     cmd.PaymentId,
+			PaymentAmount: cmd.PaymentAmount,
 		},
 		version: cmd.GetVersion(),
 	})
@@ -0,0 +1,171 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"geevly/gen/go/eda"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RosterEntry is a student on a school's daily attendance roster. Status is UNKNOWN_ATTENDANCE until
+// the student is marked for the day.
+type RosterEntry struct {
+	Student *ProjectedStudent
+	Status  eda.Student_Attendance_Status
+	Reason  string
+	Fed     bool
+}
+
+// AttendanceMark is the attendance of one student submitted from the roster
+type AttendanceMark struct {
+	StudentID uint64
+	Status    eda.Student_Attendance_Status
+	Reason    string
+}
+
+// GetAttendanceRoster lists the active students of a school with their attendance and whether they were
+// fed on the day
+func (s *StudentService) GetAttendanceRoster(ctx context.Context, schoolID string, day time.Time) ([]*RosterEntry, error) {
+	students, err := s.repo.ListStudentsForSchool(ctx, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list students: %w", err)
+	}
+
+	attendance, err := s.repo.GetAttendance(ctx, schoolID, day, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
+	}
+
+	fed, _, err := s.repo.CountFeedingDays(ctx, schoolID, day, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feedings: %w", err)
+	}
+
+	byStudent := make(map[string]*ProjectedAttendance)
+	for _, a := range attendance {
+		byStudent[a.StudentID] = a
+	}
+
+	roster := make([]*RosterEntry, 0, len(students))
+	for _, st := range students {
+		id := strconv.FormatUint(uint64(st.ID), 10)
+		entry := &RosterEntry{Student: st, Fed: fed[id] > 0}
+		if a, ok := byStudent[id]; ok {
+			entry.Status = eda.Student_Attendance_Status(eda.Student_Attendance_Status_value[a.Status])
+			entry.Reason = a.Reason
+		}
+		roster = append(roster, entry)
+	}
+
+	sort.Slice(roster, func(i, j int) bool {
+		a, b := roster[i].Student, roster[j].Student
+		if a.LastName != b.LastName {
+			return a.LastName < b.LastName
+		}
+		return a.FirstName < b.FirstName
+	})
+
+	return roster, nil
+}
+
+func (s *StudentService) AddAttendance(ctx context.Context, id uint64, attendance *eda.Student_Attendance) error {
+	studentAgg, err := s.repo.loadStudent(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load student: %w", err)
+	}
+
+	event, err := studentAgg.AddAttendance(attendance)
+	if err != nil {
+		return fmt.Errorf("failed to add attendance: %w", err)
+	}
+
+	if err := s.saveEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to save student: %w", err)
+	}
+
+	return nil
+}
+
+func (s *StudentService) RemoveAttendance(ctx context.Context, id uint64, bulkUploadID string) error {
+	studentAgg, err := s.repo.loadStudent(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load student: %w", err)
+	}
+
+	event, err := studentAgg.RemoveAttendance(bulkUploadID)
+	if err != nil {
+		return fmt.Errorf("failed to remove attendance: %w", err)
+	}
+
+	if err := s.saveEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to save student: %w", err)
+	}
+
+	return nil
+}
+
+// AttendanceDay counts a school's attendance on one day
+type AttendanceDay struct {
+	Date          time.Time
+	Present       int
+	Absent        int
+	Excused       int
+	PresentNotFed int
+}
+
+// AttendanceReport is a school's attendance over a date range with the days students were at school but
+// weren't fed
+type AttendanceReport struct {
+	SchoolID string
+	From     time.Time
+	To       time.Time
+	Days     []*AttendanceDay // newest first
+	NotFed   []*AttendedNotFed
+}
+
+// GetAttendanceReport builds the attendance report of a school, all schools when schoolID is empty
+func (s *StudentService) GetAttendanceReport(ctx context.Context, schoolID string, from, to time.Time) (*AttendanceReport, error) {
+	attendance, err := s.repo.GetAttendance(ctx, schoolID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attendance: %w", err)
+	}
+
+	notFed, err := s.repo.GetAttendedNotFed(ctx, schoolID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attended but not fed: %w", err)
+	}
+
+	report := &AttendanceReport{SchoolID: schoolID, From: from, To: to, NotFed: notFed}
+	days := make(map[time.Time]*AttendanceDay)
+	day := func(t time.Time) *AttendanceDay {
+		key := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		d, ok := days[key]
+		if !ok {
+			d = &AttendanceDay{Date: key}
+			days[key] = d
+			report.Days = append(report.Days, d)
+		}
+		return d
+	}
+
+	for _, a := range attendance {
+		d := day(a.AttendanceDate)
+		switch a.Status {
+		case eda.Student_Attendance_PRESENT.String():
+			d.Present++
+		case eda.Student_Attendance_ABSENT.String():
+			d.Absent++
+		case eda.Student_Attendance_EXCUSED.String():
+			d.Excused++
+		}
+	}
+	for _, nf := range notFed {
+		day(nf.Date).PresentNotFed++
+	}
+
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date.After(report.Days[j].Date) })
+
+	return report, nil
+}
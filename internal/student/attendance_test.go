@@ -0,0 +1,60 @@
+package student
+
+import (
+	"errors"
+	"geevly/gen/go/eda"
+	"testing"
+)
+
+func TestMarkAttendanceOnlyAtEnrolledSchool(t *testing.T) {
+	agg := &Aggregate{}
+	agg.SetIDUint64(1)
+	if _, err := agg.CreateStudent(&eda.Student_Create{FirstName: "Test", LastName: "Student"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agg.EnrollStudent(&eda.Student_Enroll{
+		SchoolId:         "A",
+		DateOfEnrollment: &eda.Date{Year: 2024, Month: 1, Day: 8},
+		Version:          agg.GetVersion(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agg.TransferStudent(&eda.Student_Transfer{
+		ToSchoolId:   "B",
+		TransferDate: &eda.Date{Year: 2024, Month: 3, Day: 1},
+		Version:      agg.GetVersion(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	mark := func(schoolID string, day *eda.Date) error {
+		_, err := agg.MarkAttendance(&eda.Student_MarkAttendance{
+			Attendance: &eda.Student_Attendance{Date: day, Status: eda.Student_Attendance_PRESENT, SchoolId: schoolID},
+			Version:    agg.GetVersion(),
+		})
+		return err
+	}
+
+	for _, tc := range []struct {
+		name     string
+		schoolID string
+		day      *eda.Date
+		valid    bool
+	}{
+		{"before the transfer", "A", &eda.Date{Year: 2024, Month: 2, Day: 15}, true},
+		{"after the transfer", "B", &eda.Date{Year: 2024, Month: 3, Day: 4}, true},
+		{"new school before the transfer", "B", &eda.Date{Year: 2024, Month: 2, Day: 15}, false},
+		{"old school after the transfer", "A", &eda.Date{Year: 2024, Month: 3, Day: 4}, false},
+		{"another school", "C", &eda.Date{Year: 2024, Month: 3, Day: 4}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := mark(tc.schoolID, tc.day)
+			switch {
+			case tc.valid && err != nil:
+				t.Errorf("rejected: %v", err)
+			case !tc.valid && !errors.Is(err, ErrInvalidAttendance):
+				t.Errorf("got %v, want ErrInvalidAttendance", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+	"strconv"
+
+	"github.com/Howard3/gosignal"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// studentCommand is how one command type is run on a student
+type studentCommand struct {
+	name protoreflect.FullName
+	run  func(ctx context.Context, s *StudentService, agg *Aggregate, cmd proto.Message) (*gosignal.Event, error)
+	// appendOnly commands only add to the student, so running one against a newer version than it was
+	// based on can't undo what changed in between. The aggregate still validates it against the current
+	// state, such as rejecting a second feeding on the same day.
+	appendOnly bool
+	// roles may dispatch the command through the bus, anyone may when empty
+	roles []string
+	// register adds the command to the bus, sending it to runCommand
+	register func(bus *infrastructure.CommandBus, runCommand func(context.Context, uint64, proto.Message) (*Aggregate, error))
+}
+
+type commandOption func(*studentCommand)
+
+func appendOnly(c *studentCommand) {
+	c.appendOnly = true
+}
+
+func allow(roles ...string) commandOption {
+	return func(c *studentCommand) {
+		c.roles = append(c.roles, roles...)
+	}
+}
+
+// serviceCommand is a command that needs the service, usually to check references with the ACL
+func serviceCommand[T proto.Message](run func(ctx context.Context, s *StudentService, agg *Aggregate, cmd T) (*gosignal.Event, error), opts ...commandOption) *studentCommand {
+	var zero T
+	c := &studentCommand{
+		name: zero.ProtoReflect().Descriptor().FullName(),
+		run: func(ctx context.Context, s *StudentService, agg *Aggregate, cmd proto.Message) (*gosignal.Event, error) {
+			return run(ctx, s, agg, cmd.(T))
+		},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.register = func(bus *infrastructure.CommandBus, runCommand func(context.Context, uint64, proto.Message) (*Aggregate, error)) {
+		infrastructure.HandleCommand(bus, "student", func(ctx context.Context, aggregateID string, cmd T, _ []byte) (any, error) {
+			id, err := strconv.ParseUint(aggregateID, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid student ID %q: %w", aggregateID, err)
+			}
+			return runCommand(ctx, id, cmd)
+		}, infrastructure.AllowRoles(c.roles...))
+	}
+
+	return c
+}
+
+// aggregateCommand is a command the aggregate runs on its own
+func aggregateCommand[T proto.Message](run func(*Aggregate, T) (*gosignal.Event, error), opts ...commandOption) *studentCommand {
+	return serviceCommand(func(_ context.Context, _ *StudentService, agg *Aggregate, cmd T) (*gosignal.Event, error) {
+		return run(agg, cmd)
+	}, opts...)
+}
+
+func commandTable(commands ...*studentCommand) map[protoreflect.FullName]*studentCommand {
+	table := make(map[protoreflect.FullName]*studentCommand, len(commands))
+	for _, c := range commands {
+		table[c.name] = c
+	}
+	return table
+}
+
+// studentCommands are the commands RunCommand and the command bus accept, a new command only needs an
+// entry here
+var studentCommands = commandTable(
+	// feeding isn't behind sign-in
+	aggregateCommand((*Aggregate).Feed, appendOnly),
+	aggregateCommand((*Aggregate).ReviewFeeding, appendOnly, allow(infrastructure.RoleAdmin)),
+	// feeders take the roster of the schools they feed at
+	aggregateCommand((*Aggregate).MarkAttendance, appendOnly, allow(infrastructure.RoleAdmin, infrastructure.RoleFeeder)),
+	aggregateCommand((*Aggregate).AddGuardian, appendOnly, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).RecordHealthAssessment, appendOnly, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).RecordGradeReport, appendOnly, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).UpdateGuardian, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).RemoveGuardian, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).UpdateHealthAssessment, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).DeleteHealthAssessment, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).UpdateGradeReport, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).DeleteGradeReport, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).UpdateStudent, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).SetStatus, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).SetEligibility, allow(infrastructure.RoleAdmin)),
+	// TODO: check for collisions on the lookup code
+	aggregateCommand((*Aggregate).SetLookupCode, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).UnenrollStudent, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).Exit, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).PromoteGrade, allow(infrastructure.RoleAdmin)),
+	aggregateCommand((*Aggregate).UpdateSponsorship, allow(infrastructure.RoleAdmin, infrastructure.RoleAPI)),
+	serviceCommand(func(ctx context.Context, s *StudentService, agg *Aggregate, cmd *eda.Student_Enroll) (*gosignal.Event, error) {
+		if err := s.acl.ValidateSchoolID(ctx, cmd.GetSchoolId()); err != nil {
+			return nil, fmt.Errorf("failed to validate school ID: %w", err)
+		}
+		return agg.EnrollStudent(cmd)
+	}, allow(infrastructure.RoleAdmin)),
+	serviceCommand(func(ctx context.Context, s *StudentService, agg *Aggregate, cmd *eda.Student_Transfer) (*gosignal.Event, error) {
+		if err := s.acl.ValidateSchoolID(ctx, cmd.GetToSchoolId()); err != nil {
+			return nil, fmt.Errorf("failed to validate school ID: %w", err)
+		}
+		return agg.TransferStudent(cmd)
+	}, allow(infrastructure.RoleAdmin)),
+	serviceCommand(func(ctx context.Context, s *StudentService, agg *Aggregate, cmd *eda.Student_SetProfilePhoto) (*gosignal.Event, error) {
+		if err := s.acl.ValidatePhotoID(ctx, cmd.GetFileId()); err != nil {
+			return nil, fmt.Errorf("failed to validate photo ID: %w", err)
+		}
+		return agg.SetProfilePhoto(cmd)
+	}, allow(infrastructure.RoleAdmin)),
+)
+
+// RegisterCommands registers the student commands on the bus, addressed by the student's ID. Creating a
+// student is dispatched without one, the new student is returned.
+func (s *StudentService) RegisterCommands(bus *infrastructure.CommandBus) {
+	for _, c := range studentCommands {
+		c.register(bus, s.RunCommand)
+	}
+
+	infrastructure.HandleCommand(bus, "student", func(ctx context.Context, _ string, cmd *eda.Student_Create, _ []byte) (any, error) {
+		return s.CreateStudent(ctx, cmd)
+	}, infrastructure.AllowRoles(infrastructure.RoleAdmin))
+}
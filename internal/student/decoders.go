@@ -0,0 +1,41 @@
+package student
+
+import (
+	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+)
+
+// decoders map each student event type to its payload for history pages and exports, register new event
+// types here as well as in the aggregate's routeEvent
+var decoders = infrastructure.NewEventDecoders(upcasters).
+	Register(EVENT_ADD_STUDENT, &eda.Student_Create_Event{}).
+	Register(EVENT_SET_STUDENT_STATUS, &eda.Student_SetStatus_Event{}).
+	Register(EVENT_UPDATE_STUDENT, &eda.Student_Update_Event{}).
+	Register(EVENT_ENROLL_STUDENT, &eda.Student_Enroll_Event{}).
+	Register(EVENT_UNENROLL_STUDENT, &eda.Student_Unenroll_Event{}).
+	Register(EVENT_SET_LOOKUP_CODE, &eda.Student_SetLookupCode_Event{}).
+	Register(EVENT_SET_PROFILE_PHOTO, &eda.Student_SetProfilePhoto_Event{}).
+	Register(EVENT_FEED_STUDENT, &eda.Student_Feeding_Event{}).
+	Register(EVENT_SET_ELIGIBILITY, &eda.Student_SetEligibility_Event{}).
+	Register(EVENT_UPDATE_SPONSORSHIP, &eda.Student_UpdateSponsorship_Event{}).
+	Register(EVENT_ADD_GRADE_REPORT, &eda.Student_GradeReport_Event{}).
+	Register(EVENT_UPDATE_GRADE_REPORT, &eda.Student_GradeReport_Event{}).
+	Register(EVENT_REMOVE_GRADE_REPORT, &eda.Student_GradeReport_UndoEvent{}).
+	Register(EVENT_ADD_HEALTH_ASSESSMENT, &eda.Student_HealthAssessment_Event{}).
+	Register(EVENT_UPDATE_HEALTH_ASSESSMENT, &eda.Student_HealthAssessment_Event{}).
+	Register(EVENT_REMOVE_HEALTH_ASSESSMENT, &eda.Student_HealthAssessment_UndoEvent{}).
+	Register(EVENT_UNDO_CREATE_STUDENT, &eda.Student_Create_UndoEvent{}).
+	Register(EVENT_TRANSFER_STUDENT, &eda.Student_Transfer_Event{}).
+	Register(EVENT_ADD_GUARDIAN, &eda.Student_Guardian_Event{}).
+	Register(EVENT_UPDATE_GUARDIAN, &eda.Student_Guardian_Event{}).
+	Register(EVENT_REMOVE_GUARDIAN, &eda.Student_RemoveGuardian_Event{}).
+	Register(EVENT_MERGE_FROM_STUDENT, &eda.Student_MergeFrom_Event{}).
+	Register(EVENT_MERGE_INTO_STUDENT, &eda.Student_MergeInto_Event{}).
+	Register(EVENT_GRADUATE_STUDENT, &eda.Student_Exit_Event{}).
+	Register(EVENT_DROP_OUT_STUDENT, &eda.Student_Exit_Event{}).
+	Register(EVENT_TRANSFER_OUT_STUDENT, &eda.Student_Exit_Event{}).
+	Register(EVENT_STUDENT_DECEASED, &eda.Student_Exit_Event{}).
+	Register(EVENT_PROMOTE_STUDENT, &eda.Student_PromoteGrade_Event{}).
+	Register(EVENT_MARK_ATTENDANCE, &eda.Student_Attendance_Event{}).
+	Register(EVENT_REMOVE_ATTENDANCE, &eda.Student_Attendance_UndoEvent{}).
+	Register(EVENT_REVIEW_FEEDING, &eda.Student_ReviewFeeding_Event{})
@@ -0,0 +1,207 @@
+package student
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NameSimilarityThreshold is the Jaro-Winkler similarity at which two names sharing a birth date are
+// treated as the same child
+const NameSimilarityThreshold = 0.9
+
+// DuplicatePair identifies two students suspected to be the same child, A sorts before B so each pair has one key
+type DuplicatePair struct {
+	A string
+	B string
+}
+
+func newDuplicatePair(a, b string) DuplicatePair {
+	if b < a {
+		a, b = b, a
+	}
+	return DuplicatePair{A: a, B: b}
+}
+
+// DuplicateCandidate is a pair of students suspected to be duplicates and why
+type DuplicateCandidate struct {
+	Pair           DuplicatePair
+	StudentA       *ProjectedStudent
+	StudentB       *ProjectedStudent
+	SameLRN        bool
+	SameBirthDate  bool
+	NameSimilarity float64
+}
+
+// Reasons describes why the pair was flagged
+func (dc *DuplicateCandidate) Reasons() []string {
+	reasons := []string{}
+	if dc.SameLRN {
+		reasons = append(reasons, "Same LRN")
+	}
+	if dc.SameBirthDate {
+		reasons = append(reasons, "Same date of birth")
+	}
+	if dc.NameSimilarity >= NameSimilarityThreshold {
+		reasons = append(reasons, fmt.Sprintf("Similar name (%.0f%%)", dc.NameSimilarity*100))
+	}
+	return reasons
+}
+
+// findDuplicates compares students that share an LRN or a birth date, comparing every pair would be
+// quadratic in the number of students
+func findDuplicates(students []*ProjectedStudent, dismissed map[DuplicatePair]bool) []*DuplicateCandidate {
+	byLRN := make(map[string][]*ProjectedStudent)
+	byDOB := make(map[string][]*ProjectedStudent)
+	for _, st := range students {
+		if lrn := strings.TrimSpace(st.StudentID); lrn != "" {
+			byLRN[lrn] = append(byLRN[lrn], st)
+		}
+		if !st.DateOfBirth.IsZero() {
+			dob := st.DateOfBirth.Format("2006-01-02")
+			byDOB[dob] = append(byDOB[dob], st)
+		}
+	}
+
+	candidates := make(map[DuplicatePair]*DuplicateCandidate)
+	compare := func(group []*ProjectedStudent) {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				a, b := group[i], group[j]
+				pair := newDuplicatePair(fmt.Sprintf("%d", a.ID), fmt.Sprintf("%d", b.ID))
+				if _, seen := candidates[pair]; seen || dismissed[pair] {
+					continue
+				}
+
+				dc := &DuplicateCandidate{
+					Pair:           pair,
+					StudentA:       a,
+					StudentB:       b,
+					SameLRN:        a.StudentID != "" && strings.EqualFold(strings.TrimSpace(a.StudentID), strings.TrimSpace(b.StudentID)),
+					SameBirthDate:  !a.DateOfBirth.IsZero() && a.DateOfBirth.Equal(b.DateOfBirth),
+					NameSimilarity: nameSimilarity(a, b),
+				}
+				if fmt.Sprintf("%d", a.ID) != pair.A {
+					dc.StudentA, dc.StudentB = b, a
+				}
+
+				if dc.SameLRN || (dc.SameBirthDate && dc.NameSimilarity >= NameSimilarityThreshold) {
+					candidates[pair] = dc
+				}
+			}
+		}
+	}
+
+	for _, group := range byLRN {
+		compare(group)
+	}
+	for _, group := range byDOB {
+		compare(group)
+	}
+
+	res := make([]*DuplicateCandidate, 0, len(candidates))
+	for _, dc := range candidates {
+		res = append(res, dc)
+	}
+
+	// strongest matches first
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].SameLRN != res[j].SameLRN {
+			return res[i].SameLRN
+		}
+		if res[i].NameSimilarity != res[j].NameSimilarity {
+			return res[i].NameSimilarity > res[j].NameSimilarity
+		}
+		return res[i].Pair.A < res[j].Pair.A
+	})
+
+	return res
+}
+
+// nameSimilarity compares full names in both orders, schools don't agree on which name goes first
+func nameSimilarity(a, b *ProjectedStudent) float64 {
+	nameA := normalizeName(a.FirstName + " " + a.LastName)
+	return max(
+		jaroWinkler(nameA, normalizeName(b.FirstName+" "+b.LastName)),
+		jaroWinkler(nameA, normalizeName(b.LastName+" "+b.FirstName)),
+	)
+}
+
+// normalizeName lowercases a name and drops punctuation and repeated whitespace
+func normalizeName(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-':
+			sb.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings, 1 being identical
+func jaroWinkler(s1, s2 string) float64 {
+	a, b := []rune(s1), []rune(s2)
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	matchDistance := max(len(a), len(b))/2 - 1
+	if matchDistance < 0 {
+		matchDistance = 0
+	}
+
+	aMatches := make([]bool, len(a))
+	bMatches := make([]bool, len(b))
+	matches := 0
+	for i := range a {
+		start := max(0, i-matchDistance)
+		end := min(len(b), i+matchDistance+1)
+		for j := start; j < end; j++ {
+			if bMatches[j] || a[i] != b[j] {
+				continue
+			}
+			aMatches[i], bMatches[j] = true, true
+			matches++
+			break
+		}
+	}
+
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	k := 0
+	for i := range a {
+		if !aMatches[i] {
+			continue
+		}
+		for !bMatches[k] {
+			k++
+		}
+		if a[i] != b[k] {
+			transpositions++
+		}
+		k++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(a)) + m/float64(len(b)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for i := 0; i < min(4, len(a), len(b)); i++ {
+		if a[i] != b[i] {
+			break
+		}
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
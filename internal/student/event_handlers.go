@@ -2,153 +2,284 @@ package student
 
 import (
 	"context"
-	"log/slog"
+	"fmt"
 	"strconv"
 
 	"github.com/Howard3/gosignal"
 )
 
 type eventHandlers struct {
-	repo Repository
+	repo            Repository
+	nutritionAlerts *nutritionAlertManager
 }
 
 func NewEventHandlers(repo Repository) *eventHandlers {
 	return &eventHandlers{
-		repo: repo,
+		repo:            repo,
+		nutritionAlerts: &nutritionAlertManager{repo: repo, config: DefaultNutritionAlertConfig()},
 	}
 }
 
 // HandleNewStudentEvent is a method that handles the NewStudentEvent
 // it loads the student aggregate from the repository and projects it to the database
-func (eh *eventHandlers) HandleNewStudentEvent(ctx context.Context, aggregateID uint64) {
+func (eh *eventHandlers) HandleNewStudentEvent(ctx context.Context, aggregateID uint64) error {
 	student, err := eh.repo.loadStudent(ctx, aggregateID)
 	if err != nil {
-		slog.Error("failed to load student", "error", err)
-		return
+		return fmt.Errorf("failed to load student: %w", err)
 	}
 
 	if err := eh.repo.upsertStudent(student); err != nil {
-		slog.Error("failed to upsert student", "error", err)
-		return
+		return fmt.Errorf("failed to upsert student: %w", err)
 	}
+
+	return nil
 }
 
 // HandleUpdateStudentEvent is a method that handles the UpdateStudentEvent
 // functionally the same as HandleNewStudentEvent, thus it just aliases it
-func (eh *eventHandlers) HandleUpdateStudentEvent(ctx context.Context, aggID uint64) {
-	eh.HandleNewStudentEvent(ctx, aggID)
+func (eh *eventHandlers) HandleUpdateStudentEvent(ctx context.Context, aggID uint64) error {
+	return eh.HandleNewStudentEvent(ctx, aggID)
 }
 
 // HandleGenerateCodeEvent is a method that handles the GenerateCodeEvent
-func (eh *eventHandlers) HandleGenerateCodeEvent(ctx context.Context, aggID uint64) {
+func (eh *eventHandlers) HandleGenerateCodeEvent(ctx context.Context, aggID uint64) error {
 	student, err := eh.repo.loadStudent(ctx, aggID)
 	if err != nil {
-		slog.Error("failed to load student", "error", err)
-		return
+		return fmt.Errorf("failed to load student: %w", err)
 	}
 
 	code := student.data.CodeUniqueId
 	if len(code) == 0 {
-		slog.Error("code is empty")
-		return
+		return fmt.Errorf("code is empty")
 	}
 
 	if err := eh.repo.insertStudentCode(ctx, aggID, code); err != nil {
-		slog.Error("failed to insert student code", "error", err)
-		return
+		return fmt.Errorf("failed to insert student code: %w", err)
 	}
+
+	return nil
 }
 
 // handleSetProfilePhotoEvent is a method that handles the SetProfilePhotoEvent
-func (eh *eventHandlers) handleSetProfilePhotoEvent(ctx context.Context, aggID uint64) {
+func (eh *eventHandlers) handleSetProfilePhotoEvent(ctx context.Context, aggID uint64) error {
 	student, err := eh.repo.loadStudent(ctx, aggID)
 	if err != nil {
-		slog.Error("failed to load student", "error", err)
-		return
+		return fmt.Errorf("failed to load student: %w", err)
 	}
 
 	if err := eh.repo.upsertStudentProfilePhoto(student); err != nil {
-		slog.Error("failed to upsert student profile photo", "error", err)
-		return
+		return fmt.Errorf("failed to upsert student profile photo: %w", err)
 	}
+
+	return nil
 }
 
 // handleFeedStudentEvent is a method that handles the FeedStudentEvent
-func (eh *eventHandlers) handleFeedStudentEvent(ctx context.Context, aggID uint64) {
+func (eh *eventHandlers) handleFeedStudentEvent(ctx context.Context, aggID uint64) error {
 	student, err := eh.repo.loadStudent(ctx, aggID)
 	if err != nil {
-		slog.Error("failed to load student", "error", err)
-		return
+		return fmt.Errorf("failed to load student: %w", err)
 	}
 
 	// TODO: use the event version here because if somehow we end up with a later version of the event
 	// we may miss a prior feeding event.
 	if err := eh.repo.upsertFeedingEventProjection(student); err != nil {
-		slog.Error("failed to upsert student feed", "error", err)
-		return
+		return fmt.Errorf("failed to upsert student feed: %w", err)
+	}
+
+	return nil
+}
+
+// handleReviewFeedingEvent refreshes the student's feeding projections, the inserts of a new feeding
+// leave existing rows alone so the review has to replace them
+func (eh *eventHandlers) handleReviewFeedingEvent(ctx context.Context, aggID uint64) error {
+	student, err := eh.repo.loadStudent(ctx, aggID)
+	if err != nil {
+		return fmt.Errorf("failed to load student: %w", err)
+	}
+
+	if err := eh.repo.updateAllFeedingProjectionsForStudent(student); err != nil {
+		return fmt.Errorf("failed to update feeding projections: %w", err)
 	}
+
+	return nil
 }
 
 // handleUpdateSponsorshipEvent is a method that handles the UpdateSponsorshipEvent
-func (eh *eventHandlers) handleUpdateSponsorshipEvent(ctx context.Context, aggID uint64) {
+func (eh *eventHandlers) handleUpdateSponsorshipEvent(ctx context.Context, aggID uint64) error {
 	student, err := eh.repo.loadStudent(ctx, aggID)
 	if err != nil {
-		slog.Error("failed to load student", "error", err)
-		return
+		return fmt.Errorf("failed to load student: %w", err)
 	}
 
 	if err := eh.repo.upsertSponsorshipProjections(student); err != nil {
-		slog.Error("failed to upsert sponsorship projections", "error", err)
-		return
+		return fmt.Errorf("failed to upsert sponsorship projections: %w", err)
 	}
+
+	return nil
 }
 
-func (eh *eventHandlers) handleHealthAssessmentEvent(ctx context.Context, aggID uint64) {
+func (eh *eventHandlers) handleHealthAssessmentEvent(ctx context.Context, aggID uint64) error {
 	student, err := eh.repo.loadStudent(ctx, aggID)
 	if err != nil {
-		slog.Error("failed to load student", "error", err)
+		return fmt.Errorf("failed to load student: %w", err)
 	}
 
 	if err = eh.repo.updateAllHealthProjectionsForStudent(student); err != nil {
-		slog.Error("failed to update health projections", "error", err)
+		return fmt.Errorf("failed to update health projections: %w", err)
+	}
+
+	return nil
+}
+
+func (eh *eventHandlers) handleAttendanceEvent(ctx context.Context, aggID uint64) error {
+	student, err := eh.repo.loadStudent(ctx, aggID)
+	if err != nil {
+		return fmt.Errorf("failed to load student: %w", err)
+	}
+
+	if err := eh.repo.upsertAttendanceProjections(student); err != nil {
+		return fmt.Errorf("failed to upsert attendance projections: %w", err)
 	}
+
+	return nil
 }
 
-func (eh *eventHandlers) handleGradeReportEvent(ctx context.Context, aggID uint64) {
+func (eh *eventHandlers) handleGradeReportEvent(ctx context.Context, aggID uint64) error {
 	student, err := eh.repo.loadStudent(ctx, aggID)
 	if err != nil {
-		slog.Error("failed to load student", "error", err)
+		return fmt.Errorf("failed to load student: %w", err)
 	}
 
 	if err = eh.repo.updateAllGradeProjectionsForStudent(student); err != nil {
-		slog.Error("failed to update grade projections", "error", err)
+		return fmt.Errorf("failed to update grade projections: %w", err)
 	}
+
+	return nil
 }
 
-// routeEvent is a method that routes an event to the appropriate handler
-func (eh *eventHandlers) routeEvent(ctx context.Context, evt *gosignal.Event) {
+// handleEnrollmentChangedEvent is a method that handles events which change the enrollment history,
+// records are re-projected as the history decides which school they're attributed to
+func (eh *eventHandlers) handleEnrollmentChangedEvent(ctx context.Context, aggID uint64) error {
+	student, err := eh.repo.loadStudent(ctx, aggID)
+	if err != nil {
+		return fmt.Errorf("failed to load student: %w", err)
+	}
+
+	if err := eh.repo.upsertStudent(student); err != nil {
+		return fmt.Errorf("failed to upsert student: %w", err)
+	}
+
+	if err := eh.repo.updateAllFeedingProjectionsForStudent(student); err != nil {
+		return fmt.Errorf("failed to update feeding projections: %w", err)
+	}
+
+	if err := eh.repo.updateAllHealthProjectionsForStudent(student); err != nil {
+		return fmt.Errorf("failed to update health projections: %w", err)
+	}
+
+	if err := eh.repo.updateAllGradeProjectionsForStudent(student); err != nil {
+		return fmt.Errorf("failed to update grade projections: %w", err)
+	}
+
+	if err := eh.repo.upsertTransferProjections(student); err != nil {
+		return fmt.Errorf("failed to upsert transfer projections: %w", err)
+	}
+
+	return nil
+}
+
+// handleMergeEvent is a method that handles both sides of a merge, records moved between the students
+// are re-projected for the student the event belongs to
+func (eh *eventHandlers) handleMergeEvent(ctx context.Context, aggID uint64) error {
+	student, err := eh.repo.loadStudent(ctx, aggID)
+	if err != nil {
+		return fmt.Errorf("failed to load student: %w", err)
+	}
+
+	// the merged student's projection is removed here, which cascades to its feedings, grades and assessments
+	if err := eh.repo.upsertStudent(student); err != nil {
+		return fmt.Errorf("failed to upsert student: %w", err)
+	}
+
+	if err := eh.repo.upsertSponsorshipProjections(student); err != nil {
+		return fmt.Errorf("failed to upsert sponsorship projections: %w", err)
+	}
+
+	if student.IsMerged() {
+		return nil
+	}
+
+	if err := eh.repo.updateAllFeedingProjectionsForStudent(student); err != nil {
+		return fmt.Errorf("failed to update feeding projections: %w", err)
+	}
+
+	if err := eh.repo.updateAllHealthProjectionsForStudent(student); err != nil {
+		return fmt.Errorf("failed to update health projections: %w", err)
+	}
+
+	if err := eh.repo.updateAllGradeProjectionsForStudent(student); err != nil {
+		return fmt.Errorf("failed to update grade projections: %w", err)
+	}
+
+	return nil
+}
+
+// handledEventTypes are the events routeEvent handles
+var handledEventTypes = []string{
+	EVENT_ADD_STUDENT, EVENT_UPDATE_STUDENT, EVENT_PROMOTE_STUDENT, EVENT_SET_STUDENT_STATUS, EVENT_SET_ELIGIBILITY,
+	EVENT_UNDO_CREATE_STUDENT, EVENT_ENROLL_STUDENT, EVENT_UNENROLL_STUDENT, EVENT_TRANSFER_STUDENT,
+	EVENT_GRADUATE_STUDENT, EVENT_DROP_OUT_STUDENT, EVENT_TRANSFER_OUT_STUDENT, EVENT_STUDENT_DECEASED,
+	EVENT_MERGE_FROM_STUDENT, EVENT_MERGE_INTO_STUDENT, EVENT_SET_LOOKUP_CODE, EVENT_SET_PROFILE_PHOTO,
+	EVENT_FEED_STUDENT, EVENT_REVIEW_FEEDING, EVENT_UPDATE_SPONSORSHIP, EVENT_ADD_HEALTH_ASSESSMENT,
+	EVENT_UPDATE_HEALTH_ASSESSMENT, EVENT_REMOVE_HEALTH_ASSESSMENT, EVENT_ADD_GRADE_REPORT,
+	EVENT_UPDATE_GRADE_REPORT, EVENT_REMOVE_GRADE_REPORT, EVENT_MARK_ATTENDANCE, EVENT_REMOVE_ATTENDANCE,
+}
+
+// routeEvent is a method that routes an event to the appropriate handler. The handlers re-project the
+// student from its events so an event delivered twice leaves the same rows.
+func (eh *eventHandlers) routeEvent(ctx context.Context, evt *gosignal.Event) error {
 	id, err := strconv.ParseUint(evt.AggregateID, 10, 64)
 	if err != nil {
-		slog.Error("failed to parse aggregate ID", "error", err)
-		return
+		return fmt.Errorf("failed to parse aggregate ID: %w", err)
 	}
 
 	switch evt.Type {
 	case EVENT_ADD_STUDENT:
-		eh.HandleNewStudentEvent(ctx, id)
-	case EVENT_UPDATE_STUDENT, EVENT_ENROLL_STUDENT, EVENT_UNENROLL_STUDENT, EVENT_SET_STUDENT_STATUS, EVENT_SET_ELIGIBILITY, EVENT_UNDO_CREATE_STUDENT:
-		eh.HandleUpdateStudentEvent(ctx, id)
+		return eh.HandleNewStudentEvent(ctx, id)
+	case EVENT_UPDATE_STUDENT, EVENT_PROMOTE_STUDENT:
+		// health projections carry the student's sex and grade level
+		if err := eh.HandleUpdateStudentEvent(ctx, id); err != nil {
+			return err
+		}
+		return eh.handleHealthAssessmentEvent(ctx, id)
+	case EVENT_SET_STUDENT_STATUS, EVENT_SET_ELIGIBILITY, EVENT_UNDO_CREATE_STUDENT:
+		return eh.HandleUpdateStudentEvent(ctx, id)
+	case EVENT_ENROLL_STUDENT, EVENT_UNENROLL_STUDENT, EVENT_TRANSFER_STUDENT,
+		EVENT_GRADUATE_STUDENT, EVENT_DROP_OUT_STUDENT, EVENT_TRANSFER_OUT_STUDENT, EVENT_STUDENT_DECEASED:
+		return eh.handleEnrollmentChangedEvent(ctx, id)
+	case EVENT_MERGE_FROM_STUDENT, EVENT_MERGE_INTO_STUDENT:
+		return eh.handleMergeEvent(ctx, id)
 	case EVENT_SET_LOOKUP_CODE:
-		eh.HandleGenerateCodeEvent(ctx, id)
+		return eh.HandleGenerateCodeEvent(ctx, id)
 	case EVENT_SET_PROFILE_PHOTO:
-		eh.handleSetProfilePhotoEvent(ctx, id)
+		return eh.handleSetProfilePhotoEvent(ctx, id)
 	case EVENT_FEED_STUDENT:
-		eh.handleFeedStudentEvent(ctx, id)
+		return eh.handleFeedStudentEvent(ctx, id)
+	case EVENT_REVIEW_FEEDING:
+		return eh.handleReviewFeedingEvent(ctx, id)
 	case EVENT_UPDATE_SPONSORSHIP:
-		eh.handleUpdateSponsorshipEvent(ctx, id)
-	case EVENT_ADD_HEALTH_ASSESSMENT, EVENT_REMOVE_HEALTH_ASSESSMENT:
-		eh.handleHealthAssessmentEvent(ctx, id)
-	case EVENT_ADD_GRADE_REPORT, EVENT_REMOVE_GRADE_REPORT:
-		eh.handleGradeReportEvent(ctx, id)
+		return eh.handleUpdateSponsorshipEvent(ctx, id)
+	case EVENT_ADD_HEALTH_ASSESSMENT, EVENT_UPDATE_HEALTH_ASSESSMENT, EVENT_REMOVE_HEALTH_ASSESSMENT:
+		if err := eh.handleHealthAssessmentEvent(ctx, id); err != nil {
+			return err
+		}
+		eh.nutritionAlerts.handleEvent(ctx, evt, id)
+	case EVENT_ADD_GRADE_REPORT, EVENT_UPDATE_GRADE_REPORT, EVENT_REMOVE_GRADE_REPORT:
+		return eh.handleGradeReportEvent(ctx, id)
+	case EVENT_MARK_ATTENDANCE, EVENT_REMOVE_ATTENDANCE:
+		return eh.handleAttendanceEvent(ctx, id)
 	}
+
+	return nil
 }
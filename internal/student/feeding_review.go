@@ -0,0 +1,54 @@
+package student
+
+import (
+	"context"
+	"time"
+)
+
+// FeedingReviewQuery selects the unreviewed feeding photos to sample, up to PerSchool from each school
+type FeedingReviewQuery struct {
+	SchoolID  string // empty samples every school
+	From, To  time.Time
+	PerSchool uint
+}
+
+// FeedingReviewItem is a feeding waiting for its proof photo to be reviewed
+type FeedingReviewItem struct {
+	StudentID       string
+	StudentSchoolID string
+	FirstName       string
+	LastName        string
+	SchoolID        string
+	FeedingID       uint64
+	FedAt           time.Time
+	FileID          string
+	FedBy           string
+}
+
+// FeederReviewStats counts the reviews of the feedings recorded by one feeder
+type FeederReviewStats struct {
+	FedBy      string // empty for feedings recorded before feeders were tracked
+	Feedings   int
+	Reviewed   int
+	Verified   int
+	Suspicious int
+	Invalid    int
+}
+
+// RejectionRate is the share of reviewed feedings found suspicious or invalid, 0 when none were reviewed
+func (f *FeederReviewStats) RejectionRate() float64 {
+	if f.Reviewed == 0 {
+		return 0
+	}
+	return float64(f.Suspicious+f.Invalid) / float64(f.Reviewed)
+}
+
+// GetFeedingReviewQueue returns a random sample of unreviewed feeding photos from each school
+func (s *StudentService) GetFeedingReviewQueue(ctx context.Context, query FeedingReviewQuery) ([]*FeedingReviewItem, error) {
+	return s.repo.GetFeedingReviewQueue(ctx, query)
+}
+
+// GetFeederReviewStats returns the review counts of each feeder for feedings within the date range
+func (s *StudentService) GetFeederReviewStats(ctx context.Context, schoolID string, from, to time.Time) ([]*FeederReviewStats, error) {
+	return s.repo.GetFeederReviewStats(ctx, schoolID, from, to)
+}
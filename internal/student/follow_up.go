@@ -0,0 +1,303 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"geevly/gen/go/eda"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var ErrFollowUpNotFound = fmt.Errorf("follow-up task not found")
+var ErrInvalidFollowUpStatus = fmt.Errorf("invalid follow-up status")
+
+// FollowUpStatus is where a follow-up task is in its resolution
+type FollowUpStatus string
+
+const (
+	FollowUpOpen       FollowUpStatus = "open"
+	FollowUpInProgress FollowUpStatus = "in_progress"
+	FollowUpResolved   FollowUpStatus = "resolved"
+	FollowUpDismissed  FollowUpStatus = "dismissed"
+)
+
+// FollowUpStatuses lists the statuses in the order a task moves through them
+var FollowUpStatuses = []FollowUpStatus{FollowUpOpen, FollowUpInProgress, FollowUpResolved, FollowUpDismissed}
+
+func (s FollowUpStatus) String() string {
+	switch s {
+	case FollowUpOpen:
+		return "Open"
+	case FollowUpInProgress:
+		return "In progress"
+	case FollowUpResolved:
+		return "Resolved"
+	case FollowUpDismissed:
+		return "Dismissed"
+	default:
+		return string(s)
+	}
+}
+
+// IsClosed reports whether the task no longer needs anyone's attention
+func (s FollowUpStatus) IsClosed() bool {
+	return s == FollowUpResolved || s == FollowUpDismissed
+}
+
+// FollowUpConfig sets when the missed-meal scan flags a student and how often it runs
+type FollowUpConfig struct {
+	// MissedDays is the number of consecutive school days without a meal before a student is flagged,
+	// schools can set their own
+	MissedDays uint32
+	// ScanInterval is the time between scans, 0 turns the scheduled scan off
+	ScanInterval time.Duration
+}
+
+func DefaultFollowUpConfig() FollowUpConfig {
+	return FollowUpConfig{
+		MissedDays:   5,
+		ScanInterval: 6 * time.Hour,
+	}
+}
+
+// FollowUpTask is an active student who stopped being fed, it's assigned to the staff of the school.
+// A task covers one run of missed days, the student is flagged again only after being fed in between.
+type FollowUpTask struct {
+	ID         uint64
+	StudentID  string
+	SchoolID   string
+	FirstName  string
+	LastName   string
+	LastFedOn  time.Time // zero when the student was never fed
+	MissedDays int       // school days without a meal when last scanned
+	Detail     string
+	Status     FollowUpStatus
+	AssignedTo string // staff member who took the task, empty while it's with the whole school
+	CreatedAt  time.Time
+	ClosedAt   time.Time
+	ClosedBy   string
+	Notes      []*FollowUpNote
+}
+
+type FollowUpNote struct {
+	ID        uint64
+	TaskID    uint64
+	Note      string
+	Author    string
+	CreatedAt time.Time
+}
+
+// FollowUpFilter narrows the follow-up tasks listed, no school IDs lists every school
+type FollowUpFilter struct {
+	SchoolIDs     []string
+	IncludeClosed bool
+}
+
+// missedMealCandidate is an active student found by the scan, before their enrollment is checked
+type missedMealCandidate struct {
+	StudentID string
+	LastFedOn time.Time
+}
+
+// DefaultMissedMealDays is the number of missed school days that flags a student at schools without their own
+func (s *StudentService) DefaultMissedMealDays() uint32 {
+	return s.followUps.MissedDays
+}
+
+// ScanMissedMeals flags the active students of every school who weren't fed on the school's last
+// school days, a school day being a day the school served meals before today. It returns the number
+// of new tasks.
+func (s *StudentService) ScanMissedMeals(ctx context.Context, now time.Time) (int, error) {
+	schoolIDs, err := s.repo.listSchoolsWithActiveStudents(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list schools: %w", err)
+	}
+
+	created := 0
+	for _, schoolID := range schoolIDs {
+		n, err := s.scanSchoolMissedMeals(ctx, schoolID, now)
+		if err != nil {
+			// one school failing shouldn't hold up the follow-ups of the others
+			slog.Error("failed to scan school for missed meals", "school_id", schoolID, "error", err)
+			continue
+		}
+		created += n
+	}
+
+	if err := s.repo.closeFollowUpsFedSince(ctx); err != nil {
+		return created, fmt.Errorf("failed to close follow-ups of students fed again: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *StudentService) scanSchoolMissedMeals(ctx context.Context, schoolID string, now time.Time) (int, error) {
+	threshold := s.followUps.MissedDays
+	if t, err := s.acl.GetMissedMealThreshold(ctx, schoolID); err != nil {
+		return 0, fmt.Errorf("failed to get missed meal threshold: %w", err)
+	} else if t > 0 {
+		threshold = t
+	}
+	if threshold == 0 {
+		return 0, nil
+	}
+
+	// newest first
+	schoolDays, err := s.repo.listSchoolFeedingDays(ctx, schoolID, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list school feeding days: %w", err)
+	}
+	if len(schoolDays) < int(threshold) {
+		return 0, nil
+	}
+
+	candidates, err := s.repo.listMissedMealCandidates(ctx, schoolID, schoolDays[threshold-1])
+	if err != nil {
+		return 0, fmt.Errorf("failed to list students: %w", err)
+	}
+
+	created := 0
+	for _, c := range candidates {
+		id, err := strconv.ParseUint(c.StudentID, 10, 64)
+		if err != nil {
+			return created, fmt.Errorf("invalid student ID %q: %w", c.StudentID, err)
+		}
+
+		agg, err := s.repo.loadStudent(ctx, id)
+		if err != nil {
+			return created, fmt.Errorf("failed to load student %d: %w", id, err)
+		}
+
+		// only days the student was enrolled at the school count, a newly enrolled student isn't missing meals
+		var missed []time.Time
+		for _, day := range schoolDays {
+			if !day.After(c.LastFedOn) || agg.SchoolAt(day) != schoolID {
+				break
+			}
+			missed = append(missed, day)
+		}
+		if len(missed) < int(threshold) {
+			continue
+		}
+
+		task := &FollowUpTask{
+			StudentID:  c.StudentID,
+			SchoolID:   schoolID,
+			LastFedOn:  c.LastFedOn,
+			MissedDays: len(missed),
+			Detail:     missedMealDetail(agg, missed, c.LastFedOn),
+		}
+
+		isNew, err := s.repo.upsertFollowUpTask(ctx, task)
+		if err != nil {
+			return created, fmt.Errorf("failed to save follow-up for student %d: %w", id, err)
+		}
+		if isNew {
+			created++
+		}
+	}
+
+	return created, nil
+}
+
+// missedMealDetail describes the missed days for staff, with the attendance marked on them so an
+// absent student can be told apart from one who was at school and not fed
+func missedMealDetail(agg *Aggregate, missed []time.Time, lastFedOn time.Time) string {
+	detail := fmt.Sprintf("Not fed on the last %d school days", len(missed))
+	if lastFedOn.IsZero() {
+		detail += ", never fed"
+	} else {
+		detail += fmt.Sprintf(", last fed %s", lastFedOn.Format("2006-01-02"))
+	}
+
+	var present, absent, excused int
+	for _, day := range missed {
+		a := agg.AttendanceOn(day)
+		if a == nil {
+			continue
+		}
+		switch a.Status {
+		case eda.Student_Attendance_PRESENT:
+			present++
+		case eda.Student_Attendance_ABSENT:
+			absent++
+		case eda.Student_Attendance_EXCUSED:
+			excused++
+		}
+	}
+	if present+absent+excused > 0 {
+		detail += fmt.Sprintf(". Attendance on those days: %d present, %d absent, %d excused", present, absent, excused)
+	}
+
+	return detail
+}
+
+// RunFollowUpScans scans for missed meals at the configured interval until the context is done
+func (s *StudentService) RunFollowUpScans(ctx context.Context) {
+	if s.followUps.ScanInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.followUps.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		created, err := s.ScanMissedMeals(ctx, time.Now())
+		if err != nil {
+			slog.Error("missed meal scan failed", "error", err)
+		} else if created > 0 {
+			slog.Info("missed meal scan created follow-ups", "count", created)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListFollowUps returns the follow-up tasks with their notes, open tasks and the longest runs first
+func (s *StudentService) ListFollowUps(ctx context.Context, filter FollowUpFilter) ([]*FollowUpTask, error) {
+	tasks, err := s.repo.listFollowUpTasks(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.Status.IsClosed() != b.Status.IsClosed() {
+			return !a.Status.IsClosed()
+		}
+		return a.MissedDays > b.MissedDays
+	})
+
+	return tasks, nil
+}
+
+// GetFollowUp returns a follow-up task with its notes
+func (s *StudentService) GetFollowUp(ctx context.Context, id uint64) (*FollowUpTask, error) {
+	return s.repo.getFollowUpTask(ctx, id)
+}
+
+// SetFollowUpStatus moves a task along, taking it into progress assigns it to the user
+func (s *StudentService) SetFollowUpStatus(ctx context.Context, id uint64, status FollowUpStatus, user string) error {
+	switch status {
+	case FollowUpOpen, FollowUpInProgress, FollowUpResolved, FollowUpDismissed:
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidFollowUpStatus, status)
+	}
+
+	return s.repo.setFollowUpStatus(ctx, id, status, user)
+}
+
+// AddFollowUpNote records a note on a task, author identifies the user
+func (s *StudentService) AddFollowUpNote(ctx context.Context, id uint64, note, author string) error {
+	if note == "" {
+		return fmt.Errorf("note is required")
+	}
+
+	return s.repo.addFollowUpNote(ctx, id, note, author)
+}
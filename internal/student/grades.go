@@ -0,0 +1,149 @@
+package student
+
+import (
+	"fmt"
+	"geevly/gen/go/eda"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultGradingScale is used when a report doesn't say which scale the school grades on, it's the
+// scale of the Philippine K to 12 report card
+func DefaultGradingScale() *eda.Student_GradeReport_GradingScale {
+	return &eda.Student_GradeReport_GradingScale{
+		Name:         "K to 12",
+		MaxScore:     100,
+		PassingScore: 75,
+	}
+}
+
+// GradingScaleOf returns the scale of a report, reports recorded before scales were kept are on the
+// default one
+func GradingScaleOf(report *eda.Student_GradeReport) *eda.Student_GradeReport_GradingScale {
+	if report.GetGradingScale().GetMaxScore() > 0 {
+		return report.GetGradingScale()
+	}
+	return DefaultGradingScale()
+}
+
+// GradeReportAverage is the overall score of a report: the average reported by the school, otherwise the
+// mean of the subject scores. Reports without subjects only have the whole number grade.
+func GradeReportAverage(report *eda.Student_GradeReport) float32 {
+	if avg, ok := gradeAverage(report.GetOverallAverage(), report.OverallAverage != nil, report.GetSubjects()); ok {
+		return avg
+	}
+	return float32(report.GetGrade())
+}
+
+func gradeAverage(overall float32, hasOverall bool, subjects []*eda.Student_GradeReport_SubjectScore) (float32, bool) {
+	if hasOverall {
+		return overall, true
+	}
+	if len(subjects) == 0 {
+		return 0, false
+	}
+
+	var sum float32
+	for _, s := range subjects {
+		sum += s.GetScore()
+	}
+	return sum / float32(len(subjects)), true
+}
+
+func validateGradeReport(g *eda.Student_GradeReport) error {
+	if g == nil {
+		return fmt.Errorf("%w: grade report is required", ErrInvalidGradeReport)
+	}
+
+	scale := GradingScaleOf(g)
+	switch {
+	case g.GetTestDate() == nil:
+		return fmt.Errorf("%w: test date is required", ErrInvalidGradeReport)
+	case dateToTime(g.GetTestDate()).After(time.Now()):
+		return fmt.Errorf("%w: test date is in the future", ErrInvalidGradeReport)
+	case scale.GetPassingScore() < 0 || scale.GetPassingScore() > scale.GetMaxScore():
+		return fmt.Errorf("%w: passing score must be between 0 and %g", ErrInvalidGradeReport, scale.GetMaxScore())
+	case len(g.GetSubjects()) == 0 && g.OverallAverage == nil:
+		return fmt.Errorf("%w: an overall average or at least one subject score is required", ErrInvalidGradeReport)
+	case g.OverallAverage != nil && !scoreInScale(g.GetOverallAverage(), scale):
+		return fmt.Errorf("%w: overall average must be between 0 and %g", ErrInvalidGradeReport, scale.GetMaxScore())
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range g.GetSubjects() {
+		name := strings.TrimSpace(s.GetSubject())
+		switch {
+		case name == "":
+			return fmt.Errorf("%w: subject name is required", ErrInvalidGradeReport)
+		case seen[strings.ToLower(name)]:
+			return fmt.Errorf("%w: %s is listed more than once", ErrInvalidGradeReport, name)
+		case !scoreInScale(s.GetScore(), scale):
+			return fmt.Errorf("%w: %s score must be between 0 and %g", ErrInvalidGradeReport, name, scale.GetMaxScore())
+		}
+		seen[strings.ToLower(name)] = true
+	}
+
+	return nil
+}
+
+func scoreInScale(score float32, scale *eda.Student_GradeReport_GradingScale) bool {
+	return score >= 0 && score <= scale.GetMaxScore()
+}
+
+// gradeReportEvent builds the event of a report, the whole number grade is derived from the subject
+// scores so reports and exports that only know the grade keep working
+func gradeReportEvent(id uint64, g *eda.Student_GradeReport) *eda.Student_GradeReport_Event {
+	subjects := make([]*eda.Student_GradeReport_SubjectScore, 0, len(g.GetSubjects()))
+	for _, s := range g.GetSubjects() {
+		subjects = append(subjects, &eda.Student_GradeReport_SubjectScore{
+			Subject: strings.TrimSpace(s.GetSubject()),
+			Score:   s.GetScore(),
+		})
+	}
+
+	grade := g.GetGrade()
+	if avg, ok := gradeAverage(g.GetOverallAverage(), g.OverallAverage != nil, subjects); ok {
+		grade = int32(math.Round(float64(avg)))
+	}
+
+	return &eda.Student_GradeReport_Event{
+		Id:                     id,
+		Grade:                  grade,
+		TestDate:               g.GetTestDate(),
+		AssociatedBulkUploadId: g.GetAssociatedBulkUploadId(),
+		SchoolYear:             strings.TrimSpace(g.GetSchoolYear()),
+		GradingPeriod:          strings.TrimSpace(g.GetGradingPeriod()),
+		Subjects:               subjects,
+		OverallAverage:         g.OverallAverage,
+		GradingScale:           g.GetGradingScale(),
+	}
+}
+
+func gradeReportFromEvent(event *eda.Student_GradeReport_Event) *eda.Student_GradeReport {
+	return &eda.Student_GradeReport{
+		Id:                     event.Id,
+		Grade:                  event.Grade,
+		TestDate:               event.TestDate,
+		AssociatedBulkUploadId: event.AssociatedBulkUploadId,
+		SchoolYear:             event.SchoolYear,
+		GradingPeriod:          event.GradingPeriod,
+		Subjects:               event.Subjects,
+		OverallAverage:         event.OverallAverage,
+		GradingScale:           event.GradingScale,
+	}
+}
+
+// SubjectsOf lists the distinct subjects across reports in the order they first appear
+func SubjectsOf(reports []*eda.Student_GradeReport) []string {
+	subjects := make([]string, 0)
+	for _, r := range reports {
+		for _, s := range r.GetSubjects() {
+			if !slices.Contains(subjects, s.GetSubject()) {
+				subjects = append(subjects, s.GetSubject())
+			}
+		}
+	}
+	return subjects
+}
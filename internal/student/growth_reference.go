@@ -0,0 +1,257 @@
+package student
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// growthReferenceFiles holds the WHO growth references as LMS parameters, one file per indicator and
+// sex, e.g. who2007/bfa_boys.tsv. who2006 is the child growth standard for 0-60 months and who2007 the
+// growth reference for 5-19 years. Rows are tab separated "key L M S" where the key is the age in
+// months, or the length/height in cm for the weight-for-length/height tables. Extra columns such as
+// the published SD curves are ignored.
+//
+//go:embed who2006/*.tsv who2007/*.tsv
+var growthReferenceFiles embed.FS
+
+var ErrNoReferenceData = fmt.Errorf("no growth reference data")
+
+// Under5MaxAgeMonths is the oldest age covered by the WHO child growth standards, older children are
+// compared against the WHO 2007 growth reference
+const Under5MaxAgeMonths = 60
+
+// GrowthIndicator is a measurement compared against the growth reference
+type GrowthIndicator string
+
+const (
+	BMIForAge       GrowthIndicator = "bfa"
+	HeightForAge    GrowthIndicator = "hfa"
+	WeightForAge    GrowthIndicator = "wfa"
+	WeightForLength GrowthIndicator = "wfl"
+	WeightForHeight GrowthIndicator = "wfh"
+)
+
+type growthStandard string
+
+const (
+	who2006 growthStandard = "who2006"
+	who2007 growthStandard = "who2007"
+)
+
+// standardIndicators lists the tables each standard publishes, weight-for-age stops at 10 years in
+// the 2007 reference and weight-for-length/height only exists for under 5s
+var standardIndicators = map[growthStandard][]GrowthIndicator{
+	who2006: {BMIForAge, HeightForAge, WeightForAge, WeightForLength, WeightForHeight},
+	who2007: {BMIForAge, HeightForAge, WeightForAge},
+}
+
+func standardForAge(ageMonths int) growthStandard {
+	if ageMonths <= Under5MaxAgeMonths {
+		return who2006
+	}
+	return who2007
+}
+
+// keyScale converts a table key to the integer it's stored under, lengths are kept to the millimetre
+func (indicator GrowthIndicator) keyScale() float64 {
+	if indicator == WeightForLength || indicator == WeightForHeight {
+		return 10
+	}
+	return 1
+}
+
+// lms are the Box-Cox power (L), median (M) and coefficient of variation (S) of a reference point
+type lms struct {
+	L, M, S float64
+}
+
+// growthReference maps a table key, an age in completed months or a length in millimetres, to its
+// reference point
+type growthReference map[int]lms
+
+type referenceKey struct {
+	standard  growthStandard
+	indicator GrowthIndicator
+	gender    Gender
+}
+
+var (
+	loadReferencesOnce sync.Once
+	references         map[referenceKey]growthReference
+	referencesErr      error
+)
+
+func (k referenceKey) fileName() string {
+	sex := "girls"
+	if k.gender == Male {
+		sex = "boys"
+	}
+	return fmt.Sprintf("%s/%s_%s.tsv", k.standard, k.indicator, sex)
+}
+
+func loadReferences() (map[referenceKey]growthReference, error) {
+	loadReferencesOnce.Do(func() {
+		references = make(map[referenceKey]growthReference)
+		for standard, indicators := range standardIndicators {
+			for _, indicator := range indicators {
+				for _, gender := range []Gender{Male, Female} {
+					key := referenceKey{standard: standard, indicator: indicator, gender: gender}
+
+					f, err := growthReferenceFiles.Open(key.fileName())
+					if err != nil {
+						referencesErr = fmt.Errorf("failed to open %s reference: %w", key.fileName(), err)
+						return
+					}
+
+					ref, err := parseGrowthReference(f, indicator.keyScale())
+					f.Close()
+					if err != nil {
+						referencesErr = fmt.Errorf("failed to parse %s reference: %w", key.fileName(), err)
+						return
+					}
+
+					if len(ref) == 0 {
+						referencesErr = fmt.Errorf("%s reference has no rows", key.fileName())
+						return
+					}
+
+					references[key] = ref
+				}
+			}
+		}
+	})
+
+	return references, referencesErr
+}
+
+// parseGrowthReference reads "key L M S" rows, lines starting with # and a header row are skipped
+func parseGrowthReference(r io.Reader, keyScale float64) (growthReference, error) {
+	ref := make(growthReference)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		key, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			// header row
+			continue
+		}
+
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: expected key, L, M and S", line)
+		}
+
+		var values [3]float64
+		for i := range values {
+			if values[i], err = strconv.ParseFloat(fields[i+1], 64); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+
+		ref[int(math.Round(key*keyScale))] = lms{L: values[0], M: values[1], S: values[2]}
+	}
+
+	return ref, scanner.Err()
+}
+
+// valueAt returns the measurement at the given z-score
+func (p lms) valueAt(z float64) float64 {
+	if p.L == 0 {
+		return p.M * math.Exp(p.S*z)
+	}
+	return p.M * math.Pow(1+p.L*p.S*z, 1/p.L)
+}
+
+// zScore returns the z-score of a measurement. When restricted, z-scores beyond ±3 are measured in
+// units of the distance between the 2 and 3 SD curves, as WHO does for weight based indicators whose
+// skewed tails would otherwise stretch extreme values.
+func (p lms) zScore(x float64, restricted bool) float64 {
+	var z float64
+	if p.L == 0 {
+		z = math.Log(x/p.M) / p.S
+	} else {
+		z = (math.Pow(x/p.M, p.L) - 1) / (p.L * p.S)
+	}
+
+	if !restricted {
+		return z
+	}
+
+	switch {
+	case z > 3:
+		sd3, sd2 := p.valueAt(3), p.valueAt(2)
+		return 3 + (x-sd3)/(sd3-sd2)
+	case z < -3:
+		sd3, sd2 := p.valueAt(-3), p.valueAt(-2)
+		return -3 + (x-sd3)/(sd2-sd3)
+	}
+
+	return z
+}
+
+// GrowthZScore returns the z-score of a measurement for a child of the given sex and age in completed
+// months, against the WHO child growth standards up to 60 months and the WHO 2007 reference after
+func GrowthZScore(indicator GrowthIndicator, gender Gender, ageMonths int, value float64) (float64, error) {
+	if indicator == WeightForLength || indicator == WeightForHeight {
+		return 0, fmt.Errorf("%s is by length, use WeightForLengthZScore", indicator)
+	}
+
+	return lookupZScore(referenceKey{standardForAge(ageMonths), indicator, gender}, ageMonths, value)
+}
+
+// WeightForLengthZScore returns the weight-for-length z-score of a child under 2, or the weight-for-height
+// z-score from 2 to 5 years, as children under 2 are measured lying down
+func WeightForLengthZScore(gender Gender, ageMonths int, lengthCm, weightKg float64) (float64, error) {
+	if ageMonths > Under5MaxAgeMonths {
+		return 0, fmt.Errorf("%w: weight-for-height is only defined up to %d months", ErrNoReferenceData, Under5MaxAgeMonths)
+	}
+
+	indicator := WeightForHeight
+	if ageMonths < 24 {
+		indicator = WeightForLength
+	}
+
+	return lookupZScore(referenceKey{who2006, indicator, gender}, int(math.Round(lengthCm*indicator.keyScale())), weightKg)
+}
+
+func lookupZScore(key referenceKey, tableKey int, value float64) (float64, error) {
+	if key.gender != Male && key.gender != Female {
+		return 0, fmt.Errorf("gender must be either 'male' or 'female', got %s", key.gender)
+	}
+
+	if value <= 0 {
+		return 0, fmt.Errorf("%s measurement must be positive, got %f", key.indicator, value)
+	}
+
+	refs, err := loadReferences()
+	if err != nil {
+		return 0, err
+	}
+
+	point, ok := refs[key][tableKey]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s for %s at %d", ErrNoReferenceData, key.fileName(), key.gender, tableKey)
+	}
+
+	return point.zScore(value, key.indicator != HeightForAge), nil
+}
+
+// ageInMonths returns the age in completed months on the given day
+func ageInMonths(dob, at time.Time) int {
+	months := (at.Year()-dob.Year())*12 + int(at.Month()) - int(dob.Month())
+	if at.Day() < dob.Day() {
+		months--
+	}
+	return months
+}
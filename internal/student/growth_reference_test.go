@@ -0,0 +1,228 @@
+package student
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLMSZScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		point      lms
+		x          float64
+		restricted bool
+		want       float64
+	}{
+		{"median", lms{L: -1.5, M: 16, S: 0.09}, 16, true, 0},
+		{"power transform", lms{L: 1, M: 10, S: 0.1}, 11, false, 1},
+		{"log transform", lms{L: 0, M: 10, S: 0.1}, 10 * math.Exp(0.2), false, 2},
+		// (5^0.5 - 1) / 0.05
+		{"beyond 3 unrestricted", lms{L: 0.5, M: 10, S: 0.1}, 50, false, (math.Sqrt(5) - 1) / 0.05},
+		// unrestricted this is 5, the 3 and 2 SD values are 100/7 and 12.5
+		{"beyond 3 restricted", lms{L: -1, M: 10, S: 0.1}, 20, true, 3 + (20-100.0/7)/(100.0/7-12.5)},
+		{"below -3 restricted", lms{L: 1, M: 10, S: 0.1}, 6.5, true, -3.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.point.zScore(tt.x, tt.restricted); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("zScore(%v) = %v, want %v", tt.x, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLMSValueAtInvertsZScore(t *testing.T) {
+	points := []lms{{L: -0.7, M: 15.3, S: 0.08}, {L: 0, M: 110, S: 0.04}, {L: 1, M: 20, S: 0.12}}
+	for _, p := range points {
+		for z := -3.0; z <= 3; z += 0.5 {
+			if got := p.zScore(p.valueAt(z), false); math.Abs(got-z) > 1e-9 {
+				t.Errorf("%+v: zScore(valueAt(%v)) = %v", p, z, got)
+			}
+		}
+	}
+}
+
+// requireAgeTable fails unless the table has a plausible row for every month from first to last
+func requireAgeTable(t *testing.T, key referenceKey, first, last int) {
+	t.Helper()
+
+	refs, err := loadReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := refs[key]
+	for month := first; month <= last; month++ {
+		point, ok := table[month]
+		if !ok {
+			t.Errorf("%s has no row for month %d", key.fileName(), month)
+			continue
+		}
+		if point.M <= 0 || point.S <= 0 || point.S >= 1 {
+			t.Errorf("%s month %d: implausible LMS %+v", key.fileName(), month, point)
+		}
+		if prev, ok := table[month-1]; ok && key.indicator != BMIForAge && point.M < prev.M {
+			t.Errorf("%s month %d: median %v is below the month before's %v", key.fileName(), month, point.M, prev.M)
+		}
+	}
+
+	if len(table) != last-first+1 {
+		t.Errorf("%s has %d rows, want %d for months %d-%d", key.fileName(), len(table), last-first+1, first, last)
+	}
+}
+
+func TestLoadReferences(t *testing.T) {
+	if _, err := loadReferences(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWHO2007ReferenceCoverage(t *testing.T) {
+	for _, gender := range []Gender{Male, Female} {
+		requireAgeTable(t, referenceKey{who2007, BMIForAge, gender}, 61, 228)
+		requireAgeTable(t, referenceKey{who2007, HeightForAge, gender}, 61, 228)
+		requireAgeTable(t, referenceKey{who2007, WeightForAge, gender}, 61, 120)
+	}
+}
+
+func TestGrowthZScoreOutsideReference(t *testing.T) {
+	if _, err := GrowthZScore(WeightForAge, Female, 121, 30); !errors.Is(err, ErrNoReferenceData) {
+		t.Errorf("weight-for-age after 10 years: got %v, want ErrNoReferenceData", err)
+	}
+	if _, err := GrowthZScore(BMIForAge, Male, 229, 20); !errors.Is(err, ErrNoReferenceData) {
+		t.Errorf("BMI-for-age after 19 years: got %v, want ErrNoReferenceData", err)
+	}
+}
+
+func TestGrowthZScoreAtMedian(t *testing.T) {
+	refs, err := loadReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []referenceKey{
+		{who2007, BMIForAge, Male}, {who2007, BMIForAge, Female},
+		{who2007, HeightForAge, Male}, {who2007, HeightForAge, Female},
+	} {
+		for _, month := range []int{61, 120, 180, 228} {
+			point, ok := refs[key][month]
+			if !ok {
+				t.Errorf("%s has no row for month %d", key.fileName(), month)
+				continue
+			}
+
+			z, err := GrowthZScore(key.indicator, key.gender, month, point.M)
+			if err != nil {
+				t.Errorf("%s month %d: %v", key.fileName(), month, err)
+			} else if math.Abs(z) > 1e-9 {
+				t.Errorf("%s month %d: z-score of the median is %v", key.fileName(), month, z)
+			}
+		}
+	}
+}
+
+// requireLengthTable fails unless the table has a plausible row for every millimetre from first to last
+func requireLengthTable(t *testing.T, key referenceKey, firstMm, lastMm int) {
+	t.Helper()
+
+	refs, err := loadReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := refs[key]
+	missing := 0
+	for mm := firstMm; mm <= lastMm; mm++ {
+		point, ok := table[mm]
+		if !ok {
+			missing++
+			continue
+		}
+		if point.M <= 0 || point.S <= 0 || point.S >= 1 {
+			t.Errorf("%s at %d mm: implausible LMS %+v", key.fileName(), mm, point)
+		}
+	}
+
+	if missing > 0 {
+		t.Errorf("%s is missing %d of the rows from %d to %d mm", key.fileName(), missing, firstMm, lastMm)
+	}
+}
+
+func TestWHO2006ReferenceCoverage(t *testing.T) {
+	for _, gender := range []Gender{Male, Female} {
+		requireAgeTable(t, referenceKey{who2006, BMIForAge, gender}, 0, 60)
+		requireAgeTable(t, referenceKey{who2006, HeightForAge, gender}, 0, 60)
+		requireAgeTable(t, referenceKey{who2006, WeightForAge, gender}, 0, 60)
+		requireLengthTable(t, referenceKey{who2006, WeightForLength, gender}, 450, 1100)
+		requireLengthTable(t, referenceKey{who2006, WeightForHeight, gender}, 650, 1200)
+	}
+}
+
+func TestWeightForLengthZScoreSwitchesToHeightAt24Months(t *testing.T) {
+	refs, err := loadReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, gender := range []Gender{Male, Female} {
+		byLength, okLength := refs[referenceKey{who2006, WeightForLength, gender}][800]
+		byHeight, okHeight := refs[referenceKey{who2006, WeightForHeight, gender}][800]
+		if !okLength || !okHeight {
+			t.Fatalf("%s: no weight-for-length/height reference at 80 cm", gender)
+		}
+
+		const weight = 10.5
+		tests := []struct {
+			ageMonths int
+			want      float64
+		}{
+			{0, byLength.zScore(weight, true)},
+			{23, byLength.zScore(weight, true)},
+			{24, byHeight.zScore(weight, true)},
+			{60, byHeight.zScore(weight, true)},
+		}
+
+		for _, tt := range tests {
+			got, err := WeightForLengthZScore(gender, tt.ageMonths, 80, weight)
+			if err != nil {
+				t.Errorf("%s at %d months: %v", gender, tt.ageMonths, err)
+			} else if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("%s at %d months: z-score %v, want %v", gender, tt.ageMonths, got, tt.want)
+			}
+		}
+
+		// 50 cm is only on the length table, which stops being used at 2 years
+		if _, err := WeightForLengthZScore(gender, 23, 50, 3.5); err != nil {
+			t.Errorf("%s 50 cm at 23 months: %v", gender, err)
+		}
+		if _, err := WeightForLengthZScore(gender, 24, 50, 3.5); !errors.Is(err, ErrNoReferenceData) {
+			t.Errorf("%s 50 cm at 24 months: got %v, want ErrNoReferenceData", gender, err)
+		}
+	}
+
+	if _, err := WeightForLengthZScore(Male, 61, 110, 18); !errors.Is(err, ErrNoReferenceData) {
+		t.Errorf("after 5 years: got %v, want ErrNoReferenceData", err)
+	}
+}
+
+func TestUnder5NutritionalStatusAtMedianWeight(t *testing.T) {
+	refs, err := loadReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	point, ok := refs[referenceKey{who2006, WeightForHeight, Female}][900]
+	if !ok {
+		t.Fatal("no weight-for-height reference at 90 cm")
+	}
+
+	assessment, err := CalculateUnder5NutritionalStatus(Female, Under5Measurements{AgeMonths: 30, HeightCm: 90, WeightKg: point.M})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assessment.HasZScore || math.Abs(assessment.ZScore) > 1e-9 {
+		t.Errorf("median weight for height: got %+v, want a z-score of 0", assessment)
+	}
+}
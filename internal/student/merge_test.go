@@ -0,0 +1,37 @@
+package student
+
+import (
+	"geevly/gen/go/eda"
+	"testing"
+)
+
+func TestHandleMergeFromLeavesEventRecordIDs(t *testing.T) {
+	agg := &Aggregate{}
+	agg.SetIDUint64(1)
+	if _, err := agg.CreateStudent(&eda.Student_Create{FirstName: "Test", LastName: "Student"}); err != nil {
+		t.Fatal(err)
+	}
+	agg.data.GradeReportNextId = 3
+	agg.data.HealthAssessmentNextId = 5
+
+	// the records as the duplicate holds them
+	grade := &eda.Student_GradeReport{Id: 1}
+	assessment := &eda.Student_HealthAssessment{Id: 1}
+	if err := agg.handleMergeFrom(wrappedEvent{data: &eda.Student_MergeFrom_Event{
+		SourceStudentId:   "2",
+		GradeReports:      []*eda.Student_GradeReport{grade},
+		HealthAssessments: []*eda.Student_HealthAssessment{assessment},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := agg.data.GradeHistory[0].Id; got != 4 {
+		t.Errorf("merged grade report id = %d, want 4", got)
+	}
+	if got := agg.data.HealthAssessments[0].Id; got != 6 {
+		t.Errorf("merged assessment id = %d, want 6", got)
+	}
+	if grade.Id != 1 || assessment.Id != 1 {
+		t.Errorf("duplicate's records were renumbered to %d and %d, want them left at 1", grade.Id, assessment.Id)
+	}
+}
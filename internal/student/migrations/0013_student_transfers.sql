@@ -0,0 +1,27 @@
+-- +goose Up
+-- Transfers between schools, for the per-school transfer report
+CREATE TABLE IF NOT EXISTS student_transfer_projections (
+    student_id TEXT NOT NULL,
+    from_school_id TEXT NOT NULL,
+    to_school_id TEXT NOT NULL,
+    transfer_date DATE NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY(student_id, transfer_date),
+    FOREIGN KEY(student_id) REFERENCES student_projections(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_stp_from_school ON student_transfer_projections (from_school_id, transfer_date);
+CREATE INDEX IF NOT EXISTS idx_stp_to_school ON student_transfer_projections (to_school_id, transfer_date);
+
+-- snapshots pre-date the enrollment history, drop them so it's rebuilt from the events
+DELETE FROM student_snapshots;
+
+-- re-attribute feedings, grades and health assessments to the school at the time they were recorded
+INSERT INTO student_projection_updates (what) VALUES
+    ('student_feeding_projections'),
+    ('student_health_projections'),
+    ('student_grade_projections'),
+    ('student_transfer_projections');
+
+-- +goose Down
+DROP TABLE IF EXISTS student_transfer_projections;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Suspected duplicate pairs an admin reviewed and dismissed, a and b are ordered so each pair is stored once
+CREATE TABLE IF NOT EXISTS student_duplicate_reviews (
+    student_a TEXT NOT NULL,
+    student_b TEXT NOT NULL,
+    reviewed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(student_a, student_b)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS student_duplicate_reviews;
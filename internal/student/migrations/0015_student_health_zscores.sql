@@ -0,0 +1,14 @@
+-- +goose Up
+-- z-scores against the WHO 2007 growth reference, null when the reference doesn't cover the student's age
+ALTER TABLE student_health_projections ADD COLUMN bmi_z_score REAL;
+ALTER TABLE student_health_projections ADD COLUMN height_for_age_z_score REAL;
+ALTER TABLE student_health_projections ADD COLUMN stunting_status TEXT;
+ALTER TABLE student_health_projections ADD COLUMN weight_for_age_z_score REAL;
+
+INSERT INTO student_projection_updates (what) VALUES ('student_health_projections');
+
+-- +goose Down
+ALTER TABLE student_health_projections DROP COLUMN weight_for_age_z_score;
+ALTER TABLE student_health_projections DROP COLUMN stunting_status;
+ALTER TABLE student_health_projections DROP COLUMN height_for_age_z_score;
+ALTER TABLE student_health_projections DROP COLUMN bmi_z_score;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- under 5 assessments: arm circumference, oedema and the weight-for-length/height z-score
+ALTER TABLE student_health_projections ADD COLUMN muac_mm REAL;
+ALTER TABLE student_health_projections ADD COLUMN oedema BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE student_health_projections ADD COLUMN weight_for_height_z_score REAL;
+
+INSERT INTO student_projection_updates (what) VALUES ('student_health_projections');
+
+-- +goose Down
+ALTER TABLE student_health_projections DROP COLUMN weight_for_height_z_score;
+ALTER TABLE student_health_projections DROP COLUMN oedema;
+ALTER TABLE student_health_projections DROP COLUMN muac_mm;
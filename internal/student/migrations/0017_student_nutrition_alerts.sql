@@ -0,0 +1,23 @@
+-- +goose Up
+-- Students flagged by the nutrition early-warning checks when a health assessment is added, one row per
+-- assessment and reason so reprocessing an event doesn't raise the alert twice
+CREATE TABLE IF NOT EXISTS student_nutrition_alerts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    alert_type TEXT NOT NULL,
+    assessment_date DATE NOT NULL,
+    previous_assessment_date DATE,
+    associated_bulk_upload_id TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by TEXT,
+    UNIQUE(student_id, assessment_date, associated_bulk_upload_id, alert_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_student_nutrition_alerts_school ON student_nutrition_alerts (school_id, acknowledged_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_student_nutrition_alerts_school;
+DROP TABLE IF EXISTS student_nutrition_alerts;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- sex and grade level of the student, for breaking nutrition down on the school dashboard
+ALTER TABLE student_health_projections ADD COLUMN sex TEXT NOT NULL DEFAULT '';
+ALTER TABLE student_health_projections ADD COLUMN grade_level INT NOT NULL DEFAULT 0;
+
+INSERT INTO student_projection_updates (what) VALUES ('student_health_projections');
+
+-- +goose Down
+ALTER TABLE student_health_projections DROP COLUMN grade_level;
+ALTER TABLE student_health_projections DROP COLUMN sex;
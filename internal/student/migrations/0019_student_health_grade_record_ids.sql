@@ -0,0 +1,154 @@
+-- +goose Up
+-- health assessments and grade reports have their own ID on the student, so individually entered records
+-- (without a bulk upload) and bulk uploaded ones no longer collide on the bulk upload ID
+DROP TABLE IF EXISTS student_health_projections;
+CREATE TABLE student_health_projections (
+    student_id TEXT NOT NULL,
+    record_id INT NOT NULL,
+    school_id TEXT NOT NULL,
+    assessment_date TIMESTAMPTZ NOT NULL,
+    height_cm REAL NOT NULL,
+    weight_kg REAL NOT NULL,
+    bmi REAL,
+    nutritional_status TEXT,
+    bmi_z_score REAL,
+    height_for_age_z_score REAL,
+    stunting_status TEXT,
+    weight_for_age_z_score REAL,
+    muac_mm REAL,
+    oedema BOOLEAN NOT NULL DEFAULT FALSE,
+    weight_for_height_z_score REAL,
+    sex TEXT NOT NULL DEFAULT '',
+    grade_level INT NOT NULL DEFAULT 0,
+    associated_bulk_upload_id TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY(student_id, record_id),
+    FOREIGN KEY(student_id) REFERENCES student_projections(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shp_school_date ON student_health_projections (school_id, assessment_date);
+CREATE INDEX IF NOT EXISTS idx_shp_student_date ON student_health_projections (student_id, assessment_date);
+
+DROP TABLE IF EXISTS student_grade_projections;
+CREATE TABLE student_grade_projections (
+    student_id TEXT NOT NULL,
+    record_id INT NOT NULL,
+    school_id TEXT NOT NULL,
+    test_date DATE NOT NULL,
+    grade INT NOT NULL,
+    school_year TEXT,
+    grading_period TEXT,
+    associated_bulk_upload_id TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY(student_id, record_id),
+    FOREIGN KEY(student_id) REFERENCES student_projections(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sgp_school_date ON student_grade_projections (school_id, test_date);
+CREATE INDEX IF NOT EXISTS idx_sgp_student_date ON student_grade_projections (student_id, test_date);
+
+-- alerts are raised once per assessment and reason, individually entered assessments share a date and an
+-- empty bulk upload ID. Alerts raised before this get their assessment ID when student_health_projections
+-- is rebuilt below, until then it's NULL.
+CREATE TABLE student_nutrition_alerts_by_assessment (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    alert_type TEXT NOT NULL,
+    assessment_id INT,
+    assessment_date DATE NOT NULL,
+    previous_assessment_date DATE,
+    associated_bulk_upload_id TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by TEXT,
+    UNIQUE(student_id, assessment_id, alert_type)
+);
+
+INSERT INTO student_nutrition_alerts_by_assessment
+    (id, student_id, school_id, alert_type, assessment_date, previous_assessment_date, associated_bulk_upload_id,
+    detail, created_at, acknowledged_at, acknowledged_by)
+SELECT id, student_id, school_id, alert_type, assessment_date, previous_assessment_date, associated_bulk_upload_id,
+    detail, created_at, acknowledged_at, acknowledged_by
+FROM student_nutrition_alerts;
+
+DROP INDEX IF EXISTS idx_student_nutrition_alerts_school;
+DROP TABLE student_nutrition_alerts;
+ALTER TABLE student_nutrition_alerts_by_assessment RENAME TO student_nutrition_alerts;
+CREATE INDEX IF NOT EXISTS idx_student_nutrition_alerts_school ON student_nutrition_alerts (school_id, acknowledged_at);
+
+INSERT INTO student_projection_updates (what) VALUES ('student_health_projections'), ('student_grade_projections');
+
+-- snapshots predate the record IDs, replaying the events assigns them
+DELETE FROM student_snapshots;
+
+-- +goose Down
+CREATE TABLE student_nutrition_alerts_by_date (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    alert_type TEXT NOT NULL,
+    assessment_date DATE NOT NULL,
+    previous_assessment_date DATE,
+    associated_bulk_upload_id TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by TEXT,
+    UNIQUE(student_id, assessment_date, associated_bulk_upload_id, alert_type)
+);
+
+INSERT OR IGNORE INTO student_nutrition_alerts_by_date
+    (id, student_id, school_id, alert_type, assessment_date, previous_assessment_date, associated_bulk_upload_id,
+    detail, created_at, acknowledged_at, acknowledged_by)
+SELECT id, student_id, school_id, alert_type, assessment_date, previous_assessment_date, associated_bulk_upload_id,
+    detail, created_at, acknowledged_at, acknowledged_by
+FROM student_nutrition_alerts;
+
+DROP INDEX IF EXISTS idx_student_nutrition_alerts_school;
+DROP TABLE student_nutrition_alerts;
+ALTER TABLE student_nutrition_alerts_by_date RENAME TO student_nutrition_alerts;
+CREATE INDEX IF NOT EXISTS idx_student_nutrition_alerts_school ON student_nutrition_alerts (school_id, acknowledged_at);
+
+DROP TABLE IF EXISTS student_grade_projections;
+CREATE TABLE student_grade_projections (
+    student_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    test_date DATE NOT NULL,
+    grade INT NOT NULL,
+    school_year TEXT,
+    grading_period TEXT,
+    associated_bulk_upload_id TEXT,
+    PRIMARY KEY(student_id, associated_bulk_upload_id),
+    FOREIGN KEY(student_id) REFERENCES student_projections(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sgp_school_date ON student_grade_projections (school_id, test_date);
+CREATE INDEX IF NOT EXISTS idx_sgp_student_date ON student_grade_projections (student_id, test_date);
+
+DROP TABLE IF EXISTS student_health_projections;
+CREATE TABLE student_health_projections (
+    student_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    assessment_date TIMESTAMPTZ NOT NULL,
+    height_cm REAL NOT NULL,
+    weight_kg REAL NOT NULL,
+    bmi REAL,
+    nutritional_status TEXT,
+    associated_bulk_upload_id TEXT,
+    bmi_z_score REAL,
+    height_for_age_z_score REAL,
+    stunting_status TEXT,
+    weight_for_age_z_score REAL,
+    muac_mm REAL,
+    oedema BOOLEAN NOT NULL DEFAULT FALSE,
+    weight_for_height_z_score REAL,
+    sex TEXT NOT NULL DEFAULT '',
+    grade_level INT NOT NULL DEFAULT 0,
+    PRIMARY KEY(student_id, associated_bulk_upload_id),
+    FOREIGN KEY(student_id) REFERENCES student_projections(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shp_school_date ON student_health_projections (school_id, assessment_date);
+CREATE INDEX IF NOT EXISTS idx_shp_student_date ON student_health_projections (student_id, assessment_date);
+
+INSERT INTO student_projection_updates (what) VALUES ('student_health_projections'), ('student_grade_projections');
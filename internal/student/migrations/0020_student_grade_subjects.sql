@@ -0,0 +1,16 @@
+-- +goose Up
+-- report cards: a score per subject as a JSON array, the average reported by the school and its grading scale
+ALTER TABLE student_grade_projections ADD COLUMN overall_average REAL;
+ALTER TABLE student_grade_projections ADD COLUMN subject_scores TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE student_grade_projections ADD COLUMN grading_scale TEXT NOT NULL DEFAULT '';
+ALTER TABLE student_grade_projections ADD COLUMN max_score REAL NOT NULL DEFAULT 100;
+ALTER TABLE student_grade_projections ADD COLUMN passing_score REAL NOT NULL DEFAULT 75;
+
+INSERT INTO student_projection_updates (what) VALUES ('student_grade_projections');
+
+-- +goose Down
+ALTER TABLE student_grade_projections DROP COLUMN passing_score;
+ALTER TABLE student_grade_projections DROP COLUMN max_score;
+ALTER TABLE student_grade_projections DROP COLUMN grading_scale;
+ALTER TABLE student_grade_projections DROP COLUMN subject_scores;
+ALTER TABLE student_grade_projections DROP COLUMN overall_average;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- School attendance per student per day, marked on the roster or bulk uploaded. Kept apart from feedings
+-- so days a student was at school but wasn't fed can be found.
+CREATE TABLE IF NOT EXISTS student_attendance_projections (
+    student_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    attendance_date DATE NOT NULL,
+    status TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    recorded_by TEXT NOT NULL DEFAULT '',
+    associated_bulk_upload_id TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY(student_id, attendance_date),
+    FOREIGN KEY(student_id) REFERENCES student_projections(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sap_school_date ON student_attendance_projections (school_id, attendance_date);
+
+INSERT INTO student_projection_updates (what) VALUES ('student_attendance_projections');
+
+-- +goose Down
+DROP TABLE IF EXISTS student_attendance_projections;
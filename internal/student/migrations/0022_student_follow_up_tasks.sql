@@ -0,0 +1,35 @@
+-- +goose Up
+-- Active students the missed-meal scan found without a meal for several school days, one task per run of
+-- missed days: last_fed_on is the day the run started after, empty when the student was never fed
+CREATE TABLE IF NOT EXISTS student_follow_up_tasks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    student_id TEXT NOT NULL,
+    school_id TEXT NOT NULL,
+    last_fed_on TEXT NOT NULL DEFAULT '',
+    missed_days INTEGER NOT NULL,
+    detail TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    assigned_to TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMPTZ,
+    closed_by TEXT,
+    UNIQUE(student_id, last_fed_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_student_follow_up_tasks_school ON student_follow_up_tasks (school_id, status);
+
+CREATE TABLE IF NOT EXISTS student_follow_up_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL REFERENCES student_follow_up_tasks (id) ON DELETE CASCADE,
+    note TEXT NOT NULL,
+    author TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_student_follow_up_notes_task ON student_follow_up_notes (task_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_student_follow_up_notes_task;
+DROP TABLE IF EXISTS student_follow_up_notes;
+DROP INDEX IF EXISTS idx_student_follow_up_tasks_school;
+DROP TABLE IF EXISTS student_follow_up_tasks;
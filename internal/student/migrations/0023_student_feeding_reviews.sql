@@ -0,0 +1,18 @@
+-- +goose Up
+-- who fed the student and the latest review of the feeding's proof photo, feedings recorded before this
+-- have no feeder and start unreviewed
+ALTER TABLE student_feeding_projections ADD COLUMN fed_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE student_feeding_projections ADD COLUMN review_status TEXT NOT NULL DEFAULT 'UNREVIEWED';
+ALTER TABLE student_feeding_projections ADD COLUMN review_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE student_feeding_projections ADD COLUMN reviewed_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE student_feeding_projections ADD COLUMN reviewed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_sfp_school_review_status ON student_feeding_projections (school_id, review_status);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_sfp_school_review_status;
+ALTER TABLE student_feeding_projections DROP COLUMN reviewed_at;
+ALTER TABLE student_feeding_projections DROP COLUMN reviewed_by;
+ALTER TABLE student_feeding_projections DROP COLUMN review_reason;
+ALTER TABLE student_feeding_projections DROP COLUMN review_status;
+ALTER TABLE student_feeding_projections DROP COLUMN fed_by;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- the school the student was enrolled at when the sponsorship started, its payments count toward that
+-- school's budget even after the student transfers. Existing rows are filled in by the rebuild below.
+ALTER TABLE student_sponsorship_projections ADD COLUMN school_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_sponsorship_school_dates ON student_sponsorship_projections (school_id, start_date);
+
+INSERT INTO student_projection_updates (what) VALUES ('student_sponsorship_projections');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_sponsorship_school_dates;
+ALTER TABLE student_sponsorship_projections DROP COLUMN school_id;
@@ -0,0 +1,209 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"geevly/gen/go/eda"
+	"log/slog"
+	"time"
+
+	"github.com/Howard3/gosignal"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrNutritionAlertNotFound = fmt.Errorf("nutrition alert not found")
+
+// NutritionAlertType is the reason a student was flagged after a health assessment
+type NutritionAlertType string
+
+const (
+	AlertStatusWorsened NutritionAlertType = "status_worsened"
+	AlertWeightLoss     NutritionAlertType = "weight_loss"
+	AlertZScoreDrop     NutritionAlertType = "z_score_drop"
+)
+
+func (t NutritionAlertType) String() string {
+	switch t {
+	case AlertStatusWorsened:
+		return "Became wasted"
+	case AlertWeightLoss:
+		return "Weight loss"
+	case AlertZScoreDrop:
+		return "Z-score drop"
+	default:
+		return string(t)
+	}
+}
+
+// NutritionAlertConfig sets how much a student has to decline between two assessments to be flagged,
+// a threshold of 0 turns that check off
+type NutritionAlertConfig struct {
+	// WeightLossPercent flags students who lost more than this percentage of their previous weight
+	WeightLossPercent float64
+	// ZScoreDrop flags students whose BMI-for-age z-score fell by at least this much
+	ZScoreDrop float64
+}
+
+func DefaultNutritionAlertConfig() NutritionAlertConfig {
+	return NutritionAlertConfig{
+		WeightLossPercent: 5,
+		ZScoreDrop:        1,
+	}
+}
+
+// NutritionAlert is a student flagged by a health assessment, it stays in the inbox until acknowledged
+type NutritionAlert struct {
+	ID                     uint64
+	StudentID              string
+	SchoolID               string
+	Type                   NutritionAlertType
+	AssessmentID           uint64
+	AssessmentDate         time.Time
+	PreviousAssessmentDate time.Time
+	AssociatedBulkUploadID string
+	Detail                 string
+	CreatedAt              time.Time
+	AcknowledgedAt         time.Time
+	AcknowledgedBy         string
+}
+
+func (na *NutritionAlert) IsAcknowledged() bool {
+	return !na.AcknowledgedAt.IsZero()
+}
+
+// NutritionAlertFilter narrows the alerts inbox, an empty SchoolID lists every school
+type NutritionAlertFilter struct {
+	SchoolID            string
+	IncludeAcknowledged bool
+}
+
+// evaluateNutritionAlerts compares an assessment with the student's previous one, prev is nil for the
+// first assessment in which case only a wasted status is flagged
+func evaluateNutritionAlerts(prev, curr *HealthReport, cfg NutritionAlertConfig) []NutritionAlert {
+	alerts := make([]NutritionAlert, 0)
+	newAlert := func(alertType NutritionAlertType, detail string) {
+		alert := NutritionAlert{
+			Type:                   alertType,
+			AssessmentID:           curr.ID,
+			AssessmentDate:         curr.AssessmentDate,
+			AssociatedBulkUploadID: curr.AssociatedBulkUploadId,
+			Detail:                 detail,
+		}
+		if prev != nil {
+			alert.PreviousAssessmentDate = prev.AssessmentDate
+		}
+		alerts = append(alerts, alert)
+	}
+
+	status := curr.NutritionalStatus().Status
+	if wastingSeverity(status) >= wastingSeverity(Wasted) {
+		switch {
+		case prev == nil:
+			newAlert(AlertStatusWorsened, fmt.Sprintf("%s at first assessment", status))
+		case wastingSeverity(status) > wastingSeverity(prev.NutritionalStatus().Status):
+			newAlert(AlertStatusWorsened, fmt.Sprintf("%s, previously %s", status, prev.NutritionalStatus().Status))
+		}
+	}
+
+	if prev == nil {
+		return alerts
+	}
+
+	if cfg.WeightLossPercent > 0 && prev.WeightKg > 0 {
+		lossPercent := float64(prev.WeightKg-curr.WeightKg) / float64(prev.WeightKg) * 100
+		if lossPercent > cfg.WeightLossPercent {
+			newAlert(AlertWeightLoss, fmt.Sprintf("Lost %.1f%% of body weight, %.1f kg to %.1f kg", lossPercent, prev.WeightKg, curr.WeightKg))
+		}
+	}
+
+	if cfg.ZScoreDrop > 0 {
+		prevZ, currZ := prev.BMIForAge(), curr.BMIForAge()
+		if prevZ.HasZScore && currZ.HasZScore && prevZ.ZScore-currZ.ZScore >= cfg.ZScoreDrop {
+			newAlert(AlertZScoreDrop, fmt.Sprintf("BMI-for-age z-score fell from %.2f to %.2f", prevZ.ZScore, currZ.ZScore))
+		}
+	}
+
+	return alerts
+}
+
+// nutritionAlertManager is a process manager on the health assessment events, it raises alerts when a
+// new or corrected assessment shows the student declining and withdraws them when the assessment is removed
+type nutritionAlertManager struct {
+	repo   Repository
+	config NutritionAlertConfig
+}
+
+func (m *nutritionAlertManager) handleEvent(ctx context.Context, evt *gosignal.Event, aggID uint64) {
+	switch evt.Type {
+	case EVENT_ADD_HEALTH_ASSESSMENT:
+		event := &eda.Student_HealthAssessment_Event{}
+		if err := proto.Unmarshal(evt.Data, event); err != nil {
+			slog.Error("failed to unmarshal health assessment event", "error", err)
+			return
+		}
+		m.evaluateAssessment(ctx, aggID, event.Id)
+	case EVENT_UPDATE_HEALTH_ASSESSMENT:
+		event := &eda.Student_HealthAssessment_Event{}
+		if err := proto.Unmarshal(evt.Data, event); err != nil {
+			slog.Error("failed to unmarshal health assessment event", "error", err)
+			return
+		}
+		// alerts already followed up stay in the inbox history, the rest are raised again from the correction
+		if err := m.repo.deleteNutritionAlertsForAssessment(ctx, evt.AggregateID, event.Id, true); err != nil {
+			slog.Error("failed to delete nutrition alerts", "error", err)
+			return
+		}
+		m.evaluateAssessment(ctx, aggID, event.Id)
+	case EVENT_REMOVE_HEALTH_ASSESSMENT:
+		event := &eda.Student_HealthAssessment_UndoEvent{}
+		if err := proto.Unmarshal(evt.Data, event); err != nil {
+			slog.Error("failed to unmarshal health assessment undo event", "error", err)
+			return
+		}
+
+		var err error
+		if event.Id != 0 {
+			err = m.repo.deleteNutritionAlertsForAssessment(ctx, evt.AggregateID, event.Id, false)
+		} else {
+			err = m.repo.deleteNutritionAlertsForUpload(ctx, evt.AggregateID, event.AssociatedBulkUploadId)
+		}
+		if err != nil {
+			slog.Error("failed to delete nutrition alerts", "error", err)
+		}
+	}
+}
+
+// evaluateAssessment compares the assessment with the one before it by date, assessments can be
+// uploaded or entered out of order
+func (m *nutritionAlertManager) evaluateAssessment(ctx context.Context, aggID, assessmentID uint64) {
+	student, err := m.repo.loadStudent(ctx, aggID)
+	if err != nil {
+		slog.Error("failed to load student", "error", err)
+		return
+	}
+
+	reports := student.GetHealthAssessments()
+	for i, report := range reports {
+		if report.ID != assessmentID {
+			continue
+		}
+
+		var prev *HealthReport
+		if i > 0 {
+			prev = reports[i-1]
+		}
+
+		alerts := evaluateNutritionAlerts(prev, report, m.config)
+		for j := range alerts {
+			alerts[j].StudentID = student.GetID()
+			alerts[j].SchoolID = student.SchoolAt(report.AssessmentDate)
+		}
+
+		if err := m.repo.insertNutritionAlerts(ctx, alerts); err != nil {
+			slog.Error("failed to insert nutrition alerts", "error", err)
+		}
+		return
+	}
+
+	slog.Error("health assessment not found on student", "student_id", aggID, "assessment_id", assessmentID)
+}
@@ -0,0 +1,104 @@
+package student
+
+import (
+	"context"
+	"geevly/gen/go/eda"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// alertTestRepo serves a single student and keeps the alerts raised for it
+type alertTestRepo struct {
+	Repository
+	student *Aggregate
+	alerts  []NutritionAlert
+}
+
+func (r *alertTestRepo) loadStudent(_ context.Context, _ uint64) (*Aggregate, error) {
+	return r.student, nil
+}
+
+func (r *alertTestRepo) insertNutritionAlerts(_ context.Context, alerts []NutritionAlert) error {
+	r.alerts = append(r.alerts, alerts...)
+	return nil
+}
+
+func TestNutritionAlertOnZScoreDrop(t *testing.T) {
+	refs, err := loadReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bfa := refs[referenceKey{who2007, BMIForAge, Male}]
+	first, second := bfa[120], bfa[126]
+	if first.M == 0 || second.M == 0 {
+		t.Fatal("no BMI-for-age reference for boys at 120 and 126 months")
+	}
+
+	const heightCm = 140
+	weightAt := func(bmi float64) float32 {
+		return float32(bmi * heightCm / 100 * heightCm / 100)
+	}
+
+	agg := &Aggregate{}
+	agg.SetIDUint64(1)
+	if _, err := agg.CreateStudent(&eda.Student_Create{
+		FirstName:   "Test",
+		LastName:    "Student",
+		DateOfBirth: &eda.Date{Year: 2014, Month: 3, Day: 1},
+		Sex:         eda.Student_MALE,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// record adds an assessment and returns it with the alerts the alert manager raised for it
+	record := func(date time.Time, weightKg float32) (*HealthReport, []NutritionAlert) {
+		t.Helper()
+
+		evt, err := agg.RecordHealthAssessment(&eda.Student_AddHealthAssessment{
+			Assessment: &eda.Student_HealthAssessment{
+				HeightCm:       heightCm,
+				WeightKg:       weightKg,
+				AssessmentDate: timestamppb.New(date),
+			},
+			Version: agg.GetVersion(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := &alertTestRepo{student: agg}
+		manager := &nutritionAlertManager{repo: repo, config: DefaultNutritionAlertConfig()}
+		manager.handleEvent(context.Background(), evt, 1)
+
+		reports := agg.GetHealthAssessments()
+		return reports[len(reports)-1], repo.alerts
+	}
+
+	// at the median at 10 years, then 1.5 SD below it six months later
+	firstReport, alerts := record(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), weightAt(first.valueAt(0)))
+	if len(alerts) != 0 {
+		t.Fatalf("first assessment at the median raised %+v", alerts)
+	}
+
+	secondReport, alerts := record(time.Date(2024, 9, 15, 0, 0, 0, 0, time.UTC), weightAt(second.valueAt(-1.5)))
+
+	var drop *NutritionAlert
+	for i, alert := range alerts {
+		if alert.Type == AlertZScoreDrop {
+			drop = &alerts[i]
+		}
+	}
+	if drop == nil {
+		t.Fatalf("no z-score drop alert, got %+v", alerts)
+	}
+
+	if drop.StudentID != "1" || drop.AssessmentID != secondReport.ID {
+		t.Errorf("alert is for student %q assessment %d, want student 1 assessment %d", drop.StudentID, drop.AssessmentID, secondReport.ID)
+	}
+	if !drop.PreviousAssessmentDate.Equal(firstReport.AssessmentDate) {
+		t.Errorf("previous assessment date %s, want %s", drop.PreviousAssessmentDate, firstReport.AssessmentDate)
+	}
+}
@@ -0,0 +1,356 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dashboardStatuses is the order nutritional statuses are listed in on the dashboard
+var dashboardStatuses = []NutritionalStatus{
+	SeverelyWasted, Wasted, Normal, Overweight, Obese, NutritionalStatusUnavailable,
+}
+
+// manualRoundPrefix starts the ID of the rounds that group assessments entered outside a bulk upload
+const manualRoundPrefix = "manual-"
+
+// NutritionRound is one assessment round of a school. Each bulk upload is a round, assessments entered
+// outside a bulk upload are grouped into a round per month. A student counts once in a round, with
+// their latest assessment in it.
+type NutritionRound struct {
+	ID          string
+	From        time.Time
+	To          time.Time
+	Assessments int
+}
+
+// nutritionRoundID is the round an assessment belongs to
+func nutritionRoundID(rec *ProjectedStudentHealth) string {
+	if rec.AssociatedBulkUploadID != "" {
+		return rec.AssociatedBulkUploadID
+	}
+	return manualRoundPrefix + rec.AssessmentDate.Format("2006-01")
+}
+
+// IsManual reports whether the round groups individually entered assessments
+func (nr NutritionRound) IsManual() bool {
+	return strings.HasPrefix(nr.ID, manualRoundPrefix)
+}
+
+func (nr NutritionRound) Label() string {
+	dates := nr.From.Format("2006-01-02")
+	if !nr.To.Equal(nr.From) {
+		dates += " to " + nr.To.Format("2006-01-02")
+	}
+	if nr.IsManual() {
+		dates = "Individually entered, " + dates
+	}
+	return fmt.Sprintf("%s (%d students)", dates, nr.Assessments)
+}
+
+// StatusCount is the number of assessments with a nutritional status
+type StatusCount struct {
+	Status string
+	Count  int
+}
+
+// NutritionSummary aggregates a set of assessments
+type NutritionSummary struct {
+	Assessed        int
+	Statuses        []StatusCount
+	zScoreSum       float64
+	ZScoreCount     int
+	Wasted          int // wasted or severely wasted
+	SeverelyWasted  int
+	StuntingChecked int // assessments with a height-for-age z-score
+	Stunted         int // stunted or severely stunted
+	SeverelyStunted int
+}
+
+// MeanBMIZScore is the mean BMI-for-age z-score of the assessments that have one
+func (ns NutritionSummary) MeanBMIZScore() (float64, bool) {
+	if ns.ZScoreCount == 0 {
+		return 0, false
+	}
+	return ns.zScoreSum / float64(ns.ZScoreCount), true
+}
+
+// WastingPrevalence is the percentage of assessed students who are wasted or severely wasted
+func (ns NutritionSummary) WastingPrevalence() float64 {
+	return percentage(ns.Wasted, ns.Assessed)
+}
+
+// StuntingPrevalence is the percentage of students with a height-for-age z-score who are stunted
+func (ns NutritionSummary) StuntingPrevalence() float64 {
+	return percentage(ns.Stunted, ns.StuntingChecked)
+}
+
+func percentage(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total) * 100
+}
+
+func summarizeNutrition(records []*ProjectedStudentHealth) NutritionSummary {
+	counts := make(map[string]int)
+	summary := NutritionSummary{}
+	for _, rec := range records {
+		summary.Assessed++
+		counts[rec.NutritionalStatus.String]++
+
+		switch rec.NutritionalStatus.String {
+		case SeverelyWasted.String():
+			summary.SeverelyWasted++
+			summary.Wasted++
+		case Wasted.String():
+			summary.Wasted++
+		}
+
+		if rec.BMIZScore.Valid {
+			summary.zScoreSum += rec.BMIZScore.Float64
+			summary.ZScoreCount++
+		}
+
+		if rec.StuntingStatus.Valid {
+			summary.StuntingChecked++
+			switch rec.StuntingStatus.String {
+			case SeverelyStunted.String():
+				summary.SeverelyStunted++
+				summary.Stunted++
+			case Stunted.String():
+				summary.Stunted++
+			}
+		}
+	}
+
+	for _, status := range dashboardStatuses {
+		summary.Statuses = append(summary.Statuses, StatusCount{Status: status.String(), Count: counts[status.String()]})
+		delete(counts, status.String())
+	}
+
+	// anything else, e.g. calculation errors, is listed after the known statuses
+	others := make([]string, 0, len(counts))
+	for status := range counts {
+		others = append(others, status)
+	}
+	sort.Strings(others)
+	for _, status := range others {
+		summary.Statuses = append(summary.Statuses, StatusCount{Status: status, Count: counts[status]})
+	}
+
+	return summary
+}
+
+// NutritionGroup is the summary of one sex or grade level
+type NutritionGroup struct {
+	Label   string
+	Summary NutritionSummary
+}
+
+func groupNutrition(records []*ProjectedStudentHealth, key func(*ProjectedStudentHealth) string, less func(a, b string) bool) []NutritionGroup {
+	byKey := make(map[string][]*ProjectedStudentHealth)
+	for _, rec := range records {
+		byKey[key(rec)] = append(byKey[key(rec)], rec)
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+
+	groups := make([]NutritionGroup, 0, len(keys))
+	for _, k := range keys {
+		groups = append(groups, NutritionGroup{Label: k, Summary: summarizeNutrition(byKey[k])})
+	}
+	return groups
+}
+
+func sexLabel(rec *ProjectedStudentHealth) string {
+	switch rec.Sex {
+	case "MALE":
+		return "Male"
+	case "FEMALE":
+		return "Female"
+	default:
+		return "Unknown"
+	}
+}
+
+// CohortComparison compares two rounds for the students assessed in both
+type CohortComparison struct {
+	From      NutritionRound
+	To        NutritionRound
+	Cohort    int
+	Before    NutritionSummary
+	After     NutritionSummary
+	Improved  int
+	Worsened  int
+	Unchanged int
+}
+
+// MeanBMIZScoreChange is the change in mean BMI-for-age z-score of the cohort between the rounds
+func (cc *CohortComparison) MeanBMIZScoreChange() (float64, bool) {
+	before, okBefore := cc.Before.MeanBMIZScore()
+	after, okAfter := cc.After.MeanBMIZScore()
+	return after - before, okBefore && okAfter
+}
+
+// statusSeverity ranks statuses by undernutrition, unavailable and error statuses can't be compared
+func statusSeverity(status string) (int, bool) {
+	switch status {
+	case SeverelyWasted.String():
+		return 2, true
+	case Wasted.String():
+		return 1, true
+	case Normal.String(), Overweight.String(), Obese.String():
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+func compareCohort(from, to NutritionRound, fromRecords, toRecords []*ProjectedStudentHealth) *CohortComparison {
+	before := make(map[string]*ProjectedStudentHealth)
+	for _, rec := range fromRecords {
+		before[rec.StudentID] = rec
+	}
+
+	cc := &CohortComparison{From: from, To: to}
+	var cohortBefore, cohortAfter []*ProjectedStudentHealth
+	for _, after := range toRecords {
+		prev, ok := before[after.StudentID]
+		if !ok {
+			continue
+		}
+
+		cohortBefore = append(cohortBefore, prev)
+		cohortAfter = append(cohortAfter, after)
+
+		prevSeverity, okPrev := statusSeverity(prev.NutritionalStatus.String)
+		afterSeverity, okAfter := statusSeverity(after.NutritionalStatus.String)
+		switch {
+		case !okPrev || !okAfter:
+		case afterSeverity < prevSeverity:
+			cc.Improved++
+		case afterSeverity > prevSeverity:
+			cc.Worsened++
+		default:
+			cc.Unchanged++
+		}
+	}
+
+	cc.Cohort = len(cohortAfter)
+	cc.Before = summarizeNutrition(cohortBefore)
+	cc.After = summarizeNutrition(cohortAfter)
+	return cc
+}
+
+// splitNutritionRounds groups the assessments into their rounds, keeping each student's latest
+// assessment in a round since an individually entered one can be redone within the month
+func splitNutritionRounds(records []*ProjectedStudentHealth) (map[string]*NutritionRound, map[string][]*ProjectedStudentHealth) {
+	type roundStudent struct{ roundID, studentID string }
+	latest := make(map[roundStudent]*ProjectedStudentHealth)
+	for _, rec := range records {
+		key := roundStudent{nutritionRoundID(rec), rec.StudentID}
+		if prev, ok := latest[key]; !ok || rec.AssessmentDate.After(prev.AssessmentDate) ||
+			(rec.AssessmentDate.Equal(prev.AssessmentDate) && rec.RecordID > prev.RecordID) {
+			latest[key] = rec
+		}
+	}
+
+	byRound := make(map[string][]*ProjectedStudentHealth)
+	rounds := make(map[string]*NutritionRound)
+	for _, rec := range records {
+		id := nutritionRoundID(rec)
+		if latest[roundStudent{id, rec.StudentID}] != rec {
+			continue
+		}
+		byRound[id] = append(byRound[id], rec)
+
+		round, ok := rounds[id]
+		if !ok {
+			round = &NutritionRound{ID: id, From: rec.AssessmentDate, To: rec.AssessmentDate}
+			rounds[id] = round
+		}
+		round.Assessments++
+		if rec.AssessmentDate.Before(round.From) {
+			round.From = rec.AssessmentDate
+		}
+		if rec.AssessmentDate.After(round.To) {
+			round.To = rec.AssessmentDate
+		}
+	}
+
+	return rounds, byRound
+}
+
+// NutritionDashboard summarises the nutrition of a school's students for an assessment round
+type NutritionDashboard struct {
+	SchoolID   string
+	Rounds     []NutritionRound // newest first
+	Round      *NutritionRound
+	Overall    NutritionSummary
+	BySex      []NutritionGroup
+	ByGrade    []NutritionGroup
+	Comparison *CohortComparison
+}
+
+// GetNutritionDashboard builds the dashboard of a school for a round, the latest when roundID is empty.
+// When compareRoundID is set the cohort assessed in both rounds is compared, from the earlier round to
+// the later one.
+func (s *StudentService) GetNutritionDashboard(ctx context.Context, schoolID, roundID, compareRoundID string) (*NutritionDashboard, error) {
+	records, err := s.repo.GetHealthAssessments(ctx, schoolID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health assessments: %w", err)
+	}
+
+	rounds, byRound := splitNutritionRounds(records)
+
+	dashboard := &NutritionDashboard{SchoolID: schoolID}
+	for _, round := range rounds {
+		dashboard.Rounds = append(dashboard.Rounds, *round)
+	}
+	sort.Slice(dashboard.Rounds, func(i, j int) bool { return dashboard.Rounds[i].From.After(dashboard.Rounds[j].From) })
+
+	if len(dashboard.Rounds) == 0 {
+		return dashboard, nil
+	}
+
+	selected := dashboard.Rounds[0]
+	if roundID != "" {
+		round, ok := rounds[roundID]
+		if !ok {
+			return nil, fmt.Errorf("assessment round %q not found for school %s", roundID, schoolID)
+		}
+		selected = *round
+	}
+	dashboard.Round = &selected
+
+	roundRecords := byRound[selected.ID]
+	dashboard.Overall = summarizeNutrition(roundRecords)
+	dashboard.BySex = groupNutrition(roundRecords, sexLabel, func(a, b string) bool { return a < b })
+	dashboard.ByGrade = groupNutrition(roundRecords, func(rec *ProjectedStudentHealth) string {
+		return fmt.Sprintf("%d", rec.GradeLevel)
+	}, func(a, b string) bool {
+		return len(a) < len(b) || (len(a) == len(b) && a < b)
+	})
+
+	if compareRoundID != "" && compareRoundID != selected.ID {
+		other, ok := rounds[compareRoundID]
+		if !ok {
+			return nil, fmt.Errorf("assessment round %q not found for school %s", compareRoundID, schoolID)
+		}
+
+		from, to := *other, selected
+		if from.From.After(to.From) {
+			from, to = to, from
+		}
+		dashboard.Comparison = compareCohort(from, to, byRound[from.ID], byRound[to.ID])
+	}
+
+	return dashboard, nil
+}
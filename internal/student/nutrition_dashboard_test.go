@@ -0,0 +1,56 @@
+package student
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestSplitNutritionRoundsCountsStudentsOnce(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC) }
+	assessment := func(studentID string, recordID uint64, date time.Time, uploadID, status string) *ProjectedStudentHealth {
+		return &ProjectedStudentHealth{
+			StudentID:              studentID,
+			RecordID:               recordID,
+			AssessmentDate:         date,
+			AssociatedBulkUploadID: uploadID,
+			NutritionalStatus:      sql.NullString{String: status, Valid: true},
+		}
+	}
+
+	rounds, byRound := splitNutritionRounds([]*ProjectedStudentHealth{
+		assessment("1", 1, day(1, 10), "upload-1", Wasted.String()),
+		assessment("2", 1, day(1, 10), "upload-1", Normal.String()),
+		// student 1 is reassessed twice in March, student 2 once in March and once in April
+		assessment("1", 2, day(3, 4), "", Wasted.String()),
+		assessment("1", 3, day(3, 18), "", Normal.String()),
+		assessment("2", 2, day(3, 11), "", Normal.String()),
+		assessment("2", 3, day(4, 2), "", Normal.String()),
+	})
+
+	if len(rounds) != 3 {
+		t.Fatalf("got %d rounds, want the upload and one for each month of individual entries", len(rounds))
+	}
+
+	march := rounds[manualRoundPrefix+"2024-03"]
+	switch {
+	case march == nil:
+		t.Fatal("no round for March")
+	case !march.IsManual():
+		t.Error("March round isn't manual")
+	case march.Assessments != 2:
+		t.Errorf("March round has %d assessments, want 2", march.Assessments)
+	case !march.From.Equal(day(3, 11)) || !march.To.Equal(day(3, 18)):
+		t.Errorf("March round spans %s to %s, want the latest assessments", march.From, march.To)
+	}
+
+	summary := summarizeNutrition(byRound[march.ID])
+	if summary.Assessed != 2 || summary.Wasted != 0 {
+		t.Errorf("March summary assessed %d with %d wasted, want 2 with student 1's latest status", summary.Assessed, summary.Wasted)
+	}
+
+	cc := compareCohort(*rounds["upload-1"], *march, byRound["upload-1"], byRound[march.ID])
+	if cc.Cohort != 2 || cc.Improved != 1 || cc.Unchanged != 1 {
+		t.Errorf("cohort %d improved %d unchanged %d, want 2, 1 and 1", cc.Cohort, cc.Improved, cc.Unchanged)
+	}
+}
@@ -0,0 +1,278 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// attendanceQuartiles is the number of buckets students are split into by feeding rate
+const attendanceQuartiles = 4
+
+// StudentOutcome is one student's feeding rate and the change of their grades and BMI-for-age z-score
+// over the report's date range
+type StudentOutcome struct {
+	StudentID   string
+	FeedingDays int
+	FeedingRate float64 // share of the school's feeding days the student was fed, 0 to 1
+
+	// GradeChange is the change between the first and last grade report in the range, in percentage
+	// points of the grading scale so reports on different scales can be compared
+	GradeChange    float64
+	HasGradeChange bool
+
+	BMIZScoreChange    float64
+	HasBMIZScoreChange bool
+}
+
+// OutcomeSummary is the summary statistics of a group of students
+type OutcomeSummary struct {
+	Label           string
+	Students        int
+	MinFeedingRate  float64
+	MaxFeedingRate  float64
+	MeanFeedingRate float64
+
+	GradeChanges    int // students with at least two grade reports in the range
+	MeanGradeChange float64
+
+	BMIZScoreChanges    int // students with at least two BMI-for-age z-scores in the range
+	MeanBMIZScoreChange float64
+}
+
+// OutcomeCorrelationReport relates how often a school's students were fed to their academic and
+// nutrition outcomes over a date range
+type OutcomeCorrelationReport struct {
+	SchoolID    string
+	From        time.Time
+	To          time.Time
+	FeedingDays int // days the school served meals in the range
+	Quartiles   []OutcomeSummary
+	Overall     OutcomeSummary
+
+	// Pearson correlation of the feeding rate with the grade and BMI-for-age z-score changes, unset when
+	// there are too few students or the values don't vary
+	GradeCorrelation        float64
+	HasGradeCorrelation     bool
+	BMIZScoreCorrelation    float64
+	HasBMIZScoreCorrelation bool
+
+	Students []StudentOutcome // ordered by feeding rate
+}
+
+// GetOutcomeCorrelation builds the correlation report of a school. Students are those enrolled at the
+// school and those fed there in the range, the feeding rate is relative to the days the school served
+// meals.
+func (s *StudentService) GetOutcomeCorrelation(ctx context.Context, schoolID string, from, to time.Time) (*OutcomeCorrelationReport, error) {
+	if schoolID == "" {
+		return nil, fmt.Errorf("school is required")
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("end date %s is before the start date %s", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	}
+
+	feedingDays, schoolDays, err := s.repo.CountFeedingDays(ctx, schoolID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feeding days: %w", err)
+	}
+
+	enrolled, err := s.repo.ListStudentsForSchool(ctx, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list students: %w", err)
+	}
+
+	grades, err := s.repo.GetGrades(ctx, schoolID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grades: %w", err)
+	}
+
+	health, err := s.repo.GetHealthAssessments(ctx, schoolID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health assessments: %w", err)
+	}
+
+	studentIDs := make(map[string]bool)
+	for _, st := range enrolled {
+		studentIDs[strconv.FormatUint(uint64(st.ID), 10)] = true
+	}
+	for id := range feedingDays {
+		studentIDs[id] = true
+	}
+
+	gradeChanges := gradeChangesByStudent(grades)
+	bmiChanges := bmiZScoreChangesByStudent(health)
+
+	report := &OutcomeCorrelationReport{SchoolID: schoolID, From: from, To: to, FeedingDays: schoolDays}
+	for id := range studentIDs {
+		outcome := StudentOutcome{StudentID: id, FeedingDays: feedingDays[id]}
+		if schoolDays > 0 {
+			outcome.FeedingRate = float64(outcome.FeedingDays) / float64(schoolDays)
+		}
+		outcome.GradeChange, outcome.HasGradeChange = gradeChanges[id]
+		outcome.BMIZScoreChange, outcome.HasBMIZScoreChange = bmiChanges[id]
+		report.Students = append(report.Students, outcome)
+	}
+
+	sort.Slice(report.Students, func(i, j int) bool {
+		a, b := report.Students[i], report.Students[j]
+		if a.FeedingRate != b.FeedingRate {
+			return a.FeedingRate < b.FeedingRate
+		}
+		return a.StudentID < b.StudentID
+	})
+
+	// students are split by rank so each quartile has the same number of students, give or take one
+	buckets := make([][]StudentOutcome, attendanceQuartiles)
+	for i, outcome := range report.Students {
+		q := i * attendanceQuartiles / len(report.Students)
+		buckets[q] = append(buckets[q], outcome)
+	}
+	for i, bucket := range buckets {
+		report.Quartiles = append(report.Quartiles, summarizeOutcomes(fmt.Sprintf("Q%d", i+1), bucket))
+	}
+	report.Overall = summarizeOutcomes("All students", report.Students)
+
+	var rates, changes, bmiRates, bmiDeltas []float64
+	for _, outcome := range report.Students {
+		if outcome.HasGradeChange {
+			rates = append(rates, outcome.FeedingRate)
+			changes = append(changes, outcome.GradeChange)
+		}
+		if outcome.HasBMIZScoreChange {
+			bmiRates = append(bmiRates, outcome.FeedingRate)
+			bmiDeltas = append(bmiDeltas, outcome.BMIZScoreChange)
+		}
+	}
+	report.GradeCorrelation, report.HasGradeCorrelation = pearson(rates, changes)
+	report.BMIZScoreCorrelation, report.HasBMIZScoreCorrelation = pearson(bmiRates, bmiDeltas)
+
+	return report, nil
+}
+
+func summarizeOutcomes(label string, outcomes []StudentOutcome) OutcomeSummary {
+	summary := OutcomeSummary{Label: label, Students: len(outcomes)}
+	var rateSum, gradeSum, bmiSum float64
+	for i, outcome := range outcomes {
+		if i == 0 || outcome.FeedingRate < summary.MinFeedingRate {
+			summary.MinFeedingRate = outcome.FeedingRate
+		}
+		if outcome.FeedingRate > summary.MaxFeedingRate {
+			summary.MaxFeedingRate = outcome.FeedingRate
+		}
+		rateSum += outcome.FeedingRate
+
+		if outcome.HasGradeChange {
+			summary.GradeChanges++
+			gradeSum += outcome.GradeChange
+		}
+		if outcome.HasBMIZScoreChange {
+			summary.BMIZScoreChanges++
+			bmiSum += outcome.BMIZScoreChange
+		}
+	}
+
+	if summary.Students > 0 {
+		summary.MeanFeedingRate = rateSum / float64(summary.Students)
+	}
+	if summary.GradeChanges > 0 {
+		summary.MeanGradeChange = gradeSum / float64(summary.GradeChanges)
+	}
+	if summary.BMIZScoreChanges > 0 {
+		summary.MeanBMIZScoreChange = bmiSum / float64(summary.BMIZScoreChanges)
+	}
+
+	return summary
+}
+
+// gradePercentage is the overall score of a projected report as a percentage of its grading scale
+func gradePercentage(rec *ProjectedStudentGrade) float64 {
+	score := float64(rec.Grade)
+	switch {
+	case rec.OverallAverage.Valid:
+		score = rec.OverallAverage.Float64
+	case len(rec.Subjects) > 0:
+		var sum float64
+		for _, s := range rec.Subjects {
+			sum += s.Score
+		}
+		score = sum / float64(len(rec.Subjects))
+	}
+
+	maxScore := rec.MaxScore
+	if maxScore <= 0 {
+		maxScore = float64(DefaultGradingScale().GetMaxScore())
+	}
+	return score / maxScore * 100
+}
+
+// gradeChangesByStudent is the change from each student's first to last grade report
+func gradeChangesByStudent(grades []*ProjectedStudentGrade) map[string]float64 {
+	byStudent := make(map[string][]*ProjectedStudentGrade)
+	for _, rec := range grades {
+		byStudent[rec.StudentID] = append(byStudent[rec.StudentID], rec)
+	}
+
+	changes := make(map[string]float64)
+	for id, recs := range byStudent {
+		if len(recs) < 2 {
+			continue
+		}
+		sort.SliceStable(recs, func(i, j int) bool { return recs[i].TestDate.Before(recs[j].TestDate) })
+		changes[id] = gradePercentage(recs[len(recs)-1]) - gradePercentage(recs[0])
+	}
+	return changes
+}
+
+// bmiZScoreChangesByStudent is the change from each student's first to last BMI-for-age z-score,
+// assessments outside the growth reference's age range are skipped
+func bmiZScoreChangesByStudent(health []*ProjectedStudentHealth) map[string]float64 {
+	byStudent := make(map[string][]*ProjectedStudentHealth)
+	for _, rec := range health {
+		if rec.BMIZScore.Valid {
+			byStudent[rec.StudentID] = append(byStudent[rec.StudentID], rec)
+		}
+	}
+
+	changes := make(map[string]float64)
+	for id, recs := range byStudent {
+		if len(recs) < 2 {
+			continue
+		}
+		sort.SliceStable(recs, func(i, j int) bool { return recs[i].AssessmentDate.Before(recs[j].AssessmentDate) })
+		changes[id] = recs[len(recs)-1].BMIZScore.Float64 - recs[0].BMIZScore.Float64
+	}
+	return changes
+}
+
+// pearson is the Pearson correlation coefficient of two series, false with fewer than three pairs or
+// when either series is constant
+func pearson(xs, ys []float64) (float64, bool) {
+	n := len(xs)
+	if n < 3 || n != len(ys) {
+		return 0, false
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+
+	return cov / math.Sqrt(varX*varY), true
+}
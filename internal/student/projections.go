@@ -0,0 +1,117 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+	"strconv"
+
+	src "github.com/Howard3/gosignal/sourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+// studentRowsWriter replaces the rows of one student in a projection table
+type studentRowsWriter func(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error
+
+// Projections returns the student read models for the projector. student_projections comes first since
+// the other tables reference it.
+func (r *sqlRepository) Projections() []infrastructure.Projection {
+	return []infrastructure.Projection{
+		r.projection("student_projections", func(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error {
+			return r.writeStudentProjection(ctx, tx, table, student)
+		}),
+		r.projection("student_feeding_projections", r.writeFeedingProjections),
+		r.projection("student_health_projections", r.writeHealthProjections),
+		r.projection("student_grade_projections", r.writeGradeProjections),
+		r.projection("student_transfer_projections", r.writeTransferProjections),
+		r.projection("student_attendance_projections", r.writeAttendanceProjections),
+		r.projection("student_sponsorship_projections", r.writeSponsorshipProjections),
+	}
+}
+
+// EventStream describes the student events and snapshots for the integrity check
+func (r *sqlRepository) EventStream() infrastructure.EventStream {
+	return infrastructure.EventStream{
+		EventTable:    "student_events",
+		New:           func() src.Aggregate { return &Aggregate{} },
+		SnapshotTable: "student_snapshots",
+		SnapshotMatches: func(agg src.Aggregate, snapshot []byte) (bool, error) {
+			var data eda.Student
+			if err := proto.Unmarshal(snapshot, &data); err != nil {
+				return false, err
+			}
+
+			return proto.Equal(agg.(*Aggregate).data, &data), nil
+		},
+	}
+}
+
+func (r *sqlRepository) projection(table string, write studentRowsWriter) infrastructure.Projection {
+	return infrastructure.Projection{
+		Table:      table,
+		EventTable: "student_events",
+		Project: func(ctx context.Context, aggregateID string) (infrastructure.ProjectionWriter, error) {
+			id, err := strconv.ParseUint(aggregateID, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid student ID %q: %w", aggregateID, err)
+			}
+
+			student, err := r.loadStudent(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			return func(ctx context.Context, tx *sql.Tx, table string) error {
+				return write(ctx, tx, table, student)
+			}, nil
+		},
+	}
+}
+
+// replaceStudentRows runs a writer against the live table in a transaction of its own, for the event
+// handlers
+func (r *sqlRepository) replaceStudentRows(table string, student *Aggregate, write studentRowsWriter) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = write(context.Background(), tx, table, student); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// TakeRequestedRebuilds returns and clears the rebuilds migrations asked for through
+// student_projection_updates
+func (r *sqlRepository) TakeRequestedRebuilds(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `DELETE FROM student_projection_updates RETURNING what`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take projection updates: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var what string
+		if err := rows.Scan(&what); err != nil {
+			return nil, fmt.Errorf("failed to scan projection update: %w", err)
+		}
+		names = append(names, what)
+	}
+
+	return names, rows.Err()
+}
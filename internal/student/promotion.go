@@ -0,0 +1,154 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"geevly/gen/go/eda"
+	"sort"
+	"time"
+
+	"github.com/Howard3/gosignal"
+)
+
+var ErrSchoolYearEndNotSet = fmt.Errorf("the school's year end date is not set")
+
+// PromotionCandidate is an active student of a school and the grade they'd be promoted to. SkipReason
+// is set when the student can't be promoted for the school year.
+type PromotionCandidate struct {
+	Student    *ProjectedStudent
+	FromGrade  uint64
+	ToGrade    uint64
+	SkipReason string
+}
+
+// PromotionPreview lists what an end of school year promotion would do, before any student is changed
+type PromotionPreview struct {
+	SchoolID      string
+	SchoolYearEnd time.Time
+	Candidates    []*PromotionCandidate
+}
+
+// Promotable returns the number of candidates that would be promoted
+func (pp *PromotionPreview) Promotable() int {
+	count := 0
+	for _, c := range pp.Candidates {
+		if c.SkipReason == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// PromotionResult summarises a promotion run
+type PromotionResult struct {
+	Promoted int
+	Excluded int
+	Skipped  int
+	Failed   map[uint]error
+}
+
+// schoolYearEndBefore returns the last school year end strictly before the day of at, promotion only
+// happens once the final day of the school year is over
+func schoolYearEndBefore(end *eda.School_MonthDay, at time.Time) time.Time {
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+
+	yearEnd := time.Date(at.Year(), time.Month(end.Month), int(end.Day), 0, 0, 0, 0, time.UTC)
+	if !yearEnd.Before(today) {
+		yearEnd = time.Date(at.Year()-1, time.Month(end.Month), int(end.Day), 0, 0, 0, 0, time.UTC)
+	}
+
+	return yearEnd
+}
+
+// PreviewPromotion lists the active students of a school and the grade each would move to for the
+// school year that most recently ended before at
+func (s *StudentService) PreviewPromotion(ctx context.Context, schoolID string, at time.Time) (*PromotionPreview, error) {
+	end, err := s.acl.GetSchoolYearEnd(ctx, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get school year end: %w", err)
+	}
+	if end == nil || end.Month == 0 || end.Day == 0 {
+		return nil, ErrSchoolYearEndNotSet
+	}
+
+	students, err := s.repo.ListStudentsForSchool(ctx, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list students: %w", err)
+	}
+
+	preview := &PromotionPreview{
+		SchoolID:      schoolID,
+		SchoolYearEnd: schoolYearEndBefore(end, at),
+	}
+	yearEnd := timeToDate(preview.SchoolYearEnd)
+
+	for _, st := range students {
+		agg, err := s.repo.loadStudent(ctx, uint64(st.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load student %d: %w", st.ID, err)
+		}
+
+		candidate := &PromotionCandidate{
+			Student:   st,
+			FromGrade: agg.data.GradeLevel,
+			ToGrade:   agg.data.GradeLevel + 1,
+		}
+		if err := agg.CanPromote(yearEnd); err != nil {
+			candidate.SkipReason = err.Error()
+		}
+
+		preview.Candidates = append(preview.Candidates, candidate)
+	}
+
+	sort.Slice(preview.Candidates, func(i, j int) bool {
+		a, b := preview.Candidates[i], preview.Candidates[j]
+		if a.FromGrade != b.FromGrade {
+			return a.FromGrade < b.FromGrade
+		}
+		if a.Student.LastName != b.Student.LastName {
+			return a.Student.LastName < b.Student.LastName
+		}
+		return a.Student.FirstName < b.Student.FirstName
+	})
+
+	return preview, nil
+}
+
+// PromoteStudents promotes the active students of a school for the school year that most recently ended
+// before at, except the excluded students. A student failing to promote doesn't stop the others, the
+// failures are returned in the result.
+func (s *StudentService) PromoteStudents(ctx context.Context, schoolID string, at time.Time, excluded map[uint64]bool) (*PromotionResult, error) {
+	preview, err := s.PreviewPromotion(ctx, schoolID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PromotionResult{Failed: make(map[uint]error)}
+	yearEnd := timeToDate(preview.SchoolYearEnd)
+
+	for _, c := range preview.Candidates {
+		switch {
+		case excluded[uint64(c.Student.ID)]:
+			result.Excluded++
+			continue
+		case c.SkipReason != "":
+			result.Skipped++
+			continue
+		}
+
+		_, err := s.withAgg(ctx, uint64(c.Student.ID), func(agg *Aggregate) (*gosignal.Event, error) {
+			return agg.PromoteGrade(&eda.Student_PromoteGrade{
+				SchoolYearEnd: yearEnd,
+				Version:       agg.Version,
+			})
+		})
+		if err != nil {
+			result.Failed[c.Student.ID] = err
+			continue
+		}
+
+		result.Promoted++
+	}
+
+	return result, nil
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"embed"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"geevly/gen/go/eda"
 	"geevly/internal/infrastructure"
@@ -83,14 +85,45 @@ type Repository interface {
 	upsertSponsorshipProjections(student *Aggregate) error
 	GetAllSponsorshipsByID(ctx context.Context, sponsorID string) ([]*SponsorshipProjection, error)
 	CountFeedingEventsInPeriod(ctx context.Context, studentID string, startDate, endDate time.Time) (int64, error)
+	CountFeedingDays(ctx context.Context, schoolID string, from, to time.Time) (map[string]int, int, error)
 	GetFeedingEventsForSponsorships(ctx context.Context, sponsorships []*SponsorshipProjection, limit, page uint) ([]*SponsorFeedingEvent, int64, error)
 	GetAllCurrentSponsorships(ctx context.Context) ([]*SponsorshipProjection, error)
+	GetSponsorshipsForSchool(ctx context.Context, schoolID string) ([]*SponsorshipProjection, error)
+	GetSponsorshipsOfSchoolStudents(ctx context.Context, schoolID string) ([]*SponsorshipProjection, error)
 	GetAllFeedingEvents(ctx context.Context, limit, page uint) ([]*SponsorFeedingEvent, int64, error)
 	getStudentByStudentAndSchoolID(ctx context.Context, studentSchoolID, schoolID string) (uint64, error)
 	updateAllHealthProjectionsForStudent(*Aggregate) error
 	updateAllGradeProjectionsForStudent(*Aggregate) error
 	GetHealthAssessments(ctx context.Context, schoolID string, from, to time.Time) ([]*ProjectedStudentHealth, error)
 	GetGrades(ctx context.Context, schoolID string, from, to time.Time) ([]*ProjectedStudentGrade, error)
+	updateAllFeedingProjectionsForStudent(*Aggregate) error
+	upsertTransferProjections(*Aggregate) error
+	upsertAttendanceProjections(*Aggregate) error
+	GetAttendance(ctx context.Context, schoolID string, from, to time.Time) ([]*ProjectedAttendance, error)
+	GetAttendedNotFed(ctx context.Context, schoolID string, from, to time.Time) ([]*AttendedNotFed, error)
+	GetFeedingReviewQueue(ctx context.Context, query FeedingReviewQuery) ([]*FeedingReviewItem, error)
+	GetFeederReviewStats(ctx context.Context, schoolID string, from, to time.Time) ([]*FeederReviewStats, error)
+	GetTransfers(ctx context.Context, from, to time.Time) ([]*ProjectedTransfer, error)
+	listStudentsForDuplicateCheck(ctx context.Context) ([]*ProjectedStudent, error)
+	listDismissedDuplicates(ctx context.Context) (map[DuplicatePair]bool, error)
+	dismissDuplicate(ctx context.Context, pair DuplicatePair) error
+	insertNutritionAlerts(ctx context.Context, alerts []NutritionAlert) error
+	deleteNutritionAlertsForUpload(ctx context.Context, studentID, bulkUploadID string) error
+	deleteNutritionAlertsForAssessment(ctx context.Context, studentID string, assessmentID uint64, unacknowledgedOnly bool) error
+	ListNutritionAlerts(ctx context.Context, filter NutritionAlertFilter) ([]*NutritionAlert, error)
+	acknowledgeNutritionAlert(ctx context.Context, id uint64, acknowledgedBy string) error
+	listSchoolsWithActiveStudents(ctx context.Context) ([]string, error)
+	listSchoolFeedingDays(ctx context.Context, schoolID string, before time.Time) ([]time.Time, error)
+	listMissedMealCandidates(ctx context.Context, schoolID string, notFedSince time.Time) ([]missedMealCandidate, error)
+	upsertFollowUpTask(ctx context.Context, task *FollowUpTask) (bool, error)
+	closeFollowUpsFedSince(ctx context.Context) error
+	listFollowUpTasks(ctx context.Context, filter FollowUpFilter) ([]*FollowUpTask, error)
+	getFollowUpTask(ctx context.Context, id uint64) (*FollowUpTask, error)
+	setFollowUpStatus(ctx context.Context, id uint64, status FollowUpStatus, user string) error
+	addFollowUpNote(ctx context.Context, id uint64, note, author string) error
+	Projections() []infrastructure.Projection
+	EventStream() infrastructure.EventStream
+	TakeRequestedRebuilds(ctx context.Context) ([]string, error)
 }
 
 // source schema:
@@ -110,6 +143,11 @@ type ProjectedFeedingEvent struct {
 	SchoolID        string
 	FeedingDateTime time.Time
 	FeedingImageID  string
+	FedBy           string
+	ReviewStatus    string
+	ReviewReason    string
+	ReviewedBy      string
+	ReviewedAt      time.Time
 }
 
 // sqlRepository is the implementation of the Repository interface using SQL
@@ -134,53 +172,40 @@ func NewRepository(conn infrastructure.SQLConnection, queue gosignal.Queue) Repo
 
 	repo.queue = queue
 	repo.setupEventSourcing(conn)
-	repo.updateProjections(context.Background()) // TODO: consider bubbling up the context further
 
 	return repo
 }
 
-func (r *sqlRepository) updateProjections(ctx context.Context) {
-	slog.Info("updating projections")
-	whatToUpdate := r.checkForProjectionUpdates()
-	if len(whatToUpdate) == 0 {
-		slog.Info("no projections to update")
-		return
-	}
-
-	toClear := []string{}
-
-	for _, v := range whatToUpdate {
-		known := true
-		switch v {
-		case "student_projections":
-			r.updateStudentProjections()
-		case "student_feeding_projections":
-			go r.rebuildStudentFeedingProjections(ctx)
-		case "student_health_projections":
-			go r.rebuildStudentHealthProjections(ctx)
-		case "student_grade_projections":
-			go r.rebuildStudentGradeProjections(ctx)
-		default:
-			slog.Error("unknown projection to update", "projection", v)
-			known = false
-		}
-
-		if known {
-			toClear = append(toClear, v)
-		}
-	}
-
-	r.clearProjectionUpdates(toClear)
-}
-
 type ProjectedStudentGrade struct {
 	StudentID              string
+	RecordID               uint64 // the grade report's ID on the student
 	SchoolID               string
 	TestDate               time.Time
 	Grade                  int
 	SchoolYear             sql.NullString
 	GradingPeriod          sql.NullString
 	AssociatedBulkUploadID string
+	OverallAverage         sql.NullFloat64 // null when the school only reported subject scores
+	Subjects               []SubjectScore
+	GradingScale           string
+	MaxScore               float64
+	PassingScore           float64
+}
+
+// SubjectScore is the score of one subject on a projected grade report
+type SubjectScore struct {
+	Subject string  `json:"subject"`
+	Score   float64 `json:"score"`
+}
+
+// Score returns the score of a subject, false when the report doesn't include it
+func (p *ProjectedStudentGrade) Score(subject string) (float64, bool) {
+	for _, s := range p.Subjects {
+		if s.Subject == subject {
+			return s.Score, true
+		}
+	}
+	return 0, false
 }
 
 // GetGrades returns grades filtered by school and date range
@@ -199,7 +224,9 @@ func (r *sqlRepository) GetGrades(ctx context.Context, schoolID string, from, to
 		wheres = append(wheres, "date(test_date) <= date(?)")
 		args = append(args, to.Format("2006-01-02"))
 	}
-	q := "SELECT student_id, school_id, test_date, grade, school_year, grading_period, associated_bulk_upload_id FROM student_grade_projections"
+	q := `SELECT student_id, record_id, school_id, test_date, grade, school_year, grading_period, associated_bulk_upload_id,
+		overall_average, subject_scores, grading_scale, max_score, passing_score
+		FROM student_grade_projections`
 	if len(wheres) > 0 {
 		q += " WHERE " + strings.Join(wheres, " AND ")
 	}
@@ -213,9 +240,14 @@ func (r *sqlRepository) GetGrades(ctx context.Context, schoolID string, from, to
 	for rows.Next() {
 		var p ProjectedStudentGrade
 		var testDate sql.NullString
-		if err := rows.Scan(&p.StudentID, &p.SchoolID, &testDate, &p.Grade, &p.SchoolYear, &p.GradingPeriod, &p.AssociatedBulkUploadID); err != nil {
+		var subjects string
+		if err := rows.Scan(&p.StudentID, &p.RecordID, &p.SchoolID, &testDate, &p.Grade, &p.SchoolYear, &p.GradingPeriod, &p.AssociatedBulkUploadID,
+			&p.OverallAverage, &subjects, &p.GradingScale, &p.MaxScore, &p.PassingScore); err != nil {
 			return nil, fmt.Errorf("scan grade: %w", err)
 		}
+		if err := json.Unmarshal([]byte(subjects), &p.Subjects); err != nil {
+			return nil, fmt.Errorf("unmarshal subject scores: %w", err)
+		}
 		// parse test date
 		dateStr := testDate.String
 		parsed := time.Time{}
@@ -252,89 +284,44 @@ func (r *sqlRepository) convertGradesToProjections(student *Aggregate) []Project
 		testDate := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
 		projection := ProjectedStudentGrade{
 			StudentID:              student.GetID(),
-			SchoolID:               student.data.SchoolId,
+			RecordID:               grade.Id,
+			SchoolID:               student.SchoolAt(testDate),
 			TestDate:               testDate,
 			Grade:                  int(grade.Grade),
 			SchoolYear:             sql.NullString{String: grade.SchoolYear, Valid: true},
 			GradingPeriod:          sql.NullString{String: grade.GradingPeriod, Valid: true},
 			AssociatedBulkUploadID: grade.AssociatedBulkUploadId,
+			OverallAverage:         sql.NullFloat64{Float64: float64(grade.GetOverallAverage()), Valid: grade.OverallAverage != nil},
+			Subjects:               make([]SubjectScore, 0, len(grade.Subjects)),
+		}
+		scale := GradingScaleOf(grade)
+		projection.GradingScale = scale.Name
+		projection.MaxScore = float64(scale.MaxScore)
+		projection.PassingScore = float64(scale.PassingScore)
+		for _, subject := range grade.Subjects {
+			projection.Subjects = append(projection.Subjects, SubjectScore{Subject: subject.Subject, Score: float64(subject.Score)})
 		}
 		projections = append(projections, projection)
 	}
 	return projections
 }
 
-func (r *sqlRepository) rebuildStudentGradeProjections(ctx context.Context) error {
-	slog.Info("updating student grade projections")
-
-	ids := r.getUniqueIDsForAggregates()
-	projections := make([]ProjectedStudentGrade, 0)
-
-	slog.Info("grade import: loading students ", "count", len(ids))
-
-	for _, id := range ids {
-		slog.Info("grade import: loading student", "id", id, "projection_count", len(projections))
-		student, err := r.loadStudent(ctx, id)
-		if err != nil {
-			slog.Error("failed to get student by ID", "id", id, "error", err)
-			continue
-		}
-
-		projections = append(projections, r.convertGradesToProjections(student)...)
-	}
-
-	slog.Info("grade import: projections", "count", len(projections))
+func (r *sqlRepository) insertStudentGradeProjection(tx *sql.Tx, table string, pge ProjectedStudentGrade) error {
+	query := fmt.Sprintf(`INSERT INTO %s
+		(student_id, record_id, school_id, test_date, grade, school_year, grading_period, associated_bulk_upload_id,
+		overall_average, subject_scores, grading_scale, max_score, passing_score)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (student_id, record_id) DO NOTHING;
+	`, table)
 
-	tx, err := r.db.BeginTx(ctx, nil)
+	subjects, err := json.Marshal(pge.Subjects)
 	if err != nil {
-		panic(fmt.Errorf("failed to begin transaction: %w", err))
-	}
-
-	defer func() {
-		slog.Info("cleaning up transaction", "error", err)
-
-		if err != nil {
-			slog.Error("rolling back transaction", "error", err)
-			tx.Rollback()
-			return
-		} else {
-			slog.Info("committing transaction")
-			tx.Commit()
-		}
-	}()
-
-	slog.Info("deleting student grade projections")
-	query := `DELETE FROM student_grade_projections`
-	if _, err = tx.Exec(query); err != nil {
-		panic(fmt.Errorf("failed to delete student grade projections: %w", err))
-	}
-
-	slog.Info("Updating student grade projections", "count", len(projections))
-
-	for _, projection := range projections {
-		slog.Info("Updating student grade projection", "student_id", projection.StudentID)
-		if err = r.insertStudentGradeProjection(tx, projection); err != nil {
-			slog.Error("failed to insert student grade projection", "student_id", projection.StudentID, "error", err)
-			continue
-		}
+		return fmt.Errorf("failed to marshal subject scores: %w", err)
 	}
 
-	if err = tx.Commit(); err != nil {
-		panic(fmt.Errorf("failed to commit transaction: %w", err))
-	}
-
-	return nil
-}
-
-func (r *sqlRepository) insertStudentGradeProjection(tx *sql.Tx, pge ProjectedStudentGrade) error {
-	query := `INSERT INTO student_grade_projections
-		(student_id, school_id, test_date, grade, school_year, grading_period, associated_bulk_upload_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT (student_id, associated_bulk_upload_id) DO NOTHING;
-	`
-
-	slog.Info("inserting student grade projection", "student_id", pge.StudentID, "school_id", pge.SchoolID, "test_date", pge.TestDate, "grade", pge.Grade, "school_year", pge.SchoolYear, "grading_period", pge.GradingPeriod, "associated_bulk_upload_id", pge.AssociatedBulkUploadID)
-	_, err := tx.Exec(query, pge.StudentID, pge.SchoolID, pge.TestDate, pge.Grade, pge.SchoolYear, pge.GradingPeriod, pge.AssociatedBulkUploadID)
+	slog.Info("inserting student grade projection", "student_id", pge.StudentID, "record_id", pge.RecordID, "school_id", pge.SchoolID, "test_date", pge.TestDate, "grade", pge.Grade, "school_year", pge.SchoolYear, "grading_period", pge.GradingPeriod, "associated_bulk_upload_id", pge.AssociatedBulkUploadID)
+	_, err = tx.Exec(query, pge.StudentID, pge.RecordID, pge.SchoolID, pge.TestDate, pge.Grade, pge.SchoolYear, pge.GradingPeriod, pge.AssociatedBulkUploadID,
+		pge.OverallAverage, string(subjects), pge.GradingScale, pge.MaxScore, pge.PassingScore)
 	if err != nil {
 		return fmt.Errorf("failed to insert student grade projection: %w", err)
 	}
@@ -343,49 +330,43 @@ func (r *sqlRepository) insertStudentGradeProjection(tx *sql.Tx, pge ProjectedSt
 }
 
 func (r *sqlRepository) updateAllGradeProjectionsForStudent(student *Aggregate) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	defer func() {
-		if err != nil {
-			slog.Error("rolling back transaction", "error", err)
-			tx.Rollback()
-		} else {
-			tx.Commit()
-		}
-	}()
+	return r.replaceStudentRows("student_grade_projections", student, r.writeGradeProjections)
+}
 
-	slog.Info("deleting student grade projections", "student_id", student.GetID())
-	query := `DELETE FROM student_grade_projections WHERE student_id = ?`
-	if _, err = tx.Exec(query, student.GetID()); err != nil {
+// writeGradeProjections - replaces the grade rows of a student in table
+func (r *sqlRepository) writeGradeProjections(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE student_id = ?`, table)
+	if _, err := tx.ExecContext(ctx, query, student.GetID()); err != nil {
 		return fmt.Errorf("failed to delete student grade projections: %w", err)
 	}
 
-	slog.Info("inserting student grade projections", "student_id", student.GetID())
-	projections := r.convertGradesToProjections(student)
-	for _, projection := range projections {
-		if err = r.insertStudentGradeProjection(tx, projection); err != nil {
-			return fmt.Errorf("failed to insert student grade projection: %w", err)
+	for _, projection := range r.convertGradesToProjections(student) {
+		if err := r.insertStudentGradeProjection(tx, table, projection); err != nil {
+			return err
 		}
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return nil
 }
 
 type ProjectedStudentHealth struct {
 	StudentID              string
+	RecordID               uint64 // the health assessment's ID on the student
 	SchoolID               string
 	AssessmentDate         time.Time
 	HeightCM               float32
 	WeightKG               float32
 	BMI                    sql.NullFloat64
 	NutritionalStatus      sql.NullString
+	BMIZScore              sql.NullFloat64
+	HeightForAgeZScore     sql.NullFloat64
+	StuntingStatus         sql.NullString
+	WeightForAgeZScore     sql.NullFloat64
+	MuacMM                 sql.NullFloat64
+	Oedema                 bool
+	WeightForHeightZScore  sql.NullFloat64
+	Sex                    string
+	GradeLevel             uint64 // the student's current grade, not the grade at the time of the assessment
 	AssociatedBulkUploadID string
 }
 
@@ -406,7 +387,10 @@ func (r *sqlRepository) GetHealthAssessments(ctx context.Context, schoolID strin
 		wheres = append(wheres, "date(assessment_date) <= date(?)")
 		args = append(args, to.Format("2006-01-02"))
 	}
-	q := "SELECT student_id, school_id, assessment_date, height_cm, weight_kg, bmi, nutritional_status, associated_bulk_upload_id FROM student_health_projections"
+	q := `SELECT student_id, record_id, school_id, assessment_date, height_cm, weight_kg, bmi, nutritional_status,
+		bmi_z_score, height_for_age_z_score, stunting_status, weight_for_age_z_score,
+		muac_mm, oedema, weight_for_height_z_score, sex, grade_level, associated_bulk_upload_id
+		FROM student_health_projections`
 	if len(wheres) > 0 {
 		q += " WHERE " + strings.Join(wheres, " AND ")
 	}
@@ -421,7 +405,9 @@ func (r *sqlRepository) GetHealthAssessments(ctx context.Context, schoolID strin
 		var p ProjectedStudentHealth
 		var assessmentDate sql.NullString
 		var height, weight float64
-		if err := rows.Scan(&p.StudentID, &p.SchoolID, &assessmentDate, &height, &weight, &p.BMI, &p.NutritionalStatus, &p.AssociatedBulkUploadID); err != nil {
+		if err := rows.Scan(&p.StudentID, &p.RecordID, &p.SchoolID, &assessmentDate, &height, &weight, &p.BMI, &p.NutritionalStatus,
+			&p.BMIZScore, &p.HeightForAgeZScore, &p.StuntingStatus, &p.WeightForAgeZScore,
+			&p.MuacMM, &p.Oedema, &p.WeightForHeightZScore, &p.Sex, &p.GradeLevel, &p.AssociatedBulkUploadID); err != nil {
 			return nil, fmt.Errorf("scan health assessment: %w", err)
 		}
 		// Parse assessment date with multiple layouts
@@ -457,15 +443,35 @@ func (r *sqlRepository) GetHealthAssessments(ctx context.Context, schoolID strin
 func (r *sqlRepository) convertHealthReportsToProjections(student *Aggregate) []ProjectedStudentHealth {
 	projections := make([]ProjectedStudentHealth, 0)
 	for _, report := range student.GetHealthAssessments() {
+		status := report.NutritionalStatus()
+		bmiForAge := report.BMIForAge()
+		heightForAge := report.HeightForAge()
+		weightForAge := report.WeightForAge()
+
+		var weightForHeight GrowthAssessment
+		if report.IsUnder5() {
+			weightForHeight = report.WeightForHeight()
+		}
+
 		projection := ProjectedStudentHealth{
 			StudentID:              student.GetID(),
-			SchoolID:               student.data.SchoolId,
+			RecordID:               report.ID,
+			SchoolID:               student.SchoolAt(report.AssessmentDate),
 			AssessmentDate:         report.AssessmentDate,
 			HeightCM:               report.HeightCm,
 			WeightKG:               report.WeightKg,
 			AssociatedBulkUploadID: report.AssociatedBulkUploadId,
-			NutritionalStatus:      sql.NullString{String: report.NutritionalStatus().String()},
+			NutritionalStatus:      sql.NullString{String: status.Status.String()},
 			BMI:                    sql.NullFloat64{Float64: float64(report.BMI()), Valid: true},
+			BMIZScore:              sql.NullFloat64{Float64: bmiForAge.ZScore, Valid: bmiForAge.HasZScore},
+			HeightForAgeZScore:     sql.NullFloat64{Float64: heightForAge.ZScore, Valid: heightForAge.HasZScore},
+			StuntingStatus:         sql.NullString{String: heightForAge.Status.String(), Valid: heightForAge.HasZScore},
+			WeightForAgeZScore:     sql.NullFloat64{Float64: weightForAge.ZScore, Valid: weightForAge.HasZScore},
+			MuacMM:                 sql.NullFloat64{Float64: float64(report.MuacMm), Valid: report.MuacMm > 0},
+			Oedema:                 report.Oedema,
+			WeightForHeightZScore:  sql.NullFloat64{Float64: weightForHeight.ZScore, Valid: weightForHeight.HasZScore},
+			Sex:                    student.data.Sex.String(),
+			GradeLevel:             student.data.GradeLevel,
 		}
 
 		projections = append(projections, projection)
@@ -474,79 +480,23 @@ func (r *sqlRepository) convertHealthReportsToProjections(student *Aggregate) []
 	return projections
 }
 
-func (r *sqlRepository) rebuildStudentHealthProjections(ctx context.Context) (err error) {
-	slog.Info("updating student health projections")
-
-	ids := r.getUniqueIDsForAggregates()
-	projections := make([]ProjectedStudentHealth, 0)
-
-	slog.Info("loading student health reports", "count", len(ids))
-
-	for _, id := range ids {
-		student, err := r.loadStudent(ctx, id)
-		if err != nil {
-			return fmt.Errorf("failed to load student: %w", err)
-		}
-
-		newProjections := r.convertHealthReportsToProjections(student)
-		projections = append(projections, newProjections...)
-	}
-
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		panic(fmt.Errorf("failed to begin transaction: %w", err))
-	}
-
-	defer func() {
-		slog.Info("cleaning up transaction", "error", err)
-
-		if err != nil {
-			slog.Error("rolling back transaction", "error", err)
-			tx.Rollback()
-			return
-		} else {
-			slog.Info("committing transaction")
-			tx.Commit()
-		}
-	}()
-
-	slog.Info("deleting student health projections")
-	query := `DELETE FROM student_health_projections`
-	if _, err = tx.Exec(query); err != nil {
-		panic(fmt.Errorf("failed to delete student health projections: %w", err))
-	}
-
-	slog.Info("Updating student health projections", "count", len(projections))
-
-	for _, projection := range projections {
-		slog.Info("Updating student health projection", "student_id", projection.StudentID)
-
-		if err = r.insertStudentHealthProjection(tx, projection); err != nil {
-			slog.Error("failed to insert student health projection", "student_id", projection.StudentID, "error", err)
-			return fmt.Errorf("failed to insert student health projection: %w", err)
-		}
-	}
-
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func (r *sqlRepository) insertStudentHealthProjection(tx *sql.Tx, phe ProjectedStudentHealth) error {
-	query := `INSERT INTO student_health_projections
-		(student_id, school_id, assessment_date, height_cm, weight_kg, bmi, nutritional_status, associated_bulk_upload_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT (student_id, associated_bulk_upload_id) DO NOTHING;
-	`
+func (r *sqlRepository) insertStudentHealthProjection(tx *sql.Tx, table string, phe ProjectedStudentHealth) error {
+	query := fmt.Sprintf(`INSERT INTO %s
+		(student_id, record_id, school_id, assessment_date, height_cm, weight_kg, bmi, nutritional_status,
+		bmi_z_score, height_for_age_z_score, stunting_status, weight_for_age_z_score,
+		muac_mm, oedema, weight_for_height_z_score, sex, grade_level, associated_bulk_upload_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (student_id, record_id) DO NOTHING;
+	`, table)
 
 	bmi := phe.BMI.Float64
 	nutritionalStatus := phe.NutritionalStatus.String
 
-	slog.Info("inserting student health projection", "student_id", phe.StudentID, "school_id", phe.SchoolID, "assessment_date", phe.AssessmentDate, "height_cm", phe.HeightCM, "weight_kg", phe.WeightKG, "bmi", bmi, "nutritional_status", nutritionalStatus, "associated_bulk_upload_id", phe.AssociatedBulkUploadID)
+	slog.Info("inserting student health projection", "student_id", phe.StudentID, "record_id", phe.RecordID, "school_id", phe.SchoolID, "assessment_date", phe.AssessmentDate, "height_cm", phe.HeightCM, "weight_kg", phe.WeightKG, "bmi", bmi, "nutritional_status", nutritionalStatus, "associated_bulk_upload_id", phe.AssociatedBulkUploadID)
 
-	_, err := tx.Exec(query, phe.StudentID, phe.SchoolID, phe.AssessmentDate, phe.HeightCM, phe.WeightKG, bmi, nutritionalStatus, phe.AssociatedBulkUploadID)
+	_, err := tx.Exec(query, phe.StudentID, phe.RecordID, phe.SchoolID, phe.AssessmentDate, phe.HeightCM, phe.WeightKG, bmi, nutritionalStatus,
+		phe.BMIZScore, phe.HeightForAgeZScore, phe.StuntingStatus, phe.WeightForAgeZScore,
+		phe.MuacMM, phe.Oedema, phe.WeightForHeightZScore, phe.Sex, phe.GradeLevel, phe.AssociatedBulkUploadID)
 	if err != nil {
 		return fmt.Errorf("failed to insert student health projection: %w", err)
 	}
@@ -555,112 +505,45 @@ func (r *sqlRepository) insertStudentHealthProjection(tx *sql.Tx, phe ProjectedS
 }
 
 func (r *sqlRepository) updateAllHealthProjectionsForStudent(student *Aggregate) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	defer func() {
-		if err != nil {
-			slog.Error("rolling back transaction", "error", err)
-			tx.Rollback()
-		} else {
-			tx.Commit()
-		}
-	}()
+	return r.replaceStudentRows("student_health_projections", student, r.writeHealthProjections)
+}
 
-	// first delete all health projections for the student
-	slog.Info("deleting student health projections", "student_id", student.GetID())
-	query := `DELETE FROM student_health_projections WHERE student_id = ?`
-	if _, err = tx.Exec(query, student.GetID()); err != nil {
+// writeHealthProjections - replaces the health assessment rows of a student in table
+func (r *sqlRepository) writeHealthProjections(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE student_id = ?`, table)
+	if _, err := tx.ExecContext(ctx, query, student.GetID()); err != nil {
 		return fmt.Errorf("failed to delete student health projections: %w", err)
 	}
 
-	slog.Info("inserting student health projections", "student_id", student.GetID())
-	projections := r.convertHealthReportsToProjections(student)
-	for _, projection := range projections {
-		if err = r.insertStudentHealthProjection(tx, projection); err != nil {
-			return fmt.Errorf("failed to insert student health projection: %w", err)
+	for _, projection := range r.convertHealthReportsToProjections(student) {
+		if err := r.insertStudentHealthProjection(tx, table, projection); err != nil {
+			return err
 		}
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
+	return r.matchLegacyNutritionAlerts(ctx, tx, student)
 }
 
-// rebuildStudentFeedingProjections - completely rebuilds the student feeding projections
-// NOTE: this is a very expensive operation and should only be used in exceptional circumstances
-func (r *sqlRepository) rebuildStudentFeedingProjections(ctx context.Context) (err error) {
-	slog.Info("updating student feeding projections")
-
-	ids := r.getUniqueIDsForAggregates()
-	projections := make([]ProjectedFeedingEvent, 0)
-
-	slog.Info("loading student feeding reports", "count", len(ids))
-
-	// populate the projections; this is necessary to do prior to starting the transaction because
-	// the loadStudent method may make it's own inserts (snapshotting); transactions will be blocking.
-	for _, id := range ids {
-		student, err := r.loadStudent(ctx, id)
-		if err != nil {
-			return fmt.Errorf("failed to load student: %w", err)
-		}
-
-		for _, report := range student.data.FeedingReport {
-			// BUG: doesn't consider the school at the time of feeding, just the current student state.
-			timestamp := time.Unix(int64(report.UnixTimestamp), 0)
-			projection := ProjectedFeedingEvent{
-				StudentID:       student.GetID(),
-				FeedingID:       report.GetUnixTimestamp(),
-				SchoolID:        student.data.SchoolId,
-				FeedingDateTime: timestamp,
-				FeedingImageID:  report.FileId,
-			}
-
-			projections = append(projections, projection)
-		}
-	}
-
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		panic(fmt.Errorf("failed to begin transaction: %w", err))
+// matchLegacyNutritionAlerts sets the assessment ID of alerts raised before assessments had one, from the
+// assessment of the same date and bulk upload
+func (r *sqlRepository) matchLegacyNutritionAlerts(ctx context.Context, tx *sql.Tx, student *Aggregate) error {
+	var legacy bool
+	query := `SELECT EXISTS (SELECT 1 FROM student_nutrition_alerts WHERE student_id = ? AND assessment_id IS NULL)`
+	if err := tx.QueryRowContext(ctx, query, student.GetID()).Scan(&legacy); err != nil {
+		return fmt.Errorf("failed to look up legacy nutrition alerts: %w", err)
 	}
 
-	defer func() {
-		if err != nil {
-			slog.Error("rolling back transaction", "error", err)
-			tx.Rollback()
-		} else {
-			tx.Commit()
-		}
-	}()
-
-	slog.Info("deleting student feeding projections")
-
-	// delete all feeding projections since we're doing a full update
-	query := `DELETE FROM student_feeding_projections`
-	if _, err := tx.Exec(query); err != nil {
-		panic(fmt.Errorf("failed to delete student feeding projections: %w", err))
+	if !legacy {
+		return nil
 	}
 
-	slog.Info("Updating student feeding projections", "count", len(ids))
-
-	for _, projection := range projections {
-		slog.Info("inserting feeding projection", "student_id", projection.StudentID, "feeding_id", projection.FeedingID, "feeding_image_id", projection.FeedingImageID)
-		if err := r.insertFeedingProjection(tx, projection); err != nil {
-			return fmt.Errorf("failed to insert feeding projection: %w", err)
-		}
-
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %w", err)
-		}
-
-		tx, err = r.db.BeginTx(ctx, nil)
+	query = `UPDATE student_nutrition_alerts SET assessment_id = ?
+		WHERE student_id = ? AND assessment_id IS NULL AND assessment_date = ? AND associated_bulk_upload_id = ?`
+	for _, report := range student.GetHealthAssessments() {
+		_, err := tx.ExecContext(ctx, query, report.ID, student.GetID(), report.AssessmentDate.Format("2006-01-02"),
+			report.AssociatedBulkUploadId)
 		if err != nil {
-			return fmt.Errorf("failed to begin transaction: %w", err)
+			return fmt.Errorf("failed to match legacy nutrition alerts: %w", err)
 		}
 	}
 
@@ -668,14 +551,21 @@ func (r *sqlRepository) rebuildStudentFeedingProjections(ctx context.Context) (e
 }
 
 // insertFeedingProjection - inserts a feeding projection into the database
-func (r *sqlRepository) insertFeedingProjection(tx *sql.Tx, pfe ProjectedFeedingEvent) error {
-	query := `INSERT INTO student_feeding_projections
-		(student_id, feeding_id, school_id, feeding_timestamp, feeding_image_id)
-		VALUES (?, ?, ?, ?, ?)
+func (r *sqlRepository) insertFeedingProjection(tx *sql.Tx, table string, pfe ProjectedFeedingEvent) error {
+	query := fmt.Sprintf(`INSERT INTO %s
+		(student_id, feeding_id, school_id, feeding_timestamp, feeding_image_id, fed_by, review_status, review_reason,
+			reviewed_by, reviewed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT (student_id, feeding_id) DO NOTHING;
-	`
+	`, table)
+
+	var reviewedAt sql.NullTime
+	if !pfe.ReviewedAt.IsZero() {
+		reviewedAt = sql.NullTime{Time: pfe.ReviewedAt, Valid: true}
+	}
 
-	_, err := tx.Exec(query, pfe.StudentID, pfe.FeedingID, pfe.SchoolID, pfe.FeedingDateTime, pfe.FeedingImageID)
+	_, err := tx.Exec(query, pfe.StudentID, pfe.FeedingID, pfe.SchoolID, pfe.FeedingDateTime, pfe.FeedingImageID,
+		pfe.FedBy, pfe.ReviewStatus, pfe.ReviewReason, pfe.ReviewedBy, reviewedAt)
 	if err != nil {
 		return fmt.Errorf("failed to insert student feeding projection: %w", err)
 	}
@@ -698,91 +588,68 @@ func (r *sqlRepository) upsertFeedingEventProjection(student *Aggregate) error {
 		}
 	}()
 
-	// BUG: doesn't consider the school at the time of feeding, just the current student state.
-	pfe := ProjectedFeedingEvent{
-		StudentID:       student.GetID(),
-		FeedingID:       student.data.FeedingReport[len(student.data.FeedingReport)-1].GetUnixTimestamp(),
-		SchoolID:        student.data.SchoolId,
-		FeedingDateTime: time.Unix(int64(student.data.FeedingReport[len(student.data.FeedingReport)-1].UnixTimestamp), 0),
-		FeedingImageID:  student.data.FeedingReport[len(student.data.FeedingReport)-1].FileId,
-	}
+	lastFeeding := student.data.FeedingReport[len(student.data.FeedingReport)-1]
+	pfe := r.convertFeedingToProjection(student, lastFeeding)
 
-	if err := r.insertFeedingProjection(tx, pfe); err != nil {
+	if err := r.insertFeedingProjection(tx, "student_feeding_projections", pfe); err != nil {
 		return fmt.Errorf("failed to insert feeding projection: %w", err)
 	}
 
 	return nil
 }
 
-func (r *sqlRepository) updateStudentProjections() {
-	slog.Info("updating student projections")
-
-	ids := r.getUniqueIDsForAggregates()
-	for _, id := range ids {
-		slog.Info("updating student projection for ID", "id", id)
-		student, err := r.loadStudent(context.Background(), id)
-		if err != nil {
-			panic(fmt.Errorf("failed to load student: %w", err))
-		}
-
-		if err := r.upsertStudent(student); err != nil {
-			panic(fmt.Errorf("failed to upsert student: %w", err))
-		}
+// convertFeedingsToProjections - converts all feedings on a student aggregate to projections, attributed
+// to the school the student was enrolled in on the day of the feeding
+func (r *sqlRepository) convertFeedingsToProjections(student *Aggregate) []ProjectedFeedingEvent {
+	projections := make([]ProjectedFeedingEvent, 0, len(student.data.FeedingReport))
+	for _, report := range student.data.FeedingReport {
+		projections = append(projections, r.convertFeedingToProjection(student, report))
 	}
-}
 
-func (r *sqlRepository) getUniqueIDsForAggregates() []uint64 {
-	ids := []uint64{}
-	query := `SELECT DISTINCT aggregate_id FROM student_events`
+	return projections
+}
 
-	rows, err := r.db.Query(query)
-	if err != nil {
-		panic(fmt.Errorf("get unique IDs for aggregates: %w", err))
+func (r *sqlRepository) convertFeedingToProjection(student *Aggregate, report *eda.Student_Feeding_Event) ProjectedFeedingEvent {
+	timestamp := time.Unix(int64(report.UnixTimestamp), 0)
+	pfe := ProjectedFeedingEvent{
+		StudentID:       student.GetID(),
+		FeedingID:       report.GetUnixTimestamp(),
+		SchoolID:        student.SchoolAt(timestamp),
+		FeedingDateTime: timestamp,
+		FeedingImageID:  report.FileId,
+		FedBy:           report.FedBy,
+		ReviewStatus:    report.GetReview().GetStatus().String(),
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var id uint64
-		if err := rows.Scan(&id); err != nil {
-			panic(fmt.Errorf("scan unique ID: %w", err))
-		}
-
-		ids = append(ids, id)
+	if review := report.GetReview(); review != nil {
+		pfe.ReviewReason = review.Reason
+		pfe.ReviewedBy = review.ReviewedBy
+		pfe.ReviewedAt = time.Unix(int64(review.ReviewedAt), 0)
 	}
 
-	return ids
+	return pfe
 }
 
-func (r *sqlRepository) checkForProjectionUpdates() []string {
-	whatToUpdate := []string{}
-	query := `SELECT what from student_projection_updates`
+// updateAllFeedingProjectionsForStudent - replaces the feeding projections of a single student, used when
+// a change to the enrollment history moves feedings between schools
+func (r *sqlRepository) updateAllFeedingProjectionsForStudent(student *Aggregate) error {
+	return r.replaceStudentRows("student_feeding_projections", student, r.writeFeedingProjections)
+}
 
-	rows, err := r.db.Query(query)
-	if err != nil {
-		panic(fmt.Errorf("failed to check for projection updates: %w", err))
+// writeFeedingProjections - replaces the feeding rows of a student in table
+func (r *sqlRepository) writeFeedingProjections(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE student_id = ?`, table)
+	if _, err := tx.ExecContext(ctx, query, student.GetID()); err != nil {
+		return fmt.Errorf("failed to delete student feeding projections: %w", err)
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var what string
-		if err := rows.Scan(&what); err != nil {
-			panic(fmt.Errorf("failed to scan projection update: %w", err))
+	for _, projection := range r.convertFeedingsToProjections(student) {
+		if err := r.insertFeedingProjection(tx, table, projection); err != nil {
+			return err
 		}
-
-		whatToUpdate = append(whatToUpdate, what)
 	}
 
-	return whatToUpdate
-}
-
-func (r *sqlRepository) clearProjectionUpdates(toClear []string) {
-	slog.Info("clearing projection updates", "what", toClear)
-
-	for _, what := range toClear {
-		query := `DELETE FROM student_projection_updates WHERE what = ?`
-		if _, err := r.db.Exec(query, what); err != nil {
-		}
-	}
+	return nil
 }
 
 // GetNewID - returns a new unique ID
@@ -1004,23 +871,22 @@ func (r *sqlRepository) parseDate(datestr string) time.Time {
 	return t
 }
 
-func (r *sqlRepository) deleteStudentProjection(id string) error {
-	query := `DELETE FROM student_projections WHERE id = :id`
-	_, err := r.db.Exec(query, sql.Named("id", id))
-	if err != nil {
-		return fmt.Errorf("when deleting student projection: %w", err)
-	}
-	return nil
-}
-
 // upsertStudent - persists the student projection to the database
 func (r *sqlRepository) upsertStudent(agg *Aggregate) error {
-	if agg.data.IsDeleted {
-		// ensure these projections are deleted
-		return r.deleteStudentProjection(agg.GetID())
+	return r.writeStudentProjection(context.Background(), r.db, "student_projections", agg)
+}
+
+// writeStudentProjection - upserts the student's row in table, deleted and merged students are removed
+func (r *sqlRepository) writeStudentProjection(ctx context.Context, db infrastructure.Execer, table string, agg *Aggregate) error {
+	if agg.data.IsDeleted || agg.IsMerged() {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE id = :id`, table)
+		if _, err := db.ExecContext(ctx, query, sql.Named("id", agg.GetID())); err != nil {
+			return fmt.Errorf("when deleting student projection: %w", err)
+		}
+		return nil
 	}
 
-	query := `INSERT INTO student_projections
+	query := fmt.Sprintf(`INSERT INTO %s
 		(id, first_name, last_name, school_id, date_of_birth, version, active, student_id, age, grade, eligible_for_sponsorship, max_sponsorship_date)
 		VALUES (:id, :first_name, :last_name, :school_id, :date_of_birth, :version, :active, :student_id, :age, :grade, :eligible_for_sponsorship, :max_sponsorship_date)
 		ON CONFLICT (id) DO UPDATE SET
@@ -1036,7 +902,7 @@ func (r *sqlRepository) upsertStudent(agg *Aggregate) error {
 			eligible_for_sponsorship = excluded.eligible_for_sponsorship,
 			max_sponsorship_date = excluded.max_sponsorship_date,
 			updated_at = CURRENT_TIMESTAMP;
-	`
+	`, table)
 
 	active := agg.data.Status == eda.Student_ACTIVE
 	dob := agg.data.DateOfBirth
@@ -1050,7 +916,8 @@ func (r *sqlRepository) upsertStudent(agg *Aggregate) error {
 		dateOfEnrollment.Valid = true
 	}
 
-	_, err := r.db.Exec(
+	_, err := db.ExecContext(
+		ctx,
 		query,
 		sql.Named("id", agg.ID),
 		sql.Named("first_name", agg.data.FirstName),
@@ -1411,8 +1278,7 @@ func (r *sqlRepository) GetCurrentSponsorships(ctx context.Context, sponsorID st
 }
 
 // upsertSponsorshipProjections - persists the sponsorship projections to the database
-func (r *sqlRepository) upsertSponsorshipProjections(student *Aggregate) error {
-	// Start a transaction
+func (r *sqlRepository) upsertSponsorshipProjections(student *Aggregate) (err error) {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -1423,12 +1289,33 @@ func (r *sqlRepository) upsertSponsorshipProjections(student *Aggregate) error {
 		}
 	}()
 
+	if err = r.writeSponsorshipProjections(context.Background(), tx, "student_sponsorship_projections", student); err != nil {
+		return err
+	}
+
+	if maxSponsorshipDate := student.MaxSponsorshipDate(); maxSponsorshipDate != nil && !student.IsMerged() {
+		_, err = tx.Exec("UPDATE student_projections SET max_sponsorship_date = ? WHERE id = ?", maxSponsorshipDate, student.GetID())
+		if err != nil {
+			return fmt.Errorf("failed to update max sponsorship date: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// writeSponsorshipProjections - replaces the sponsorship rows of a student in table
+func (r *sqlRepository) writeSponsorshipProjections(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error {
 	// Delete existing sponsorships for this student
-	_, err = tx.Exec("DELETE FROM student_sponsorship_projections WHERE student_id = ?", student.GetID())
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE student_id = ?", table), student.GetID())
 	if err != nil {
 		return fmt.Errorf("failed to delete existing sponsorships: %w", err)
 	}
 
+	// a merged student's sponsorships now belong to the surviving student
+	if student.IsMerged() {
+		return nil
+	}
+
 	// Insert all sponsorships from history
 	for _, sponsorship := range student.GetStudent().GetSponsorshipHistory() {
 		startDate := time.Date(
@@ -1446,25 +1333,20 @@ func (r *sqlRepository) upsertSponsorshipProjections(student *Aggregate) error {
 			time.UTC,
 		)
 
-		_, err = tx.Exec(`
-			INSERT INTO student_sponsorship_projections
-			(student_id, sponsor_id, start_date, end_date)
-			VALUES (?, ?, ?, ?)
-		`, student.GetID(), sponsorship.SponsorId, startDate, endDate)
+		// payments are attributed to the school the student was at when the sponsorship started
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s
+			(student_id, sponsor_id, start_date, end_date, payment_id, payment_amount, school_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, table), student.GetID(), sponsorship.SponsorId, startDate, endDate, sponsorship.PaymentId, sponsorship.PaymentAmount,
+			student.SchoolAt(startDate))
 
 		if err != nil {
 			return fmt.Errorf("failed to insert sponsorship: %w", err)
 		}
 	}
 
-	if maxSponsorshipDate := student.MaxSponsorshipDate(); maxSponsorshipDate != nil {
-		_, err = tx.Exec("UPDATE student_projections SET max_sponsorship_date = ? WHERE id = ?", maxSponsorshipDate, student.GetID())
-		if err != nil {
-			return fmt.Errorf("failed to update max sponsorship date: %w", err)
-		}
-	}
-
-	return tx.Commit()
+	return nil
 }
 
 func (r *sqlRepository) GetAllSponsorshipsByID(ctx context.Context, sponsorID string) ([]*SponsorshipProjection, error) {
@@ -1519,6 +1401,42 @@ func (r *sqlRepository) CountFeedingEventsInPeriod(ctx context.Context, studentI
 	return count, nil
 }
 
+// CountFeedingDays returns the number of days each student was fed at a school in a date range, and the
+// number of days the school served meals at all
+func (r *sqlRepository) CountFeedingDays(ctx context.Context, schoolID string, from, to time.Time) (map[string]int, int, error) {
+	where := `WHERE school_id = ?
+		AND date(feeding_timestamp) >= date(?)
+		AND date(feeding_timestamp) <= date(?)`
+	args := []any{schoolID, from.Format("2006-01-02"), to.Format("2006-01-02")}
+
+	var schoolDays int
+	query := `SELECT COUNT(DISTINCT date(feeding_timestamp)) FROM student_feeding_projections ` + where
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&schoolDays); err != nil {
+		return nil, 0, fmt.Errorf("failed to count school feeding days: %w", err)
+	}
+
+	query = `SELECT student_id, COUNT(DISTINCT date(feeding_timestamp))
+		FROM student_feeding_projections ` + where + `
+		GROUP BY student_id`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count student feeding days: %w", err)
+	}
+	defer rows.Close()
+
+	days := make(map[string]int)
+	for rows.Next() {
+		var studentID string
+		var count int
+		if err := rows.Scan(&studentID, &count); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan student feeding days: %w", err)
+		}
+		days[studentID] = count
+	}
+
+	return days, schoolDays, rows.Err()
+}
+
 func (r *sqlRepository) GetAllFeedingEvents(ctx context.Context, limit, page uint) ([]*SponsorFeedingEvent, int64, error) {
 	// Get total count first
 	countQuery := `
@@ -1706,3 +1624,913 @@ func (r *sqlRepository) GetAllCurrentSponsorships(ctx context.Context) ([]*Spons
 
 	return sponsorships, nil
 }
+
+// GetSponsorshipsForSchool - returns all sponsorships, including payments, that started while the student
+// was enrolled in a school
+func (r *sqlRepository) GetSponsorshipsForSchool(ctx context.Context, schoolID string) ([]*SponsorshipProjection, error) {
+	return r.querySchoolSponsorships(ctx, `
+		SELECT ssp.student_id, ssp.sponsor_id, ssp.start_date, ssp.end_date,
+			   ssp.payment_id, ssp.payment_amount
+		FROM student_sponsorship_projections ssp
+		WHERE ssp.school_id = ?
+		ORDER BY ssp.start_date ASC
+	`, schoolID)
+}
+
+// GetSponsorshipsOfSchoolStudents - returns all sponsorships of the students currently enrolled in a school,
+// wherever they were enrolled when the sponsorship started
+func (r *sqlRepository) GetSponsorshipsOfSchoolStudents(ctx context.Context, schoolID string) ([]*SponsorshipProjection, error) {
+	return r.querySchoolSponsorships(ctx, `
+		SELECT ssp.student_id, ssp.sponsor_id, ssp.start_date, ssp.end_date,
+			   ssp.payment_id, ssp.payment_amount
+		FROM student_sponsorship_projections ssp
+		JOIN student_projections sp ON sp.id = ssp.student_id
+		WHERE sp.school_id = ?
+		ORDER BY ssp.start_date ASC
+	`, schoolID)
+}
+
+func (r *sqlRepository) querySchoolSponsorships(ctx context.Context, query, schoolID string) ([]*SponsorshipProjection, error) {
+	rows, err := r.db.QueryContext(ctx, query, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sponsorships for school: %w", err)
+	}
+	defer rows.Close()
+
+	var sponsorships []*SponsorshipProjection
+	for rows.Next() {
+		sp := &SponsorshipProjection{}
+		var startDate, endDate, paymentID sql.NullString
+		var paymentAmount sql.NullFloat64
+
+		if err := rows.Scan(
+			&sp.StudentID,
+			&sp.SponsorID,
+			&startDate,
+			&endDate,
+			&paymentID,
+			&paymentAmount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan sponsorship: %w", err)
+		}
+
+		sp.StartDate = r.parseDate(startDate.String)
+		sp.EndDate = r.parseDate(endDate.String)
+		sp.PaymentID = paymentID.String
+		sp.PaymentAmount = paymentAmount.Float64
+
+		sponsorships = append(sponsorships, sp)
+	}
+
+	return sponsorships, rows.Err()
+}
+
+// ProjectedTransfer is a transfer of a student between two schools
+type ProjectedTransfer struct {
+	StudentID    string
+	FromSchoolID string
+	ToSchoolID   string
+	TransferDate time.Time
+	Reason       string
+}
+
+// convertTransfersToProjections - converts the transfer history of a student aggregate to projections
+func (r *sqlRepository) convertTransfersToProjections(student *Aggregate) []ProjectedTransfer {
+	projections := make([]ProjectedTransfer, 0, len(student.data.TransferHistory))
+	for _, transfer := range student.data.TransferHistory {
+		projections = append(projections, ProjectedTransfer{
+			StudentID:    student.GetID(),
+			FromSchoolID: transfer.FromSchoolId,
+			ToSchoolID:   transfer.ToSchoolId,
+			TransferDate: dateToTime(transfer.TransferDate),
+			Reason:       transfer.Reason,
+		})
+	}
+
+	return projections
+}
+
+func (r *sqlRepository) insertTransferProjection(tx *sql.Tx, table string, pt ProjectedTransfer) error {
+	query := fmt.Sprintf(`INSERT INTO %s
+		(student_id, from_school_id, to_school_id, transfer_date, reason)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (student_id, transfer_date) DO UPDATE SET
+			from_school_id = excluded.from_school_id,
+			to_school_id = excluded.to_school_id,
+			reason = excluded.reason;
+	`, table)
+
+	_, err := tx.Exec(query, pt.StudentID, pt.FromSchoolID, pt.ToSchoolID, pt.TransferDate.Format("2006-01-02"), pt.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to insert student transfer projection: %w", err)
+	}
+
+	return nil
+}
+
+// upsertTransferProjections - replaces the transfer projections of a single student
+func (r *sqlRepository) upsertTransferProjections(student *Aggregate) error {
+	return r.replaceStudentRows("student_transfer_projections", student, r.writeTransferProjections)
+}
+
+// writeTransferProjections - replaces the transfer rows of a student in table
+func (r *sqlRepository) writeTransferProjections(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE student_id = ?`, table)
+	if _, err := tx.ExecContext(ctx, query, student.GetID()); err != nil {
+		return fmt.Errorf("failed to delete student transfer projections: %w", err)
+	}
+
+	for _, projection := range r.convertTransfersToProjections(student) {
+		if err := r.insertTransferProjection(tx, table, projection); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTransfers returns the transfers within the date range, newest first. Zero times leave the range open.
+func (r *sqlRepository) GetTransfers(ctx context.Context, from, to time.Time) ([]*ProjectedTransfer, error) {
+	args := []any{}
+	wheres := []string{}
+	if !from.IsZero() {
+		wheres = append(wheres, "date(transfer_date) >= date(?)")
+		args = append(args, from.Format("2006-01-02"))
+	}
+	if !to.IsZero() {
+		wheres = append(wheres, "date(transfer_date) <= date(?)")
+		args = append(args, to.Format("2006-01-02"))
+	}
+
+	q := "SELECT student_id, from_school_id, to_school_id, transfer_date, reason FROM student_transfer_projections"
+	if len(wheres) > 0 {
+		q += " WHERE " + strings.Join(wheres, " AND ")
+	}
+	q += " ORDER BY transfer_date DESC"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*ProjectedTransfer
+	for rows.Next() {
+		var p ProjectedTransfer
+		var transferDate string
+		if err := rows.Scan(&p.StudentID, &p.FromSchoolID, &p.ToSchoolID, &transferDate, &p.Reason); err != nil {
+			return nil, fmt.Errorf("scan transfer: %w", err)
+		}
+		p.TransferDate = r.parseDate(transferDate)
+		res = append(res, &p)
+	}
+
+	return res, rows.Err()
+}
+
+// ProjectedAttendance is a student's attendance on a school day
+type ProjectedAttendance struct {
+	StudentID              string
+	SchoolID               string
+	AttendanceDate         time.Time
+	Status                 string
+	Reason                 string
+	RecordedBy             string
+	AssociatedBulkUploadID string
+}
+
+// convertAttendanceToProjections - converts the attendance of a student aggregate to projections
+func (r *sqlRepository) convertAttendanceToProjections(student *Aggregate) []ProjectedAttendance {
+	projections := make([]ProjectedAttendance, 0, len(student.data.Attendance))
+	for _, a := range student.data.Attendance {
+		projections = append(projections, ProjectedAttendance{
+			StudentID:              student.GetID(),
+			SchoolID:               a.SchoolId,
+			AttendanceDate:         dateToTime(a.Date),
+			Status:                 a.Status.String(),
+			Reason:                 a.Reason,
+			RecordedBy:             a.RecordedBy,
+			AssociatedBulkUploadID: a.AssociatedBulkUploadId,
+		})
+	}
+
+	return projections
+}
+
+func (r *sqlRepository) insertAttendanceProjection(tx *sql.Tx, table string, pa ProjectedAttendance) error {
+	query := fmt.Sprintf(`INSERT INTO %s
+		(student_id, school_id, attendance_date, status, reason, recorded_by, associated_bulk_upload_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (student_id, attendance_date) DO UPDATE SET
+			school_id = excluded.school_id,
+			status = excluded.status,
+			reason = excluded.reason,
+			recorded_by = excluded.recorded_by,
+			associated_bulk_upload_id = excluded.associated_bulk_upload_id;
+	`, table)
+
+	_, err := tx.Exec(query, pa.StudentID, pa.SchoolID, pa.AttendanceDate.Format("2006-01-02"), pa.Status, pa.Reason, pa.RecordedBy, pa.AssociatedBulkUploadID)
+	if err != nil {
+		return fmt.Errorf("failed to insert student attendance projection: %w", err)
+	}
+
+	return nil
+}
+
+// upsertAttendanceProjections - replaces the attendance projections of a single student
+func (r *sqlRepository) upsertAttendanceProjections(student *Aggregate) error {
+	return r.replaceStudentRows("student_attendance_projections", student, r.writeAttendanceProjections)
+}
+
+// writeAttendanceProjections - replaces the attendance rows of a student in table
+func (r *sqlRepository) writeAttendanceProjections(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE student_id = ?`, table)
+	if _, err := tx.ExecContext(ctx, query, student.GetID()); err != nil {
+		return fmt.Errorf("failed to delete student attendance projections: %w", err)
+	}
+
+	for _, projection := range r.convertAttendanceToProjections(student) {
+		if err := r.insertAttendanceProjection(tx, table, projection); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAttendance returns the attendance of a school's students within the date range, newest first
+func (r *sqlRepository) GetAttendance(ctx context.Context, schoolID string, from, to time.Time) ([]*ProjectedAttendance, error) {
+	args := []any{}
+	wheres := []string{}
+	if schoolID != "" {
+		wheres = append(wheres, "school_id = ?")
+		args = append(args, schoolID)
+	}
+	if !from.IsZero() {
+		wheres = append(wheres, "date(attendance_date) >= date(?)")
+		args = append(args, from.Format("2006-01-02"))
+	}
+	if !to.IsZero() {
+		wheres = append(wheres, "date(attendance_date) <= date(?)")
+		args = append(args, to.Format("2006-01-02"))
+	}
+
+	q := `SELECT student_id, school_id, attendance_date, status, reason, recorded_by, associated_bulk_upload_id
+		FROM student_attendance_projections`
+	if len(wheres) > 0 {
+		q += " WHERE " + strings.Join(wheres, " AND ")
+	}
+	q += " ORDER BY attendance_date DESC"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query attendance: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*ProjectedAttendance
+	for rows.Next() {
+		var p ProjectedAttendance
+		var attendanceDate string
+		if err := rows.Scan(&p.StudentID, &p.SchoolID, &attendanceDate, &p.Status, &p.Reason, &p.RecordedBy, &p.AssociatedBulkUploadID); err != nil {
+			return nil, fmt.Errorf("scan attendance: %w", err)
+		}
+		p.AttendanceDate = r.parseDate(attendanceDate)
+		res = append(res, &p)
+	}
+
+	return res, rows.Err()
+}
+
+// AttendedNotFed is a day a student was marked present at school without a feeding being recorded
+type AttendedNotFed struct {
+	StudentID       string
+	StudentSchoolID string
+	FirstName       string
+	LastName        string
+	SchoolID        string
+	Date            time.Time
+	RecordedBy      string
+}
+
+// GetAttendedNotFed returns the days students were present but not fed within the date range, newest first
+func (r *sqlRepository) GetAttendedNotFed(ctx context.Context, schoolID string, from, to time.Time) ([]*AttendedNotFed, error) {
+	args := []any{eda.Student_Attendance_PRESENT.String(), from.Format("2006-01-02"), to.Format("2006-01-02")}
+	q := `SELECT sap.student_id, COALESCE(sp.student_id, ''), sp.first_name, sp.last_name, sap.school_id,
+			sap.attendance_date, sap.recorded_by
+		FROM student_attendance_projections sap
+		JOIN student_projections sp ON sp.id = sap.student_id
+		WHERE sap.status = ?
+		AND date(sap.attendance_date) >= date(?)
+		AND date(sap.attendance_date) <= date(?)
+		AND NOT EXISTS (
+			SELECT 1 FROM student_feeding_projections sfp
+			WHERE sfp.student_id = sap.student_id
+			AND date(sfp.feeding_timestamp) = date(sap.attendance_date)
+		)`
+	if schoolID != "" {
+		q += " AND sap.school_id = ?"
+		args = append(args, schoolID)
+	}
+	q += " ORDER BY sap.attendance_date DESC, sp.last_name, sp.first_name"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query attended not fed: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*AttendedNotFed
+	for rows.Next() {
+		var p AttendedNotFed
+		var attendanceDate string
+		if err := rows.Scan(&p.StudentID, &p.StudentSchoolID, &p.FirstName, &p.LastName, &p.SchoolID, &attendanceDate, &p.RecordedBy); err != nil {
+			return nil, fmt.Errorf("scan attended not fed: %w", err)
+		}
+		p.Date = r.parseDate(attendanceDate)
+		res = append(res, &p)
+	}
+
+	return res, rows.Err()
+}
+
+// GetFeedingReviewQueue samples unreviewed feedings with a proof photo, picking up to PerSchool at random
+// from each school so a busy school doesn't crowd out the others
+func (r *sqlRepository) GetFeedingReviewQueue(ctx context.Context, query FeedingReviewQuery) ([]*FeedingReviewItem, error) {
+	args := []any{eda.Student_Feeding_Review_UNREVIEWED.String(), query.From.Format("2006-01-02"), query.To.Format("2006-01-02")}
+	q := `SELECT sfp.student_id, COALESCE(sp.student_id, '') AS student_school_id, sp.first_name, sp.last_name,
+			sfp.school_id, sfp.feeding_id, sfp.feeding_timestamp, sfp.feeding_image_id, sfp.fed_by,
+			ROW_NUMBER() OVER (PARTITION BY sfp.school_id ORDER BY RANDOM()) AS pick
+		FROM student_feeding_projections sfp
+		JOIN student_projections sp ON sp.id = sfp.student_id
+		WHERE sfp.review_status = ?
+		AND COALESCE(sfp.feeding_image_id, '') != ''
+		AND date(sfp.feeding_timestamp) >= date(?)
+		AND date(sfp.feeding_timestamp) <= date(?)`
+	if query.SchoolID != "" {
+		q += " AND sfp.school_id = ?"
+		args = append(args, query.SchoolID)
+	}
+	q = `SELECT student_id, student_school_id, first_name, last_name, school_id, feeding_id, feeding_image_id, fed_by
+		FROM (` + q + `)
+		WHERE pick <= ?
+		ORDER BY school_id, feeding_timestamp`
+	args = append(args, query.PerSchool)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query feeding review queue: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*FeedingReviewItem
+	for rows.Next() {
+		var item FeedingReviewItem
+		if err := rows.Scan(&item.StudentID, &item.StudentSchoolID, &item.FirstName, &item.LastName, &item.SchoolID,
+			&item.FeedingID, &item.FileID, &item.FedBy); err != nil {
+			return nil, fmt.Errorf("scan feeding review item: %w", err)
+		}
+		// the feeding ID is the feeding's unix timestamp
+		item.FedAt = time.Unix(int64(item.FeedingID), 0)
+		res = append(res, &item)
+	}
+
+	return res, rows.Err()
+}
+
+// GetFeederReviewStats counts the feedings of each feeder within the date range and how their photos were reviewed
+func (r *sqlRepository) GetFeederReviewStats(ctx context.Context, schoolID string, from, to time.Time) ([]*FeederReviewStats, error) {
+	args := []any{
+		eda.Student_Feeding_Review_UNREVIEWED.String(),
+		eda.Student_Feeding_Review_VERIFIED.String(),
+		eda.Student_Feeding_Review_SUSPICIOUS.String(),
+		eda.Student_Feeding_Review_INVALID.String(),
+		from.Format("2006-01-02"), to.Format("2006-01-02"),
+	}
+	q := `SELECT fed_by, COUNT(*),
+			SUM(CASE WHEN review_status != ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN review_status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN review_status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN review_status = ? THEN 1 ELSE 0 END)
+		FROM student_feeding_projections
+		WHERE date(feeding_timestamp) >= date(?)
+		AND date(feeding_timestamp) <= date(?)`
+	if schoolID != "" {
+		q += " AND school_id = ?"
+		args = append(args, schoolID)
+	}
+	q += " GROUP BY fed_by"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query feeder review stats: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*FeederReviewStats
+	for rows.Next() {
+		var st FeederReviewStats
+		if err := rows.Scan(&st.FedBy, &st.Feedings, &st.Reviewed, &st.Verified, &st.Suspicious, &st.Invalid); err != nil {
+			return nil, fmt.Errorf("scan feeder review stats: %w", err)
+		}
+		res = append(res, &st)
+	}
+
+	return res, rows.Err()
+}
+
+// listStudentsForDuplicateCheck returns every projected student with the fields used to detect duplicates
+func (r *sqlRepository) listStudentsForDuplicateCheck(ctx context.Context) ([]*ProjectedStudent, error) {
+	query := `SELECT id, first_name, last_name, school_id, date_of_birth, COALESCE(student_id, ''), active
+		FROM student_projections`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query students: %w", err)
+	}
+	defer rows.Close()
+
+	students := make([]*ProjectedStudent, 0)
+	for rows.Next() {
+		var st ProjectedStudent
+		var dob string
+		if err := rows.Scan(&st.ID, &st.FirstName, &st.LastName, &st.SchoolID, &dob, &st.StudentID, &st.Active); err != nil {
+			return nil, fmt.Errorf("failed to scan student: %w", err)
+		}
+		st.DateOfBirth = r.parseDate(dob)
+		students = append(students, &st)
+	}
+
+	return students, rows.Err()
+}
+
+// listDismissedDuplicates returns the pairs an admin reviewed and decided aren't duplicates
+func (r *sqlRepository) listDismissedDuplicates(ctx context.Context) (map[DuplicatePair]bool, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT student_a, student_b FROM student_duplicate_reviews`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate reviews: %w", err)
+	}
+	defer rows.Close()
+
+	dismissed := make(map[DuplicatePair]bool)
+	for rows.Next() {
+		var pair DuplicatePair
+		if err := rows.Scan(&pair.A, &pair.B); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate review: %w", err)
+		}
+		dismissed[pair] = true
+	}
+
+	return dismissed, rows.Err()
+}
+
+// dismissDuplicate records that a suspected duplicate pair was reviewed and isn't a duplicate
+func (r *sqlRepository) dismissDuplicate(ctx context.Context, pair DuplicatePair) error {
+	query := `INSERT INTO student_duplicate_reviews (student_a, student_b)
+		VALUES (?, ?)
+		ON CONFLICT (student_a, student_b) DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, pair.A, pair.B); err != nil {
+		return fmt.Errorf("failed to dismiss duplicate: %w", err)
+	}
+
+	return nil
+}
+
+// insertNutritionAlerts stores new alerts, an alert already raised for the same assessment keeps its
+// acknowledgement
+func (r *sqlRepository) insertNutritionAlerts(ctx context.Context, alerts []NutritionAlert) error {
+	query := `INSERT INTO student_nutrition_alerts
+		(student_id, school_id, alert_type, assessment_id, assessment_date, previous_assessment_date, associated_bulk_upload_id, detail)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (student_id, assessment_id, alert_type) DO NOTHING`
+
+	for _, alert := range alerts {
+		var previous sql.NullString
+		if !alert.PreviousAssessmentDate.IsZero() {
+			previous = sql.NullString{String: alert.PreviousAssessmentDate.Format("2006-01-02"), Valid: true}
+		}
+
+		_, err := r.db.ExecContext(ctx, query, alert.StudentID, alert.SchoolID, string(alert.Type), alert.AssessmentID,
+			alert.AssessmentDate.Format("2006-01-02"), previous, alert.AssociatedBulkUploadID, alert.Detail)
+		if err != nil {
+			return fmt.Errorf("failed to insert nutrition alert: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteNutritionAlertsForUpload removes the alerts raised by assessments of an undone bulk upload
+func (r *sqlRepository) deleteNutritionAlertsForUpload(ctx context.Context, studentID, bulkUploadID string) error {
+	query := `DELETE FROM student_nutrition_alerts WHERE student_id = ? AND associated_bulk_upload_id = ?`
+	if _, err := r.db.ExecContext(ctx, query, studentID, bulkUploadID); err != nil {
+		return fmt.Errorf("failed to delete nutrition alerts: %w", err)
+	}
+
+	return nil
+}
+
+// deleteNutritionAlertsForAssessment removes the alerts raised by a single assessment, acknowledged alerts
+// are kept when unacknowledgedOnly is set
+func (r *sqlRepository) deleteNutritionAlertsForAssessment(ctx context.Context, studentID string, assessmentID uint64, unacknowledgedOnly bool) error {
+	query := `DELETE FROM student_nutrition_alerts WHERE student_id = ? AND assessment_id = ?`
+	if unacknowledgedOnly {
+		query += " AND acknowledged_at IS NULL"
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, studentID, assessmentID); err != nil {
+		return fmt.Errorf("failed to delete nutrition alerts: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) ListNutritionAlerts(ctx context.Context, filter NutritionAlertFilter) ([]*NutritionAlert, error) {
+	args := []any{}
+	wheres := []string{}
+	if filter.SchoolID != "" {
+		wheres = append(wheres, "school_id = ?")
+		args = append(args, filter.SchoolID)
+	}
+	if !filter.IncludeAcknowledged {
+		wheres = append(wheres, "acknowledged_at IS NULL")
+	}
+
+	q := `SELECT id, student_id, school_id, alert_type, COALESCE(assessment_id, 0), assessment_date, COALESCE(previous_assessment_date, ''),
+		associated_bulk_upload_id, detail, COALESCE(created_at, ''), COALESCE(acknowledged_at, ''), COALESCE(acknowledged_by, '')
+		FROM student_nutrition_alerts`
+	if len(wheres) > 0 {
+		q += " WHERE " + strings.Join(wheres, " AND ")
+	}
+	q += " ORDER BY assessment_date DESC, id DESC"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query nutrition alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*NutritionAlert
+	for rows.Next() {
+		var a NutritionAlert
+		var alertType, assessmentDate, previousDate, createdAt, acknowledgedAt string
+		if err := rows.Scan(&a.ID, &a.StudentID, &a.SchoolID, &alertType, &a.AssessmentID, &assessmentDate, &previousDate,
+			&a.AssociatedBulkUploadID, &a.Detail, &createdAt, &acknowledgedAt, &a.AcknowledgedBy); err != nil {
+			return nil, fmt.Errorf("scan nutrition alert: %w", err)
+		}
+		a.Type = NutritionAlertType(alertType)
+		a.AssessmentDate = r.parseDate(assessmentDate)
+		if previousDate != "" {
+			a.PreviousAssessmentDate = r.parseDate(previousDate)
+		}
+		if createdAt != "" {
+			a.CreatedAt = r.parseDate(createdAt)
+		}
+		if acknowledgedAt != "" {
+			a.AcknowledgedAt = r.parseDate(acknowledgedAt)
+		}
+		res = append(res, &a)
+	}
+
+	return res, rows.Err()
+}
+
+func (r *sqlRepository) acknowledgeNutritionAlert(ctx context.Context, id uint64, acknowledgedBy string) error {
+	query := `UPDATE student_nutrition_alerts SET acknowledged_at = CURRENT_TIMESTAMP, acknowledged_by = ?
+		WHERE id = ? AND acknowledged_at IS NULL`
+
+	res, err := r.db.ExecContext(ctx, query, acknowledgedBy, id)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge nutrition alert: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNutritionAlertNotFound
+	}
+
+	return nil
+}
+
+// listSchoolsWithActiveStudents returns the schools that have at least one active student
+func (r *sqlRepository) listSchoolsWithActiveStudents(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT school_id FROM student_projections
+		WHERE active = TRUE AND school_id != ''`)
+	if err != nil {
+		return nil, fmt.Errorf("query schools: %w", err)
+	}
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var schoolID string
+		if err := rows.Scan(&schoolID); err != nil {
+			return nil, fmt.Errorf("scan school: %w", err)
+		}
+		res = append(res, schoolID)
+	}
+
+	return res, rows.Err()
+}
+
+// listSchoolFeedingDays returns the days the school served meals before the given day, newest first.
+// Only the last year is looked at, a longer run of missed days was flagged long ago.
+func (r *sqlRepository) listSchoolFeedingDays(ctx context.Context, schoolID string, before time.Time) ([]time.Time, error) {
+	q := `SELECT DISTINCT date(feeding_timestamp) AS day FROM student_feeding_projections
+		WHERE school_id = ?
+		AND date(feeding_timestamp) < date(?)
+		AND date(feeding_timestamp) >= date(?, '-1 year')
+		ORDER BY day DESC`
+
+	day := before.Format("2006-01-02")
+	rows, err := r.db.QueryContext(ctx, q, schoolID, day, day)
+	if err != nil {
+		return nil, fmt.Errorf("query school feeding days: %w", err)
+	}
+	defer rows.Close()
+
+	var res []time.Time
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			return nil, fmt.Errorf("scan school feeding day: %w", err)
+		}
+		res = append(res, r.parseDate(d))
+	}
+
+	return res, rows.Err()
+}
+
+// listMissedMealCandidates returns the active students of a school who haven't been fed anywhere since
+// before the given day, with the day they were last fed
+func (r *sqlRepository) listMissedMealCandidates(ctx context.Context, schoolID string, notFedSince time.Time) ([]missedMealCandidate, error) {
+	q := `SELECT sp.id, COALESCE(MAX(date(sfp.feeding_timestamp)), '') AS last_fed_on
+		FROM student_projections sp
+		LEFT JOIN student_feeding_projections sfp ON sfp.student_id = sp.id
+		WHERE sp.school_id = ? AND sp.active = TRUE
+		GROUP BY sp.id
+		HAVING last_fed_on = '' OR last_fed_on < date(?)`
+
+	rows, err := r.db.QueryContext(ctx, q, schoolID, notFedSince.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("query missed meal candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var res []missedMealCandidate
+	for rows.Next() {
+		var c missedMealCandidate
+		var lastFedOn string
+		if err := rows.Scan(&c.StudentID, &lastFedOn); err != nil {
+			return nil, fmt.Errorf("scan missed meal candidate: %w", err)
+		}
+		if lastFedOn != "" {
+			c.LastFedOn = r.parseDate(lastFedOn)
+		}
+		res = append(res, c)
+	}
+
+	return res, rows.Err()
+}
+
+func followUpLastFedOn(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// upsertFollowUpTask creates the task for a run of missed days, or brings the count of an open task for the
+// same run up to date. It reports whether the task is new.
+func (r *sqlRepository) upsertFollowUpTask(ctx context.Context, task *FollowUpTask) (bool, error) {
+	lastFedOn := followUpLastFedOn(task.LastFedOn)
+
+	var id uint64
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM student_follow_up_tasks WHERE student_id = ? AND last_fed_on = ?`,
+		task.StudentID, lastFedOn).Scan(&id)
+	switch {
+	case err == nil:
+		// a closed task stays closed, staff already dealt with this run
+		_, err = r.db.ExecContext(ctx, `UPDATE student_follow_up_tasks SET missed_days = ?, detail = ?
+			WHERE id = ? AND status IN (?, ?)`, task.MissedDays, task.Detail, id, string(FollowUpOpen), string(FollowUpInProgress))
+		if err != nil {
+			return false, fmt.Errorf("failed to update follow-up task: %w", err)
+		}
+		task.ID = id
+		return false, nil
+	case errors.Is(err, sql.ErrNoRows):
+	default:
+		return false, fmt.Errorf("failed to query follow-up task: %w", err)
+	}
+
+	err = r.db.QueryRowContext(ctx, `INSERT INTO student_follow_up_tasks (student_id, school_id, last_fed_on, missed_days, detail, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id`, task.StudentID, task.SchoolID, lastFedOn, task.MissedDays, task.Detail, string(FollowUpOpen)).Scan(&task.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert follow-up task: %w", err)
+	}
+
+	return true, nil
+}
+
+// closeFollowUpsFedSince resolves the open tasks of students who have been fed again, tasks someone is working
+// on are left for them to close
+func (r *sqlRepository) closeFollowUpsFedSince(ctx context.Context) error {
+	q := `UPDATE student_follow_up_tasks SET status = ?, closed_at = CURRENT_TIMESTAMP, closed_by = ''
+		WHERE status = ?
+		AND EXISTS (
+			SELECT 1 FROM student_feeding_projections sfp
+			WHERE sfp.student_id = student_follow_up_tasks.student_id
+			AND (student_follow_up_tasks.last_fed_on = '' OR date(sfp.feeding_timestamp) > date(student_follow_up_tasks.last_fed_on))
+		)`
+
+	if _, err := r.db.ExecContext(ctx, q, string(FollowUpResolved), string(FollowUpOpen)); err != nil {
+		return fmt.Errorf("failed to close follow-up tasks: %w", err)
+	}
+
+	return nil
+}
+
+const followUpTaskColumns = `t.id, t.student_id, t.school_id, COALESCE(sp.first_name, ''), COALESCE(sp.last_name, ''),
+	t.last_fed_on, t.missed_days, t.detail, t.status, t.assigned_to, COALESCE(t.created_at, ''),
+	COALESCE(t.closed_at, ''), COALESCE(t.closed_by, '')`
+
+func (r *sqlRepository) scanFollowUpTask(row interface{ Scan(...any) error }) (*FollowUpTask, error) {
+	var t FollowUpTask
+	var lastFedOn, status, createdAt, closedAt string
+	if err := row.Scan(&t.ID, &t.StudentID, &t.SchoolID, &t.FirstName, &t.LastName, &lastFedOn, &t.MissedDays,
+		&t.Detail, &status, &t.AssignedTo, &createdAt, &closedAt, &t.ClosedBy); err != nil {
+		return nil, err
+	}
+	t.Status = FollowUpStatus(status)
+	if lastFedOn != "" {
+		t.LastFedOn = r.parseDate(lastFedOn)
+	}
+	if createdAt != "" {
+		t.CreatedAt = r.parseDate(createdAt)
+	}
+	if closedAt != "" {
+		t.ClosedAt = r.parseDate(closedAt)
+	}
+
+	return &t, nil
+}
+
+func (r *sqlRepository) listFollowUpTasks(ctx context.Context, filter FollowUpFilter) ([]*FollowUpTask, error) {
+	args := []any{}
+	wheres := []string{}
+	if len(filter.SchoolIDs) > 0 {
+		wheres = append(wheres, "t.school_id IN (?"+strings.Repeat(", ?", len(filter.SchoolIDs)-1)+")")
+		for _, id := range filter.SchoolIDs {
+			args = append(args, id)
+		}
+	}
+	if !filter.IncludeClosed {
+		wheres = append(wheres, "t.status IN (?, ?)")
+		args = append(args, string(FollowUpOpen), string(FollowUpInProgress))
+	}
+
+	q := `SELECT ` + followUpTaskColumns + `
+		FROM student_follow_up_tasks t
+		LEFT JOIN student_projections sp ON sp.id = t.student_id`
+	if len(wheres) > 0 {
+		q += " WHERE " + strings.Join(wheres, " AND ")
+	}
+	q += " ORDER BY t.created_at DESC, t.id DESC"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query follow-up tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*FollowUpTask
+	byID := make(map[uint64]*FollowUpTask)
+	for rows.Next() {
+		t, err := r.scanFollowUpTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan follow-up task: %w", err)
+		}
+		res = append(res, t)
+		byID[t.ID] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return res, nil
+	}
+
+	ids := make([]any, 0, len(res))
+	for _, t := range res {
+		ids = append(ids, t.ID)
+	}
+	notes, err := r.listFollowUpNotes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notes {
+		if t, ok := byID[n.TaskID]; ok {
+			t.Notes = append(t.Notes, n)
+		}
+	}
+
+	return res, nil
+}
+
+// listFollowUpNotes returns the notes of the given tasks, oldest first
+func (r *sqlRepository) listFollowUpNotes(ctx context.Context, taskIDs []any) ([]*FollowUpNote, error) {
+	q := `SELECT id, task_id, note, author, COALESCE(created_at, '') FROM student_follow_up_notes
+		WHERE task_id IN (?` + strings.Repeat(", ?", len(taskIDs)-1) + `)
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, q, taskIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("query follow-up notes: %w", err)
+	}
+	defer rows.Close()
+
+	var res []*FollowUpNote
+	for rows.Next() {
+		var n FollowUpNote
+		var createdAt string
+		if err := rows.Scan(&n.ID, &n.TaskID, &n.Note, &n.Author, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan follow-up note: %w", err)
+		}
+		if createdAt != "" {
+			n.CreatedAt = r.parseDate(createdAt)
+		}
+		res = append(res, &n)
+	}
+
+	return res, rows.Err()
+}
+
+func (r *sqlRepository) getFollowUpTask(ctx context.Context, id uint64) (*FollowUpTask, error) {
+	q := `SELECT ` + followUpTaskColumns + `
+		FROM student_follow_up_tasks t
+		LEFT JOIN student_projections sp ON sp.id = t.student_id
+		WHERE t.id = ?`
+
+	t, err := r.scanFollowUpTask(r.db.QueryRowContext(ctx, q, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFollowUpNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follow-up task: %w", err)
+	}
+
+	t.Notes, err = r.listFollowUpNotes(ctx, []any{id})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (r *sqlRepository) setFollowUpStatus(ctx context.Context, id uint64, status FollowUpStatus, user string) error {
+	var q string
+	var args []any
+	switch {
+	case status == FollowUpInProgress:
+		q = `UPDATE student_follow_up_tasks SET status = ?, assigned_to = ?, closed_at = NULL, closed_by = NULL WHERE id = ?`
+		args = []any{string(status), user, id}
+	case status.IsClosed():
+		q = `UPDATE student_follow_up_tasks SET status = ?, closed_at = CURRENT_TIMESTAMP, closed_by = ? WHERE id = ?`
+		args = []any{string(status), user, id}
+	default:
+		// reopening hands the task back to the whole school
+		q = `UPDATE student_follow_up_tasks SET status = ?, assigned_to = '', closed_at = NULL, closed_by = NULL WHERE id = ?`
+		args = []any{string(status), id}
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update follow-up task: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrFollowUpNotFound
+	}
+
+	return nil
+}
+
+func (r *sqlRepository) addFollowUpNote(ctx context.Context, id uint64, note, author string) error {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM student_follow_up_tasks WHERE id = ?)`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to query follow-up task: %w", err)
+	}
+	if !exists {
+		return ErrFollowUpNotFound
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO student_follow_up_notes (task_id, note, author) VALUES (?, ?, ?)`, id, note, author)
+	if err != nil {
+		return fmt.Errorf("failed to add follow-up note: %w", err)
+	}
+
+	return nil
+}
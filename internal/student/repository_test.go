@@ -0,0 +1,189 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// studentTestRepo migrates a fresh SQLite database for the student domain
+func studentTestRepo(t *testing.T) *sqlRepository {
+	t.Helper()
+
+	conn := infrastructure.SQLConnection{Type: "sqlite3", URI: "file:" + filepath.Join(t.TempDir(), "student.db")}
+	repo := NewRepository(conn, nil).(*sqlRepository)
+	t.Cleanup(func() { repo.db.Close() })
+
+	return repo
+}
+
+func TestNutritionAlertsForSameDayAssessments(t *testing.T) {
+	repo := studentTestRepo(t)
+	ctx := context.Background()
+
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	alert := func(assessmentID uint64) NutritionAlert {
+		return NutritionAlert{StudentID: "1", SchoolID: "2", Type: AlertWeightLoss, AssessmentID: assessmentID,
+			AssessmentDate: date, Detail: "Lost weight"}
+	}
+
+	// two individually entered assessments on one day share the date and the empty bulk upload ID
+	if err := repo.insertNutritionAlerts(ctx, []NutritionAlert{alert(1), alert(2), alert(2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err := repo.ListNutritionAlerts(ctx, NutritionAlertFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("got %d alerts, want one for each assessment", len(alerts))
+	}
+
+	if err := repo.deleteNutritionAlertsForAssessment(ctx, "1", 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err = repo.ListNutritionAlerts(ctx, NutritionAlertFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].AssessmentID != 2 {
+		t.Errorf("after removing assessment 1's alerts got %+v, want assessment 2's", alerts)
+	}
+}
+
+func TestLegacyNutritionAlertsMatchedOnProjection(t *testing.T) {
+	repo := studentTestRepo(t)
+	ctx := context.Background()
+
+	agg := &Aggregate{}
+	agg.SetIDUint64(1)
+	if _, err := agg.CreateStudent(&eda.Student_Create{
+		FirstName:   "Test",
+		LastName:    "Student",
+		DateOfBirth: &eda.Date{Year: 2014, Month: 3, Day: 1},
+		Sex:         eda.Student_MALE,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, date := range []time.Time{
+		time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 9, 15, 0, 0, 0, 0, time.UTC),
+	} {
+		_, err := agg.RecordHealthAssessment(&eda.Student_AddHealthAssessment{
+			Assessment: &eda.Student_HealthAssessment{HeightCm: 140, WeightKg: 30, AssessmentDate: timestamppb.New(date)},
+			Version:    agg.GetVersion(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// as the migration leaves an alert raised before assessments had IDs
+	_, err := repo.db.Exec(`INSERT INTO student_nutrition_alerts
+		(student_id, school_id, alert_type, assessment_date, associated_bulk_upload_id, detail)
+		VALUES ('1', '2', ?, '2024-09-15', '', 'Lost weight')`, string(AlertWeightLoss))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.replaceStudentRows("student_health_projections", agg, repo.writeHealthProjections); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err := repo.ListNutritionAlerts(ctx, NutritionAlertFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := agg.GetHealthAssessments()[1].ID
+	if len(alerts) != 1 || alerts[0].AssessmentID != want {
+		t.Errorf("got %+v, want the alert matched to assessment %d", alerts, want)
+	}
+}
+
+func TestSponsorshipsAttributedToSchoolAtStart(t *testing.T) {
+	repo := studentTestRepo(t)
+	ctx := context.Background()
+
+	agg := &Aggregate{}
+	agg.SetIDUint64(1)
+	if _, err := agg.CreateStudent(&eda.Student_Create{
+		FirstName:   "Test",
+		LastName:    "Student",
+		DateOfBirth: &eda.Date{Year: 2014, Month: 3, Day: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agg.EnrollStudent(&eda.Student_Enroll{
+		SchoolId:         "1",
+		DateOfEnrollment: &eda.Date{Year: 2024, Month: 1, Day: 8},
+		Version:          agg.GetVersion(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sponsor := func(paymentID string, start, end *eda.Date) {
+		if _, err := agg.UpdateSponsorship(&eda.Student_UpdateSponsorship{
+			SponsorId: "sponsor", StartDate: start, EndDate: end, PaymentId: paymentID, PaymentAmount: 100,
+			Version: agg.GetVersion(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sponsor("before-transfer", &eda.Date{Year: 2024, Month: 2, Day: 1}, &eda.Date{Year: 2024, Month: 12, Day: 31})
+	if _, err := agg.TransferStudent(&eda.Student_Transfer{
+		ToSchoolId:   "2",
+		TransferDate: &eda.Date{Year: 2024, Month: 3, Day: 1},
+		Version:      agg.GetVersion(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sponsor("after-transfer", &eda.Date{Year: 2024, Month: 4, Day: 1}, &eda.Date{Year: 2024, Month: 12, Day: 31})
+
+	if err := repo.replaceStudentRows("student_projections", agg, func(ctx context.Context, tx *sql.Tx, table string, student *Aggregate) error {
+		return repo.writeStudentProjection(ctx, tx, table, student)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.replaceStudentRows("student_sponsorship_projections", agg, repo.writeSponsorshipProjections); err != nil {
+		t.Fatal(err)
+	}
+
+	paymentIDs := func(sponsorships []*SponsorshipProjection, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, 0, len(sponsorships))
+		for _, sp := range sponsorships {
+			ids = append(ids, sp.PaymentID)
+		}
+		return ids
+	}
+
+	for _, tc := range []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"payments at the old school", paymentIDs(repo.GetSponsorshipsForSchool(ctx, "1")), []string{"before-transfer"}},
+		{"payments at the new school", paymentIDs(repo.GetSponsorshipsForSchool(ctx, "2")), []string{"after-transfer"}},
+		{"sponsorships of the new school's students", paymentIDs(repo.GetSponsorshipsOfSchoolStudents(ctx, "2")),
+			[]string{"before-transfer", "after-transfer"}},
+		{"sponsorships of the old school's students", paymentIDs(repo.GetSponsorshipsOfSchoolStudents(ctx, "1")), []string{}},
+	} {
+		if !slices.Equal(tc.got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
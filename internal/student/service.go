@@ -2,8 +2,11 @@ package student
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+	"log/slog"
 	"time"
 
 	"github.com/Howard3/gosignal"
@@ -16,19 +19,51 @@ type StudentService struct {
 	repo          Repository
 	eventHandlers *eventHandlers
 	acl           AntiCorruptionLayer
+	followUps     FollowUpConfig
+	// consuming is set once the projections are updated from the queue rather than after each save
+	consuming bool
 }
 
 type AntiCorruptionLayer interface {
 	ValidateSchoolID(ctx context.Context, schoolID string) error
 	ValidatePhotoID(ctx context.Context, photoID string) error
+	// GetSchoolYearEnd returns the month and day the school's year ends, nil when it isn't set
+	GetSchoolYearEnd(ctx context.Context, schoolID string) (*eda.School_MonthDay, error)
+	// GetMissedMealThreshold returns the school days without a meal before a student is followed up, 0
+	// when the school uses the default
+	GetMissedMealThreshold(ctx context.Context, schoolID string) (uint32, error)
 }
 
-func NewStudentService(repo Repository, acl AntiCorruptionLayer) *StudentService {
-	return &StudentService{
+// ServiceOption configures optional behaviour of the StudentService
+type ServiceOption func(*StudentService)
+
+// WithNutritionAlertConfig overrides the thresholds at which health assessments raise nutrition alerts
+func WithNutritionAlertConfig(cfg NutritionAlertConfig) ServiceOption {
+	return func(s *StudentService) {
+		s.eventHandlers.nutritionAlerts.config = cfg
+	}
+}
+
+// WithFollowUpConfig overrides when the missed-meal scan flags students and how often it runs
+func WithFollowUpConfig(cfg FollowUpConfig) ServiceOption {
+	return func(s *StudentService) {
+		s.followUps = cfg
+	}
+}
+
+func NewStudentService(repo Repository, acl AntiCorruptionLayer, opts ...ServiceOption) *StudentService {
+	s := &StudentService{
 		repo:          repo,
 		eventHandlers: NewEventHandlers(repo),
 		acl:           acl,
+		followUps:     DefaultFollowUpConfig(),
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 type ListStudentsResponse struct {
@@ -37,41 +72,54 @@ type ListStudentsResponse struct {
 	Filters  StudentListFilters
 }
 
-// RunCommand runs a command on a user aggregate
+// RunCommand runs a command on a user aggregate. When the student has changed since the version the command
+// was based on, commands that only add to the record are run against the current version, any other
+// command fails with an infrastructure.ConflictError listing what changed.
 func (s *StudentService) RunCommand(ctx context.Context, aggID uint64, cmd proto.Message) (*Aggregate, error) {
+	command, ok := studentCommands[cmd.ProtoReflect().Descriptor().FullName()]
+	if !ok {
+		return nil, fmt.Errorf("unknown command type: %T", cmd)
+	}
+
+	expected, versioned := infrastructure.CommandVersion(cmd)
+
 	return s.withAgg(ctx, aggID, func(agg *Aggregate) (*gosignal.Event, error) {
-		switch cmd := cmd.(type) {
-		case *eda.Student_Feeding:
-			return agg.Feed(cmd)
-		case *eda.Student_Enroll:
-			if err := s.acl.ValidateSchoolID(ctx, cmd.GetSchoolId()); err != nil {
-				return nil, fmt.Errorf("failed to validate school ID: %w", err)
-			}
-			return agg.EnrollStudent(cmd)
-		case *eda.Student_SetLookupCode:
-			// TODO: check for collisions on the lookup code
-			return agg.SetLookupCode(cmd)
-		case *eda.Student_Unenroll:
-			return agg.UnenrollStudent(cmd)
-		case *eda.Student_Update:
-			return agg.UpdateStudent(cmd)
-		case *eda.Student_SetStatus:
-			return agg.SetStatus(cmd)
-		case *eda.Student_SetProfilePhoto:
-			if err := s.acl.ValidatePhotoID(ctx, cmd.GetFileId()); err != nil {
-				return nil, fmt.Errorf("failed to validate photo ID: %w", err)
+		if agg.IsMerged() {
+			return nil, ErrStudentMerged
+		}
+
+		if versioned && expected != agg.GetVersion() {
+			if !command.appendOnly {
+				return nil, s.conflict(ctx, agg, expected)
 			}
-			return agg.SetProfilePhoto(cmd)
-		case *eda.Student_SetEligibility:
-			return agg.SetEligibility(cmd)
-		case *eda.Student_UpdateSponsorship:
-			return agg.UpdateSponsorship(cmd)
-		default:
-			return nil, fmt.Errorf("unknown command type: %T", cmd)
+			cmd = infrastructure.RebaseCommand(cmd, agg.GetVersion())
 		}
+
+		return command.run(ctx, s, agg, cmd)
 	})
 }
 
+// conflict describes what changed on the student since the version a command was based on
+func (s *StudentService) conflict(ctx context.Context, agg *Aggregate, expected uint64) error {
+	conflict := &infrastructure.ConflictError{
+		AggregateID: agg.GetID(),
+		Expected:    expected,
+		Current:     agg.GetVersion(),
+	}
+
+	before, err := s.GetStudentAsOf(ctx, agg.GetIDUint64(), infrastructure.AsOfVersion(expected))
+	switch {
+	case err == nil:
+		conflict.Changes = infrastructure.DiffMessages(before.data, agg.data)
+	case errors.Is(err, infrastructure.ErrNotYetCreated):
+		conflict.Changes = infrastructure.DiffMessages(nil, agg.data)
+	default:
+		slog.Warn("failed to work out what changed on the student", "student_id", agg.GetID(), "error", err)
+	}
+
+	return conflict
+}
+
 func (s *StudentService) DeleteStudent(ctx context.Context, id uint64, associatedBulkUploadID string) error {
 	agg, err := s.repo.loadStudent(ctx, id)
 	if err != nil {
@@ -86,19 +134,29 @@ func (s *StudentService) DeleteStudent(ctx context.Context, id uint64, associate
 	return s.saveEvent(ctx, evt)
 }
 
-// withUser is a helper function that loads an user aggregate from the repository and executes a function on it
+// withAgg is a helper function that loads a student aggregate from the repository and executes a function on
+// it. When another write stores the student's next version first, the student is reloaded and fn run again.
 func (s *StudentService) withAgg(ctx context.Context, id uint64, fn func(*Aggregate) (*gosignal.Event, error)) (*Aggregate, error) {
-	agg, err := s.repo.loadStudent(ctx, id)
-	if err != nil {
-		return nil, err
-	}
+	var agg *Aggregate
+	err := infrastructure.RetryOnConflict(func() error {
+		var err error
+		agg, err = s.repo.loadStudent(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		evt, err := fn(agg)
+		if err != nil {
+			return err
+		}
 
-	evt, err := fn(agg)
+		return s.saveEvent(ctx, evt)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return agg, s.saveEvent(ctx, evt)
+	return agg, nil
 }
 
 func (s *StudentService) saveEvent(ctx context.Context, evt *gosignal.Event) error {
@@ -107,13 +165,39 @@ func (s *StudentService) saveEvent(ctx context.Context, evt *gosignal.Event) err
 			return err
 		}
 
-		go s.eventHandlers.routeEvent(context.Background(), evt)
+		if !s.consuming {
+			go s.routeEvent(context.Background(), *evt)
+		}
+	}
 
+	return nil
+}
+
+// ConsumeEvents updates the student projections from the events delivered by the queue, in place of
+// handling them after each save. It must be called before the service handles commands.
+func (s *StudentService) ConsumeEvents(ctx context.Context, q gosignal.Queue) error {
+	if err := infrastructure.ConsumeEvents(ctx, q, infrastructure.Subscriber{Name: "student", EventTable: "student_events"}, s.routeEvent, handledEventTypes...); err != nil {
+		return fmt.Errorf("failed to consume student events: %w", err)
 	}
 
+	s.consuming = true
+
 	return nil
 }
 
+func (s *StudentService) routeEvent(ctx context.Context, evt gosignal.Event) error {
+	// queued events are as stored, the nutrition alerts read their payload
+	evt, err := upcasters.Upcast(evt)
+	if err == nil {
+		err = s.eventHandlers.routeEvent(ctx, &evt)
+	}
+	if err != nil {
+		slog.Error("failed to handle student event", "type", evt.Type, "aggregate_id", evt.AggregateID, "error", err)
+	}
+
+	return err
+}
+
 // GetStudentEvent returns a specific event for a student Aggregate
 func (s *StudentService) GetStudentEvent(ctx context.Context, studentID, eventID uint64) (*gosignal.Event, error) {
 	return s.repo.getEvent(ctx, studentID, eventID)
@@ -219,9 +303,58 @@ func (s *StudentService) GetStudent(ctx context.Context, studentID uint64) (*Agg
 	return studentAgg, nil
 }
 
-// GetHistory returns the event history for a student aggregate
-func (s *StudentService) GetHistory(ctx context.Context, studentID uint64) ([]gosignal.Event, error) {
-	return s.repo.getEventHistory(ctx, studentID)
+// GetHistory returns the event history for a student aggregate with each payload decoded
+func (s *StudentService) GetHistory(ctx context.Context, studentID uint64) ([]infrastructure.DecodedEvent, error) {
+	events, err := s.repo.getEventHistory(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load student events: %w", err)
+	}
+
+	return decoders.DecodeHistory(events), nil
+}
+
+// HistoryExport is a student's full event history as it's exported to JSON
+type HistoryExport struct {
+	StudentID  uint64                         `json:"student_id"`
+	ExportedAt time.Time                      `json:"exported_at"`
+	Events     []infrastructure.ExportedEvent `json:"events"`
+}
+
+// ExportHistory returns every event of the student with its payload, for the JSON export
+func (s *StudentService) ExportHistory(ctx context.Context, studentID uint64) (*HistoryExport, error) {
+	history, err := s.GetHistory(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &HistoryExport{
+		StudentID:  studentID,
+		ExportedAt: time.Now().UTC(),
+		Events:     make([]infrastructure.ExportedEvent, len(history)),
+	}
+	for i, evt := range history {
+		export.Events[i] = evt.Export()
+	}
+
+	return export, nil
+}
+
+// GetStudentAsOf replays the student's events up to a point in its past, to show what the record looked
+// like on a given date or at a given version
+func (s *StudentService) GetStudentAsOf(ctx context.Context, studentID uint64, at infrastructure.AsOf) (*Aggregate, error) {
+	events, err := s.repo.getEventHistory(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load student events: %w", err)
+	}
+
+	studentAgg := &Aggregate{}
+	studentAgg.SetIDUint64(studentID)
+
+	if err := infrastructure.ReplayAsOf(studentAgg, events, at); err != nil {
+		return nil, fmt.Errorf("failed to replay student %d: %w", studentID, err)
+	}
+
+	return studentAgg, nil
 }
 
 // GetStudentByCode returns a student by a lookup code
@@ -360,6 +493,26 @@ func (s *StudentService) GetAllCurrentSponsorships(ctx context.Context) ([]*Spon
 	return sponsorships, nil
 }
 
+// GetSponsorshipsForSchool returns the sponsorships, including payments, that started while the student was
+// enrolled in a school
+func (s *StudentService) GetSponsorshipsForSchool(ctx context.Context, schoolID string) ([]*SponsorshipProjection, error) {
+	sponsorships, err := s.repo.GetSponsorshipsForSchool(ctx, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sponsorships for school: %w", err)
+	}
+	return sponsorships, nil
+}
+
+// GetSponsorshipsOfSchoolStudents returns the sponsorships of the students currently enrolled in a school,
+// including those that started at another school
+func (s *StudentService) GetSponsorshipsOfSchoolStudents(ctx context.Context, schoolID string) ([]*SponsorshipProjection, error) {
+	sponsorships, err := s.repo.GetSponsorshipsOfSchoolStudents(ctx, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sponsorships of school students: %w", err)
+	}
+	return sponsorships, nil
+}
+
 func (s *StudentService) GetRecentFeedingEvents(ctx context.Context, page, limit int) ([]*SponsorFeedingEvent, int64, error) {
 	// Get recent feeding events from all students
 	events, total, err := s.repo.GetAllFeedingEvents(ctx, uint(limit), uint(page))
@@ -380,6 +533,69 @@ func (s *StudentService) GetGrades(ctx context.Context, schoolID string, from, t
 	return s.repo.GetGrades(ctx, schoolID, from, to)
 }
 
+// FindDuplicates returns the pairs of students suspected to be the same child that haven't been dismissed
+func (s *StudentService) FindDuplicates(ctx context.Context) ([]*DuplicateCandidate, error) {
+	students, err := s.repo.listStudentsForDuplicateCheck(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list students: %w", err)
+	}
+
+	dismissed, err := s.repo.listDismissedDuplicates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dismissed duplicates: %w", err)
+	}
+
+	return findDuplicates(students, dismissed), nil
+}
+
+// DismissDuplicate removes a pair from the duplicate review queue after an admin decided they're different children
+func (s *StudentService) DismissDuplicate(ctx context.Context, a, b string) error {
+	return s.repo.dismissDuplicate(ctx, newDuplicatePair(a, b))
+}
+
+// MergeStudents folds the duplicate student into the survivor, then marks the duplicate as merged into it.
+// The duplicate is reloaded whenever the survivor's save is retried so records added meanwhile are folded in.
+func (s *StudentService) MergeStudents(ctx context.Context, survivorID, duplicateID uint64) (*Aggregate, error) {
+	survivor, err := s.withAgg(ctx, survivorID, func(agg *Aggregate) (*gosignal.Event, error) {
+		duplicate, err := s.repo.loadStudent(ctx, duplicateID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load duplicate student: %w", err)
+		}
+
+		return agg.MergeFrom(duplicate, agg.Version)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge into surviving student: %w", err)
+	}
+
+	_, err = s.withAgg(ctx, duplicateID, func(agg *Aggregate) (*gosignal.Event, error) {
+		return agg.MergeInto(&eda.Student_MergeInto{
+			TargetStudentId: survivor.GetID(),
+			Version:         agg.Version,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark student as merged: %w", err)
+	}
+
+	return survivor, nil
+}
+
+// GetTransfers returns the transfers between schools within the date range for reporting
+func (s *StudentService) GetTransfers(ctx context.Context, from, to time.Time) ([]*ProjectedTransfer, error) {
+	return s.repo.GetTransfers(ctx, from, to)
+}
+
+// ListNutritionAlerts returns the nutrition alerts for the inbox, newest assessments first
+func (s *StudentService) ListNutritionAlerts(ctx context.Context, filter NutritionAlertFilter) ([]*NutritionAlert, error) {
+	return s.repo.ListNutritionAlerts(ctx, filter)
+}
+
+// AcknowledgeNutritionAlert marks an alert as seen, acknowledgedBy identifies the user
+func (s *StudentService) AcknowledgeNutritionAlert(ctx context.Context, alertID uint64, acknowledgedBy string) error {
+	return s.repo.acknowledgeNutritionAlert(ctx, alertID, acknowledgedBy)
+}
+
 func (s *StudentService) AddGradeReport(ctx context.Context, id uint64, report *eda.Student_GradeReport) error {
 	studentAgg, err := s.repo.loadStudent(ctx, id)
 	if err != nil {
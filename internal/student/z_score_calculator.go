@@ -1,9 +1,12 @@
 package student
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // NutritionalStatus represents the classification of a student's nutritional status
-// based on their BMI z-score
+// based on a z-score against the growth reference
 type NutritionalStatus int
 
 const (
@@ -11,11 +14,25 @@ const (
 	SeverelyWasted NutritionalStatus = iota
 	// Wasted indicates BMI is below the -2 SD but above the -3 SD threshold
 	Wasted
-	// Normal indicates BMI is at or above the -2 SD threshold
+	// Normal indicates the measurement is within the normal range for the indicator
 	Normal
 	// Error indicates an error occurred during calculation
 	NutritionalStatusError
 	NutritionalStatusGenderError
+	// Overweight indicates BMI is above the +1 SD threshold
+	Overweight
+	// Obese indicates BMI is above the +2 SD threshold
+	Obese
+	// SeverelyStunted indicates height is below the -3 SD threshold
+	SeverelyStunted
+	// Stunted indicates height is below the -2 SD but above the -3 SD threshold
+	Stunted
+	// SeverelyUnderweight indicates weight is below the -3 SD threshold
+	SeverelyUnderweight
+	// Underweight indicates weight is below the -2 SD but above the -3 SD threshold
+	Underweight
+	// NutritionalStatusUnavailable indicates the growth reference doesn't cover the child's age
+	NutritionalStatusUnavailable
 )
 
 // String returns the string representation of the nutritional status
@@ -31,11 +48,168 @@ func (ns NutritionalStatus) String() string {
 		return "Error"
 	case NutritionalStatusGenderError:
 		return "Gender Error"
+	case Overweight:
+		return "Overweight"
+	case Obese:
+		return "Obese"
+	case SeverelyStunted:
+		return "Severely Stunted"
+	case Stunted:
+		return "Stunted"
+	case SeverelyUnderweight:
+		return "Severely Underweight"
+	case Underweight:
+		return "Underweight"
+	case NutritionalStatusUnavailable:
+		return "Not Available"
 	default:
 		return "Unknown"
 	}
 }
 
+// GrowthAssessment is a measurement's z-score against the growth reference and its classification.
+// HasZScore is false when the classification came from the yearly threshold table because the
+// reference doesn't cover the child's age.
+type GrowthAssessment struct {
+	Status    NutritionalStatus
+	ZScore    float64
+	HasZScore bool
+}
+
+// ClassifyBMIForAge classifies a BMI-for-age z-score using the WHO cut-offs for 5-19 year olds
+func ClassifyBMIForAge(z float64) NutritionalStatus {
+	switch {
+	case z < -3:
+		return SeverelyWasted
+	case z < -2:
+		return Wasted
+	case z > 2:
+		return Obese
+	case z > 1:
+		return Overweight
+	default:
+		return Normal
+	}
+}
+
+// ClassifyHeightForAge classifies a height-for-age z-score
+func ClassifyHeightForAge(z float64) NutritionalStatus {
+	switch {
+	case z < -3:
+		return SeverelyStunted
+	case z < -2:
+		return Stunted
+	default:
+		return Normal
+	}
+}
+
+// ClassifyWeightForAge classifies a weight-for-age z-score, WHO only publishes the reference up to 10 years
+func ClassifyWeightForAge(z float64) NutritionalStatus {
+	switch {
+	case z < -3:
+		return SeverelyUnderweight
+	case z < -2:
+		return Underweight
+	default:
+		return Normal
+	}
+}
+
+// ClassifyWeightForHeight classifies a weight-for-length/height z-score using the WHO cut-offs for
+// children under 5, which are also used for their BMI-for-age
+func ClassifyWeightForHeight(z float64) NutritionalStatus {
+	switch {
+	case z < -3:
+		return SeverelyWasted
+	case z < -2:
+		return Wasted
+	case z > 3:
+		return Obese
+	case z > 2:
+		return Overweight
+	default:
+		return Normal
+	}
+}
+
+// MUAC cut-offs for severe and moderate acute malnutrition in children 6-59 months old
+const (
+	MUACSevereThresholdMm   = 115
+	MUACModerateThresholdMm = 125
+	muacMinAgeMonths        = 6
+)
+
+// ClassifyMUAC classifies a mid-upper arm circumference in millimetres
+func ClassifyMUAC(muacMm float64) NutritionalStatus {
+	switch {
+	case muacMm < MUACSevereThresholdMm:
+		return SeverelyWasted
+	case muacMm < MUACModerateThresholdMm:
+		return Wasted
+	default:
+		return Normal
+	}
+}
+
+// wastingSeverity ranks statuses so the most severe finding of an assessment wins, a missing
+// reference ranks below any actual classification
+func wastingSeverity(status NutritionalStatus) int {
+	switch status {
+	case SeverelyWasted:
+		return 3
+	case Wasted:
+		return 2
+	case NutritionalStatusUnavailable:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// Under5Measurements are the measurements taken when assessing a child under 5. MuacMm is 0 when
+// the arm circumference wasn't measured.
+type Under5Measurements struct {
+	AgeMonths int
+	HeightCm  float64
+	WeightKg  float64
+	MuacMm    float64
+	Oedema    bool
+}
+
+// CalculateUnder5NutritionalStatus determines the nutritional status of a child 0-60 months old from
+// the WHO child growth standards. The child is classified by the most severe of weight-for-length/height,
+// MUAC from 6 months and bilateral oedema, which always indicates severe acute malnutrition.
+func CalculateUnder5NutritionalStatus(gender Gender, m Under5Measurements) (GrowthAssessment, error) {
+	if m.AgeMonths < 0 || m.AgeMonths > Under5MaxAgeMonths {
+		return GrowthAssessment{Status: NutritionalStatusError}, fmt.Errorf("age must be between 0 and %d months, got %d", Under5MaxAgeMonths, m.AgeMonths)
+	}
+
+	if gender != Male && gender != Female {
+		return GrowthAssessment{Status: NutritionalStatusGenderError}, fmt.Errorf("gender must be either 'male' or 'female', got %s", gender)
+	}
+
+	assessment := GrowthAssessment{Status: NutritionalStatusUnavailable}
+	z, err := WeightForLengthZScore(gender, m.AgeMonths, m.HeightCm, m.WeightKg)
+	if err == nil {
+		assessment = GrowthAssessment{Status: ClassifyWeightForHeight(z), ZScore: z, HasZScore: true}
+	} else if !errors.Is(err, ErrNoReferenceData) {
+		return GrowthAssessment{Status: NutritionalStatusError}, err
+	}
+
+	if m.MuacMm > 0 && m.AgeMonths >= muacMinAgeMonths {
+		if status := ClassifyMUAC(m.MuacMm); wastingSeverity(status) > wastingSeverity(assessment.Status) {
+			assessment.Status = status
+		}
+	}
+
+	if m.Oedema {
+		assessment.Status = SeverelyWasted
+	}
+
+	return assessment, nil
+}
+
 // Gender represents the gender of a student
 type Gender string
 
@@ -45,7 +219,8 @@ const (
 )
 
 // bmiThresholds contains the lower thresholds for BMI classifications by gender and age
-// The first threshold is for -2 SD (wasted) and the second is for -3 SD (severely wasted)
+// The first threshold is for -2 SD (wasted) and the second is for -3 SD (severely wasted).
+// It's only used when the growth reference doesn't cover the child's age in months.
 var bmiThresholds = map[Gender]map[int][2]float32{
 	Female: {
 		5:  {13.9, 13.0},
@@ -0,0 +1,107 @@
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"geevly/internal/school"
+	"geevly/internal/student"
+	"time"
+)
+
+// SchoolBudgetACL is an anti-corruption layer that gives the school budget service access to
+// feeding and sponsorship data from the student domain
+type SchoolBudgetACL struct {
+	studentService *student.StudentService
+}
+
+// NewSchoolBudgetACL creates a new SchoolBudgetACL instance
+func NewSchoolBudgetACL(studentService *student.StudentService) *SchoolBudgetACL {
+	return &SchoolBudgetACL{
+		studentService: studentService,
+	}
+}
+
+// CountMealsServed counts the feedings recorded for a school in the given window
+func (a *SchoolBudgetACL) CountMealsServed(ctx context.Context, schoolID uint64, from, to time.Time) (uint64, error) {
+	grouped, err := a.studentService.GetSchoolFeedingEvents(ctx, fmt.Sprintf("%d", schoolID), from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	for _, g := range grouped {
+		count += uint64(len(g.FeedingEvents))
+	}
+
+	return count, nil
+}
+
+// ListSponsorshipPayments returns the sponsorship payments made while students were enrolled in a school
+func (a *SchoolBudgetACL) ListSponsorshipPayments(ctx context.Context, schoolID uint64) ([]school.SponsorshipPayment, error) {
+	sponsorships, err := a.studentService.GetSponsorshipsForSchool(ctx, fmt.Sprintf("%d", schoolID))
+	if err != nil {
+		return nil, err
+	}
+
+	payments := make([]school.SponsorshipPayment, 0, len(sponsorships))
+	for _, sp := range sponsorships {
+		payments = append(payments, school.SponsorshipPayment{
+			StudentID: sp.StudentID,
+			StartDate: sp.StartDate,
+			EndDate:   sp.EndDate,
+			Amount:    sp.PaymentAmount,
+		})
+	}
+
+	return payments, nil
+}
+
+// ListUnfundedStudents returns the active students of a school without a sponsorship covering the given time
+func (a *SchoolBudgetACL) ListUnfundedStudents(ctx context.Context, schoolID uint64, at time.Time) ([]school.UnfundedStudent, error) {
+	sID := fmt.Sprintf("%d", schoolID)
+
+	students, err := a.studentService.ListForSchool(ctx, sID)
+	if err != nil {
+		return nil, err
+	}
+
+	// a student who transferred in is still funded by a sponsorship that started at their previous school
+	sponsorships, err := a.studentService.GetSponsorshipsOfSchoolStudents(ctx, sID)
+	if err != nil {
+		return nil, err
+	}
+
+	sponsored := make(map[string]bool)
+	for _, sp := range sponsorships {
+		if sponsorshipCovers(sp.StartDate, sp.EndDate, at) {
+			sponsored[sp.StudentID] = true
+		}
+	}
+
+	unfunded := make([]school.UnfundedStudent, 0)
+	for _, st := range students {
+		id := fmt.Sprintf("%d", st.ID)
+		if sponsored[id] {
+			continue
+		}
+
+		unfunded = append(unfunded, school.UnfundedStudent{
+			StudentID:       id,
+			StudentSchoolID: st.StudentID,
+			Name:            fmt.Sprintf("%s %s", st.FirstName, st.LastName),
+		})
+	}
+
+	return unfunded, nil
+}
+
+// sponsorshipCovers reports whether a sponsorship running from start to end, both days inclusive, covers the
+// day at falls on. Sponsorship dates are stored as days, so the time of day at carries doesn't count.
+func sponsorshipCovers(start, end, at time.Time) bool {
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	d := day(at)
+	return !d.Before(day(start)) && !d.After(day(end))
+}
@@ -0,0 +1,32 @@
+package webapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSponsorshipCovers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"day before start", time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC), false},
+		{"start of first day", start, true},
+		{"last day morning", time.Date(2024, 6, 30, 8, 0, 0, 0, time.UTC), true},
+		{"end of last day", time.Date(2024, 6, 30, 23, 59, 59, 0, time.UTC), true},
+		{"midnight after last day", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), false},
+		{"day after last day", time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sponsorshipCovers(start, end, tt.at); got != tt.want {
+				t.Errorf("sponsorshipCovers(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
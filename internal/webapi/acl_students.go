@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"geevly/gen/go/eda"
 	"geevly/internal/file"
 	"geevly/internal/school"
 	"strconv"
@@ -33,6 +34,36 @@ func (as AclStudents) ValidatePhotoID(ctx context.Context, photoID string) error
 	return as.fileService.ValidateFileID(ctx, photoID)
 }
 
+// GetSchoolYearEnd returns the month and day the school's year ends, nil when the school period isn't set
+func (as AclStudents) GetSchoolYearEnd(ctx context.Context, schoolID string) (*eda.School_MonthDay, error) {
+	id, err := strconv.ParseUint(schoolID, 10, 64)
+	if err != nil {
+		return nil, errors.Join(ErrSchoolIDInvalid, err)
+	}
+
+	agg, err := as.schoolService.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return agg.GetData().GetSchoolEnd(), nil
+}
+
+// GetMissedMealThreshold returns the school days without a meal before a student is followed up
+func (as AclStudents) GetMissedMealThreshold(ctx context.Context, schoolID string) (uint32, error) {
+	id, err := strconv.ParseUint(schoolID, 10, 64)
+	if err != nil {
+		return 0, errors.Join(ErrSchoolIDInvalid, err)
+	}
+
+	agg, err := as.schoolService.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return agg.GetData().GetMissedMealThreshold(), nil
+}
+
 // NewAclStudents creates a new AclStudents instance
 func NewAclStudents(schoolService *school.Service, fileService *file.Service) AclStudents {
 	return AclStudents{
@@ -8,6 +8,8 @@ import (
 	"strconv"
 
 	"geevly/gen/go/eda"
+	bulkupload "geevly/internal/bulk_upload"
+	"geevly/internal/infrastructure"
 	"geevly/internal/webapi/static"
 	"geevly/internal/webapi/templates/admin/bulk_upload"
 	components "geevly/internal/webapi/templates/components"
@@ -67,6 +69,9 @@ func (s *Server) bulkUploadAdminUploadForm(w http.ResponseWriter, r *http.Reques
 	case "health_assessment":
 		s.healthAssessment(w, r)
 		return
+	case "attendance":
+		s.attendance(w, r)
+		return
 	}
 
 	// TODO: handle fallthrough
@@ -92,6 +97,16 @@ func (s *Server) healthAssessment(w http.ResponseWriter, r *http.Request) {
 	s.renderTempl(w, r, bulk_upload.HealthAssessmentForm(schools))
 }
 
+func (s *Server) attendance(w http.ResponseWriter, r *http.Request) {
+	schools, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.renderTempl(w, r, bulk_upload.AttendanceForm(schools))
+}
+
 func (s *Server) newStudents(w http.ResponseWriter, r *http.Request) {
 	schools, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
 	if err != nil {
@@ -122,6 +137,8 @@ func (s *Server) getDomain(domain string) eda.BulkUpload_Domain {
 		return eda.BulkUpload_NEW_STUDENTS
 	case "health_assessment":
 		return eda.BulkUpload_HEALTH_ASSESSMENT
+	case "attendance":
+		return eda.BulkUpload_ATTENDANCE
 	default:
 		return eda.BulkUpload_UNKNOWN_DOMAIN
 	}
@@ -152,10 +169,12 @@ func (s *Server) bulkUploadAdminStoreUpload(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Build the aggregate
-	agg, err := s.Services.BulkUploadSvc.Create(r.Context(), &eda.BulkUpload_Create{
-		TargetDomain:   domain.GetDomain(),
-		FileId:         fileID,
-		UploadMetadata: metadata,
+	agg, err := infrastructure.DispatchAs[*bulkupload.Aggregate](s.commandContext(r), s.Services.Commands, infrastructure.Command{
+		Message: &eda.BulkUpload_Create{
+			TargetDomain:   domain.GetDomain(),
+			FileId:         fileID,
+			UploadMetadata: metadata,
+		},
 	})
 	if err != nil {
 		s.handleBulkUploadError(w, err.Error(), http.StatusInternalServerError)
@@ -286,13 +305,15 @@ func (s *Server) bulkUploadAdminProcessUpload(w http.ResponseWriter, r *http.Req
 	}
 
 	// TODO: move to a goroutine
-	// use background context for processing, because we don't want to stop processing if the request is canceled
-	if err := domain.ProcessUpload(context.Background(), agg, s.Services.BulkUploadSvc, data); err != nil {
+	// use background context for processing, because we don't want to stop processing if the request is canceled,
+	// it runs as the system since the request's actor doesn't outlive the request
+	ctx := infrastructure.WithActor(context.Background(), infrastructure.SystemActor)
+	if err := domain.ProcessUpload(ctx, agg, s.Services.BulkUploadSvc, data); err != nil {
 		http.Error(w, "Error processing upload: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if err := s.Services.BulkUploadSvc.SetStatus(context.Background(), id, eda.BulkUpload_COMPLETED); err != nil {
+	if err := s.Services.BulkUploadSvc.SetStatus(ctx, id, eda.BulkUpload_COMPLETED); err != nil {
 		http.Error(w, "Error setting status: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
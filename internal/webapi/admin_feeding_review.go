@@ -0,0 +1,205 @@
+package webapi
+
+import (
+	"fmt"
+	"geevly/gen/go/eda"
+	"geevly/internal/student"
+	reportstempl "geevly/internal/webapi/templates/admin/reports"
+	studenttempl "geevly/internal/webapi/templates/admin/student"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clerkinc/clerk-sdk-go/clerk"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultFeedingReviewSample is how many photos are sampled from each school when the reviewer doesn't say
+const defaultFeedingReviewSample = 10
+
+// reviewDateRange reads the from and to dates of the review pages, the last 30 days by default
+func reviewDateRange(r *http.Request) (time.Time, time.Time, error) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	for key, ref := range map[string]*time.Time{"from": &from, "to": &to} {
+		if d := r.URL.Query().Get(key); d != "" {
+			parsed, err := time.Parse("2006-01-02", d)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("invalid %s date %q: %w", key, d, err)
+			}
+			*ref = parsed
+		}
+	}
+	return from, to, nil
+}
+
+// userNames maps clerk user IDs to display names, users that can't be found keep their ID
+func (s *Server) userNames(ids []string) map[string]string {
+	names := make(map[string]string, len(ids))
+	lookup := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != "" {
+			names[id] = id
+			lookup = append(lookup, id)
+		}
+	}
+	if len(lookup) == 0 {
+		return names
+	}
+
+	limit := len(lookup)
+	users, err := s.Clerk.Users().ListAll(clerk.ListAllUsersParams{UserIDs: lookup, Limit: &limit})
+	if err != nil {
+		slog.Error("failed to look up user names", "error", err)
+		return names
+	}
+
+	for _, u := range users {
+		var parts []string
+		if u.FirstName != nil && *u.FirstName != "" {
+			parts = append(parts, *u.FirstName)
+		}
+		if u.LastName != nil && *u.LastName != "" {
+			parts = append(parts, *u.LastName)
+		}
+		switch {
+		case len(parts) > 0:
+			names[u.ID] = strings.Join(parts, " ")
+		case u.Username != nil:
+			names[u.ID] = *u.Username
+		}
+	}
+
+	return names
+}
+
+func (s *Server) adminFeedingReviewQueue(w http.ResponseWriter, r *http.Request) {
+	from, to, err := reviewDateRange(r)
+	if err != nil {
+		s.errorPage(w, r, "Invalid date", err)
+		return
+	}
+
+	query := student.FeedingReviewQuery{
+		SchoolID:  r.URL.Query().Get("school_id"),
+		From:      from,
+		To:        to,
+		PerSchool: defaultFeedingReviewSample,
+	}
+	if v := r.URL.Query().Get("sample"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n == 0 {
+			s.errorPage(w, r, "Invalid sample size", fmt.Errorf("sample size must be a positive number, got %q", v))
+			return
+		}
+		query.PerSchool = uint(n)
+	}
+
+	items, err := s.Services.StudentSvc.GetFeedingReviewQueue(r.Context(), query)
+	if err != nil {
+		s.errorPage(w, r, "Error fetching feedings to review", err)
+		return
+	}
+
+	schools, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error getting schools", err)
+		return
+	}
+
+	schoolsMap := make(map[string]string)
+	for id, school := range schools {
+		schoolsMap[fmt.Sprintf("%d", id)] = school
+	}
+
+	feeders := make([]string, 0, len(items))
+	for _, item := range items {
+		feeders = append(feeders, item.FedBy)
+	}
+
+	s.renderTempl(w, r, studenttempl.FeedingReviewQueue(items, schoolsMap, s.userNames(feeders), query))
+}
+
+func (s *Server) adminReviewFeeding(w http.ResponseWriter, r *http.Request) {
+	studentID, err := strconv.ParseUint(chi.URLParam(r, "ID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid student ID", err)
+		return
+	}
+
+	feedingID, err := strconv.ParseUint(chi.URLParam(r, "FEEDINGID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid feeding ID", err)
+		return
+	}
+
+	status, ok := eda.Student_Feeding_Review_Status_value[r.FormValue("status")]
+	if !ok {
+		s.errorPage(w, r, "Invalid review", fmt.Errorf("unknown review status %q", r.FormValue("status")))
+		return
+	}
+
+	userID, err := s.getSessionUserID(r)
+	if err != nil {
+		s.errorPage(w, r, "Error getting user", err)
+		return
+	}
+
+	reviewStatus := eda.Student_Feeding_Review_Status(status)
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	_, err = s.studentCommand(r, studentID, &eda.Student_ReviewFeeding{
+		FeedingId: feedingID,
+		Review: &eda.Student_Feeding_Review{
+			Status:     reviewStatus,
+			Reason:     reason,
+			ReviewedBy: userID,
+		},
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error reviewing feeding", err)
+		return
+	}
+
+	s.renderTempl(w, r, studenttempl.FeedingReviewed(reviewStatus, reason))
+}
+
+func (s *Server) adminFeederReviewReport(w http.ResponseWriter, r *http.Request) {
+	from, to, err := reviewDateRange(r)
+	if err != nil {
+		s.errorPage(w, r, "Invalid date", err)
+		return
+	}
+	schoolID := r.URL.Query().Get("school_id")
+
+	stats, err := s.Services.StudentSvc.GetFeederReviewStats(r.Context(), schoolID, from, to)
+	if err != nil {
+		s.errorPage(w, r, "Error building feeder report", err)
+		return
+	}
+
+	// the feeders most often rejected first, they're the ones to look into
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].RejectionRate() > stats[j].RejectionRate()
+	})
+
+	schoolMap, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error fetching schools", err)
+		return
+	}
+
+	schoolStrMap := make(map[string]string)
+	for k, v := range schoolMap {
+		schoolStrMap[fmt.Sprintf("%d", k)] = v
+	}
+
+	feeders := make([]string, 0, len(stats))
+	for _, st := range stats {
+		feeders = append(feeders, st.FedBy)
+	}
+
+	s.renderTempl(w, r, reportstempl.FeederReviews(schoolStrMap, schoolID, from, to, stats, s.userNames(feeders)))
+}
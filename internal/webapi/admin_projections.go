@@ -0,0 +1,77 @@
+package webapi
+
+import (
+	"errors"
+	"fmt"
+	"geevly/internal/infrastructure"
+	projectionstempl "geevly/internal/webapi/templates/admin/projections"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) projectionAdminRoutes(r chi.Router) {
+	r.Get("/", s.adminProjections)
+	r.Get("/status", s.adminProjectionStatus)
+	r.Post("/aggregate", s.adminRebuildProjectionAggregate)
+	r.Post("/{NAME}/rebuild", s.adminRebuildProjection)
+}
+
+func (s *Server) adminProjections(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.Projector.Statuses(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error reading projections", err)
+		return
+	}
+
+	s.renderTempl(w, r, projectionstempl.Projections(statuses, s.Projector.Jobs(), ""))
+}
+
+// renderProjectionStatus renders the polled status panel with an optional message about the last action
+func (s *Server) renderProjectionStatus(w http.ResponseWriter, r *http.Request, message string) {
+	statuses, err := s.Projector.Statuses(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error reading projections", err)
+		return
+	}
+
+	s.renderTempl(w, r, projectionstempl.Status(statuses, s.Projector.Jobs(), message))
+}
+
+func (s *Server) adminProjectionStatus(w http.ResponseWriter, r *http.Request) {
+	s.renderProjectionStatus(w, r, "")
+}
+
+func (s *Server) adminRebuildProjection(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "NAME")
+
+	err := s.Projector.StartRebuild(name)
+	switch {
+	case errors.Is(err, infrastructure.ErrProjectionBusy):
+		s.renderProjectionStatus(w, r, fmt.Sprintf("%s is already running, wait for it to finish", name))
+	case err != nil:
+		s.errorPage(w, r, "Error starting rebuild", err)
+	default:
+		s.renderProjectionStatus(w, r, fmt.Sprintf("Rebuild of %s started", name))
+	}
+}
+
+func (s *Server) adminRebuildProjectionAggregate(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("projection")
+	aggregateID := strings.TrimSpace(r.FormValue("aggregate_id"))
+
+	err := s.Projector.RebuildAggregate(r.Context(), name, aggregateID)
+	switch {
+	case errors.Is(err, infrastructure.ErrUnknownAggregate):
+		s.renderProjectionStatus(w, r, fmt.Sprintf("No events found for %s in %s", aggregateID, name))
+	case errors.Is(err, infrastructure.ErrRebuildInProgress):
+		s.renderProjectionStatus(w, r, fmt.Sprintf("%s is being rebuilt, which includes aggregate %s. Check it again once the rebuild finishes.", name, aggregateID))
+	case errors.Is(err, infrastructure.ErrProjectionBusy):
+		s.renderProjectionStatus(w, r, fmt.Sprintf("%s is catching up with its events, try again in a moment", name))
+	case err != nil:
+		s.errorPage(w, r, "Error rebuilding aggregate", err)
+	default:
+		s.renderProjectionStatus(w, r, fmt.Sprintf("Rebuilt %s for aggregate %s", name, aggregateID))
+	}
+}
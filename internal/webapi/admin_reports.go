@@ -1,6 +1,7 @@
 package webapi
 
 import (
+	"database/sql"
 	"encoding/csv"
 	"fmt"
 	"geevly/internal/student"
@@ -19,8 +20,13 @@ func (s *Server) adminReports(r chi.Router) {
 	r.Get("/", s.reportsHome)
 	r.Get("/sponsored-students", s.adminSponsoredStudentsReport)
 	r.Get("/recent-feedings", s.adminRecentFeedingsReport)
+	r.Get("/budget", s.adminBudgetReport)
+	r.Get("/transfers", s.adminTransferReport)
 	r.Get("/health-csv", s.adminHealthCSV)
 	r.Get("/grades-csv", s.adminGradesCSV)
+	r.Get("/outcomes", s.adminOutcomeCorrelationReport)
+	r.Get("/attendance", s.adminAttendanceReport)
+	r.Get("/feeders", s.adminFeederReviewReport)
 	r.Post("/export", s.exportFeedingReport)
 	r.Get("/student-qr", s.studentQRLeadIn)
 	r.Get("/student-qr-bulk", s.exportStudentQRBulk)
@@ -424,6 +430,117 @@ func (s *Server) adminRecentFeedingsReport(w http.ResponseWriter, r *http.Reques
 	s.renderTempl(w, r, reportstempl.RecentFeedingsReport(recentFeedings, pagination))
 }
 
+// adminBudgetReport shows budget burn, projected run-out and unfunded students for every school
+func (s *Server) adminBudgetReport(w http.ResponseWriter, r *http.Request) {
+	at := time.Now()
+	if d := r.URL.Query().Get("date"); d != "" {
+		parsed, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			s.errorPage(w, r, "Invalid date", err)
+			return
+		}
+		at = parsed
+	}
+
+	reports, err := s.Services.BudgetSvc.ListReports(r.Context(), at)
+	if err != nil {
+		s.errorPage(w, r, "Error building budget report", err)
+		return
+	}
+
+	s.renderTempl(w, r, reportstempl.BudgetReport(reports, at))
+}
+
+// adminTransferReport lists the transfers in and out of each school, defaulting to the last year
+func (s *Server) adminTransferReport(w http.ResponseWriter, r *http.Request) {
+	to := time.Now()
+	from := to.AddDate(-1, 0, 0)
+	for key, ref := range map[string]*time.Time{"from": &from, "to": &to} {
+		if d := r.URL.Query().Get(key); d != "" {
+			parsed, err := time.Parse("2006-01-02", d)
+			if err != nil {
+				s.errorPage(w, r, "Invalid date", err)
+				return
+			}
+			*ref = parsed
+		}
+	}
+
+	transfers, err := s.Services.StudentSvc.GetTransfers(r.Context(), from, to)
+	if err != nil {
+		s.errorPage(w, r, "Error fetching transfers", err)
+		return
+	}
+
+	schoolMap, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error fetching schools", err)
+		return
+	}
+
+	schoolName := func(id string) string {
+		if sid, err := strconv.ParseUint(id, 10, 64); err == nil {
+			if name, ok := schoolMap[sid]; ok {
+				return name
+			}
+		}
+		return "Unknown School"
+	}
+
+	studentIDs := make([]string, 0, len(transfers))
+	for _, t := range transfers {
+		studentIDs = append(studentIDs, t.StudentID)
+	}
+
+	studentNames := make(map[string]string)
+	if len(studentIDs) > 0 {
+		students, err := s.Services.StudentSvc.FetchManyStudentProjections(r.Context(), studentIDs)
+		if err != nil {
+			s.errorPage(w, r, "Error fetching students", err)
+			return
+		}
+		for _, st := range students {
+			studentNames[fmt.Sprintf("%d", st.ID)] = fmt.Sprintf("%s %s", st.FirstName, st.LastName)
+		}
+	}
+
+	bySchool := make(map[string]*reportstempl.SchoolTransfers)
+	group := func(id string) *reportstempl.SchoolTransfers {
+		if _, ok := bySchool[id]; !ok {
+			bySchool[id] = &reportstempl.SchoolTransfers{SchoolID: id, SchoolName: schoolName(id)}
+		}
+		return bySchool[id]
+	}
+
+	for _, t := range transfers {
+		row := reportstempl.TransferRow{
+			StudentID:   t.StudentID,
+			StudentName: studentNames[t.StudentID],
+			Date:        t.TransferDate,
+			Reason:      t.Reason,
+		}
+
+		in := row
+		in.OtherSchool = schoolName(t.FromSchoolID)
+		group(t.ToSchoolID).In = append(group(t.ToSchoolID).In, in)
+
+		out := row
+		out.OtherSchool = schoolName(t.ToSchoolID)
+		group(t.FromSchoolID).Out = append(group(t.FromSchoolID).Out, out)
+	}
+
+	schools := make([]reportstempl.SchoolTransfers, 0, len(bySchool))
+	for _, st := range bySchool {
+		schools = append(schools, *st)
+	}
+
+	sort.Slice(schools, func(i, j int) bool {
+		return schools[i].SchoolName < schools[j].SchoolName
+	})
+
+	s.renderTempl(w, r, reportstempl.TransferReport(schools, from, to))
+}
+
 // adminHealthCSV streams a CSV of height and weight assessments
 func (s *Server) adminHealthCSV(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
@@ -479,7 +596,7 @@ func (s *Server) adminHealthCSV(w http.ResponseWriter, r *http.Request) {
 	defer cw.Flush()
 
 	// header (combined Health + BMI + Nutrition)
-	_ = cw.Write([]string{"Student ID", "Student LRN", "First Name", "Last Name", "School", "Assessment Date", "Height (cm)", "Weight (kg)", "BMI", "Nutritional Status"})
+	_ = cw.Write([]string{"Student ID", "Student LRN", "First Name", "Last Name", "School", "Assessment Date", "Height (cm)", "Weight (kg)", "BMI", "Nutritional Status", "BMI-for-age Z", "Height-for-age Z", "Stunting Status", "Weight-for-age Z", "Weight-for-height Z", "MUAC (mm)", "Oedema"})
 	for _, rec := range recs {
 		sid, _ := strconv.ParseUint(rec.SchoolID, 10, 64)
 		schoolName := schoolMap[sid]
@@ -487,6 +604,14 @@ func (s *Server) adminHealthCSV(w http.ResponseWriter, r *http.Request) {
 		if rec.BMI.Valid {
 			bmiStr = fmt.Sprintf("%.1f", rec.BMI.Float64)
 		}
+		muacStr := ""
+		if rec.MuacMM.Valid {
+			muacStr = fmt.Sprintf("%.0f", rec.MuacMM.Float64)
+		}
+		oedemaStr := "No"
+		if rec.Oedema {
+			oedemaStr = "Yes"
+		}
 		st := studentsByAggID[rec.GetStudentAggID()]
 		lrn := ""
 		firstName := ""
@@ -507,11 +632,34 @@ func (s *Server) adminHealthCSV(w http.ResponseWriter, r *http.Request) {
 			fmt.Sprintf("%.1f", rec.WeightKG),
 			bmiStr,
 			rec.NutritionalStatus.String,
+			formatZScore(rec.BMIZScore),
+			formatZScore(rec.HeightForAgeZScore),
+			rec.StuntingStatus.String,
+			formatZScore(rec.WeightForAgeZScore),
+			formatZScore(rec.WeightForHeightZScore),
+			muacStr,
+			oedemaStr,
 		}
 		_ = cw.Write(row)
 	}
 }
 
+// formatZScore formats a z-score for export, empty when the growth reference didn't cover the student's age
+func formatZScore(z sql.NullFloat64) string {
+	if !z.Valid {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", z.Float64)
+}
+
+// formatOverallAverage is empty when the school only reported subject scores
+func formatOverallAverage(avg sql.NullFloat64) string {
+	if !avg.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(avg.Float64, 'f', -1, 64)
+}
+
 // adminGradesCSV streams a CSV of student grades
 func (s *Server) adminGradesCSV(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
@@ -566,7 +714,21 @@ func (s *Server) adminGradesCSV(w http.ResponseWriter, r *http.Request) {
 	cw := csv.NewWriter(w)
 	defer cw.Flush()
 
-	_ = cw.Write([]string{"Student ID", "Student LRN", "First Name", "Last Name", "School", "Test Date", "Grade", "School Year", "Grading Period"})
+	// each subject reported in the range gets its own column, empty for reports without it
+	subjectSet := make(map[string]bool)
+	for _, rec := range recs {
+		for _, subj := range rec.Subjects {
+			subjectSet[subj.Subject] = true
+		}
+	}
+	subjects := make([]string, 0, len(subjectSet))
+	for subj := range subjectSet {
+		subjects = append(subjects, subj)
+	}
+	sort.Strings(subjects)
+
+	header := []string{"Student ID", "Student LRN", "First Name", "Last Name", "School", "Test Date", "Grade", "Overall Average", "School Year", "Grading Period", "Grading Scale", "Max Score", "Passing Score"}
+	_ = cw.Write(append(header, subjects...))
 	for _, rec := range recs {
 		sid, _ := strconv.ParseUint(rec.SchoolID, 10, 64)
 		schoolName := schoolMap[sid]
@@ -587,9 +749,160 @@ func (s *Server) adminGradesCSV(w http.ResponseWriter, r *http.Request) {
 			schoolName,
 			rec.TestDate.Format("2006-01-02"),
 			fmt.Sprintf("%d", rec.Grade),
+			formatOverallAverage(rec.OverallAverage),
 			rec.SchoolYear.String,
 			rec.GradingPeriod.String,
+			rec.GradingScale,
+			strconv.FormatFloat(rec.MaxScore, 'f', -1, 64),
+			strconv.FormatFloat(rec.PassingScore, 'f', -1, 64),
+		}
+		for _, subj := range subjects {
+			score := ""
+			if v, ok := rec.Score(subj); ok {
+				score = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+			row = append(row, score)
 		}
 		_ = cw.Write(row)
 	}
 }
+
+// adminOutcomeCorrelationReport relates students' feeding rate to their change in grades and BMI-for-age
+// z-score, as a page or a CSV of the quartile statistics when output=csv
+func (s *Server) adminOutcomeCorrelationReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	schoolID := q.Get("school_id")
+	to := time.Now()
+	from := to.AddDate(-1, 0, 0)
+	for key, ref := range map[string]*time.Time{"from": &from, "to": &to} {
+		if d := q.Get(key); d != "" {
+			parsed, err := time.Parse("2006-01-02", d)
+			if err != nil {
+				s.errorPage(w, r, "Invalid date", err)
+				return
+			}
+			*ref = parsed
+		}
+	}
+
+	var report *student.OutcomeCorrelationReport
+	if schoolID != "" {
+		var err error
+		report, err = s.Services.StudentSvc.GetOutcomeCorrelation(r.Context(), schoolID, from, to)
+		if err != nil {
+			s.errorPage(w, r, "Error building outcome report", err)
+			return
+		}
+	}
+
+	if q.Get("output") == "csv" && report != nil {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=feeding_outcomes_%s_%s_%s.csv", schoolID, from.Format("2006-01-02"), to.Format("2006-01-02")))
+		cw := csv.NewWriter(w)
+		defer cw.Flush()
+
+		_ = cw.Write([]string{"Quartile", "Students", "Min Feeding Rate", "Max Feeding Rate", "Mean Feeding Rate",
+			"Students With Grade Change", "Mean Grade Change (pts)", "Students With BMI Z-Score Change", "Mean BMI Z-Score Change",
+			"Feeding/Grade Correlation", "Feeding/BMI Z-Score Correlation"})
+		summaries := append(report.Quartiles[:len(report.Quartiles):len(report.Quartiles)], report.Overall)
+		for i, summary := range summaries {
+			row := []string{
+				summary.Label,
+				strconv.Itoa(summary.Students),
+				fmt.Sprintf("%.3f", summary.MinFeedingRate),
+				fmt.Sprintf("%.3f", summary.MaxFeedingRate),
+				fmt.Sprintf("%.3f", summary.MeanFeedingRate),
+				strconv.Itoa(summary.GradeChanges),
+				fmt.Sprintf("%.2f", summary.MeanGradeChange),
+				strconv.Itoa(summary.BMIZScoreChanges),
+				fmt.Sprintf("%.3f", summary.MeanBMIZScoreChange),
+				"",
+				"",
+			}
+			// the correlations are across all students, they're only on the last row
+			if i == len(summaries)-1 {
+				if report.HasGradeCorrelation {
+					row[9] = fmt.Sprintf("%.3f", report.GradeCorrelation)
+				}
+				if report.HasBMIZScoreCorrelation {
+					row[10] = fmt.Sprintf("%.3f", report.BMIZScoreCorrelation)
+				}
+			}
+			_ = cw.Write(row)
+		}
+		return
+	}
+
+	schoolMap, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error fetching schools", err)
+		return
+	}
+
+	schoolStrMap := make(map[string]string)
+	for k, v := range schoolMap {
+		schoolStrMap[fmt.Sprintf("%d", k)] = v
+	}
+
+	s.renderTempl(w, r, reportstempl.OutcomeCorrelation(schoolStrMap, schoolID, from, to, report))
+}
+
+// adminAttendanceReport shows a school's daily attendance and the students who were at school but
+// weren't fed, all schools when none is selected
+func (s *Server) adminAttendanceReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	schoolID := q.Get("school_id")
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	for key, ref := range map[string]*time.Time{"from": &from, "to": &to} {
+		if d := q.Get(key); d != "" {
+			parsed, err := time.Parse("2006-01-02", d)
+			if err != nil {
+				s.errorPage(w, r, "Invalid date", err)
+				return
+			}
+			*ref = parsed
+		}
+	}
+
+	report, err := s.Services.StudentSvc.GetAttendanceReport(r.Context(), schoolID, from, to)
+	if err != nil {
+		s.errorPage(w, r, "Error building attendance report", err)
+		return
+	}
+
+	schoolMap, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error fetching schools", err)
+		return
+	}
+
+	schoolStrMap := make(map[string]string)
+	for k, v := range schoolMap {
+		schoolStrMap[fmt.Sprintf("%d", k)] = v
+	}
+
+	if q.Get("output") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=attended_not_fed_%s_%s.csv", from.Format("2006-01-02"), to.Format("2006-01-02")))
+		cw := csv.NewWriter(w)
+		defer cw.Flush()
+
+		_ = cw.Write([]string{"Date", "School ID", "School", "Student ID", "LRN", "First Name", "Last Name", "Recorded By"})
+		for _, nf := range report.NotFed {
+			_ = cw.Write([]string{
+				nf.Date.Format("2006-01-02"),
+				nf.SchoolID,
+				schoolStrMap[nf.SchoolID],
+				nf.StudentID,
+				nf.StudentSchoolID,
+				nf.FirstName,
+				nf.LastName,
+				nf.RecordedBy,
+			})
+		}
+		return
+	}
+
+	s.renderTempl(w, r, reportstempl.Attendance(schoolStrMap, report))
+}
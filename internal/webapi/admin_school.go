@@ -1,14 +1,18 @@
 package webapi
 
 import (
+	"errors"
 	"fmt"
 	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
+	"geevly/internal/student"
 	schooltempl "geevly/internal/webapi/templates/admin/school"
 	components "geevly/internal/webapi/templates/components"
 	layouts "geevly/internal/webapi/templates/layouts"
 	"net/http"
 	"sort"
 	"strconv"
+	"time"
 
 	vex "github.com/Howard3/valueextractor"
 	"github.com/go-chi/chi/v5"
@@ -21,8 +25,19 @@ func (s *Server) schoolAdminRoutes(r chi.Router) {
 	r.Get("/{ID}", s.adminViewSchool)
 	r.Post("/{ID}", s.adminUpdateSchool)
 	r.Get("/{ID}/history", s.adminSchoolHistory)
+	r.Get("/{ID}/asOf", s.adminViewSchoolAsOf)
 	r.Get("/{ID}/period", s.adminSchoolPeriodForm)
 	r.Post("/{ID}/period", s.adminSetSchoolPeriod)
+	r.Get("/{ID}/budget", s.adminSchoolBudgetForm)
+	r.Post("/{ID}/meal-cost", s.adminSetSchoolMealCost)
+	r.Get("/{ID}/follow-ups", s.adminSchoolFollowUps)
+	r.Post("/{ID}/missed-meals", s.adminSetSchoolMissedMealThreshold)
+	r.Post("/{ID}/budget", s.adminSetSchoolBudgetPeriod)
+	r.Get("/{ID}/promotion", s.adminSchoolPromotionPreview)
+	r.Post("/{ID}/promotion", s.adminPromoteSchoolStudents)
+	r.Get("/{ID}/nutrition", s.adminSchoolNutritionDashboard)
+	r.Get("/{ID}/attendance", s.adminSchoolAttendance)
+	r.Post("/{ID}/attendance", s.adminMarkSchoolAttendance)
 	r.Get("/locations", s.getSchoolLocations)
 }
 
@@ -58,7 +73,7 @@ func (s *Server) adminCreateSchool(w http.ResponseWriter, r *http.Request) {
 		City:      r.FormValue("city"),
 	}
 
-	res, err := s.Services.SchoolSvc.Create(r.Context(), &cmd)
+	res, err := infrastructure.DispatchAs[*eda.School_Create_Response](s.commandContext(r), s.Services.Commands, infrastructure.Command{Message: &cmd})
 	if err != nil {
 		// TODO: handle error on-form
 		s.errorPage(w, r, "Error creating student", err)
@@ -123,7 +138,7 @@ func (s *Server) adminUpdateSchool(w http.ResponseWriter, r *http.Request) {
 		City:      city,
 	}
 
-	if _, err = s.Services.SchoolSvc.Update(r.Context(), &cmd); err != nil {
+	if _, err = s.dispatch(r, "", &cmd); err != nil {
 		s.errorPage(w, r, "Error updating school", err)
 		return
 	}
@@ -146,6 +161,43 @@ func (s *Server) adminSchoolHistory(w http.ResponseWriter, r *http.Request) {
 	s.renderTempl(w, r, schooltempl.EventHistory(history))
 }
 
+// adminViewSchoolAsOf shows the school as it was on a past date next to what has changed since
+func (s *Server) adminViewSchoolAsOf(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	at, date, err := s.asOfQuery(r)
+	if err != nil {
+		s.errorPage(w, r, "Invalid date", err)
+		return
+	}
+
+	current, err := s.Services.SchoolSvc.Get(r.Context(), id)
+	if err != nil {
+		s.errorPage(w, r, "Error getting school", err)
+		return
+	}
+
+	params := schooltempl.AsOfParams{ID: id, Date: date, CurrentVersion: current.GetVersion()}
+
+	past, err := s.Services.SchoolSvc.GetAsOf(r.Context(), id, at)
+	switch {
+	case errors.Is(err, infrastructure.ErrNotYetCreated):
+		// rendered without a school, the page says it didn't exist yet
+	case err != nil:
+		s.errorPage(w, r, "Error replaying school", err)
+		return
+	default:
+		params.School = past.GetData()
+		params.Version = past.GetVersion()
+		params.Changes = infrastructure.DiffMessages(past.GetData(), current.GetData())
+	}
+
+	s.renderTempl(w, r, schooltempl.AsOf(params))
+}
+
 func (s *Server) toggleSchoolStatus(w http.ResponseWriter, r *http.Request) {
 	// ...
 }
@@ -226,10 +278,222 @@ func (s *Server) adminSetSchoolPeriod(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	if _, err = s.Services.SchoolSvc.SetSchoolPeriod(r.Context(), &cmd); err != nil {
+	if _, err = s.dispatch(r, "", &cmd); err != nil {
 		s.errorPage(w, r, "Error setting school period", err)
 		return
 	}
 
 	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/school/%d", id), "School period updated"))
 }
+
+func (s *Server) adminSchoolBudgetForm(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	agg, err := s.Services.SchoolSvc.Get(r.Context(), id)
+	if err != nil {
+		s.errorPage(w, r, "Error getting school", err)
+		return
+	}
+
+	s.renderTempl(w, r, schooltempl.Budget(id, agg.GetData(), agg.GetVersion()))
+}
+
+func (s *Server) adminSetSchoolMealCost(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	ex := vex.Using(&vex.FormExtractor{Request: r})
+	version := vex.Result(ex, "version", vex.AsUint64)
+	mealCost := vex.Result(ex, "meal_cost", AsFloat64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	cmd := eda.School_SetMealCost{
+		Id:       id,
+		Version:  version,
+		MealCost: mealCost,
+	}
+
+	if _, err = s.dispatch(r, "", &cmd); err != nil {
+		s.errorPage(w, r, "Error setting meal cost", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/school/%d", id), "Meal cost updated"))
+}
+
+func (s *Server) adminSchoolFollowUps(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	agg, err := s.Services.SchoolSvc.Get(r.Context(), id)
+	if err != nil {
+		s.errorPage(w, r, "Error getting school", err)
+		return
+	}
+
+	tasks, err := s.Services.StudentSvc.ListFollowUps(r.Context(), student.FollowUpFilter{SchoolIDs: []string{strconv.FormatUint(id, 10)}})
+	if err != nil {
+		s.errorPage(w, r, "Error getting follow-ups", err)
+		return
+	}
+
+	s.renderTempl(w, r, schooltempl.FollowUps(id, agg.GetData(), agg.GetVersion(), s.Services.StudentSvc.DefaultMissedMealDays(), tasks))
+}
+
+func (s *Server) adminSetSchoolMissedMealThreshold(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	ex := vex.Using(&vex.FormExtractor{Request: r})
+	version := vex.Result(ex, "version", vex.AsUint64)
+	threshold := vex.Result(ex, "missed_meal_threshold", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	cmd := eda.School_SetMissedMealThreshold{
+		Id:                  id,
+		Version:             version,
+		MissedMealThreshold: uint32(threshold),
+	}
+
+	if _, err = s.dispatch(r, "", &cmd); err != nil {
+		s.errorPage(w, r, "Error setting missed meal threshold", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/school/%d", id), "Missed meal follow-up updated"))
+}
+
+func (s *Server) adminSetSchoolBudgetPeriod(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	ex := vex.Using(&vex.FormExtractor{Request: r})
+	version := vex.Result(ex, "version", vex.AsUint64)
+	startDate := ReturnProtoDate(ex, "start_date")
+	endDate := ReturnProtoDate(ex, "end_date")
+	fundedAmount := vex.Result(ex, "funded_amount", AsFloat64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	cmd := eda.School_SetBudgetPeriod{
+		Id:      id,
+		Version: version,
+		Period: &eda.School_BudgetPeriod{
+			StartDate:    startDate,
+			EndDate:      endDate,
+			FundedAmount: fundedAmount,
+		},
+	}
+
+	if _, err = s.dispatch(r, "", &cmd); err != nil {
+		s.errorPage(w, r, "Error setting budget period", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/school/%d", id), "Budget period saved"))
+}
+
+func (s *Server) adminSchoolPromotionPreview(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	agg, err := s.Services.SchoolSvc.Get(r.Context(), id)
+	if err != nil {
+		s.errorPage(w, r, "Error getting school", err)
+		return
+	}
+
+	preview, err := s.Services.StudentSvc.PreviewPromotion(r.Context(), strconv.FormatUint(id, 10), time.Now())
+	if err != nil {
+		s.errorPage(w, r, "Error previewing grade promotion", err)
+		return
+	}
+
+	s.renderTempl(w, r, schooltempl.PromotionPreview(id, agg.GetData(), preview))
+}
+
+func (s *Server) adminPromoteSchoolStudents(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		s.errorPage(w, r, "Error parsing form", err)
+		return
+	}
+
+	excluded := make(map[uint64]bool)
+	for _, studentID := range r.Form["exclude"] {
+		sid, err := strconv.ParseUint(studentID, 10, 64)
+		if err != nil {
+			s.errorPage(w, r, "Invalid student ID", err)
+			return
+		}
+		excluded[sid] = true
+	}
+
+	result, err := s.Services.StudentSvc.PromoteStudents(r.Context(), strconv.FormatUint(id, 10), time.Now(), excluded)
+	if err != nil {
+		s.errorPage(w, r, "Error promoting students", err)
+		return
+	}
+
+	if len(result.Failed) > 0 {
+		errs := make([]error, 0, len(result.Failed))
+		for studentID, err := range result.Failed {
+			errs = append(errs, fmt.Errorf("student %d: %w", studentID, err))
+		}
+		s.errorPage(w, r, fmt.Sprintf("Promoted %d students, %d failed", result.Promoted, len(result.Failed)), errors.Join(errs...))
+		return
+	}
+
+	msg := fmt.Sprintf("Promoted %d students, %d excluded", result.Promoted, result.Excluded)
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/school/%d/promotion", id), msg))
+}
+
+func (s *Server) adminSchoolNutritionDashboard(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	agg, err := s.Services.SchoolSvc.Get(r.Context(), id)
+	if err != nil {
+		s.errorPage(w, r, "Error getting school", err)
+		return
+	}
+
+	query := r.URL.Query()
+	dashboard, err := s.Services.StudentSvc.GetNutritionDashboard(r.Context(), strconv.FormatUint(id, 10), query.Get("round"), query.Get("compare"))
+	if err != nil {
+		s.errorPage(w, r, "Error building nutrition dashboard", err)
+		return
+	}
+
+	s.renderTempl(w, r, schooltempl.NutritionDashboard(id, agg.GetData(), dashboard, query.Get("compare")))
+}
@@ -3,12 +3,18 @@ package webapi
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"geevly/gen/go/eda"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
+	"geevly/internal/infrastructure"
 	"geevly/internal/student"
 	studenttempl "geevly/internal/webapi/templates/admin/student"
 	templates "geevly/internal/webapi/templates/admin/student"
@@ -27,6 +33,13 @@ func (s *Server) studentAdminRoutes(r chi.Router) {
 	r.Get("/create", s.adminCreateStudentForm)
 	r.Post("/create", s.adminCreateStudent)
 	r.Get("/QRCode", s.adminQRCode)
+	r.Get("/duplicates", s.adminDuplicateReview)
+	r.Post("/duplicates/dismiss", s.adminDismissDuplicate)
+	r.Post("/duplicates/merge", s.adminMergeDuplicate)
+	r.Get("/alerts", s.adminNutritionAlerts)
+	r.Post(`/alerts/{ALERTID:(^\d+)}/acknowledge`, s.adminAcknowledgeNutritionAlert)
+	r.Get("/feeding-review", s.adminFeedingReviewQueue)
+	r.Post(`/feeding-review/{ID:(^\d+)}/{FEEDINGID:(^\d+)}`, s.adminReviewFeeding)
 
 	r.Group(func(r chi.Router) {
 		r.Use(s.setStudentIDMiddleware)
@@ -34,10 +47,23 @@ func (s *Server) studentAdminRoutes(r chi.Router) {
 		r.Post(`/{ID:(^\d+)}`, s.adminUpdateStudent)
 		r.Get(`/{ID:(^\d+)}/feedingReport/{EVENTID:(^\d+)}`, s.feedingReport)
 		r.Get(`/{ID:(^\d+)}/history`, s.adminStudentHistory)
+		r.Get(`/{ID:(^\d+)}/history/export`, s.adminExportStudentHistory)
+		r.Get(`/{ID:(^\d+)}/asOf`, s.adminViewStudentAsOf)
 		r.Put(`/{ID:(^\d+)}/toggleStatus`, s.toggleStudentStatus)
 		r.Post(`/{ID:(^\d+)}/enroll`, s.adminEnrollStudent)
 		r.Post(`/{ID:(^\d+)}/profilePhoto`, s.adminUploadProfilePhoto)
 		r.Delete(`/{ID:(^\d+)}/enrollment`, s.adminUnenrollStudent)
+		r.Post(`/{ID:(^\d+)}/transfer`, s.adminTransferStudent)
+		r.Post(`/{ID:(^\d+)}/exit`, s.adminExitStudent)
+		r.Post(`/{ID:(^\d+)}/guardians`, s.adminAddGuardian)
+		r.Post(`/{ID:(^\d+)}/guardians/{GUARDIANID:(^\d+)}`, s.adminUpdateGuardian)
+		r.Delete(`/{ID:(^\d+)}/guardians/{GUARDIANID:(^\d+)}`, s.adminRemoveGuardian)
+		r.Post(`/{ID:(^\d+)}/health`, s.adminAddHealthAssessment)
+		r.Post(`/{ID:(^\d+)}/health/{RECORDID:(^\d+)}`, s.adminUpdateHealthAssessment)
+		r.Delete(`/{ID:(^\d+)}/health/{RECORDID:(^\d+)}`, s.adminRemoveHealthAssessment)
+		r.Post(`/{ID:(^\d+)}/grades`, s.adminAddGradeReport)
+		r.Post(`/{ID:(^\d+)}/grades/{RECORDID:(^\d+)}`, s.adminUpdateGradeReport)
+		r.Delete(`/{ID:(^\d+)}/grades/{RECORDID:(^\d+)}`, s.adminRemoveGradeReport)
 		r.Post(`/{ID:(^\d+)}/regenerateCode`, s.adminRegenerateCode)
 		r.Put(`/{ID:(^\d+)}/eligibility`, s.toggleStudentEligibility)
 	})
@@ -105,17 +131,12 @@ func (s *Server) adminViewStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	schools, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	schoolsMap, err := s.schoolNamesByID(r.Context())
 	if err != nil {
 		s.errorPage(w, r, "Error getting schools", err)
 		return
 	}
 
-	schoolsMap := make(map[string]string)
-	for id, school := range schools {
-		schoolsMap[fmt.Sprintf("%d", id)] = school
-	}
-
 	viewParams := studenttempl.ViewParams{
 		SchoolMap: schoolsMap,
 		Student:   student.GetStudent(),
@@ -127,6 +148,67 @@ func (s *Server) adminViewStudent(w http.ResponseWriter, r *http.Request) {
 	s.renderTempl(w, r, templates.AdminViewStudent(viewParams))
 }
 
+// schoolNamesByID maps school IDs, as students reference them, to the school names
+func (s *Server) schoolNamesByID(ctx context.Context) (map[string]string, error) {
+	schools, err := s.Services.SchoolSvc.MapSchoolsByID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	schoolsMap := make(map[string]string)
+	for id, school := range schools {
+		schoolsMap[fmt.Sprintf("%d", id)] = school
+	}
+
+	return schoolsMap, nil
+}
+
+// adminViewStudentAsOf shows the student as it was on a past date next to what has changed since
+func (s *Server) adminViewStudentAsOf(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	at, date, err := s.asOfQuery(r)
+	if err != nil {
+		s.errorPage(w, r, "Invalid date", err)
+		return
+	}
+
+	current, err := s.Services.StudentSvc.GetStudent(r.Context(), studentID)
+	if err != nil {
+		s.errorPage(w, r, "Error getting student", err)
+		return
+	}
+
+	params := studenttempl.AsOfParams{
+		ViewParams:     studenttempl.ViewParams{ID: studentID},
+		Date:           date,
+		CurrentVersion: current.GetVersion(),
+	}
+
+	past, err := s.Services.StudentSvc.GetStudentAsOf(r.Context(), studentID, at)
+	switch {
+	case errors.Is(err, infrastructure.ErrNotYetCreated):
+		s.renderTempl(w, r, studenttempl.AdminViewStudentAsOf(params))
+		return
+	case err != nil:
+		s.errorPage(w, r, "Error replaying student", err)
+		return
+	}
+
+	schoolsMap, err := s.schoolNamesByID(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error getting schools", err)
+		return
+	}
+
+	params.SchoolMap = schoolsMap
+	params.Student = past.GetStudent()
+	params.Aggregate = past
+	params.Version = past.GetVersion()
+	params.Changes = infrastructure.DiffMessages(past.GetStudent(), current.GetStudent())
+
+	s.renderTempl(w, r, studenttempl.AdminViewStudentAsOf(params))
+}
+
 func (s *Server) adminListStudents(w http.ResponseWriter, r *http.Request) {
 	page := s.pageQuery(r)
 	limit := s.limitQuery(r)
@@ -156,7 +238,7 @@ func (s *Server) adminCreateStudentForm(w http.ResponseWriter, r *http.Request)
 
 func (s *Server) adminCreateStudent(w http.ResponseWriter, r *http.Request) {
 	ex := vex.Using(&vex.FormExtractor{Request: r}, vex.WithOptionalKeys("grade_level"))
-	student := eda.Student_Create{
+	cmd := eda.Student_Create{
 		FirstName:       *vex.ReturnString(ex, "first_name"),
 		LastName:        *vex.ReturnString(ex, "last_name"),
 		DateOfBirth:     ReturnProtoDate(ex, "date_of_birth"),
@@ -170,7 +252,7 @@ func (s *Server) adminCreateStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	agg, err := s.Services.StudentSvc.CreateStudent(r.Context(), &student)
+	agg, err := infrastructure.DispatchAs[*student.Aggregate](s.commandContext(r), s.Services.Commands, infrastructure.Command{Message: &cmd})
 	if err != nil {
 		// TODO: handle error on-form
 		s.errorPage(w, r, "Error creating student", err)
@@ -198,7 +280,7 @@ func (s *Server) adminUpdateStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := s.Services.StudentSvc.RunCommand(r.Context(), studentID, &cmd)
+	_, err := s.studentCommand(r, studentID, &cmd)
 	if err != nil {
 		s.errorPage(w, r, "Error updating student", err)
 		return
@@ -222,7 +304,7 @@ func (s *Server) toggleStudentStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := s.Services.StudentSvc.RunCommand(r.Context(), studentID, &eda.Student_SetStatus{
+	_, err := s.studentCommand(r, studentID, &eda.Student_SetStatus{
 		Version: *vex.ReturnUint64(ex, "ver"),
 		Status:  newStatus,
 	})
@@ -243,7 +325,27 @@ func (s *Server) adminStudentHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.renderTempl(w, r, templates.StudentHistorySection(history))
+	s.renderTempl(w, r, templates.StudentHistorySection(studentID, history))
+}
+
+// adminExportStudentHistory downloads every event of the student, with its payload, as JSON
+func (s *Server) adminExportStudentHistory(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+
+	export, err := s.Services.StudentSvc.ExportHistory(r.Context(), studentID)
+	if err != nil {
+		s.errorPage(w, r, "Error exporting student history", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=student_%d_history.json", studentID))
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		slog.Error("failed to write student history export", "student_id", studentID, "error", err)
+	}
 }
 
 func (s *Server) adminEnrollStudent(w http.ResponseWriter, r *http.Request) {
@@ -260,7 +362,7 @@ func (s *Server) adminEnrollStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := s.Services.StudentSvc.RunCommand(r.Context(), studentID, &cmd)
+	_, err := s.studentCommand(r, studentID, &cmd)
 	if err != nil {
 		s.errorPage(w, r, "Error enrolling student", err)
 		return
@@ -280,7 +382,7 @@ func (s *Server) adminUnenrollStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := s.Services.StudentSvc.RunCommand(r.Context(), studentID, &eda.Student_Unenroll{
+	_, err := s.studentCommand(r, studentID, &eda.Student_Unenroll{
 		Version: version,
 	})
 	if err != nil {
@@ -291,6 +393,368 @@ func (s *Server) adminUnenrollStudent(w http.ResponseWriter, r *http.Request) {
 	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Student unenrolled"))
 }
 
+func (s *Server) adminTransferStudent(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	ex := vex.Using(&vex.FormExtractor{Request: r})
+	cmd := eda.Student_Transfer{
+		ToSchoolId:   *vex.ReturnString(ex, "to_school_id"),
+		TransferDate: ReturnProtoDate(ex, "transfer_date"),
+		Reason:       r.FormValue("reason"),
+		Version:      *vex.ReturnUint64(ex, "version"),
+	}
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	_, err := s.studentCommand(r, studentID, &cmd)
+	if err != nil {
+		s.errorPage(w, r, "Error transferring student", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Student transferred"))
+}
+
+func (s *Server) adminExitStudent(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	ex := vex.Using(&vex.FormExtractor{Request: r})
+	exitStatus := *vex.ReturnString(ex, "exit_status")
+	cmd := eda.Student_Exit{
+		Status:   eda.Student_Status(eda.Student_Status_value[exitStatus]),
+		ExitDate: ReturnProtoDate(ex, "exit_date"),
+		Reason:   r.FormValue("exit_reason"),
+		Version:  *vex.ReturnUint64(ex, "version"),
+	}
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	_, err := s.studentCommand(r, studentID, &cmd)
+	if err != nil {
+		s.errorPage(w, r, "Error recording student exit", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Student exit recorded"))
+}
+
+// guardianFromForm reads the guardian fields shared by the add and update forms
+func guardianFromForm(r *http.Request) *eda.Student_Guardian {
+	return &eda.Student_Guardian{
+		Name:             r.FormValue("name"),
+		Relationship:     r.FormValue("relationship"),
+		Phone:            r.FormValue("phone"),
+		ConsentToContact: r.FormValue("consent_to_contact") == "on",
+		ConsentToShare:   r.FormValue("consent_to_share") == "on",
+	}
+}
+
+func (s *Server) adminAddGuardian(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	ex := vex.Using(&vex.FormExtractor{Request: r})
+	version := vex.Result(ex, "version", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	_, err := s.studentCommand(r, studentID, &eda.Student_AddGuardian{
+		Guardian: guardianFromForm(r),
+		Version:  version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error adding guardian", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Guardian added"))
+}
+
+func (s *Server) adminUpdateGuardian(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	guardianID, err := strconv.ParseUint(chi.URLParam(r, "GUARDIANID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid guardian ID", err)
+		return
+	}
+
+	ex := vex.Using(&vex.FormExtractor{Request: r})
+	version := vex.Result(ex, "version", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	guardian := guardianFromForm(r)
+	guardian.Id = guardianID
+
+	_, err = s.studentCommand(r, studentID, &eda.Student_UpdateGuardian{
+		Guardian: guardian,
+		Version:  version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error updating guardian", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Guardian updated"))
+}
+
+func (s *Server) adminRemoveGuardian(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	guardianID, err := strconv.ParseUint(chi.URLParam(r, "GUARDIANID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid guardian ID", err)
+		return
+	}
+
+	ex := vex.Using(vex.QueryExtractor{Query: r.URL.Query()})
+	version := vex.Result(ex, "version", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	_, err = s.studentCommand(r, studentID, &eda.Student_RemoveGuardian{
+		GuardianId: guardianID,
+		Version:    version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error removing guardian", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Guardian removed"))
+}
+
+// healthAssessmentFromForm reads the assessment fields shared by the add and update forms, arm
+// circumference is only measured for young children
+func healthAssessmentFromForm(r *http.Request) (*eda.Student_HealthAssessment, uint64, error) {
+	ex := vex.Using(&vex.FormExtractor{Request: r}, vex.WithOptionalKeys("muac_mm"))
+	assessment := &eda.Student_HealthAssessment{
+		AssessmentDate: ReturnProtoTimestamp(ex, "assessment_date"),
+		HeightCm:       float32(vex.Result(ex, "height_cm", AsFloat64)),
+		WeightKg:       float32(vex.Result(ex, "weight_kg", AsFloat64)),
+		MuacMm:         float32(vex.Result(ex, "muac_mm", AsFloat64)),
+		Oedema:         r.FormValue("oedema") == "on",
+	}
+	version := vex.Result(ex, "version", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		return nil, 0, ex.JoinedErrors()
+	}
+
+	return assessment, version, nil
+}
+
+func (s *Server) adminAddHealthAssessment(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	assessment, version, err := healthAssessmentFromForm(r)
+	if err != nil {
+		s.errorPage(w, r, "Error parsing form", err)
+		return
+	}
+
+	_, err = s.studentCommand(r, studentID, &eda.Student_AddHealthAssessment{
+		Assessment: assessment,
+		Version:    version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error adding health assessment", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Health assessment added"))
+}
+
+func (s *Server) adminUpdateHealthAssessment(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	recordID, err := strconv.ParseUint(chi.URLParam(r, "RECORDID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid health assessment ID", err)
+		return
+	}
+
+	assessment, version, err := healthAssessmentFromForm(r)
+	if err != nil {
+		s.errorPage(w, r, "Error parsing form", err)
+		return
+	}
+	assessment.Id = recordID
+
+	_, err = s.studentCommand(r, studentID, &eda.Student_UpdateHealthAssessment{
+		Assessment: assessment,
+		Version:    version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error updating health assessment", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Health assessment updated"))
+}
+
+func (s *Server) adminRemoveHealthAssessment(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	recordID, err := strconv.ParseUint(chi.URLParam(r, "RECORDID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid health assessment ID", err)
+		return
+	}
+
+	ex := vex.Using(vex.QueryExtractor{Query: r.URL.Query()})
+	version := vex.Result(ex, "version", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	_, err = s.studentCommand(r, studentID, &eda.Student_RemoveHealthAssessment{
+		Id:      recordID,
+		Version: version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error removing health assessment", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Health assessment removed"))
+}
+
+// gradeReportFromForm reads the grade report fields shared by the add and update forms
+func gradeReportFromForm(r *http.Request) (*eda.Student_GradeReport, uint64, error) {
+	ex := vex.Using(&vex.FormExtractor{Request: r}, vex.WithOptionalKeys("school_year", "grading_period", "subjects"))
+	report := &eda.Student_GradeReport{
+		TestDate:      ReturnProtoDate(ex, "test_date"),
+		SchoolYear:    *vex.ReturnString(ex, "school_year"),
+		GradingPeriod: *vex.ReturnString(ex, "grading_period"),
+	}
+	if r.FormValue("overall_average") != "" {
+		avg := float32(vex.Result(ex, "overall_average", vex.AsFloat64))
+		report.OverallAverage = &avg
+	}
+	version := vex.Result(ex, "version", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		return nil, 0, ex.JoinedErrors()
+	}
+
+	subjects, err := parseSubjectScores(r.FormValue("subjects"))
+	if err != nil {
+		return nil, 0, err
+	}
+	report.Subjects = subjects
+
+	return report, version, nil
+}
+
+// parseSubjectScores reads subject scores written as "Math=90, English=85"
+func parseSubjectScores(value string) ([]*eda.Student_GradeReport_SubjectScore, error) {
+	scores := make([]*eda.Student_GradeReport_SubjectScore, 0)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		subject, score, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("subject score %q should be written as Subject=score", strings.TrimSpace(entry))
+		}
+
+		v, err := strconv.ParseFloat(strings.TrimSpace(score), 32)
+		if err != nil {
+			return nil, fmt.Errorf("score of %s is not a number", strings.TrimSpace(subject))
+		}
+
+		scores = append(scores, &eda.Student_GradeReport_SubjectScore{Subject: strings.TrimSpace(subject), Score: float32(v)})
+	}
+
+	return scores, nil
+}
+
+func (s *Server) adminAddGradeReport(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	report, version, err := gradeReportFromForm(r)
+	if err != nil {
+		s.errorPage(w, r, "Error parsing form", err)
+		return
+	}
+
+	_, err = s.studentCommand(r, studentID, &eda.Student_AddGradeReport{
+		Report:  report,
+		Version: version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error adding grade report", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Grade report added"))
+}
+
+func (s *Server) adminUpdateGradeReport(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	recordID, err := strconv.ParseUint(chi.URLParam(r, "RECORDID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid grade report ID", err)
+		return
+	}
+
+	report, version, err := gradeReportFromForm(r)
+	if err != nil {
+		s.errorPage(w, r, "Error parsing form", err)
+		return
+	}
+	report.Id = recordID
+
+	_, err = s.studentCommand(r, studentID, &eda.Student_UpdateGradeReport{
+		Report:  report,
+		Version: version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error updating grade report", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Grade report updated"))
+}
+
+func (s *Server) adminRemoveGradeReport(w http.ResponseWriter, r *http.Request) {
+	studentID := s.getStudentIDFromContext(r.Context())
+	recordID, err := strconv.ParseUint(chi.URLParam(r, "RECORDID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid grade report ID", err)
+		return
+	}
+
+	ex := vex.Using(vex.QueryExtractor{Query: r.URL.Query()})
+	version := vex.Result(ex, "version", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
+		return
+	}
+
+	_, err = s.studentCommand(r, studentID, &eda.Student_RemoveGradeReport{
+		Id:      recordID,
+		Version: version,
+	})
+	if err != nil {
+		s.errorPage(w, r, "Error removing grade report", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Grade report removed"))
+}
+
 func (s *Server) adminRegenerateCode(w http.ResponseWriter, r *http.Request) {
 	studentID := s.getStudentIDFromContext(r.Context())
 	fe := vex.FormExtractor{Request: r}
@@ -310,7 +774,7 @@ func (s *Server) adminRegenerateCode(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// TODO: retry on fail
-	_, err = s.Services.StudentSvc.RunCommand(r.Context(), studentID, &eda.Student_SetLookupCode{
+	_, err = s.studentCommand(r, studentID, &eda.Student_SetLookupCode{
 		CodeUniqueId: code,
 		Version:      ver,
 	})
@@ -333,7 +797,7 @@ func (s *Server) toggleStudentEligibility(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = s.Services.StudentSvc.RunCommand(r.Context(), studentID, &eda.Student_SetEligibility{
+	_, err = s.studentCommand(r, studentID, &eda.Student_SetEligibility{
 		Version:  *vex.ReturnUint64(ex, "ver"),
 		Eligible: eligible,
 	})
@@ -400,9 +864,12 @@ func (s *Server) adminUploadProfilePhoto(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	fileID, err := s.Services.FileSvc.CreateFile(r.Context(), fileBytes, &eda.File_Create{
-		Name:            "profile_photo",
-		DomainReference: eda.File_STUDENT_PROFILE_PHOTO,
+	fileID, err := infrastructure.DispatchAs[string](s.commandContext(r), s.Services.Commands, infrastructure.Command{
+		Message: &eda.File_Create{
+			Name:            "profile_photo",
+			DomainReference: eda.File_STUDENT_PROFILE_PHOTO,
+		},
+		Attachment: fileBytes,
 	})
 
 	if err != nil {
@@ -410,7 +877,7 @@ func (s *Server) adminUploadProfilePhoto(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = s.Services.StudentSvc.RunCommand(r.Context(), studentID, &eda.Student_SetProfilePhoto{
+	_, err = s.studentCommand(r, studentID, &eda.Student_SetProfilePhoto{
 		FileId:  fileID,
 		Version: ver,
 	})
@@ -422,3 +889,128 @@ func (s *Server) adminUploadProfilePhoto(w http.ResponseWriter, r *http.Request)
 
 	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%d", studentID), "Profile photo updated"))
 }
+
+func (s *Server) adminDuplicateReview(w http.ResponseWriter, r *http.Request) {
+	candidates, err := s.Services.StudentSvc.FindDuplicates(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error finding duplicates", err)
+		return
+	}
+
+	schools, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error getting schools", err)
+		return
+	}
+
+	schoolsMap := make(map[string]string)
+	for id, school := range schools {
+		schoolsMap[fmt.Sprintf("%d", id)] = school
+	}
+
+	s.renderTempl(w, r, templates.DuplicateReview(candidates, schoolsMap))
+}
+
+func (s *Server) adminDismissDuplicate(w http.ResponseWriter, r *http.Request) {
+	a, b := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if a == "" || b == "" {
+		s.errorPage(w, r, "Invalid duplicate pair", fmt.Errorf("both students are required"))
+		return
+	}
+
+	if err := s.Services.StudentSvc.DismissDuplicate(r.Context(), a, b); err != nil {
+		s.errorPage(w, r, "Error dismissing duplicate", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect("/admin/student/duplicates", "Duplicate dismissed"))
+}
+
+func (s *Server) adminMergeDuplicate(w http.ResponseWriter, r *http.Request) {
+	ex := vex.Using(vex.QueryExtractor{Query: r.URL.Query()})
+	keep := vex.Result(ex, "keep", vex.AsUint64)
+	merge := vex.Result(ex, "merge", vex.AsUint64)
+
+	if err := ex.Errors(); err != nil {
+		s.errorPage(w, r, "Error parsing request", ex.JoinedErrors())
+		return
+	}
+
+	survivor, err := s.Services.StudentSvc.MergeStudents(r.Context(), keep, merge)
+	if err != nil {
+		s.errorPage(w, r, "Error merging students", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("/admin/student/%s", survivor.GetID()), "Students merged"))
+}
+
+// adminNutritionAlerts lists the students flagged by the nutrition early-warning checks, optionally for one school
+func (s *Server) adminNutritionAlerts(w http.ResponseWriter, r *http.Request) {
+	filter := student.NutritionAlertFilter{
+		SchoolID:            r.URL.Query().Get("school_id"),
+		IncludeAcknowledged: r.URL.Query().Get("show") == "all",
+	}
+
+	alerts, err := s.Services.StudentSvc.ListNutritionAlerts(r.Context(), filter)
+	if err != nil {
+		s.errorPage(w, r, "Error listing nutrition alerts", err)
+		return
+	}
+
+	studentIDs := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		studentIDs = append(studentIDs, alert.StudentID)
+	}
+
+	studentsByID := make(map[string]*student.ProjectedStudent)
+	if len(studentIDs) > 0 {
+		sts, err := s.Services.StudentSvc.FetchManyStudentProjections(r.Context(), studentIDs)
+		if err != nil {
+			s.errorPage(w, r, "Error fetching students", err)
+			return
+		}
+		for _, st := range sts {
+			studentsByID[fmt.Sprintf("%d", st.ID)] = st
+		}
+	}
+
+	schools, err := s.Services.SchoolSvc.MapSchoolsByID(r.Context())
+	if err != nil {
+		s.errorPage(w, r, "Error getting schools", err)
+		return
+	}
+
+	schoolsMap := make(map[string]string)
+	for id, school := range schools {
+		schoolsMap[fmt.Sprintf("%d", id)] = school
+	}
+
+	s.renderTempl(w, r, templates.NutritionAlerts(alerts, studentsByID, schoolsMap, filter))
+}
+
+func (s *Server) adminAcknowledgeNutritionAlert(w http.ResponseWriter, r *http.Request) {
+	alertID, err := strconv.ParseUint(chi.URLParam(r, "ALERTID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Invalid alert ID", err)
+		return
+	}
+
+	userID, err := s.getSessionUserID(r)
+	if err != nil {
+		s.errorPage(w, r, "Error getting user", err)
+		return
+	}
+
+	if err := s.Services.StudentSvc.AcknowledgeNutritionAlert(r.Context(), alertID, userID); err != nil {
+		s.errorPage(w, r, "Error acknowledging alert", err)
+		return
+	}
+
+	redirect := "/admin/student/alerts"
+	if schoolID := r.URL.Query().Get("school_id"); schoolID != "" {
+		redirect += "?school_id=" + url.QueryEscape(schoolID)
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect(redirect, "Alert acknowledged"))
+}
@@ -2,11 +2,14 @@ package webapi
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/a-h/templ"
 	"github.com/clerkinc/clerk-sdk-go/clerk"
@@ -15,6 +18,7 @@ import (
 
 	"geevly/internal/bulk_upload"
 	"geevly/internal/file"
+	"geevly/internal/infrastructure"
 	"geevly/internal/school"
 	"geevly/internal/student"
 	"geevly/internal/webapi/bulk_domains"
@@ -29,6 +33,9 @@ type ServiceRegistry struct {
 	SchoolSvc     *school.Service
 	FileSvc       *file.Service
 	BulkUploadSvc *bulk_upload.Service
+	BudgetSvc     *school.BudgetService
+	// Commands dispatches the domains' commands, HTML handlers and the JSON API both go through it
+	Commands *infrastructure.CommandBus
 }
 
 // NewServiceRegistry creates a new service registry with the provided services
@@ -37,12 +44,16 @@ func NewServiceRegistry(
 	schoolSvc *school.Service,
 	fileSvc *file.Service,
 	bulkUploadSvc *bulk_upload.Service,
+	budgetSvc *school.BudgetService,
+	commands *infrastructure.CommandBus,
 ) *ServiceRegistry {
 	return &ServiceRegistry{
 		StudentSvc:    studentSvc,
 		SchoolSvc:     schoolSvc,
 		FileSvc:       fileSvc,
 		BulkUploadSvc: bulkUploadSvc,
+		BudgetSvc:     budgetSvc,
+		Commands:      commands,
 	}
 }
 
@@ -52,6 +63,7 @@ type Server struct {
 	StaticFS           fs.FS
 	Services           *ServiceRegistry
 	Clerk              clerk.Client
+	Projector          *infrastructure.Projector
 	bulkDomainRegistry *bulk_domains.DomainRegistry
 }
 
@@ -63,6 +75,9 @@ func NewServer(
 	schoolSvc *school.Service,
 	fileSvc *file.Service,
 	bulkUploadSvc *bulk_upload.Service,
+	budgetSvc *school.BudgetService,
+	commands *infrastructure.CommandBus,
+	projector *infrastructure.Projector,
 	clerk clerk.Client,
 ) *Server {
 	return &Server{
@@ -73,8 +88,11 @@ func NewServer(
 			schoolSvc,
 			fileSvc,
 			bulkUploadSvc,
+			budgetSvc,
+			commands,
 		),
-		Clerk: clerk,
+		Projector: projector,
+		Clerk:     clerk,
 	}
 }
 
@@ -103,6 +121,9 @@ func (s *Server) verifyConfig() {
 	if s.Services.BulkUploadSvc == nil {
 		panic("BulkUploadSvc is required")
 	}
+	if s.Projector == nil {
+		panic("Projector is required")
+	}
 
 	// Initialize the bulk domain registry if not already set
 	if s.bulkDomainRegistry == nil {
@@ -124,9 +145,23 @@ func (s *Server) getListenAddress() string {
 
 // TODO: more secure error page, anything could be dumped here!
 func (s *Server) errorPage(w http.ResponseWriter, r *http.Request, title string, err error) {
+	var conflict *infrastructure.ConflictError
+	if errors.As(err, &conflict) {
+		s.renderTempl(w, r, templates.Conflict(title, conflict, reloadURL(r)))
+		return
+	}
+
 	s.renderTempl(w, r, templates.SystemError(title, err.Error()))
 }
 
+// reloadURL is the page the request was made from, htmx sends it along with each request it makes
+func reloadURL(r *http.Request) string {
+	if u := r.Header.Get("HX-Current-URL"); u != "" {
+		return u
+	}
+	return r.Referer()
+}
+
 func (s *Server) renderTempl(w http.ResponseWriter, r *http.Request, page templ.Component) {
 	// add roles to the context for the layout
 	ctx := r.Context()
@@ -173,6 +208,22 @@ func (s *Server) limitQuery(r *http.Request) uint {
 	return uint(limit)
 }
 
+// asOfQuery reads the date of an "as of" page, defaulting to today. The whole day is included so the
+// record is shown as it was at the end of it.
+func (s *Server) asOfQuery(r *http.Request) (infrastructure.AsOf, string, error) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return infrastructure.AsOf{}, date, fmt.Errorf("invalid date %q: %w", date, err)
+	}
+
+	return infrastructure.AsOfTime(day.AddDate(0, 0, 1)), date, nil
+}
+
 func (s *Server) Start(ctx context.Context) {
 	s.ctx = ctx
 
@@ -215,6 +266,8 @@ func (s *Server) Start(ctx context.Context) {
 		r.Route("/user", s.userAdminRouter)
 		r.Route("/reports", s.adminReports)
 		r.Route("/bulk-upload", s.bulkUploadAdminRoutes)
+		r.Route("/projections", s.projectionAdminRoutes)
+		r.Get("/metrics", expvar.Handler().ServeHTTP)
 		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 			s.renderTempl(w, r, admin.AdminHome())
 		})
@@ -1,17 +1,21 @@
 package webapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	_ "geevly/docs" // This is where the generated swagger docs are
 	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
 	"geevly/internal/school"
 	"geevly/internal/student"
 
@@ -93,6 +97,14 @@ type GetStudentResponse struct {
 	DateOfBirth     string `json:"dateOfBirth,omitempty"`
 	Grade           string `json:"grade,omitempty"`
 	Active          bool   `json:"active"`
+	// Guardians is only populated for API keys granted the guardians:read scope
+	Guardians []GuardianResponse `json:"guardians,omitempty"`
+}
+
+type GuardianResponse struct {
+	Name         string `json:"name"`
+	Relationship string `json:"relationship,omitempty"`
+	Phone        string `json:"phone,omitempty"`
 }
 
 // Add this new request type after the other request types
@@ -138,6 +150,28 @@ type ListSponsorFeedingEventsResponse struct {
 	Total  int64                         `json:"total"`
 }
 
+// APIScopeGuardians grants access to guardian contact details, which are otherwise left out of every response
+const APIScopeGuardians = "guardians:read"
+
+type apiScopesKey struct{}
+
+// apiKeyScopes returns the optional scopes granted to the API key, configured as a comma separated list
+func apiKeyScopes() []string {
+	scopes := []string{}
+	for _, scope := range strings.Split(os.Getenv("API_KEY_SCOPES"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// hasAPIScope reports whether the authenticated API request was granted the scope
+func hasAPIScope(r *http.Request, scope string) bool {
+	scopes, _ := r.Context().Value(apiScopesKey{}).([]string)
+	return slices.Contains(scopes, scope)
+}
+
 // Add middleware for API key authentication
 func (s *Server) apiKeyAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -160,7 +194,8 @@ func (s *Server) apiKeyAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), apiScopesKey{}, apiKeyScopes())
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -438,6 +473,20 @@ func (s *Server) apiGetStudent(w http.ResponseWriter, r *http.Request) {
 		ProfilePhotoURL: photoURL,
 	}
 
+	// guardians are only shared when the key has the scope and the guardian consented to sharing
+	if hasAPIScope(r, APIScopeGuardians) {
+		for _, g := range student.GetGuardians() {
+			if !g.ConsentToShare {
+				continue
+			}
+			response.Guardians = append(response.Guardians, GuardianResponse{
+				Name:         g.Name,
+				Relationship: g.Relationship,
+				Phone:        g.Phone,
+			})
+		}
+	}
+
 	s.respondWithJSON(w, http.StatusOK, response)
 }
 
@@ -450,7 +499,9 @@ func (s *Server) apiGetStudent(w http.ResponseWriter, r *http.Request) {
 // @Param       request body      SponsorStudentRequest true "Sponsorship details"
 // @Success     200     {object}  SponsorStudentResponse
 // @Failure     400     {object}  ErrorResponse
+// @Failure     403     {object}  ErrorResponse
 // @Failure     404     {object}  ErrorResponse
+// @Failure     409     {object}  ErrorResponse
 // @Failure     500     {object}  ErrorResponse
 // @Router      /students/{id}/sponsor [post]
 // @Security    ApiKeyAuth
@@ -539,7 +590,15 @@ func (s *Server) apiSponsorStudent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Run the command
-	_, err = s.Services.StudentSvc.RunCommand(r.Context(), id, cmd)
+	_, err = s.studentCommand(r, id, cmd)
+	if errors.Is(err, infrastructure.ErrForbidden) {
+		s.respondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if errors.Is(err, infrastructure.ErrConflict) {
+		s.respondWithError(w, http.StatusConflict, fmt.Sprintf("Student changed while sponsoring, try again: %v", err))
+		return
+	}
 	if err != nil {
 		s.respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to sponsor student: %v", err))
 		return
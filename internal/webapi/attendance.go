@@ -0,0 +1,206 @@
+package webapi
+
+import (
+	"errors"
+	"fmt"
+	"geevly/gen/go/eda"
+	"geevly/internal/student"
+	"geevly/internal/webapi/templates/layouts"
+	stafftempl "geevly/internal/webapi/templates/staff"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// attendanceDayFromRequest reads the roster's school day, today when it isn't set
+func attendanceDayFromRequest(r *http.Request) (time.Time, error) {
+	value := r.FormValue("date")
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %w", value, err)
+	}
+	if day.After(time.Now()) {
+		return time.Time{}, fmt.Errorf("attendance can't be marked for %s, it's in the future", value)
+	}
+
+	return day, nil
+}
+
+// attendanceMarksFromForm reads the roster form, students left unmarked are skipped
+func attendanceMarksFromForm(r *http.Request) ([]student.AttendanceMark, error) {
+	marks := make([]student.AttendanceMark, 0, len(r.Form["student"]))
+	for _, id := range r.Form["student"] {
+		studentID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid student ID %q: %w", id, err)
+		}
+
+		value := r.FormValue("status_" + id)
+		if value == "" {
+			continue
+		}
+
+		status, ok := eda.Student_Attendance_Status_value[value]
+		if !ok || status == int32(eda.Student_Attendance_UNKNOWN_ATTENDANCE) {
+			return nil, fmt.Errorf("invalid attendance status %q for student %d", value, studentID)
+		}
+
+		mark := student.AttendanceMark{StudentID: studentID, Status: eda.Student_Attendance_Status(status)}
+		if mark.Status != eda.Student_Attendance_PRESENT {
+			mark.Reason = strings.TrimSpace(r.FormValue("reason_" + id))
+		}
+		marks = append(marks, mark)
+	}
+
+	return marks, nil
+}
+
+// renderAttendanceRoster renders the roster of a school for the requested day, url is the page's own
+// address so the admin and staff rosters post back to themselves
+func (s *Server) renderAttendanceRoster(w http.ResponseWriter, r *http.Request, schoolID uint64, url, backURL string) {
+	day, err := attendanceDayFromRequest(r)
+	if err != nil {
+		s.errorPage(w, r, "Invalid date", err)
+		return
+	}
+
+	school, err := s.Services.SchoolSvc.Get(r.Context(), schoolID)
+	if err != nil {
+		s.errorPage(w, r, "Error fetching school", err)
+		return
+	}
+
+	roster, err := s.Services.StudentSvc.GetAttendanceRoster(r.Context(), strconv.FormatUint(schoolID, 10), day)
+	if err != nil {
+		s.errorPage(w, r, "Error fetching attendance", err)
+		return
+	}
+
+	s.renderTempl(w, r, stafftempl.AttendanceRoster(stafftempl.AttendanceRosterConfig{
+		SchoolName: school.GetData().Name,
+		URL:        url,
+		BackURL:    backURL,
+		Day:        day,
+		Roster:     roster,
+	}))
+}
+
+// saveAttendanceRoster records the submitted roster and reloads it for the same day
+func (s *Server) saveAttendanceRoster(w http.ResponseWriter, r *http.Request, schoolID uint64, url string) {
+	if err := r.ParseForm(); err != nil {
+		s.errorPage(w, r, "Error parsing form", err)
+		return
+	}
+
+	day, err := attendanceDayFromRequest(r)
+	if err != nil {
+		s.errorPage(w, r, "Invalid date", err)
+		return
+	}
+
+	marks, err := attendanceMarksFromForm(r)
+	if err != nil {
+		s.errorPage(w, r, "Invalid attendance", err)
+		return
+	}
+
+	userID, err := s.getSessionUserID(r)
+	if err != nil {
+		s.errorPage(w, r, "Error getting user", err)
+		return
+	}
+
+	// the latest mark of a day wins, a student failing to be marked doesn't stop the others
+	var errs []error
+	for _, mark := range marks {
+		_, err := s.studentCommand(r, mark.StudentID, &eda.Student_MarkAttendance{
+			Attendance: &eda.Student_Attendance{
+				Date:       &eda.Date{Year: int32(day.Year()), Month: int32(day.Month()), Day: int32(day.Day())},
+				Status:     mark.Status,
+				Reason:     mark.Reason,
+				SchoolId:   strconv.FormatUint(schoolID, 10),
+				RecordedBy: userID,
+			},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("student %d: %w", mark.StudentID, err))
+		}
+	}
+
+	marked := len(marks) - len(errs)
+	if len(errs) > 0 {
+		s.errorPage(w, r, fmt.Sprintf("Marked %d students, %d failed", marked, len(errs)), errors.Join(errs...))
+		return
+	}
+
+	msg := fmt.Sprintf("Attendance saved, %d students marked", marked)
+	s.renderTempl(w, r, layouts.HTMXRedirect(fmt.Sprintf("%s?date=%s", url, day.Format("2006-01-02")), msg))
+}
+
+func (s *Server) adminSchoolAttendance(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	s.renderAttendanceRoster(w, r, id, fmt.Sprintf("/admin/school/%d/attendance", id), fmt.Sprintf("/admin/school/%d", id))
+}
+
+func (s *Server) adminMarkSchoolAttendance(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readSchoolIDFromURL(w, r)
+	if err != nil {
+		return
+	}
+
+	s.saveAttendanceRoster(w, r, id, fmt.Sprintf("/admin/school/%d/attendance", id))
+}
+
+// staffAttendanceSchool reads the school from the URL, staff may only take attendance at the schools
+// they're enrolled to feed
+func (s *Server) staffAttendanceSchool(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	schoolID, err := strconv.ParseUint(chi.URLParam(r, "schoolID"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Error parsing school ID", err)
+		return 0, false
+	}
+
+	feederEnrollments, err := s.getFeederEnrollments(r)
+	if err != nil {
+		s.errorPage(w, r, "Error fetching feeder enrollments", err)
+		return 0, false
+	}
+
+	if !slices.Contains(feederEnrollments, schoolID) {
+		s.errorPage(w, r, "Not assigned to this school", fmt.Errorf("user isn't a feeder at school %d", schoolID))
+		return 0, false
+	}
+
+	return schoolID, true
+}
+
+func (s *Server) staffSchoolAttendance(w http.ResponseWriter, r *http.Request) {
+	schoolID, ok := s.staffAttendanceSchool(w, r)
+	if !ok {
+		return
+	}
+
+	s.renderAttendanceRoster(w, r, schoolID, fmt.Sprintf("/staff/school/%d/attendance", schoolID), fmt.Sprintf("/staff/school/%d", schoolID))
+}
+
+func (s *Server) staffMarkSchoolAttendance(w http.ResponseWriter, r *http.Request) {
+	schoolID, ok := s.staffAttendanceSchool(w, r)
+	if !ok {
+		return
+	}
+
+	s.saveAttendanceRoster(w, r, schoolID, fmt.Sprintf("/staff/school/%d/attendance", schoolID))
+}
@@ -0,0 +1,448 @@
+package bulk_domains
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"geevly/gen/go/eda"
+	"geevly/internal/bulk_upload"
+	"geevly/internal/file"
+	"geevly/internal/student"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	attendanceWorkerPoolSize = 50
+)
+
+type AttendanceRow struct {
+	LRN    string
+	Date   time.Time
+	Status eda.Student_Attendance_Status
+	Reason string
+}
+
+// AttendanceDomain implements BulkUploadDomain for attendance uploads
+type AttendanceDomain struct {
+	services *ServiceRegistry
+}
+
+// NewAttendanceDomain creates a new AttendanceDomain with the provided services
+func NewAttendanceDomain(services *ServiceRegistry) *AttendanceDomain {
+	return &AttendanceDomain{
+		services: services,
+	}
+}
+
+func (d *AttendanceDomain) schoolIsValid(ctx context.Context, schoolID string) bool {
+	schoolIDUint, err := strconv.ParseUint(schoolID, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	err = d.services.SchoolService.ValidateSchoolID(ctx, schoolIDUint)
+	return err == nil
+}
+
+// ValidateFormData validates domain-specific form data
+func (d *AttendanceDomain) ValidateFormData(r *http.Request) (map[string]string, error) {
+	schoolID := r.FormValue("school_id")
+	if schoolID == "" {
+		return nil, fmt.Errorf("Missing required fields")
+	}
+
+	if !d.schoolIsValid(r.Context(), schoolID) {
+		return nil, fmt.Errorf("Invalid school ID")
+	}
+
+	return map[string]string{
+		"school_id": schoolID,
+	}, nil
+}
+
+// UploadFile handles the file upload process
+func (d *AttendanceDomain) UploadFile(r *http.Request, fileSvc *file.Service) (string, error) {
+	if err := r.ParseMultipartForm(d.GetMaxFileSize()); err != nil {
+		return "", fmt.Errorf("parsing form: %w", err)
+	}
+
+	file, _, err := r.FormFile(d.GetFileName())
+	if err != nil {
+		return "", fmt.Errorf("getting file: %w", err)
+	}
+	defer file.Close()
+
+	fileBytes, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	fileID, err := fileSvc.CreateFile(r.Context(), fileBytes, &eda.File_Create{
+		Name:            "bulk_upload_attendance",
+		DomainReference: eda.File_BULK_UPLOAD,
+	})
+	if err != nil {
+		return "", fmt.Errorf("storing file: %w", err)
+	}
+
+	return fileID, nil
+}
+
+// GetDomain returns the EDA domain type
+func (d *AttendanceDomain) GetDomain() eda.BulkUpload_Domain {
+	return eda.BulkUpload_ATTENDANCE
+}
+
+// GetFileName returns the name of the file field in the form
+func (d *AttendanceDomain) GetFileName() string {
+	return "attendance_file"
+}
+
+// GetMaxFileSize returns the maximum file size in bytes
+func (d *AttendanceDomain) GetMaxFileSize() int64 {
+	return 10 * 1024 * 1024 // 10MB
+}
+
+// ValidateUpload validates the uploaded file against business rules
+func (d *AttendanceDomain) ValidateUpload(ctx context.Context, aggregate *bulk_upload.Aggregate, fileBytes []byte) *ValidationResult {
+	result := &ValidationResult{
+		IsValid: true,
+		Errors:  []*eda.BulkUpload_ValidationError{},
+	}
+
+	schoolID := aggregate.GetUploadMetadataField("school_id")
+	if !d.schoolIsValid(ctx, schoolID) {
+		result.Errors = append(result.Errors, &eda.BulkUpload_ValidationError{
+			Context: eda.BulkUpload_ValidationError_METADATA_FIELD,
+			Message: fmt.Sprintf("School with ID %s not found", schoolID),
+		})
+	}
+
+	_, rows, errors := d.parseCSV(fileBytes)
+	for _, err := range errors {
+		result.Errors = append(result.Errors, &eda.BulkUpload_ValidationError{
+			Context: eda.BulkUpload_ValidationError_CSV_DATA,
+			Message: fmt.Sprintf("Error parsing CSV: %v", err),
+		})
+	}
+
+	// a student usually has a row per day, look each LRN up once
+	checked := make(map[string]bool)
+	for rowNum, row := range rows {
+		if _, ok := checked[row.LRN]; ok {
+			continue
+		}
+
+		_, err := d.services.StudentService.GetStudentByStudentAndSchoolID(ctx, row.LRN, schoolID)
+		checked[row.LRN] = err == nil
+		if err != nil {
+			result.Errors = append(result.Errors, &eda.BulkUpload_ValidationError{
+				Context:   eda.BulkUpload_ValidationError_CSV_DATA,
+				Message:   fmt.Sprintf("Student with LRN %s not found for school ID %s", row.LRN, schoolID),
+				RowNumber: uint64(rowNum),
+				Field:     "LRN",
+			})
+		}
+	}
+
+	return result
+}
+
+func (d *AttendanceDomain) parseCSV(data []byte) (header []string, rows []AttendanceRow, errors []error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, []error{fmt.Errorf("reading csv %w", err)}
+	}
+
+	lrnIndex := slices.Index(header, "lrn")
+	dateIndex := slices.Index(header, "date")
+	statusIndex := slices.Index(header, "status")
+
+	if lrnIndex == -1 || dateIndex == -1 || statusIndex == -1 {
+		return nil, nil, []error{fmt.Errorf("missing required columns in CSV")}
+	}
+
+	// the reason is optional, it's kept for absent and excused students
+	reasonIndex := slices.Index(header, "reason")
+
+	rowNum := 0
+	errors = make([]error, 0)
+	seen := make(map[string]int)
+
+	for {
+		rowNum++
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errors = append(errors, fmt.Errorf("reading csv at row %d: %w", rowNum, err))
+			continue
+		}
+
+		lrn := strings.TrimSpace(record[lrnIndex])
+		if lrn == "" {
+			errors = append(errors, fmt.Errorf("missing LRN at row %d", rowNum))
+			continue
+		}
+
+		originalDateValue := record[dateIndex]
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(originalDateValue))
+		if err != nil {
+			errors = append(errors, fmt.Errorf("parsing date at row %d (value: '%s'): %w", rowNum, originalDateValue, err))
+			continue
+		}
+		if date.After(time.Now()) {
+			errors = append(errors, fmt.Errorf("date at row %d (value: '%s') is in the future", rowNum, originalDateValue))
+			continue
+		}
+
+		status, err := parseAttendanceStatus(record[statusIndex])
+		if err != nil {
+			errors = append(errors, fmt.Errorf("parsing status at row %d: %w", rowNum, err))
+			continue
+		}
+
+		key := lrn + "|" + date.Format("2006-01-02")
+		if prev, ok := seen[key]; ok {
+			errors = append(errors, fmt.Errorf("row %d repeats LRN %s on %s from row %d", rowNum, lrn, date.Format("2006-01-02"), prev))
+			continue
+		}
+		seen[key] = rowNum
+
+		var reason string
+		if reasonIndex != -1 {
+			reason = strings.TrimSpace(record[reasonIndex])
+		}
+
+		rows = append(rows, AttendanceRow{
+			LRN:    lrn,
+			Date:   date,
+			Status: status,
+			Reason: reason,
+		})
+	}
+
+	if len(errors) > 0 {
+		return nil, nil, errors
+	}
+
+	return header, rows, nil
+}
+
+// parseAttendanceStatus reads a status cell, the first letter is enough
+func parseAttendanceStatus(value string) (eda.Student_Attendance_Status, error) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "PRESENT", "P":
+		return eda.Student_Attendance_PRESENT, nil
+	case "ABSENT", "A":
+		return eda.Student_Attendance_ABSENT, nil
+	case "EXCUSED", "E":
+		return eda.Student_Attendance_EXCUSED, nil
+	default:
+		return eda.Student_Attendance_UNKNOWN_ATTENDANCE, fmt.Errorf("expected PRESENT, ABSENT or EXCUSED, got '%s'", value)
+	}
+}
+
+// ProcessUpload processes the validated upload. A student's days are recorded in turn so their events
+// don't race each other, students are processed concurrently.
+func (d *AttendanceDomain) ProcessUpload(ctx context.Context, aggregate *bulk_upload.Aggregate, svc *bulk_upload.Service, fileBytes []byte) error {
+	_, rows, errors := d.parseCSV(fileBytes)
+	if len(errors) > 0 {
+		errMsgs := make([]string, len(errors))
+		for i, err := range errors {
+			errMsgs[i] = err.Error()
+		}
+		return fmt.Errorf("errors occurred while parsing CSV: %s", strings.Join(errMsgs, ", "))
+	}
+
+	byLRN := make(map[string][]AttendanceRow)
+	for _, row := range rows {
+		byLRN[row.LRN] = append(byLRN[row.LRN], row)
+	}
+
+	toProcess := make(map[uint64][]*eda.Student_Attendance)
+	toProcessIDs := make([]string, 0)
+	recentlyProcessed := make([]string, 0)
+
+	defer func() {
+		actions := bulk_upload.RecordActions{
+			RecordIds:  recentlyProcessed,
+			RecordType: eda.BulkUpload_STUDENT,
+			Reason:     eda.BulkUpload_RecordAction_PROCESSING,
+		}
+		svc.MarkRecordsAsUpdated(ctx, aggregate.GetID(), actions)
+	}()
+
+	// Phase 1: Fetch all students concurrently
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	semaphore := make(chan struct{}, attendanceWorkerPoolSize)
+
+	schoolID := aggregate.GetUploadMetadataField("school_id")
+
+	for lrn, days := range byLRN {
+		lrn, days := lrn, days // capture loop variables
+
+		semaphore <- struct{}{} // acquire
+		g.Go(func() error {
+			defer func() { <-semaphore }() // release
+
+			student, err := d.services.StudentService.GetStudentByStudentAndSchoolID(gctx, lrn, schoolID)
+			if err != nil {
+				return fmt.Errorf("error getting student by LRN %s: %w", lrn, err)
+			}
+
+			attendance := make([]*eda.Student_Attendance, 0, len(days))
+			for _, day := range days {
+				attendance = append(attendance, &eda.Student_Attendance{
+					Date: &eda.Date{
+						Year:  int32(day.Date.Year()),
+						Month: int32(day.Date.Month()),
+						Day:   int32(day.Date.Day()),
+					},
+					Status:                 day.Status,
+					Reason:                 day.Reason,
+					SchoolId:               schoolID,
+					AssociatedBulkUploadId: aggregate.GetID(),
+				})
+			}
+
+			mu.Lock()
+			toProcessIDs = append(toProcessIDs, student.GetID())
+			toProcess[student.GetIDUint64()] = attendance
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	recordActions := bulk_upload.RecordActions{
+		RecordIds:  toProcessIDs,
+		RecordType: eda.BulkUpload_STUDENT,
+		Reason:     eda.BulkUpload_RecordAction_PROCESSING,
+	}
+
+	if err := svc.AddRecordsToProcess(ctx, aggregate.ID, recordActions); err != nil {
+		return fmt.Errorf("error adding records to process: %w", err)
+	}
+
+	// Phase 2: Record attendance concurrently across students
+	g2, gctx2 := errgroup.WithContext(ctx)
+	semaphore2 := make(chan struct{}, attendanceWorkerPoolSize)
+
+	for id, attendance := range toProcess {
+		id, attendance := id, attendance // capture loop variables
+
+		semaphore2 <- struct{}{} // acquire
+		g2.Go(func() error {
+			defer func() { <-semaphore2 }() // release
+
+			for _, a := range attendance {
+				if err := d.services.StudentService.AddAttendance(gctx2, id, a); err != nil {
+					return fmt.Errorf("error recording attendance for student ID %d: %w", id, err)
+				}
+			}
+
+			mu.Lock()
+			recentlyProcessed = append(recentlyProcessed, fmt.Sprintf("%d", id))
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	if err := g2.Wait(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *AttendanceDomain) UndoUpload(ctx context.Context, aggregate *bulk_upload.Aggregate, svc *bulk_upload.Service) (err error) {
+	recordsUpdated := make([]string, 0)
+	defer func() {
+		action := bulk_upload.RecordActions{
+			RecordIds:  recordsUpdated,
+			RecordType: eda.BulkUpload_STUDENT,
+			Reason:     eda.BulkUpload_RecordAction_INVALIDATED,
+		}
+
+		if err := svc.MarkRecordsAsUndone(context.Background(), aggregate.ID, action); err != nil {
+			err = fmt.Errorf("error marking records as undone: %w", err)
+		}
+	}()
+
+	type studentToUndo struct {
+		id     string
+		idUint uint64
+	}
+	studentsToUndo := make([]studentToUndo, 0)
+
+	for studentID, states := range aggregate.GetRecordStates() {
+		finalState := states.RecordActions[len(states.RecordActions)-1]
+		if finalState.Reason != eda.BulkUpload_RecordAction_PROCESSING {
+			continue
+		}
+
+		studentIDUint, err := strconv.ParseUint(studentID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing student ID %s: %w", studentID, err)
+		}
+
+		studentsToUndo = append(studentsToUndo, studentToUndo{
+			id:     studentID,
+			idUint: studentIDUint,
+		})
+	}
+
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	semaphore := make(chan struct{}, attendanceWorkerPoolSize)
+
+	for _, stud := range studentsToUndo {
+		stud := stud // capture loop variable
+
+		semaphore <- struct{}{} // acquire
+		g.Go(func() error {
+			defer func() { <-semaphore }() // release
+
+			if err := d.services.StudentService.RemoveAttendance(gctx, stud.idUint, aggregate.GetID()); err != nil {
+				// the days may have been marked again since, nothing is left to remove
+				if errors.Is(err, student.ErrAttendanceNotFound) {
+					slog.Error("error removing attendance", "student_id", stud.id, "error", err)
+					return nil
+				}
+				return fmt.Errorf("error removing attendance for student ID %d: %w", stud.idUint, err)
+			}
+
+			mu.Lock()
+			recordsUpdated = append(recordsUpdated, stud.id)
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -65,6 +65,7 @@ func NewDomainRegistry(services *ServiceRegistry) *DomainRegistry {
 	registry.domains[eda.BulkUpload_NEW_STUDENTS] = NewNewStudentsDomain(services)
 	registry.domains[eda.BulkUpload_GRADES] = NewGradesDomain(services)
 	registry.domains[eda.BulkUpload_HEALTH_ASSESSMENT] = NewHealthAsssementDomain(services)
+	registry.domains[eda.BulkUpload_ATTENDANCE] = NewAttendanceDomain(services)
 
 	return registry
 }
@@ -9,6 +9,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -54,12 +55,52 @@ func (d *GradesDomain) ValidateFormData(r *http.Request) (map[string]string, err
 	}
 
 	// Return the metadata - deep validation happens in the aggregate
-	return map[string]string{
+	metadata := map[string]string{
 		"school_id":      schoolID,
 		"school_year":    schoolYear,
 		"grading_period": gradingPeriod,
 		"effective_date": effectiveDate,
-	}, nil
+		"grading_scale":  r.FormValue("grading_scale"),
+		"max_score":      r.FormValue("max_score"),
+		"passing_score":  r.FormValue("passing_score"),
+	}
+
+	if _, err := gradingScaleFromMetadata(metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// gradingScaleFromMetadata reads the school's grading scale from the upload, fields left empty fall back
+// to the default scale
+func gradingScaleFromMetadata(metadata map[string]string) (*eda.Student_GradeReport_GradingScale, error) {
+	scale := student.DefaultGradingScale()
+	if name := strings.TrimSpace(metadata["grading_scale"]); name != "" {
+		scale.Name = name
+	}
+
+	if v := strings.TrimSpace(metadata["max_score"]); v != "" {
+		maxScore, err := strconv.ParseFloat(v, 32)
+		if err != nil || maxScore <= 0 {
+			return nil, fmt.Errorf("invalid maximum score: %s", v)
+		}
+		scale.MaxScore = float32(maxScore)
+	}
+
+	if v := strings.TrimSpace(metadata["passing_score"]); v != "" {
+		passing, err := strconv.ParseFloat(v, 32)
+		if err != nil || passing < 0 {
+			return nil, fmt.Errorf("invalid passing score: %s", v)
+		}
+		scale.PassingScore = float32(passing)
+	}
+
+	if scale.PassingScore > scale.MaxScore {
+		return nil, fmt.Errorf("passing score %g is above the maximum score %g", scale.PassingScore, scale.MaxScore)
+	}
+
+	return scale, nil
 }
 
 func (d *GradesDomain) validateSchoolID(ctx context.Context, schoolID string) error {
@@ -75,43 +116,112 @@ func (d *GradesDomain) validateSchoolID(ctx context.Context, schoolID string) er
 	return nil
 }
 
+// gradeColumns are the columns that aren't a subject, any other column of the CSV is a subject score
+var gradeColumns = map[string]bool{"LRN": true, "Grade": true}
+
 func (d *GradesDomain) validateHeaders(_ context.Context, firstRow []string) error {
-	requiredColumns := []string{"LRN", "Grade"}
+	requiredColumns := []string{"LRN"}
 	missingColumns := validateCSVHeaders(firstRow, requiredColumns)
 
 	if len(missingColumns) > 0 {
 		return fmt.Errorf("missing required columns: %v", missingColumns)
 	}
 
+	if len(subjectColumns(firstRow)) == 0 && !slices.Contains(firstRow, "Grade") {
+		return fmt.Errorf("a Grade column or at least one subject column is required")
+	}
+
 	return nil
 }
 
+// subjectColumns returns the index of each subject column by subject name
+func subjectColumns(header []string) map[int]string {
+	subjects := make(map[int]string)
+	for i, col := range header {
+		col = strings.TrimSpace(col)
+		if col != "" && !gradeColumns[col] {
+			subjects[i] = col
+		}
+	}
+	return subjects
+}
+
 // GradeRow represents a single row in the grades CSV file
 type GradeRow struct {
-	LRN   string
-	Grade string
+	LRN      string
+	Grade    string // the overall average, optional when the row has subject scores
+	Subjects []SubjectCell
+}
+
+// SubjectCell is the score of one subject column, empty when the student doesn't take the subject
+type SubjectCell struct {
+	Subject string
+	Score   string
 }
 
-func (row *GradeRow) Validate() error {
+func (row *GradeRow) Validate(scale *eda.Student_GradeReport_GradingScale) error {
 	if row.LRN == "" {
 		return errors.New("LRN is required")
 	}
-	if row.Grade == "" {
-		return errors.New("Grade is required")
+
+	if _, err := row.OverallAverage(scale); err != nil {
+		return err
 	}
-	grade, err := row.GradeInt()
+
+	subjects, err := row.SubjectScores(scale)
 	if err != nil {
-		return errors.New("Grade must be a number")
+		return err
 	}
-	if grade < 0 || grade >= 100 {
-		return errors.New("Grade must be between 0 and 100")
+
+	if row.Grade == "" && len(subjects) == 0 {
+		return errors.New("Grade or at least one subject score is required")
 	}
+
 	return nil
 }
 
-// GradeInt returns the grade as an integer
-func (row *GradeRow) GradeInt() (int, error) {
-	return strconv.Atoi(row.Grade)
+// OverallAverage returns the Grade column, nil when it's empty
+func (row *GradeRow) OverallAverage(scale *eda.Student_GradeReport_GradingScale) (*float32, error) {
+	if row.Grade == "" {
+		return nil, nil
+	}
+
+	score, err := parseScore(row.Grade, scale)
+	if err != nil {
+		return nil, fmt.Errorf("Grade %w", err)
+	}
+
+	return &score, nil
+}
+
+// SubjectScores returns the scores of the subjects the student has a score for
+func (row *GradeRow) SubjectScores(scale *eda.Student_GradeReport_GradingScale) ([]*eda.Student_GradeReport_SubjectScore, error) {
+	scores := make([]*eda.Student_GradeReport_SubjectScore, 0, len(row.Subjects))
+	for _, cell := range row.Subjects {
+		if cell.Score == "" {
+			continue
+		}
+
+		score, err := parseScore(cell.Score, scale)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", cell.Subject, err)
+		}
+
+		scores = append(scores, &eda.Student_GradeReport_SubjectScore{Subject: cell.Subject, Score: score})
+	}
+
+	return scores, nil
+}
+
+func parseScore(value string, scale *eda.Student_GradeReport_GradingScale) (float32, error) {
+	score, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return 0, errors.New("must be a number")
+	}
+	if score < 0 || float32(score) > scale.MaxScore {
+		return 0, fmt.Errorf("must be between 0 and %g", scale.MaxScore)
+	}
+	return float32(score), nil
 }
 
 // parseCSV parses the CSV file bytes and returns rows as GradeRow structs
@@ -126,18 +236,12 @@ func (d *GradesDomain) parseCSV(fileBytes []byte) (header []string, rows []Grade
 	}
 
 	// Find column indexes
-	lrnIndex := -1
-	gradeIndex := -1
-	for i, col := range header {
-		if col == "LRN" {
-			lrnIndex = i
-		} else if col == "Grade" {
-			gradeIndex = i
-		}
-	}
+	lrnIndex := slices.Index(header, "LRN")
+	gradeIndex := slices.Index(header, "Grade")
+	subjects := subjectColumns(header)
 
-	if lrnIndex == -1 || gradeIndex == -1 {
-		return header, nil, fmt.Errorf("required columns not found: LRN and/or Grade")
+	if lrnIndex == -1 {
+		return header, nil, fmt.Errorf("required column not found: LRN")
 	}
 
 	// Read all data rows
@@ -146,16 +250,23 @@ func (d *GradesDomain) parseCSV(fileBytes []byte) (header []string, rows []Grade
 		return header, nil, fmt.Errorf("failed to read CSV data: %w", err)
 	}
 
-	// Convert rows to structs
+	// Convert rows to structs, subjects are kept in column order
 	rows = make([]GradeRow, 0, len(dataRows))
 	for _, row := range dataRows {
-		if len(row) <= max(lrnIndex, gradeIndex) {
+		if len(row) <= lrnIndex {
 			continue // Skip rows that don't have enough columns
 		}
 
-		gradeRow := GradeRow{
-			LRN:   row[lrnIndex],
-			Grade: row[gradeIndex],
+		gradeRow := GradeRow{LRN: strings.TrimSpace(row[lrnIndex])}
+		if gradeIndex != -1 && gradeIndex < len(row) {
+			gradeRow.Grade = strings.TrimSpace(row[gradeIndex])
+		}
+		for i := range header {
+			subject, ok := subjects[i]
+			if !ok || i >= len(row) {
+				continue
+			}
+			gradeRow.Subjects = append(gradeRow.Subjects, SubjectCell{Subject: subject, Score: strings.TrimSpace(row[i])})
 		}
 		rows = append(rows, gradeRow)
 	}
@@ -202,6 +313,17 @@ func (d *GradesDomain) ValidateUpload(ctx context.Context, aggregate *bulk_uploa
 		}
 	}
 
+	scale, err := gradingScaleFromMetadata(metadata)
+	if err != nil {
+		result.IsValid = false
+		result.Errors = append(result.Errors, &eda.BulkUpload_ValidationError{
+			Context: eda.BulkUpload_ValidationError_METADATA_FIELD,
+			Field:   "grading_scale",
+			Message: err.Error(),
+		})
+		return result
+	}
+
 	// Track LRNs to check for duplicates
 	lrnMap := make(map[string]int)
 
@@ -211,14 +333,13 @@ func (d *GradesDomain) ValidateUpload(ctx context.Context, aggregate *bulk_uploa
 
 		// Check for missing data
 		lrn := row.LRN
-		grade := row.Grade
 
-		if lrn == "" || grade == "" {
+		if lrn == "" {
 			result.IsValid = false
 			result.Errors = append(result.Errors, &eda.BulkUpload_ValidationError{
 				Context:   eda.BulkUpload_ValidationError_ROW_NUMBER,
 				RowNumber: uint64(rowNum),
-				Message:   "Row is missing required data (LRN or Grade)",
+				Message:   "Row is missing required data (LRN)",
 			})
 			continue
 		}
@@ -235,14 +356,14 @@ func (d *GradesDomain) ValidateUpload(ctx context.Context, aggregate *bulk_uploa
 			lrnMap[lrn] = rowNum
 		}
 
-		// Validate grade format (numeric, 0-100)
-		if err := row.Validate(); err != nil {
+		// Validate the scores against the grading scale
+		if err := row.Validate(scale); err != nil {
 			result.IsValid = false
 			result.Errors = append(result.Errors, &eda.BulkUpload_ValidationError{
 				Context:   eda.BulkUpload_ValidationError_ROW_NUMBER,
 				RowNumber: uint64(rowNum),
 				Field:     "Grade",
-				Message:   fmt.Sprintf("Invalid LRN or grade value: %s (must be a number between 0 and 100)", grade),
+				Message:   fmt.Sprintf("Invalid grades for LRN %s: %s", lrn, err.Error()),
 			})
 		}
 
@@ -341,11 +462,17 @@ func (d *GradesDomain) ProcessUpload(ctx context.Context, aggregate *bulk_upload
 		return fmt.Errorf("invalid effective date: %s", err.Error())
 	}
 
+	scale, err := gradingScaleFromMetadata(metadata)
+	if err != nil {
+		return fmt.Errorf("invalid grading scale: %w", err)
+	}
+
 	// Track processed records
 	type studentGrade struct {
-		studentID    uint64
-		studentIDStr string
-		gradeVal     int
+		studentID      uint64
+		studentIDStr   string
+		overallAverage *float32
+		subjects       []*eda.Student_GradeReport_SubjectScore
 	}
 	toProcess := make([]studentGrade, 0)
 	toProcessIDs := make([]string, 0)
@@ -373,11 +500,15 @@ func (d *GradesDomain) ProcessUpload(ctx context.Context, aggregate *bulk_upload
 		g.Go(func() error {
 			defer func() { <-semaphore }() // release
 
-			// Parse the grade value
-			gradeVal, err := row.GradeInt()
+			// Parse the scores
+			overallAverage, err := row.OverallAverage(scale)
 			if err != nil {
 				return fmt.Errorf("failed to parse grade value: %w", err)
 			}
+			subjects, err := row.SubjectScores(scale)
+			if err != nil {
+				return fmt.Errorf("failed to parse subject scores: %w", err)
+			}
 
 			// Find the student by LRN and school ID
 			student, err := d.services.StudentService.GetStudentByStudentAndSchoolID(gctx, row.LRN, schoolID)
@@ -388,9 +519,10 @@ func (d *GradesDomain) ProcessUpload(ctx context.Context, aggregate *bulk_upload
 			mu.Lock()
 			toProcessIDs = append(toProcessIDs, student.GetID())
 			toProcess = append(toProcess, studentGrade{
-				studentID:    student.GetIDUint64(),
-				studentIDStr: student.GetID(),
-				gradeVal:     gradeVal,
+				studentID:      student.GetIDUint64(),
+				studentIDStr:   student.GetID(),
+				overallAverage: overallAverage,
+				subjects:       subjects,
 			})
 			mu.Unlock()
 
@@ -426,7 +558,9 @@ func (d *GradesDomain) ProcessUpload(ctx context.Context, aggregate *bulk_upload
 
 			// Create a grade update command based on your actual data model
 			gradeCmd := &eda.Student_GradeReport{
-				Grade: int32(sg.gradeVal),
+				OverallAverage: sg.overallAverage,
+				Subjects:       sg.subjects,
+				GradingScale:   scale,
 				TestDate: &eda.Date{
 					Year:  int32(effectiveDateParsed.Year()),
 					Month: int32(effectiveDateParsed.Month()),
@@ -31,6 +31,8 @@ type HealthAssessmentRow struct {
 	HeightCM      float64
 	WeightKG      float64
 	AssesmentDate time.Time
+	MuacMM        float64
+	Oedema        bool
 }
 
 // HealthAssessmentDomain implements BulkUploadDomain for grades uploads
@@ -175,6 +177,10 @@ func (d *HealthAssessmentDomain) parseCSV(data []byte) (header []string, rows []
 		return nil, nil, []error{fmt.Errorf("missing required columns in CSV")}
 	}
 
+	// optional columns for children under 5
+	muacIndex := slices.Index(header, "muac_mm")
+	oedemaIndex := slices.Index(header, "oedema")
+
 	rowNum := 0
 	errors = make([]error, 0)
 
@@ -213,11 +219,33 @@ func (d *HealthAssessmentDomain) parseCSV(data []byte) (header []string, rows []
 			continue
 		}
 
+		// MUAC is optional, blank when it wasn't measured
+		var muacMM float64
+		if muacIndex != -1 && strings.TrimSpace(record[muacIndex]) != "" {
+			originalMuacValue := record[muacIndex]
+			muacMM, err = strconv.ParseFloat(strings.TrimSpace(originalMuacValue), 64)
+			if err != nil || muacMM < 0 {
+				errors = append(errors, fmt.Errorf("parsing MUAC at row %d (value: '%s'): expected millimetres", rowNum, originalMuacValue))
+				continue
+			}
+		}
+
+		var oedema bool
+		if oedemaIndex != -1 {
+			oedema, err = parseYesNo(record[oedemaIndex])
+			if err != nil {
+				errors = append(errors, fmt.Errorf("parsing oedema at row %d: %w", rowNum, err))
+				continue
+			}
+		}
+
 		rows = append(rows, HealthAssessmentRow{
 			LRN:           record[lrnIndex],
 			HeightCM:      heightCM,
 			WeightKG:      weightKG,
 			AssesmentDate: assessmentDate,
+			MuacMM:        muacMM,
+			Oedema:        oedema,
 		})
 	}
 
@@ -233,6 +261,18 @@ func (d *HealthAssessmentDomain) parseCSV(data []byte) (header []string, rows []
 	return header, rows, nil
 }
 
+// parseYesNo reads a yes/no cell, blank is no
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "no", "n", "false", "0":
+		return false, nil
+	case "yes", "y", "true", "1":
+		return true, nil
+	default:
+		return false, fmt.Errorf("expected yes or no, got '%s'", value)
+	}
+}
+
 // ProcessUpload processes the validated upload
 func (d *HealthAssessmentDomain) ProcessUpload(ctx context.Context, aggregate *bulk_upload.Aggregate, svc *bulk_upload.Service, fileBytes []byte) error {
 	_, rows, errors := d.parseCSV(fileBytes)
@@ -280,6 +320,8 @@ func (d *HealthAssessmentDomain) ProcessUpload(ctx context.Context, aggregate *b
 				WeightKg:               float32(row.WeightKG),
 				AssociatedBulkUploadId: aggregate.GetID(),
 				AssessmentDate:         timestamppb.New(row.AssesmentDate),
+				MuacMm:                 float32(row.MuacMM),
+				Oedema:                 row.Oedema,
 			}
 
 			student, err := d.services.StudentService.GetStudentByStudentAndSchoolID(gctx, row.LRN, schoolID)
@@ -141,6 +141,12 @@ type newStudentHeaderIndexes struct {
 	gender      int
 	status      int
 	sponsorship int
+
+	// optional guardian columns, -1 when not present in the upload
+	guardianName         int
+	guardianRelationship int
+	guardianPhone        int
+	guardianConsent      int
 }
 
 func (h *newStudentHeaderIndexes) ValidateHeaders(headers []string) []string {
@@ -182,9 +188,41 @@ func (h *newStudentHeaderIndexes) ValidateHeaders(headers []string) []string {
 		}
 	}
 
+	h.guardianName = slices.Index(headers, "Guardian Name")
+	h.guardianRelationship = slices.Index(headers, "Guardian Relationship")
+	h.guardianPhone = slices.Index(headers, "Guardian Phone")
+	h.guardianConsent = slices.Index(headers, "Guardian Consent")
+
 	return missingHeaders
 }
 
+// optionalValue returns the trimmed value of an optional column, or an empty string when the column is absent
+func (h *newStudentHeaderIndexes) optionalValue(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+
+	return strings.TrimSpace(row[index])
+}
+
+// getGuardian returns the guardian in the row, or nil when no guardian name was provided.
+// Consent covers being contacted by the school, sharing with partners requires explicit consent on the admin page.
+func (h *newStudentHeaderIndexes) getGuardian(row []string) *eda.Student_Guardian {
+	name := h.optionalValue(row, h.guardianName)
+	if name == "" {
+		return nil
+	}
+
+	consent := strings.ToLower(h.optionalValue(row, h.guardianConsent))
+
+	return &eda.Student_Guardian{
+		Name:             name,
+		Relationship:     h.optionalValue(row, h.guardianRelationship),
+		Phone:            h.optionalValue(row, h.guardianPhone),
+		ConsentToContact: consent == "yes" || consent == "y",
+	}
+}
+
 func (h *newStudentHeaderIndexes) getFirstName(row []string) string {
 	return row[h.firstName]
 }
@@ -497,6 +535,16 @@ func (d *NewStudentsDomain) ProcessUpload(ctx context.Context, aggregate *bulk_u
 			return d.errorHandler(logger, err, "when setting active status")
 		}
 
+		if guardian := nsr.headerIndexes.getGuardian(record); guardian != nil {
+			newStudent, err = d.services.StudentService.RunCommand(ctx, newStudent.GetIDUint64(), &eda.Student_AddGuardian{
+				Guardian: guardian,
+				Version:  newStudent.GetVersion(),
+			})
+			if err != nil {
+				return d.errorHandler(logger, err, "when adding guardian")
+			}
+		}
+
 		// set sponsorship status
 		if nsr.headerIndexes.isEligibleForSponsorship(record) {
 			newStudent, err = d.services.StudentService.RunCommand(ctx, newStudent.GetIDUint64(), &eda.Student_SetEligibility{
@@ -603,6 +651,13 @@ func (d *NewStudentsDomain) validateRows(ctx context.Context, newStudentReader *
 			continue
 		}
 
+		if guardian := newStudentReader.headerIndexes.getGuardian(record); guardian != nil {
+			if guardian.ConsentToContact && guardian.Phone == "" {
+				errors = append(errors, fmt.Errorf("guardian consents to contact but has no phone number for student with LRN %s", lrn))
+				continue
+			}
+		}
+
 		// TODO: right now we validate a student by the school+student ID, are student id's universally unique?
 
 		photoFileName := newStudentReader.getStudentPhotoPath(lrn)
@@ -0,0 +1,51 @@
+package webapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+
+	"geevly/internal/infrastructure"
+	"geevly/internal/student"
+)
+
+// requestActor is who the request runs commands as: the API key, or the session user with the roles
+// AddRolesToContext found, anonymous when neither is present
+func (s *Server) requestActor(r *http.Request) infrastructure.Actor {
+	if _, ok := r.Context().Value(apiScopesKey{}).([]string); ok {
+		return infrastructure.Actor{ID: "api-key", Roles: []string{infrastructure.RoleAPI}, Source: "api"}
+	}
+
+	actor := infrastructure.Actor{Source: "web"}
+	actor.ID, _ = s.getSessionUserID(r)
+
+	roles, _ := r.Context().Value("roles").(Roles)
+	if roles.Admin {
+		actor.Roles = append(actor.Roles, infrastructure.RoleAdmin)
+	}
+	if roles.IsFeeder {
+		actor.Roles = append(actor.Roles, infrastructure.RoleFeeder)
+	}
+
+	return actor
+}
+
+// commandContext is the request's context carrying its actor
+func (s *Server) commandContext(r *http.Request) context.Context {
+	return infrastructure.WithActor(r.Context(), s.requestActor(r))
+}
+
+// dispatch runs a command through the bus as the request's actor
+func (s *Server) dispatch(r *http.Request, aggregateID string, cmd proto.Message) (any, error) {
+	return s.Services.Commands.Dispatch(s.commandContext(r), infrastructure.Command{AggregateID: aggregateID, Message: cmd})
+}
+
+// studentCommand runs a command on the student as the request's actor and returns the updated student
+func (s *Server) studentCommand(r *http.Request, studentID uint64, cmd proto.Message) (*student.Aggregate, error) {
+	return infrastructure.DispatchAs[*student.Aggregate](s.commandContext(r), s.Services.Commands, infrastructure.Command{
+		AggregateID: strconv.FormatUint(studentID, 10),
+		Message:     cmd,
+	})
+}
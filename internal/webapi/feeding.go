@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"geevly/gen/go/eda"
+	"geevly/internal/infrastructure"
 	"geevly/internal/student"
 	"geevly/internal/webapi/feeding"
 	feedingtempl "geevly/internal/webapi/templates/feeding"
@@ -57,7 +58,6 @@ func (s *Server) feedingProofUpload(w http.ResponseWriter, r *http.Request) {
 	studID := *vex.ReturnUint64(ex, "student_id")
 	base64Photo := *vex.ReturnString(ex, "base64_photo")
 	studVer := *vex.ReturnUint64(ex, "version")
-	ctx := r.Context()
 
 	if err := ex.Errors(); err != nil {
 		s.errorPage(w, r, "Error parsing form", ex.JoinedErrors())
@@ -70,19 +70,26 @@ func (s *Server) feedingProofUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fileID, err := s.Services.FileSvc.CreateFile(ctx, photo, &eda.File_Create{
-		Name:            "feeding_proof",
-		DomainReference: eda.File_FEEDING_HISTORY,
+	fileID, err := infrastructure.DispatchAs[string](s.commandContext(r), s.Services.Commands, infrastructure.Command{
+		Message: &eda.File_Create{
+			Name:            "feeding_proof",
+			DomainReference: eda.File_FEEDING_HISTORY,
+		},
+		Attachment: photo,
 	})
 	if err != nil {
 		s.errorPage(w, r, "Error saving photo", err)
 		return
 	}
 
-	agg, err := s.Services.StudentSvc.RunCommand(r.Context(), studID, &eda.Student_Feeding{
+	// feeding isn't behind sign-in, a feeding without a session is recorded without a feeder
+	fedBy, _ := s.getSessionUserID(r)
+
+	agg, err := s.studentCommand(r, studID, &eda.Student_Feeding{
 		UnixTimestamp: uint64(time.Now().Unix()),
 		FileId:        fileID,
 		Version:       studVer,
+		FedBy:         fedBy,
 	})
 
 	if err != nil {
@@ -153,7 +160,7 @@ func (s *Server) feedingConfirm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	agg, err := s.Services.StudentSvc.RunCommand(r.Context(), studID, &eda.Student_Feeding{
+	agg, err := s.studentCommand(r, studID, &eda.Student_Feeding{
 		UnixTimestamp: uint64(time.Now().Unix()),
 		Version:       studVer,
 	})
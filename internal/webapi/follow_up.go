@@ -0,0 +1,86 @@
+package webapi
+
+import (
+	"fmt"
+	"geevly/internal/student"
+	"geevly/internal/webapi/templates/layouts"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// staffFollowUp reads the follow-up task from the URL, staff may only work the tasks of the schools
+// they're enrolled to feed
+func (s *Server) staffFollowUp(w http.ResponseWriter, r *http.Request) (*student.FollowUpTask, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.errorPage(w, r, "Error parsing follow-up ID", err)
+		return nil, false
+	}
+
+	task, err := s.Services.StudentSvc.GetFollowUp(r.Context(), id)
+	if err != nil {
+		s.errorPage(w, r, "Error fetching follow-up", err)
+		return nil, false
+	}
+
+	feederEnrollments, err := s.getFeederEnrollments(r)
+	if err != nil {
+		s.errorPage(w, r, "Error fetching feeder enrollments", err)
+		return nil, false
+	}
+
+	schoolID, err := strconv.ParseUint(task.SchoolID, 10, 64)
+	if err != nil || !slices.Contains(feederEnrollments, schoolID) {
+		s.errorPage(w, r, "Not assigned to this school", fmt.Errorf("user isn't a feeder at school %s", task.SchoolID))
+		return nil, false
+	}
+
+	return task, true
+}
+
+func (s *Server) staffSetFollowUpStatus(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.staffFollowUp(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := s.getSessionUserID(r)
+	if err != nil {
+		s.errorPage(w, r, "Error getting user", err)
+		return
+	}
+
+	status := student.FollowUpStatus(r.FormValue("status"))
+	if err := s.Services.StudentSvc.SetFollowUpStatus(r.Context(), task.ID, status, userID); err != nil {
+		s.errorPage(w, r, "Error updating follow-up", err)
+		return
+	}
+
+	msg := fmt.Sprintf("Follow-up for %s %s is now %s", task.FirstName, task.LastName, strings.ToLower(status.String()))
+	s.renderTempl(w, r, layouts.HTMXRedirect("/staff", msg))
+}
+
+func (s *Server) staffAddFollowUpNote(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.staffFollowUp(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := s.getSessionUserID(r)
+	if err != nil {
+		s.errorPage(w, r, "Error getting user", err)
+		return
+	}
+
+	note := strings.TrimSpace(r.FormValue("note"))
+	if err := s.Services.StudentSvc.AddFollowUpNote(r.Context(), task.ID, note, userID); err != nil {
+		s.errorPage(w, r, "Error adding note", err)
+		return
+	}
+
+	s.renderTempl(w, r, layouts.HTMXRedirect("/staff", "Note added"))
+}
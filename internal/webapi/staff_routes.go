@@ -10,12 +10,17 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"geevly/internal/student"
 	stafftempl "geevly/internal/webapi/templates/staff"
 )
 
 func (s *Server) staffRoutes(r chi.Router) {
 	r.Get("/", s.staffHome)
 	r.Get("/school/{schoolID}", s.staffSchoolStudents)
+	r.Get("/school/{schoolID}/attendance", s.staffSchoolAttendance)
+	r.Post("/school/{schoolID}/attendance", s.staffMarkSchoolAttendance)
+	r.Post("/follow-up/{id}/status", s.staffSetFollowUpStatus)
+	r.Post("/follow-up/{id}/note", s.staffAddFollowUpNote)
 }
 
 func (s *Server) getFeederEnrollments(r *http.Request) ([]uint64, error) {
@@ -64,13 +69,24 @@ func (s *Server) staffHome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// if there is only one school, redirect to the school students page
-	if len(schools) == 1 {
+	schoolIDs := make([]string, 0, len(feederEnrollments))
+	for _, id := range feederEnrollments {
+		schoolIDs = append(schoolIDs, strconv.FormatUint(id, 10))
+	}
+
+	followUps, err := s.Services.StudentSvc.ListFollowUps(r.Context(), student.FollowUpFilter{SchoolIDs: schoolIDs})
+	if err != nil {
+		s.errorPage(w, r, "Error fetching follow-ups", err)
+		return
+	}
+
+	// if there is only one school and nothing to follow up, redirect to the school students page
+	if len(schools) == 1 && len(followUps) == 0 {
 		http.Redirect(w, r, fmt.Sprintf("/staff/school/%d", schools[0].ID), http.StatusSeeOther)
 		return
 	}
 
-	s.renderTempl(w, r, stafftempl.Home(schools))
+	s.renderTempl(w, r, stafftempl.Home(schools, followUps))
 }
 
 func (s *Server) staffSchoolStudents(w http.ResponseWriter, r *http.Request) {
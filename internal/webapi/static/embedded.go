@@ -125,9 +125,9 @@ func createNewStudentsZipTemplate() ([]byte, error) {
 func getHealthAssessmentTemplate(templateType BulkTemplateType) (BulkTemplateInfo, error) {
 	switch templateType {
 	case CSV:
-		data := []byte(`lrn,height_cm,weight_kg,assessment_date
-"ST001",120.5,23.4,"2023-10-15"
-"ST002",115.0,21.0,"2023-10-15"`)
+		data := []byte(`lrn,height_cm,weight_kg,assessment_date,muac_mm,oedema
+"ST001",120.5,23.4,"2023-10-15",,
+"ST002",98.0,14.2,"2023-10-15",132,"no"`)
 
 		return BulkTemplateInfo{
 			Filename:    "health_assessment_template.csv",
@@ -143,10 +143,10 @@ func getHealthAssessmentTemplate(templateType BulkTemplateType) (BulkTemplateInf
 func getGradesTemplate(templateType BulkTemplateType) (BulkTemplateInfo, error) {
 	switch templateType {
 	case CSV:
-		data := []byte(`LRN,Grade
-"12345678",92
-"23456789",88
-"34567890",90`)
+		data := []byte(`LRN,Grade,Math,English,Science,Filipino
+"12345678",,92,90,88,94
+"23456789",88,85,91,,89
+"34567890",90,,,,`)
 
 		return BulkTemplateInfo{
 			Filename:    "grades_template.csv",
@@ -162,10 +162,10 @@ func getGradesTemplate(templateType BulkTemplateType) (BulkTemplateInfo, error)
 func getAttendanceTemplate(templateType BulkTemplateType) (BulkTemplateInfo, error) {
 	switch templateType {
 	case CSV:
-		data := []byte(`student_id,date,status,reason
-"ST001","2023-10-01","PRESENT",""
-"ST001","2023-10-02","ABSENT","Sick"
-"ST001","2023-10-03","PRESENT",""`)
+		data := []byte(`lrn,date,status,reason
+"ST001","2023-10-02","PRESENT",""
+"ST001","2023-10-03","ABSENT","Sick"
+"ST002","2023-10-02","EXCUSED","Family event"`)
 
 		return BulkTemplateInfo{
 			Filename:    "attendance_template.csv",
@@ -3,9 +3,11 @@ package webapi
 import (
 	"fmt"
 	"geevly/gen/go/eda"
+	"strconv"
 	"time"
 
 	vex "github.com/Howard3/valueextractor"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func AsProtoDate(ref *eda.Date) vex.Converter {
@@ -26,3 +28,22 @@ func ReturnProtoDate(ec *vex.Extractor, key string) *eda.Date {
 	ec.With(key, AsProtoDate(&date))
 	return &date
 }
+
+func AsFloat64(ref *float64) vex.Converter {
+	return func(ec *vex.Extractor, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number")
+		}
+
+		*ref = f
+
+		return nil
+	}
+}
+
+// ReturnProtoTimestamp reads a date field as a timestamp at midnight UTC
+func ReturnProtoTimestamp(ec *vex.Extractor, key string) *timestamppb.Timestamp {
+	date := ReturnProtoDate(ec, key)
+	return timestamppb.New(time.Date(int(date.Year), time.Month(date.Month), int(date.Day), 0, 0, 0, 0, time.UTC))
+}
@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
+	"strconv"
+	"time"
 
-	"github.com/Howard3/gosignal/drivers/queue"
+	"github.com/Howard3/gosignal"
 	"github.com/clerkinc/clerk-sdk-go/clerk"
+	"github.com/nats-io/nats.go"
 
 	"geevly/internal/bulk_upload"
 	"geevly/internal/file"
@@ -27,11 +32,145 @@ func getStaticFS() fs.FS {
 	return os.DirFS("./static")
 }
 
+// nutritionAlertConfig reads the nutrition alert thresholds, falling back to the defaults when unset
+func nutritionAlertConfig() student.NutritionAlertConfig {
+	cfg := student.DefaultNutritionAlertConfig()
+	if v, err := strconv.ParseFloat(os.Getenv("NUTRITION_ALERT_WEIGHT_LOSS_PERCENT"), 64); err == nil {
+		cfg.WeightLossPercent = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("NUTRITION_ALERT_ZSCORE_DROP"), 64); err == nil {
+		cfg.ZScoreDrop = v
+	}
+	return cfg
+}
+
+// followUpConfig reads when students are flagged for missed meals and how often the scan runs
+func followUpConfig() student.FollowUpConfig {
+	cfg := student.DefaultFollowUpConfig()
+	if v, err := strconv.ParseUint(os.Getenv("FOLLOW_UP_MISSED_DAYS"), 10, 32); err == nil {
+		cfg.MissedDays = uint32(v)
+	}
+	if v, err := time.ParseDuration(os.Getenv("FOLLOW_UP_SCAN_INTERVAL")); err == nil {
+		cfg.ScanInterval = v
+	}
+	return cfg
+}
+
+// projectorInterval reads how often projections are caught up with their event tables
+func projectorInterval() time.Duration {
+	if v, err := time.ParseDuration(os.Getenv("PROJECTOR_INTERVAL")); err == nil && v > 0 {
+		return v
+	}
+	return 30 * time.Second
+}
+
+// dbConnection configures the sqlite connection
+func dbConnection() infrastructure.SQLConnection {
+	return infrastructure.SQLConnection{
+		Type: "libsql",
+		URI:  os.Getenv("DB_URI"),
+	}
+}
+
+// eventQueue returns the queue selected by QUEUE_DRIVER, "sql" (the default) delivers the events straight
+// from the outbox and "nats" relays them through JetStream at NATS_URL
+func eventQueue(ctx context.Context, outbox *infrastructure.SQLQueue) gosignal.Queue {
+	switch driver := os.Getenv("QUEUE_DRIVER"); driver {
+	case "", "sql":
+		return outbox
+	case "nats":
+		nc, err := nats.Connect(os.Getenv("NATS_URL"))
+		if err != nil {
+			panic(fmt.Errorf("error connecting to nats: %w", err))
+		}
+
+		q, err := infrastructure.NewJetStreamQueue(ctx, nc, outbox)
+		if err != nil {
+			panic(fmt.Errorf("error creating jetstream queue: %w", err))
+		}
+		return q
+	default:
+		panic(fmt.Errorf("unknown QUEUE_DRIVER %q", driver))
+	}
+}
+
+// domainRepository is what main needs from each domain's repository to run its read models
+type domainRepository interface {
+	Projections() []infrastructure.Projection
+	EventStream() infrastructure.EventStream
+}
+
+// commandDomain is a domain service that handles commands dispatched through the bus
+type commandDomain interface {
+	RegisterCommands(bus *infrastructure.CommandBus)
+}
+
+// newCommandBus creates the bus the web handlers and the API dispatch commands through, with each domain's
+// commands registered. Its counts are published with expvar as "commands".
+func newCommandBus(domains ...commandDomain) *infrastructure.CommandBus {
+	metrics := infrastructure.NewCommandMetrics()
+	expvar.Publish("commands", metrics)
+
+	bus := infrastructure.NewCommandBus(
+		infrastructure.ActorMiddleware(),
+		metrics.Middleware(),
+		infrastructure.LoggingMiddleware(slog.Default()),
+		infrastructure.AuthorizationMiddleware(),
+		infrastructure.ValidationMiddleware(),
+	)
+
+	for _, domain := range domains {
+		domain.RegisterCommands(bus)
+	}
+
+	return bus
+}
+
+// newProjector creates the projector with every domain's read models registered
+func newProjector(ctx context.Context, db infrastructure.SQLConnection, domains ...domainRepository) *infrastructure.Projector {
+	projector, err := infrastructure.NewProjector(db)
+	if err != nil {
+		panic(fmt.Errorf("error creating projector: %w", err))
+	}
+
+	for _, domain := range domains {
+		if err := projector.Register(ctx, domain.Projections()...); err != nil {
+			panic(fmt.Errorf("error registering projections: %w", err))
+		}
+	}
+
+	return projector
+}
+
+// startProjector registers every domain's read models, queues the rebuilds migrations asked for and starts
+// the projector in the background
+func startProjector(ctx context.Context, db infrastructure.SQLConnection, studentRepo student.Repository, domains ...domainRepository) *infrastructure.Projector {
+	projector := newProjector(ctx, db, domains...)
+
+	requested, err := studentRepo.TakeRequestedRebuilds(ctx)
+	if err != nil {
+		panic(fmt.Errorf("error reading requested rebuilds: %w", err))
+	}
+
+	for _, name := range requested {
+		if err := projector.RequestRebuild(ctx, name); err != nil {
+			slog.Error("failed to request projection rebuild", "projection", name, "error", err)
+		}
+	}
+
+	go projector.Run(ctx, projectorInterval())
+
+	return projector
+}
+
 func main() {
 	_ = godotenv.Load()
 	ctx := context.Background()
 
-	mq := queue.MemoryQueue{} // TODO: swap to nats
+	if len(os.Args) > 1 && os.Args[1] == "verify-events" {
+		os.Exit(verifyEvents(ctx, os.Args[2:]))
+	}
+
 	s3 := infrastructure.S3Storage{
 		Endpoint:     os.Getenv("S3_ENDPOINT"),
 		AccessKey:    os.Getenv("S3_ACCESS_KEY"),
@@ -45,27 +184,49 @@ func main() {
 		panic(fmt.Errorf("error creating clerk client: %w", err))
 	}
 
-	// configure a sqlite connection
-	db := infrastructure.SQLConnection{
-		Type: "libsql",
-		URI:  os.Getenv("DB_URI"),
+	db := dbConnection()
+
+	outbox, err := infrastructure.NewSQLQueue(db, "file_events", "school_events", "student_events", "bulk_upload_events")
+	if err != nil {
+		panic(fmt.Errorf("error creating queue: %w", err))
 	}
 
-	fileRepo := file.NewRepository(db, &mq)
+	mq := eventQueue(ctx, outbox)
+
+	fileRepo := file.NewRepository(db, mq)
 	fileService := file.NewService(fileRepo, &s3)
 
-	schoolRepo := school.NewRepository(db, &mq)
+	schoolRepo := school.NewRepository(db, mq)
 	schoolService := school.NewService(schoolRepo)
 
 	studentACL := webapi.NewAclStudents(schoolService, fileService)
 
-	studentRepo := student.NewRepository(db, &mq)
-	studentService := student.NewStudentService(studentRepo, studentACL)
+	studentRepo := student.NewRepository(db, mq)
+	studentService := student.NewStudentService(studentRepo, studentACL,
+		student.WithNutritionAlertConfig(nutritionAlertConfig()),
+		student.WithFollowUpConfig(followUpConfig()),
+	)
+	go studentService.RunFollowUpScans(ctx)
+
+	if err := fileService.ConsumeEvents(ctx, mq); err != nil {
+		panic(fmt.Errorf("error consuming file events: %w", err))
+	}
+
+	if err := studentService.ConsumeEvents(ctx, mq); err != nil {
+		panic(fmt.Errorf("error consuming student events: %w", err))
+	}
 
 	bulkUploadACL := webapi.NewBulkUploadACL(fileService)
-	bulkUploadRepo := bulk_upload.NewRepository(db, &mq)
+	bulkUploadRepo := bulk_upload.NewRepository(db, mq)
 	bulkUploadService := bulk_upload.NewService(bulkUploadRepo, bulkUploadACL)
 
-	server := webapi.NewServer(":3000", getStaticFS(), studentService, schoolService, fileService, bulkUploadService, clerkClient)
+	projector := startProjector(ctx, db, studentRepo, fileRepo, schoolRepo, studentRepo, bulkUploadRepo)
+
+	schoolBudgetACL := webapi.NewSchoolBudgetACL(studentService)
+	schoolBudgetService := school.NewBudgetService(schoolRepo, schoolBudgetACL)
+
+	commands := newCommandBus(studentService, schoolService, fileService, bulkUploadService)
+
+	server := webapi.NewServer(":3000", getStaticFS(), studentService, schoolService, fileService, bulkUploadService, schoolBudgetService, commands, projector, clerkClient)
 	server.Start(ctx)
 }
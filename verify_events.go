@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"geevly/internal/bulk_upload"
+	"geevly/internal/file"
+	"geevly/internal/infrastructure"
+	"geevly/internal/school"
+	"geevly/internal/student"
+)
+
+// verifyEvents runs the event store integrity check, "run-app verify-events [-repair] [-json]". It exits
+// with 1 when issues remain and 2 when the check couldn't run.
+func verifyEvents(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("verify-events", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "delete snapshots that don't match their events and rebuild drifted projections")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	_ = flags.Parse(args)
+
+	db := dbConnection()
+
+	// the queue is only there for the repositories, nothing subscribes to it while the check runs
+	outbox, err := infrastructure.NewSQLQueue(db, "file_events", "school_events", "student_events", "bulk_upload_events")
+	if err != nil {
+		slog.Error("failed to create queue", "error", err)
+		return 2
+	}
+
+	domains := []domainRepository{
+		file.NewRepository(db, outbox),
+		school.NewRepository(db, outbox),
+		student.NewRepository(db, outbox),
+		bulk_upload.NewRepository(db, outbox),
+	}
+
+	streams := make([]infrastructure.EventStream, 0, len(domains))
+	for _, domain := range domains {
+		streams = append(streams, domain.EventStream())
+	}
+
+	checker, err := infrastructure.NewIntegrityChecker(db, newProjector(ctx, db, domains...), streams...)
+	if err != nil {
+		slog.Error("failed to create integrity checker", "error", err)
+		return 2
+	}
+	checker.Repair = *repair
+
+	report, err := checker.Check(ctx)
+	if err != nil {
+		slog.Error("integrity check failed", "error", err)
+		return 2
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			slog.Error("failed to write report", "error", err)
+			return 2
+		}
+	} else {
+		printIntegrityReport(report)
+	}
+
+	if len(report.Unrepaired()) > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func printIntegrityReport(report infrastructure.IntegrityReport) {
+	fmt.Printf("checked %d events of %d aggregates, %d snapshots and %d projections in %s\n",
+		report.Events, report.Aggregates, report.Snapshots, report.Projections,
+		report.FinishedAt.Sub(report.StartedAt).Round(time.Millisecond))
+
+	if len(report.Issues) == 0 {
+		fmt.Println("no issues found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tTABLE\tAGGREGATE\tVERSION\tEVENT\tPROJECTION\tREPAIRED\tDETAIL")
+	for _, issue := range report.Issues {
+		version := "-"
+		if issue.AggregateID != "" {
+			version = fmt.Sprint(issue.Version)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%t\t%s\n", issue.Kind, issue.EventTable, dash(issue.AggregateID),
+			version, dash(issue.EventType), dash(issue.Projection), issue.Repaired, issue.Detail)
+	}
+	w.Flush()
+
+	fmt.Printf("%d issues, %d repaired\n", len(report.Issues), len(report.Issues)-len(report.Unrepaired()))
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}